@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sangkips/revenue-system/internal/db"
+	"github.com/sangkips/revenue-system/internal/domain/payments"
+)
+
+const ledgerUsage = `usage: server ledger <command>
+
+commands:
+  verify [county_id]  check the receipt ledger of one county or of all of them
+  seal                seal unsealed ledger entries into blocks now
+  backfill            add receipts issued before the ledger existed`
+
+// runLedger implements the "ledger" subcommand. Like migrate it only needs
+// DB_URL. verify exits with status 1 when it finds any problem.
+func runLedger(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, ledgerUsage)
+		os.Exit(2)
+	}
+
+	dbURL := os.Getenv("DB_URL")
+	if dbURL == "" {
+		log.Fatal().Msg("DB_URL not set")
+	}
+	sqlDB := db.Connect(dbURL)
+	defer sqlDB.Close()
+	svc := payments.NewLedgerService(sqlDB)
+
+	ctx := context.Background()
+	switch args[0] {
+	case "verify":
+		var countyID int64
+		if len(args) > 1 {
+			var err error
+			if countyID, err = strconv.ParseInt(args[1], 10, 32); err != nil || countyID < 1 {
+				log.Fatal().Str("county_id", args[1]).Msg("verify takes a county ID")
+			}
+		}
+		reports, err := svc.VerifyLedger(ctx, int32(countyID))
+		if err != nil {
+			log.Fatal().Err(err).Msg("Ledger verification failed")
+		}
+		failed := false
+		for _, report := range reports {
+			status := "ok"
+			if !report.OK() {
+				status, failed = fmt.Sprintf("%d problems", len(report.Problems)), true
+			}
+			fmt.Printf("county %d: %d entries, %d blocks: %s\n", report.CountyID, report.Entries, report.Blocks, status)
+			for _, p := range report.Problems {
+				switch {
+				case p.Sequence != 0:
+					fmt.Printf("  entry %d: %s\n", p.Sequence, p.Problem)
+				case p.Block != 0:
+					fmt.Printf("  block %d: %s\n", p.Block, p.Problem)
+				default:
+					fmt.Printf("  %s\n", p.Problem)
+				}
+			}
+		}
+		sqlDB.Close()
+		if failed {
+			os.Exit(1)
+		}
+
+	case "seal":
+		blocks, err := svc.SealLedger(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Int("blocks", len(blocks)).Msg("Sealing failed")
+		}
+		log.Info().Int("blocks", len(blocks)).Msg("Ledger sealed")
+
+	case "backfill":
+		added, err := svc.BackfillLedger(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Int("added", added).Msg("Backfill failed")
+		}
+		log.Info().Int("added", added).Msg("Ledger backfill complete")
+
+	default:
+		fmt.Fprintln(os.Stderr, ledgerUsage)
+		os.Exit(2)
+	}
+}
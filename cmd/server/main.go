@@ -1,14 +1,17 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/sangkips/revenue-system/internal/bankstatement"
 	"github.com/sangkips/revenue-system/internal/config"
 	"github.com/sangkips/revenue-system/internal/db"
 	"github.com/sangkips/revenue-system/internal/domain/assessment"
@@ -17,113 +20,258 @@ import (
 	"github.com/sangkips/revenue-system/internal/domain/revenue"
 	"github.com/sangkips/revenue-system/internal/domain/taxpayers"
 	"github.com/sangkips/revenue-system/internal/domain/user"
+	"github.com/sangkips/revenue-system/internal/filestore"
 	"github.com/sangkips/revenue-system/internal/middleware/auth"
+	"github.com/sangkips/revenue-system/internal/middleware/idempotency"
+	"github.com/sangkips/revenue-system/internal/middleware/ratelimit"
+	"github.com/sangkips/revenue-system/internal/mpesa"
+	"github.com/sangkips/revenue-system/internal/notify"
+	"github.com/sangkips/revenue-system/internal/numbering"
+	"github.com/sangkips/revenue-system/internal/receipt"
 )
 
 func main() {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "ledger" {
+		runLedger(os.Args[2:])
+		return
+	}
+
 	cfg := config.Load()
-	sqlDB := db.ConnectAndMigrate(cfg.DBURL)
+	sqlDB := db.Connect(cfg.DBURL)
+	if cfg.MigrateOnStart {
+		db.Migrate(sqlDB)
+	}
 	defer sqlDB.Close()
 
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 
+	authService := auth.NewAuthServiceWithTaxpayer(
+		user.NewRepository(sqlDB),
+		taxpayers.NewRepository(sqlDB),
+		cfg.JWTSecret,
+		auth.WithSessions(user.NewSessionRepository(sqlDB), cfg.AccessTokenTTL, cfg.RefreshTokenTTL),
+		auth.WithPasswordReset(user.NewPasswordResetRepository(sqlDB), notify.New(cfg.Notifier, cfg.NotifierFile), cfg.PasswordResetTTL, cfg.PasswordResetURL),
+		auth.WithMFA(user.NewMFARepository(sqlDB), cfg.MFAIssuer, cfg.MFARequiredSuperAdmins),
+		auth.WithLoginThrottle(user.NewLoginThrottleRepository(sqlDB), auth.DefaultThrottlePolicy),
+		auth.WithTransactions(db.NewTxManager(sqlDB), func(tx db.DBTX) (auth.Repository, auth.TaxpayerRepository) {
+			return user.NewRepository(tx), taxpayers.NewRepository(tx)
+		}),
+	)
+	authHandler := auth.NewHandler(authService, cfg.JWTSecret)
+
 	usersHandler := user.NewHandler(sqlDB)
 	r.Route("/users", func(r chi.Router) {
-		r.Use(auth.JWTAuth(cfg.JWTSecret))
-		usersHandler.RegisterUserRoutes(r)
+		r.Use(auth.JWTAuth(cfg.JWTSecret, authService))
+
+		// Self-service routes are open to any authenticated account, including taxpayers.
+		authHandler.RegisterAccountRoutes(r)
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireRoles(auth.StaffRoles...))
+			usersHandler.RegisterUserRoutes(r)
+		})
 	})
 
 	countiesHandler := counties.NewHandler(sqlDB)
 	r.Route("/counties", func(r chi.Router) {
-		r.Use(auth.JWTAuth(cfg.JWTSecret))
+		r.Use(auth.JWTAuth(cfg.JWTSecret, authService))
 		countiesHandler.RegisterCountyRoutes(r)
 	})
 
-	taxpayerHandler := taxpayers.NewHandler(sqlDB)
-	r.Route("/taxpayers", func(r chi.Router) {
-		r.Use(auth.JWTAuth(cfg.JWTSecret))
-		taxpayerHandler.RegisterTaxpayerRoutes(r)
-	})
-
 	revenueHandler := revenue.NewHandler(sqlDB)
 	r.Route("/revenues", func(r chi.Router) {
-		r.Use(auth.JWTAuth(cfg.JWTSecret))
+		r.Use(auth.JWTAuth(cfg.JWTSecret, authService))
 		revenueHandler.RegisterRevenueRoutes(r)
 	})
 
-	assessmentHandler := assessment.NewHandler(sqlDB)
+	// Routers that create or move money honour Idempotency-Key on POST.
+	idempotencyStore := idempotency.NewRepository(sqlDB)
+	idempotent := idempotency.Middleware(idempotencyStore, idempotency.DefaultPolicy)
+	// Statement imports are uploads, larger than any other request body.
+	statementPolicy := idempotency.DefaultPolicy
+	statementPolicy.MaxBodyBytes = payments.MaxStatementRequestSize
+	idempotentUploads := idempotency.Middleware(idempotencyStore, statementPolicy)
+
+	numberScheme := numbering.Scheme{
+		Pattern:   cfg.NumberPattern,
+		YearStart: time.Month(cfg.FinancialYearStartMonth),
+		Digits:    cfg.NumberDigits,
+	}
+	if err := numberScheme.Validate(); err != nil {
+		log.Fatal().Err(err).Msg("Invalid NUMBER_PATTERN, FINANCIAL_YEAR_START_MONTH or NUMBER_DIGITS")
+	}
+
+	assessmentHandler := assessment.NewHandler(sqlDB, assessment.WithNumbering(numberScheme))
 	r.Route("/assessments", func(r chi.Router) {
-		r.Use(auth.JWTAuth(cfg.JWTSecret))
+		r.Use(auth.JWTAuth(cfg.JWTSecret, authService))
+		r.Use(idempotent)
 		assessmentHandler.RegisterAssessmentRoutes(r)
 	})
+	r.Route("/tariffs", func(r chi.Router) {
+		r.Use(auth.JWTAuth(cfg.JWTSecret, authService))
+		assessmentHandler.RegisterTariffRoutes(r)
+	})
+
+	allocationStrategy, err := payments.ParseAllocationStrategy(cfg.AllocationStrategy)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid ALLOCATION_STRATEGY")
+	}
+	receiptFiles, err := filestore.NewLocal(cfg.ReceiptStorageDir)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid RECEIPT_STORAGE_DIR")
+	}
+	paymentOpts := []payments.Option{
+		payments.WithMatchTolerance(bankstatement.Tolerance{
+			Amount: cfg.ReconcileAmountTolerance,
+			Days:   cfg.ReconcileDateWindowDays,
+		}),
+		payments.WithAllocationStrategy(allocationStrategy),
+		payments.WithNumbering(numberScheme),
+		payments.WithReceiptPDFs(receipt.NewRenderer(cfg.ReceiptLogoDir), receiptFiles),
+		payments.WithReceiptVerification([]byte(cfg.ReceiptVerifySecret), cfg.PublicURL),
+		payments.WithReceiptDelivery(cfg.NotifyMaxAttempts),
+	}
+	if cfg.MpesaEnv != "" {
+		if cfg.MpesaEnv == "simulator" {
+			simulator := mpesa.NewSimulator(mpesa.SimulatorConfig{
+				ConsumerKey:    cfg.MpesaConsumerKey,
+				ConsumerSecret: cfg.MpesaConsumerSecret,
+				ShortCode:      cfg.MpesaShortCode,
+				Passkey:        cfg.MpesaPasskey,
+				CallbackDelay:  2 * time.Second,
+
+				ValidationURL:   strings.TrimRight(cfg.MpesaCallbackURL, "/") + "/paybill/validation",
+				ConfirmationURL: strings.TrimRight(cfg.MpesaCallbackURL, "/") + "/paybill/confirmation",
+				SigningSecret:   cfg.MpesaC2BSecret,
+			})
+			r.Mount("/simulator/mpesa", http.StripPrefix("/simulator/mpesa", simulator))
+			log.Warn().Msg("M-Pesa is running against the local Daraja simulator")
+		}
+		mpesaClient := mpesa.NewClient(mpesa.Config{
+			BaseURL:        cfg.MpesaBaseURL,
+			ConsumerKey:    cfg.MpesaConsumerKey,
+			ConsumerSecret: cfg.MpesaConsumerSecret,
+			ShortCode:      cfg.MpesaShortCode,
+			Passkey:        cfg.MpesaPasskey,
+			CallbackURL:    strings.TrimRight(cfg.MpesaCallbackURL, "/") + "/mpesa/callback/" + cfg.MpesaCallbackToken,
+		}, nil)
+		paymentOpts = append(paymentOpts, payments.WithMpesa(mpesaClient), payments.WithPaybill(cfg.MpesaShortCode))
+	}
 
-	paymentHandler := payments.NewHandler(sqlDB)
+	paymentHandler := payments.NewHandler(sqlDB, paymentOpts...)
+	taxpayerHandler := taxpayers.NewHandler(sqlDB)
+	r.Route("/taxpayers", func(r chi.Router) {
+		r.Use(auth.JWTAuth(cfg.JWTSecret, authService))
+		r.Use(idempotent)
+		taxpayerHandler.RegisterTaxpayerRoutes(r)
+		paymentHandler.RegisterTaxpayerBalanceRoutes(r)
+	})
 	r.Route("/payments", func(r chi.Router) {
-		r.Use(auth.JWTAuth(cfg.JWTSecret))
+		r.Use(auth.JWTAuth(cfg.JWTSecret, authService))
+		r.Use(idempotent)
 		paymentHandler.RegisterPaymentsRoutes(r)
 	})
+	r.Route("/reconciliation", func(r chi.Router) {
+		r.Use(auth.JWTAuth(cfg.JWTSecret, authService))
+		r.Use(idempotentUploads)
+		paymentHandler.RegisterReconciliationRoutes(r)
+	})
+	r.Route("/refunds", func(r chi.Router) {
+		r.Use(auth.JWTAuth(cfg.JWTSecret, authService))
+		r.Use(idempotent)
+		paymentHandler.RegisterRefundRoutes(r)
+	})
+	r.Route("/collector-sessions", func(r chi.Router) {
+		r.Use(auth.JWTAuth(cfg.JWTSecret, authService))
+		r.Use(idempotent)
+		paymentHandler.RegisterCollectorSessionRoutes(r)
+	})
+	// Public receipt checks for traders; rate limited to stop scraping.
+	trustedProxies, err := mpesa.ParseAllowList(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid TRUSTED_PROXIES")
+	}
+	verifyLimit := ratelimit.New(ratelimit.Policy{Requests: cfg.VerifyRatePerMinute, Per: time.Minute, TrustedProxies: trustedProxies})
+	r.Route("/verify", func(r chi.Router) {
+		r.Use(verifyLimit.Middleware)
+		paymentHandler.RegisterVerificationRoutes(r)
+	})
 
-	taxpayerRepo := taxpayers.NewRepository(sqlDB)
-	authService := auth.NewAuthServiceWithTaxpayer(user.NewRepository(sqlDB), taxpayerRepo, cfg.JWTSecret)
-	r.Route("/auth", func(r chi.Router) {
-		r.Post("/login", func(w http.ResponseWriter, r *http.Request) {
-			var req auth.LoginRequest
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
-
-			resp, err := authService.Login(r.Context(), req)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusUnauthorized)
-				return
-			}
-			json.NewEncoder(w).Encode(resp)
+	if cfg.MpesaEnv != "" {
+		// Daraja callbacks are unauthenticated; the token in the path guards them.
+		r.Route("/mpesa", func(r chi.Router) {
+			paymentHandler.RegisterMpesaCallbackRoutes(r, cfg.MpesaCallbackToken)
 		})
 
-		r.Post("/register", func(w http.ResponseWriter, r *http.Request) {
-			var req auth.RegisterRequest
-
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
-
-			user, err := authService.Register(r.Context(), req)
-			if err != nil {
-				log.Error().Err(err).Msg("Failed to register user")
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			type UserResponse struct {
-				ID        string `json:"id"`
-				Email     string `json:"email"`
-				FirstName string `json:"first_name"`
-				LastName  string `json:"last_name"`
-				Role      string `json:"role"`
-			}
-			resp := UserResponse{
-				ID:        user.ID.String(),
-				Email:     user.Email,
-				FirstName: user.FirstName,
-				LastName:  user.LastName,
-				Role:      user.Role,
-			}
-
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusCreated)
-			json.NewEncoder(w).Encode(resp)
+		// Daraja refuses C2B URLs containing "mpesa", hence the separate prefix.
+		allowedIPs, err := mpesa.ParseAllowList(cfg.MpesaC2BAllowedIPs)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid MPESA_C2B_ALLOWED_IPS")
+		}
+		if len(allowedIPs) == 0 {
+			log.Warn().Msg("Paybill URLs accept requests from any address; set MPESA_C2B_ALLOWED_IPS")
+		}
+		r.Route("/paybill", func(r chi.Router) {
+			paymentHandler.RegisterC2BRoutes(r, mpesa.Guard(mpesa.GuardConfig{
+				AllowedNets:   allowedIPs,
+				SigningSecret: cfg.MpesaC2BSecret,
+			}))
 		})
+	}
+
+	r.Route("/auth", func(r chi.Router) {
+		authHandler.RegisterAuthRoutes(r)
 	})
 
+	if cfg.LedgerSealInterval > 0 {
+		go payments.NewLedgerService(sqlDB).SealLedgerEvery(context.Background(), cfg.LedgerSealInterval)
+	}
+	if cfg.NotifyDispatchInterval > 0 {
+		dispatcher := notify.NewDispatcher(payments.NewOutboxRepository(sqlDB), map[string]notify.Notifier{
+			notify.SMS:   smsNotifier(cfg),
+			notify.Email: emailNotifier(cfg),
+		}, notify.DefaultPolicy)
+		go dispatcher.Run(context.Background(), cfg.NotifyDispatchInterval)
+	}
+
 	log.Info().Msgf("Server starting on :%s", cfg.Port)
 	if err := http.ListenAndServe(":"+cfg.Port, r); err != nil {
 		log.Fatal().Err(err).Msg("Server failed")
 	}
 
 }
+
+// smsNotifier returns the provider receipt SMS are sent with.
+func smsNotifier(cfg *config.Config) notify.Notifier {
+	if cfg.SMSProvider == "africastalking" {
+		return notify.NewAfricasTalking(notify.AfricasTalkingConfig{
+			BaseURL:  cfg.ATBaseURL,
+			Username: cfg.ATUsername,
+			APIKey:   cfg.ATAPIKey,
+			SenderID: cfg.ATSenderID,
+		}, nil)
+	}
+	return notify.New(cfg.SMSProvider, cfg.NotifierFile)
+}
+
+// emailNotifier returns the provider receipt emails are sent with.
+func emailNotifier(cfg *config.Config) notify.Notifier {
+	if cfg.EmailProvider == "smtp" {
+		return notify.NewSMTP(notify.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		})
+	}
+	return notify.New(cfg.EmailProvider, cfg.NotifierFile)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sangkips/revenue-system/internal/db"
+	"github.com/sangkips/revenue-system/migrations"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up                  apply all pending migrations
+  down [n]            revert the last n applied migrations (default 1)
+  status              list migrations and whether they are applied
+  baseline <version>  record migrations up to version as applied without
+                      running them, for a schema created by hand`
+
+// runMigrate implements the "migrate" subcommand. It only needs DB_URL, so it
+// can run before the rest of the configuration exists.
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	dbURL := os.Getenv("DB_URL")
+	if dbURL == "" {
+		log.Fatal().Msg("DB_URL not set")
+	}
+	sqlDB := db.Connect(dbURL)
+	defer sqlDB.Close()
+
+	migrator, err := db.NewMigrator(sqlDB, migrations.FS)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load migrations")
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Migration failed")
+		}
+		log.Info().Int("applied", len(applied)).Msg("Migrations complete")
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				log.Fatal().Str("steps", args[1]).Msg("down takes a positive number of steps")
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Rollback failed")
+		}
+		log.Info().Int("reverted", len(reverted)).Msg("Rollback complete")
+
+	case "baseline":
+		if len(args) < 2 {
+			log.Fatal().Msg("baseline takes the version the schema is already at")
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 1 {
+			log.Fatal().Str("version", args[1]).Msg("baseline takes a positive migration version")
+		}
+		recorded, err := migrator.Baseline(ctx, version)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Baseline failed")
+		}
+		log.Info().Int("recorded", len(recorded)).Msg("Baseline complete")
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to read migration status")
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			state, appliedAt := "pending", ""
+			if s.Applied {
+				state, appliedAt = "applied", s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				state = "modified"
+			}
+			fmt.Fprintf(w, "%03d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		w.Flush()
+
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,148 @@
+// Package bankstatement reads bank statements of the collection accounts and
+// matches their credit lines against recorded payments. CSV exports, SWIFT
+// MT940 and ISO 20022 CAMT.053 statements are supported.
+package bankstatement
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sangkips/revenue-system/internal/money"
+)
+
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatMT940   Format = "mt940"
+	FormatCAMT053 Format = "camt053"
+)
+
+var (
+	ErrUnknownFormat = errors.New("bankstatement: unknown statement format")
+	ErrNoLines       = errors.New("bankstatement: statement has no credit lines")
+)
+
+// Statement is a parsed bank statement. Only credits are kept: money paid
+// into the collection account is what reconciles against payments.
+type Statement struct {
+	Format        Format `json:"format"`
+	AccountNumber string `json:"account_number,omitempty"`
+	Reference     string `json:"reference,omitempty"` // the bank's statement identifier
+	Lines         []Line `json:"lines"`
+}
+
+// Total is the sum of the statement's credits.
+func (s Statement) Total() money.Amount {
+	total := money.Zero
+	for _, l := range s.Lines {
+		total = total.Add(l.Amount)
+	}
+	return total
+}
+
+// Line is one credit on a statement.
+type Line struct {
+	Number      int          `json:"line_number"` // 1-based position among the statement's entries
+	ValueDate   time.Time    `json:"value_date"`
+	Amount      money.Amount `json:"amount"`
+	Reference   string       `json:"reference"`
+	Description string       `json:"description"`
+}
+
+// Text is everything on the line a payment reference may appear in.
+func (l Line) Text() string {
+	return l.Reference + " " + l.Description
+}
+
+// ParseFormat accepts a format name as given by a client.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.NewReplacer(".", "", "-", "", "_", "").Replace(name)) {
+	case "csv":
+		return FormatCSV, nil
+	case "mt940", "sta":
+		return FormatMT940, nil
+	case "camt053", "xml":
+		return FormatCAMT053, nil
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownFormat, name)
+}
+
+// DetectFormat guesses the format from the file name and its first bytes.
+func DetectFormat(fileName string, data []byte) (Format, error) {
+	head := bytes.TrimSpace(data[:min(len(data), 512)])
+	switch {
+	case bytes.HasPrefix(head, []byte("<")):
+		return FormatCAMT053, nil
+	case bytes.HasPrefix(head, []byte(":20:")), bytes.HasPrefix(head, []byte("{1:")):
+		return FormatMT940, nil
+	}
+	if ext := path.Ext(strings.ToLower(fileName)); ext != "" {
+		return ParseFormat(ext)
+	}
+	return "", ErrUnknownFormat
+}
+
+// Parse reads a statement in the given format.
+func Parse(format Format, r io.Reader) (Statement, error) {
+	var st Statement
+	var err error
+	switch format {
+	case FormatCSV:
+		st, err = ParseCSV(r)
+	case FormatMT940:
+		st, err = ParseMT940(r)
+	case FormatCAMT053:
+		st, err = ParseCAMT053(r)
+	default:
+		return Statement{}, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return Statement{}, err
+	}
+	if len(st.Lines) == 0 {
+		return Statement{}, ErrNoLines
+	}
+	return st, nil
+}
+
+// parseAmount reads amounts as banks write them: "1,234.50", "1234,50"
+// (MT940 decimal comma) or "1234.5".
+func parseAmount(s string, decimalComma bool) (money.Amount, error) {
+	s = strings.TrimSpace(s)
+	if decimalComma {
+		s = strings.TrimSuffix(strings.ReplaceAll(s, ",", "."), ".")
+	} else {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	return money.Parse(s)
+}
+
+var dateLayouts = []string{
+	"2006-01-02",
+	"02/01/2006",
+	"02-01-2006",
+	"02.01.2006",
+	"2006/01/02",
+	"02-Jan-2006",
+	"02 Jan 2006",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+}
+
+// parseDate reads the date formats seen in Kenyan bank exports. Slashed
+// dates are day first.
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("bankstatement: unrecognised date %q", s)
+}
@@ -0,0 +1,289 @@
+package bankstatement
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseCSV(t *testing.T) {
+	data := "\ufeffValue Date,Narrative,Transaction Reference,Debit,Credit\n" +
+		"01/07/2025,Business permit NRB/PAY/0001,FT2518200001,,\"12,500.00\"\n" +
+		"01/07/2025,Bank charges,CHG001,35.00,\n" +
+		"\n" +
+		"2025-07-02,Land rates,FT2518300002,,800\n"
+
+	st, err := Parse(FormatCSV, strings.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, st.Lines, 2)
+
+	assert.Equal(t, Line{
+		Number:      1,
+		ValueDate:   date(2025, 7, 1),
+		Amount:      money.MustParse("12500"),
+		Reference:   "FT2518200001",
+		Description: "Business permit NRB/PAY/0001",
+	}, st.Lines[0])
+	assert.Equal(t, 3, st.Lines[1].Number) // blank lines are not entries
+	assert.Equal(t, "13300.00", st.Total().String())
+}
+
+func TestParseCSVSignedAmount(t *testing.T) {
+	data := "date,amount,reference\n2025-07-01,-500,OUT1\n2025-07-01,500.5,IN1\n"
+
+	st, err := ParseCSV(strings.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, st.Lines, 1)
+	assert.Equal(t, "500.50", st.Lines[0].Amount.String())
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"no date column":   "amount,reference\n100,X\n",
+		"no amount column": "date,reference\n2025-07-01,X\n",
+		"bad date":         "date,amount\n31/31/2025,100\n",
+		"bad amount":       "date,amount\n2025-07-01,lots\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseCSV(strings.NewReader(data))
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := Parse(FormatCSV, strings.NewReader("date,amount\n2025-07-01,-5\n"))
+	assert.ErrorIs(t, err, ErrNoLines)
+}
+
+const mt940Sample = `{1:F01KCBLKENXAXXX0000000000}{2:O9401200250701KCBLKENXAXXX00000000002507011200N}{4:
+:20:STMT250701
+:25:1234567890
+:28C:182/1
+:60F:C250630KES100000,00
+:61:2507010701CK12500,00NTRFNRB/PAY/0001//FT2518200001
+PERMIT PAYMENT
+:86:JOHN DOE BUSINESS PERMIT
+ NAIROBI
+:61:250701D35,NCHGNONREF//CHG001
+:86:BANK CHARGES
+:61:250702C800,NTRFNONREF//FT2518300002
+:62F:C250702KES113265,00
+-}`
+
+func TestParseMT940(t *testing.T) {
+	format, err := DetectFormat("statement.txt", []byte(mt940Sample))
+	require.NoError(t, err)
+	assert.Equal(t, FormatMT940, format)
+
+	st, err := Parse(format, strings.NewReader(mt940Sample))
+	require.NoError(t, err)
+	assert.Equal(t, "STMT250701", st.Reference)
+	assert.Equal(t, "1234567890", st.AccountNumber)
+	require.Len(t, st.Lines, 2)
+
+	assert.Equal(t, Line{
+		Number:      1,
+		ValueDate:   date(2025, 7, 1),
+		Amount:      money.MustParse("12500"),
+		Reference:   "NRB/PAY/0001",
+		Description: "FT2518200001 PERMIT PAYMENT JOHN DOE BUSINESS PERMIT NAIROBI",
+	}, st.Lines[0])
+	assert.Equal(t, Line{
+		Number:    3,
+		ValueDate: date(2025, 7, 2),
+		Amount:    money.MustParse("800"),
+		Reference: "FT2518300002",
+	}, st.Lines[1])
+}
+
+func TestParseMT940Malformed(t *testing.T) {
+	_, err := ParseMT940(strings.NewReader(":20:X\n:61:not a statement line\n"))
+	assert.Error(t, err)
+}
+
+const camtSample = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Id>STMT-2025-07-01</Id>
+      <Acct><Id><Othr><Id>1234567890</Id></Othr></Id></Acct>
+      <Ntry>
+        <Amt Ccy="KES">12500.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <BookgDt><Dt>2025-07-01</Dt></BookgDt>
+        <ValDt><Dt>2025-07-01</Dt></ValDt>
+        <AcctSvcrRef>FT2518200001</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <Refs><EndToEndId>NRB/PAY/0001</EndToEndId></Refs>
+          <RmtInf><Ustrd>Business permit</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="KES">35.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts><Cd>BOOK</Cd></Sts>
+        <ValDt><Dt>2025-07-01</Dt></ValDt>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="KES">900.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts><Cd>PDNG</Cd></Sts>
+        <ValDt><Dt>2025-07-02</Dt></ValDt>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="KES">800.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><DtTm>2025-07-02T09:30:00</DtTm></BookgDt>
+        <AcctSvcrRef>FT2518300002</AcctSvcrRef>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+
+func TestParseCAMT053(t *testing.T) {
+	format, err := DetectFormat("statement.xml", []byte(camtSample))
+	require.NoError(t, err)
+	assert.Equal(t, FormatCAMT053, format)
+
+	st, err := Parse(format, strings.NewReader(camtSample))
+	require.NoError(t, err)
+	assert.Equal(t, "STMT-2025-07-01", st.Reference)
+	assert.Equal(t, "1234567890", st.AccountNumber)
+	require.Len(t, st.Lines, 2)
+
+	assert.Equal(t, Line{
+		Number:      1,
+		ValueDate:   date(2025, 7, 1),
+		Amount:      money.MustParse("12500"),
+		Reference:   "NRB/PAY/0001",
+		Description: "Business permit FT2518200001",
+	}, st.Lines[0])
+	assert.Equal(t, 4, st.Lines[1].Number)
+	assert.Equal(t, "FT2518300002", st.Lines[1].Reference)
+	assert.Equal(t, date(2025, 7, 2), st.Lines[1].ValueDate)
+}
+
+func TestParseCAMT053RejectsForeignCurrency(t *testing.T) {
+	doc := strings.Replace(camtSample, `Ccy="KES">12500.00`, `Ccy="USD">12500.00`, 1)
+	_, err := ParseCAMT053(strings.NewReader(doc))
+	assert.Error(t, err)
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, want := range map[string]Format{"CSV": FormatCSV, "mt940": FormatMT940, ".sta": FormatMT940, "camt.053": FormatCAMT053} {
+		got, err := ParseFormat(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+	_, err := ParseFormat("pdf")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestMatch(t *testing.T) {
+	line := Line{
+		ValueDate:   date(2025, 7, 1),
+		Amount:      money.MustParse("12500"),
+		Reference:   "FT2518200001",
+		Description: "business permit nrb-pay-0001",
+	}
+	// 21:30 UTC on 30 June is 00:30 on 1 July in Nairobi.
+	paidAt := time.Date(2025, 6, 30, 21, 30, 0, 0, time.UTC)
+	tol := Tolerance{Days: 0}
+
+	tests := []struct {
+		name       string
+		candidates []Candidate
+		tol        Tolerance
+		wantIndex  int
+		wantKind   MatchKind
+	}{
+		{
+			name: "reference beats an exact amount",
+			candidates: []Candidate{
+				{Amount: money.MustParse("12500"), Date: paidAt, References: []string{"NRB/PAY/0002"}},
+				{Amount: money.MustParse("12500"), Date: paidAt, References: []string{"NRB/PAY/0001"}},
+			},
+			tol:       tol,
+			wantIndex: 1, wantKind: MatchReference,
+		},
+		{
+			name: "reference within amount tolerance",
+			candidates: []Candidate{
+				{Amount: money.MustParse("12550"), Date: paidAt, References: []string{"NRB/PAY/0001"}},
+			},
+			tol:       Tolerance{Amount: money.MustParse("50")},
+			wantIndex: 0, wantKind: MatchReference,
+		},
+		{
+			name: "reference outside amount tolerance",
+			candidates: []Candidate{
+				{Amount: money.MustParse("12550"), Date: paidAt, References: []string{"NRB/PAY/0001"}},
+			},
+			tol:       tol,
+			wantIndex: -1, wantKind: MatchNone,
+		},
+		{
+			name: "single exact amount in the window",
+			candidates: []Candidate{
+				{Amount: money.MustParse("12500"), Date: paidAt.AddDate(0, 0, 2), References: []string{"CASH-1"}},
+				{Amount: money.MustParse("800"), Date: paidAt},
+			},
+			tol:       Tolerance{Days: 2},
+			wantIndex: 0, wantKind: MatchAmountDate,
+		},
+		{
+			name: "exact amount outside the window",
+			candidates: []Candidate{
+				{Amount: money.MustParse("12500"), Date: paidAt.AddDate(0, 0, 3)},
+			},
+			tol:       Tolerance{Days: 2},
+			wantIndex: -1, wantKind: MatchNone,
+		},
+		{
+			name: "ambiguous amounts",
+			candidates: []Candidate{
+				{Amount: money.MustParse("12500"), Date: paidAt},
+				{Amount: money.MustParse("12500"), Date: paidAt},
+			},
+			tol:       tol,
+			wantIndex: -1, wantKind: MatchNone,
+		},
+		{
+			name: "short references are ignored",
+			candidates: []Candidate{
+				{Amount: money.MustParse("12500"), Date: paidAt, References: []string{"PAY"}},
+				{Amount: money.MustParse("12500"), Date: paidAt, References: []string{"0001"}},
+			},
+			tol:       tol,
+			wantIndex: -1, wantKind: MatchNone,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, kind := Match(line, tt.candidates, tt.tol)
+			assert.Equal(t, tt.wantIndex, index)
+			assert.Equal(t, tt.wantKind, kind)
+		})
+	}
+}
+
+func TestToleranceRanges(t *testing.T) {
+	tol := Tolerance{Amount: money.MustParse("50"), Days: 1}
+
+	lo, hi := tol.AmountRange(money.MustParse("1000"))
+	assert.Equal(t, "950.00", lo.String())
+	assert.Equal(t, "1050.00", hi.String())
+
+	from, to := tol.DateRange(date(2025, 7, 1))
+	assert.Equal(t, time.Date(2025, 6, 29, 21, 0, 0, 0, time.UTC), from.UTC())
+	assert.Equal(t, time.Date(2025, 7, 2, 21, 0, 0, 0, time.UTC), to.UTC())
+}
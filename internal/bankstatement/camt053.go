@@ -0,0 +1,149 @@
+package bankstatement
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sangkips/revenue-system/internal/money"
+)
+
+// camtDocument is the part of an ISO 20022 camt.053 BankToCustomerStatement
+// the importer reads. Element names are matched without their namespace so
+// every camt.053.001.xx version parses.
+type camtDocument struct {
+	Statements []struct {
+		ID      string `xml:"Id"`
+		Account struct {
+			IBAN  string `xml:"Id>IBAN"`
+			Other string `xml:"Id>Othr>Id"`
+		} `xml:"Acct"`
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtEntry struct {
+	Amount struct {
+		Value    string `xml:",chardata"`
+		Currency string `xml:"Ccy,attr"`
+	} `xml:"Amt"`
+	CreditDebit string `xml:"CdtDbtInd"`
+	Reversal    bool   `xml:"RvslInd"`
+	Status      struct {
+		Text string `xml:",chardata"` // camt.053.001.02 to .07
+		Code string `xml:"Cd"`        // camt.053.001.08 onwards
+	} `xml:"Sts"`
+	BookingDate camtDate `xml:"BookgDt"`
+	ValueDate   camtDate `xml:"ValDt"`
+	ServicerRef string   `xml:"AcctSvcrRef"`
+	Info        string   `xml:"AddtlNtryInf"`
+	Details     []struct {
+		EndToEndID   string   `xml:"Refs>EndToEndId"`
+		TxID         string   `xml:"Refs>TxId"`
+		Unstructured []string `xml:"RmtInf>Ustrd"`
+		Structured   []string `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+		Info         string   `xml:"AddtlTxInf"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+func (d camtDate) String() string {
+	if d.Date != "" {
+		return d.Date
+	}
+	return d.DateTime
+}
+
+// ParseCAMT053 reads an ISO 20022 camt.053 statement. Booked credit entries
+// (and reversed debits) are kept; pending entries are not final and are
+// skipped. Every amount must be in Kenya shillings.
+func ParseCAMT053(r io.Reader) (Statement, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return Statement{}, fmt.Errorf("bankstatement: reading camt.053: %w", err)
+	}
+	if len(doc.Statements) == 0 {
+		return Statement{}, fmt.Errorf("bankstatement: camt.053 document has no statement")
+	}
+
+	st := Statement{Format: FormatCAMT053}
+	entry := 0
+	for _, s := range doc.Statements {
+		if st.Reference == "" {
+			st.Reference = strings.TrimSpace(s.ID)
+		}
+		if st.AccountNumber == "" {
+			st.AccountNumber = strings.TrimSpace(s.Account.IBAN + s.Account.Other)
+		}
+
+		for _, e := range s.Entries {
+			entry++
+			status := strings.TrimSpace(e.Status.Text + e.Status.Code)
+			if status != "" && !strings.EqualFold(status, "BOOK") {
+				continue
+			}
+			credit := e.CreditDebit == "CRDT"
+			if e.Reversal {
+				credit = !credit
+			}
+			if !credit {
+				continue
+			}
+			if ccy := strings.TrimSpace(e.Amount.Currency); ccy != "" && ccy != money.Currency {
+				return Statement{}, fmt.Errorf("bankstatement: camt.053 entry %d is in %s, not %s", entry, ccy, money.Currency)
+			}
+
+			amount, err := parseAmount(e.Amount.Value, false)
+			if err != nil {
+				return Statement{}, fmt.Errorf("bankstatement: camt.053 entry %d: %w", entry, err)
+			}
+			dateText := e.ValueDate.String()
+			if dateText == "" {
+				dateText = e.BookingDate.String()
+			}
+			date, err := parseDate(dateText)
+			if err != nil {
+				return Statement{}, fmt.Errorf("bankstatement: camt.053 entry %d: %w", entry, err)
+			}
+
+			var reference string
+			var description []string
+			for _, d := range e.Details {
+				refs := append(append([]string{}, d.Structured...), d.EndToEndID, d.TxID)
+				for _, ref := range refs {
+					ref = strings.TrimSpace(ref)
+					if ref == "" || strings.EqualFold(ref, "NOTPROVIDED") {
+						continue
+					}
+					if reference == "" {
+						reference = ref
+					} else {
+						description = append(description, ref)
+					}
+				}
+				description = append(description, d.Unstructured...)
+				description = append(description, d.Info)
+			}
+			if reference == "" {
+				reference = strings.TrimSpace(e.ServicerRef)
+			} else {
+				description = append(description, e.ServicerRef)
+			}
+			description = append(description, e.Info)
+
+			st.Lines = append(st.Lines, Line{
+				Number:      entry,
+				ValueDate:   date,
+				Amount:      amount,
+				Reference:   reference,
+				Description: strings.Join(strings.Fields(strings.Join(description, " ")), " "),
+			})
+		}
+	}
+	return st, nil
+}
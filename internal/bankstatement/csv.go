@@ -0,0 +1,113 @@
+package bankstatement
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvColumns maps the header names banks use to the field they hold.
+var csvColumns = map[string]string{
+	"date":                  "date",
+	"value date":            "date",
+	"transaction date":      "date",
+	"posting date":          "date",
+	"amount":                "amount",
+	"credit":                "credit",
+	"credit amount":         "credit",
+	"money in":              "credit",
+	"reference":             "reference",
+	"ref":                   "reference",
+	"bank reference":        "reference",
+	"transaction reference": "reference",
+	"customer reference":    "reference",
+	"description":           "description",
+	"narrative":             "description",
+	"details":               "description",
+	"particulars":           "description",
+	"transaction details":   "description",
+}
+
+// ParseCSV reads a CSV export with a header row. It needs a date column and
+// either a signed amount column or a credit column; debits, negative amounts
+// and rows with an empty credit are skipped.
+func ParseCSV(r io.Reader) (Statement, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return Statement{}, fmt.Errorf("bankstatement: reading CSV header: %w", err)
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.NewReplacer("_", " ", "-", " ").Replace(name)
+		if field, ok := csvColumns[name]; ok {
+			if _, dup := cols[field]; !dup {
+				cols[field] = i
+			}
+		}
+	}
+	if _, ok := cols["date"]; !ok {
+		return Statement{}, errors.New("bankstatement: CSV has no date column")
+	}
+	_, hasAmount := cols["amount"]
+	_, hasCredit := cols["credit"]
+	if !hasAmount && !hasCredit {
+		return Statement{}, errors.New("bankstatement: CSV has no amount or credit column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	st := Statement{Format: FormatCSV}
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Statement{}, fmt.Errorf("bankstatement: CSV row %d: %w", row, err)
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		raw := field(record, "credit")
+		if !hasCredit {
+			raw = field(record, "amount")
+		}
+		if raw == "" {
+			continue // a debit-only row
+		}
+		amount, err := parseAmount(raw, false)
+		if err != nil {
+			return Statement{}, fmt.Errorf("bankstatement: CSV row %d: %w", row, err)
+		}
+		if !amount.IsPositive() {
+			continue
+		}
+
+		date, err := parseDate(field(record, "date"))
+		if err != nil {
+			return Statement{}, fmt.Errorf("bankstatement: CSV row %d: %w", row, err)
+		}
+
+		st.Lines = append(st.Lines, Line{
+			Number:      row,
+			ValueDate:   date,
+			Amount:      amount,
+			Reference:   field(record, "reference"),
+			Description: field(record, "description"),
+		})
+	}
+	return st, nil
+}
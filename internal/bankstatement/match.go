@@ -0,0 +1,125 @@
+package bankstatement
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sangkips/revenue-system/internal/money"
+)
+
+// Statement dates are Kenyan calendar days.
+var eat = time.FixedZone("EAT", 3*60*60)
+
+// minReferenceLength keeps short references such as "1" or "PAY" from
+// matching inside unrelated narrative text.
+const minReferenceLength = 5
+
+// Tolerance bounds how far a statement line may differ from a payment and
+// still match it.
+type Tolerance struct {
+	Amount money.Amount // largest difference between line and payment amounts, e.g. bank charges
+	Days   int          // days either side of the value date the payment may be dated
+}
+
+// AmountRange is the smallest and largest payment amount a line of amount
+// can match.
+func (tol Tolerance) AmountRange(amount money.Amount) (lo, hi money.Amount) {
+	return amount.Sub(tol.Amount), amount.Add(tol.Amount)
+}
+
+// DateRange is the half-open interval [from, to) a payment for a line with
+// valueDate must be dated in, as instants so it can be used against
+// timestamps directly.
+func (tol Tolerance) DateRange(valueDate time.Time) (from, to time.Time) {
+	day := time.Date(valueDate.Year(), valueDate.Month(), valueDate.Day(), 0, 0, 0, 0, eat)
+	return day.AddDate(0, 0, -tol.Days), day.AddDate(0, 0, tol.Days+1)
+}
+
+// Candidate is a payment a statement line might settle.
+type Candidate struct {
+	Amount     money.Amount
+	Date       time.Time
+	References []string // payment number, bank reference, M-Pesa receipt and the like
+}
+
+type MatchKind string
+
+const (
+	MatchNone       MatchKind = ""
+	MatchReference  MatchKind = "reference"   // a candidate reference appears on the line
+	MatchAmountDate MatchKind = "amount_date" // the only candidate with the exact amount in the date window
+)
+
+// Match returns the index of the candidate line settles and how it was
+// chosen, or -1 and MatchNone. Only candidates within tol are considered. A
+// reference match wins; failing that, a single candidate with exactly the
+// line's amount is taken. Ambiguous lines are left for a person to resolve.
+func Match(line Line, candidates []Candidate, tol Tolerance) (int, MatchKind) {
+	text := normalizeReference(line.Text())
+
+	var byReference, byAmount []int
+	for i, c := range candidates {
+		if !withinTolerance(line, c, tol) {
+			continue
+		}
+		for _, ref := range c.References {
+			if ref = normalizeReference(ref); len(ref) >= minReferenceLength && strings.Contains(text, ref) {
+				byReference = append(byReference, i)
+				break
+			}
+		}
+		if c.Amount.Cmp(line.Amount) == 0 {
+			byAmount = append(byAmount, i)
+		}
+	}
+
+	switch len(byReference) {
+	case 0:
+	case 1:
+		return byReference[0], MatchReference
+	default:
+		// Several references on one line; settle only if the amount singles one out.
+		var exact []int
+		for _, i := range byReference {
+			if candidates[i].Amount.Cmp(line.Amount) == 0 {
+				exact = append(exact, i)
+			}
+		}
+		if len(exact) == 1 {
+			return exact[0], MatchReference
+		}
+		return -1, MatchNone
+	}
+
+	if len(byAmount) == 1 {
+		return byAmount[0], MatchAmountDate
+	}
+	return -1, MatchNone
+}
+
+func withinTolerance(line Line, c Candidate, tol Tolerance) bool {
+	if line.Amount.Sub(c.Amount).Abs().Cmp(tol.Amount) > 0 {
+		return false
+	}
+	days := calendarDay(line.ValueDate).Sub(calendarDay(c.Date.In(eat))).Hours() / 24
+	if days < 0 {
+		days = -days
+	}
+	return days <= float64(tol.Days)
+}
+
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// normalizeReference upper-cases s and drops everything but letters and
+// digits, so "pay-2025/001" on a statement matches "PAY2025001".
+func normalizeReference(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, s)
+}
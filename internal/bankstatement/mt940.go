@@ -0,0 +1,116 @@
+package bankstatement
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	mt940Tag  = regexp.MustCompile(`^:(\d{2}[A-Z]?):(.*)$`)
+	mt940Line = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d*)([NSF][A-Z0-9]{3})(.*)$`)
+)
+
+// ParseMT940 reads a SWIFT MT940 customer statement, with or without the
+// {1:}{2:}{4: envelope. Each :61: statement line is an entry; its :86:
+// information becomes the description. Credits and reversed debits are kept.
+func ParseMT940(r io.Reader) (Statement, error) {
+	st := Statement{Format: FormatMT940}
+
+	type field struct{ tag, value string }
+	var fields []field
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if i := strings.Index(text, "{4:"); i >= 0 {
+			text = text[i+3:]
+		}
+		if strings.HasPrefix(text, "-}") || strings.HasPrefix(text, "{") || text == "-" {
+			continue
+		}
+		if m := mt940Tag.FindStringSubmatch(text); m != nil {
+			fields = append(fields, field{tag: m[1], value: m[2]})
+			continue
+		}
+		if len(fields) > 0 && text != "" {
+			fields[len(fields)-1].value += "\n" + text
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Statement{}, err
+	}
+
+	entry := 0
+	var pending *Line // the last :61: credit, waiting for its :86:
+	for _, f := range fields {
+		switch f.tag {
+		case "20":
+			if st.Reference == "" {
+				st.Reference = strings.TrimSpace(f.value)
+			}
+		case "25":
+			if st.AccountNumber == "" {
+				st.AccountNumber = strings.TrimSpace(f.value)
+			}
+		case "61":
+			entry++
+			pending = nil
+			line, credit, err := parseMT940Line(f.value)
+			if err != nil {
+				return Statement{}, fmt.Errorf("bankstatement: MT940 entry %d: %w", entry, err)
+			}
+			if !credit {
+				continue
+			}
+			line.Number = entry
+			st.Lines = append(st.Lines, line)
+			pending = &st.Lines[len(st.Lines)-1]
+		case "86":
+			if pending != nil {
+				info := strings.Join(strings.Fields(f.value), " ")
+				pending.Description = strings.TrimSpace(pending.Description + " " + info)
+				pending = nil
+			}
+		}
+	}
+	return st, nil
+}
+
+// parseMT940Line reads a :61: field: value date, optional entry date,
+// debit/credit mark, optional funds code, amount, transaction type, the
+// customer reference and, after "//", the bank's reference. A second line
+// holds supplementary details.
+func parseMT940Line(value string) (Line, bool, error) {
+	first, supplementary, _ := strings.Cut(value, "\n")
+	m := mt940Line.FindStringSubmatch(strings.TrimSpace(first))
+	if m == nil {
+		return Line{}, false, fmt.Errorf("malformed :61: field %q", first)
+	}
+
+	date, err := time.Parse("060102", m[1])
+	if err != nil {
+		return Line{}, false, err
+	}
+	amount, err := parseAmount(m[5], true)
+	if err != nil {
+		return Line{}, false, err
+	}
+
+	customerRef, bankRef, _ := strings.Cut(m[7], "//")
+	customerRef, bankRef = strings.TrimSpace(customerRef), strings.TrimSpace(bankRef)
+	reference := customerRef
+	if reference == "" || strings.EqualFold(reference, "NONREF") {
+		reference, bankRef = bankRef, ""
+	}
+
+	credit := m[3] == "C" || m[3] == "RD"
+	return Line{
+		ValueDate:   date,
+		Amount:      amount,
+		Reference:   reference,
+		Description: strings.TrimSpace(bankRef + " " + strings.Join(strings.Fields(supplementary), " ")),
+	}, credit, nil
+}
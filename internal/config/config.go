@@ -2,14 +2,78 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/sangkips/revenue-system/internal/numbering"
 )
 
 type Config struct {
 	DBURL     string
 	Port      string
 	JWTSecret string
+
+	MigrateOnStart bool // apply pending migrations before serving
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	PasswordResetTTL time.Duration
+	PasswordResetURL string // prefix for the token in reset links, e.g. https://portal/reset?token=
+
+	Notifier     string // "log" or "file"
+	NotifierFile string
+
+	SMSProvider            string        // receipt SMS: "log", "file" or "africastalking"; defaults to NOTIFIER
+	EmailProvider          string        // receipt email: "log", "file" or "smtp"; defaults to NOTIFIER
+	NotifyMaxAttempts      int           // sends tried per message before it is given up
+	NotifyDispatchInterval time.Duration // how often the notification outbox is drained; 0 disables
+
+	ATBaseURL  string // Africa's Talking API, e.g. https://api.sandbox.africastalking.com
+	ATUsername string
+	ATAPIKey   string
+	ATSenderID string // optional registered sender ID
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string // e.g. "County Revenue <receipts@county.go.ke>"
+
+	MFAIssuer              string // shown by authenticator apps
+	MFARequiredSuperAdmins bool
+
+	ReconcileAmountTolerance money.Amount // bank charges a statement line may differ from its payment by
+	ReconcileDateWindowDays  int          // days either side of the value date a matching payment may fall
+
+	AllocationStrategy string // default for completed payments: "oldest_due", "penalty_first" or "targeted"
+
+	NumberPattern           string // layout of issued document numbers, from {county}, {kind}, {fy} and {seq}
+	FinancialYearStartMonth int    // 1-12; numbering restarts each financial year
+	NumberDigits            int    // width the sequence in a document number is zero-padded to
+
+	ReceiptStorageDir string // where rendered receipt PDFs are kept
+	ReceiptLogoDir    string // optional county logos, one <county code>.png each
+
+	LedgerSealInterval time.Duration // how often receipt ledger entries are sealed into blocks; 0 disables
+
+	PublicURL           string // public base URL of this server, printed in receipt QR codes
+	ReceiptVerifySecret string // signs receipt QR codes; kept apart from JWT_SECRET
+	VerifyRatePerMinute int    // public receipt checks allowed per client address per minute
+	TrustedProxies      string // comma-separated proxy IPs or CIDRs whose X-Forwarded-For is believed; without them all clients behind a proxy share one limit
+
+	MpesaEnv            string // "" (disabled), "simulator", "sandbox" or "production"
+	MpesaBaseURL        string
+	MpesaConsumerKey    string
+	MpesaConsumerSecret string
+	MpesaShortCode      string
+	MpesaPasskey        string
+	MpesaCallbackURL    string // public base URL of this server; the callback path is appended
+	MpesaCallbackToken  string // secret path segment on the callback URL
+	MpesaC2BAllowedIPs  string // comma-separated addresses or CIDRs allowed to call the paybill URLs
+	MpesaC2BSecret      string // optional HMAC secret for signed paybill requests
 }
 
 func Load() *Config {
@@ -17,6 +81,43 @@ func Load() *Config {
 		DBURL:     os.Getenv("DB_URL"),
 		Port:      os.Getenv("PORT"),
 		JWTSecret: os.Getenv("JWT_SECRET"),
+
+		PasswordResetURL: os.Getenv("PASSWORD_RESET_URL"),
+		Notifier:         os.Getenv("NOTIFIER"),
+		NotifierFile:     os.Getenv("NOTIFIER_FILE"),
+		MFAIssuer:        os.Getenv("MFA_ISSUER"),
+
+		SMSProvider:   os.Getenv("SMS_PROVIDER"),
+		EmailProvider: os.Getenv("EMAIL_PROVIDER"),
+		ATBaseURL:     os.Getenv("AT_BASE_URL"),
+		ATUsername:    os.Getenv("AT_USERNAME"),
+		ATAPIKey:      os.Getenv("AT_API_KEY"),
+		ATSenderID:    os.Getenv("AT_SENDER_ID"),
+		SMTPHost:      os.Getenv("SMTP_HOST"),
+		SMTPUsername:  os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:  os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:      os.Getenv("SMTP_FROM"),
+
+		AllocationStrategy: os.Getenv("ALLOCATION_STRATEGY"),
+		NumberPattern:      os.Getenv("NUMBER_PATTERN"),
+
+		ReceiptStorageDir: os.Getenv("RECEIPT_STORAGE_DIR"),
+		ReceiptLogoDir:    os.Getenv("RECEIPT_LOGO_DIR"),
+
+		PublicURL:           os.Getenv("PUBLIC_URL"),
+		ReceiptVerifySecret: os.Getenv("RECEIPT_VERIFY_SECRET"),
+
+		MpesaEnv:            os.Getenv("MPESA_ENV"),
+		MpesaBaseURL:        os.Getenv("MPESA_BASE_URL"),
+		MpesaConsumerKey:    os.Getenv("MPESA_CONSUMER_KEY"),
+		MpesaConsumerSecret: os.Getenv("MPESA_CONSUMER_SECRET"),
+		MpesaShortCode:      os.Getenv("MPESA_SHORTCODE"),
+		MpesaPasskey:        os.Getenv("MPESA_PASSKEY"),
+		MpesaCallbackURL:    os.Getenv("MPESA_CALLBACK_URL"),
+		MpesaCallbackToken:  os.Getenv("MPESA_CALLBACK_TOKEN"),
+		TrustedProxies:      os.Getenv("TRUSTED_PROXIES"),
+		MpesaC2BAllowedIPs:  os.Getenv("MPESA_C2B_ALLOWED_IPS"),
+		MpesaC2BSecret:      os.Getenv("MPESA_C2B_SIGNING_SECRET"),
 	}
 
 	if cfg.DBURL == "" {
@@ -30,5 +131,176 @@ func Load() *Config {
 	if cfg.Port == "" {
 		cfg.Port = "8080"
 	}
+
+	cfg.MigrateOnStart = boolEnv("MIGRATE_ON_START", true)
+
+	cfg.AccessTokenTTL = durationEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
+	cfg.RefreshTokenTTL = durationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	cfg.PasswordResetTTL = durationEnv("PASSWORD_RESET_TTL", time.Hour)
+	cfg.LedgerSealInterval = durationEnv("LEDGER_SEAL_INTERVAL", 10*time.Minute)
+
+	// Printed receipts outlive any login token, so the QR signing key must be
+	// rotatable on its own and must not sign sessions if it leaks.
+	if cfg.ReceiptVerifySecret == "" {
+		log.Fatal().Msg("RECEIPT_VERIFY_SECRET not set")
+	}
+	if cfg.ReceiptVerifySecret == cfg.JWTSecret {
+		log.Warn().Msg("RECEIPT_VERIFY_SECRET is the same as JWT_SECRET; give it its own value (receipts already printed will stop verifying)")
+	}
+	cfg.VerifyRatePerMinute = intEnv("VERIFY_RATE_PER_MINUTE", 30)
+	if cfg.VerifyRatePerMinute < 1 {
+		log.Fatal().Int("VERIFY_RATE_PER_MINUTE", cfg.VerifyRatePerMinute).Msg("VERIFY_RATE_PER_MINUTE must be at least 1")
+	}
+
+	if cfg.Notifier == "" {
+		cfg.Notifier = "log"
+	}
+
+	if cfg.NotifierFile == "" {
+		cfg.NotifierFile = "notifications.log"
+	}
+
+	if cfg.SMSProvider == "" {
+		cfg.SMSProvider = cfg.Notifier
+	}
+	if cfg.EmailProvider == "" {
+		cfg.EmailProvider = cfg.Notifier
+	}
+	if cfg.SMSProvider == "africastalking" {
+		if cfg.ATUsername == "" || cfg.ATAPIKey == "" {
+			log.Fatal().Msg("SMS_PROVIDER=africastalking requires AT_USERNAME and AT_API_KEY")
+		}
+		if cfg.ATBaseURL == "" {
+			cfg.ATBaseURL = "https://api.africastalking.com"
+			if cfg.ATUsername == "sandbox" {
+				cfg.ATBaseURL = "https://api.sandbox.africastalking.com"
+			}
+		}
+	}
+	cfg.SMTPPort = intEnv("SMTP_PORT", 587)
+	if cfg.EmailProvider == "smtp" && (cfg.SMTPHost == "" || cfg.SMTPFrom == "") {
+		log.Fatal().Msg("EMAIL_PROVIDER=smtp requires SMTP_HOST and SMTP_FROM")
+	}
+	cfg.NotifyMaxAttempts = intEnv("NOTIFY_MAX_ATTEMPTS", 5)
+	if cfg.NotifyMaxAttempts < 1 {
+		log.Fatal().Int("NOTIFY_MAX_ATTEMPTS", cfg.NotifyMaxAttempts).Msg("NOTIFY_MAX_ATTEMPTS must be at least 1")
+	}
+	cfg.NotifyDispatchInterval = durationEnv("NOTIFY_DISPATCH_INTERVAL", 15*time.Second)
+
+	if cfg.MFAIssuer == "" {
+		cfg.MFAIssuer = "County Revenue System"
+	}
+	cfg.MFARequiredSuperAdmins = boolEnv("MFA_REQUIRED_SUPER_ADMIN", true)
+
+	cfg.ReconcileAmountTolerance = amountEnv("RECONCILE_AMOUNT_TOLERANCE", money.Zero)
+	cfg.ReconcileDateWindowDays = intEnv("RECONCILE_DATE_WINDOW_DAYS", 3)
+
+	if cfg.AllocationStrategy == "" {
+		cfg.AllocationStrategy = "oldest_due"
+	}
+
+	if cfg.NumberPattern == "" {
+		cfg.NumberPattern = numbering.DefaultScheme.Pattern
+	}
+	cfg.FinancialYearStartMonth = intEnv("FINANCIAL_YEAR_START_MONTH", int(numbering.DefaultScheme.YearStart))
+	cfg.NumberDigits = intEnv("NUMBER_DIGITS", numbering.DefaultScheme.Digits)
+
+	if cfg.ReceiptStorageDir == "" {
+		cfg.ReceiptStorageDir = "data/receipts"
+	}
+
+	switch cfg.MpesaEnv {
+	case "":
+	case "simulator":
+		// Everything runs in-process against the Daraja simulator.
+		local := "http://localhost:" + cfg.Port
+		if cfg.MpesaBaseURL == "" {
+			cfg.MpesaBaseURL = local + "/simulator/mpesa"
+		}
+		if cfg.MpesaCallbackURL == "" {
+			cfg.MpesaCallbackURL = local
+		}
+		if cfg.MpesaConsumerKey == "" {
+			cfg.MpesaConsumerKey, cfg.MpesaConsumerSecret = "simulator", "simulator"
+		}
+		if cfg.MpesaShortCode == "" {
+			cfg.MpesaShortCode, cfg.MpesaPasskey = "174379", "simulator"
+		}
+		if cfg.MpesaCallbackToken == "" {
+			cfg.MpesaCallbackToken = "simulator"
+		}
+		if cfg.MpesaC2BAllowedIPs == "" {
+			cfg.MpesaC2BAllowedIPs = "127.0.0.1,::1"
+		}
+	case "sandbox", "production":
+		if cfg.MpesaBaseURL == "" {
+			cfg.MpesaBaseURL = "https://sandbox.safaricom.co.ke"
+			if cfg.MpesaEnv == "production" {
+				cfg.MpesaBaseURL = "https://api.safaricom.co.ke"
+			}
+		}
+		if cfg.MpesaConsumerKey == "" || cfg.MpesaConsumerSecret == "" || cfg.MpesaShortCode == "" ||
+			cfg.MpesaPasskey == "" || cfg.MpesaCallbackURL == "" || cfg.MpesaCallbackToken == "" {
+			log.Fatal().Msg("MPESA_CONSUMER_KEY, MPESA_CONSUMER_SECRET, MPESA_SHORTCODE, MPESA_PASSKEY, MPESA_CALLBACK_URL and MPESA_CALLBACK_TOKEN are required when MPESA_ENV is set")
+		}
+		// The paybill URLs are public; in production only Safaricom may call them.
+		if cfg.MpesaEnv == "production" && cfg.MpesaC2BAllowedIPs == "" {
+			log.Fatal().Msg("MPESA_C2B_ALLOWED_IPS is required when MPESA_ENV is production")
+		}
+	default:
+		log.Fatal().Str("MPESA_ENV", cfg.MpesaEnv).Msg("MPESA_ENV must be simulator, sandbox or production")
+	}
 	return cfg
 }
+
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("%s is not a valid duration", key)
+	}
+	return d
+}
+
+func boolEnv(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("%s is not a valid boolean", key)
+	}
+	return b
+}
+
+func intEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Fatal().Err(err).Msgf("%s is not a valid non-negative integer", key)
+	}
+	return n
+}
+
+func amountEnv(key string, fallback money.Amount) money.Amount {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	a, err := money.Parse(value)
+	if err != nil || a.IsNegative() {
+		log.Fatal().Err(err).Msgf("%s is not a valid non-negative amount", key)
+	}
+	return a
+}
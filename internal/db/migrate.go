@@ -0,0 +1,346 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// migrationLockID is the pg_advisory_lock key held while migrations run, so
+// several server instances starting at once apply each migration only once.
+const migrationLockID int64 = 0x7265_7665_6e75_65 // "revenue"
+
+const createMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    checksum VARCHAR(64) NOT NULL, -- SHA-256 hex of the up file
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// Migration is a pair of NNN_name.sql and NNN_name.down.sql files.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus describes one migration as seen by the database.
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	// Modified is set when the file changed after it was applied.
+	Modified bool `json:"modified"`
+}
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration has been modified")
+	// ErrUnrecordedSchema is returned by Up for a database whose tables were
+	// created before the runner kept schema_migrations, e.g. with psql.
+	ErrUnrecordedSchema = errors.New("database has tables but no recorded migrations; record the ones already applied with \"server migrate baseline <version>\"")
+)
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator reads the migrations in the root of fsys. Every up file needs a
+// down file and versions must be unique.
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// LoadMigrations returns the migrations in fsys ordered by version.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		file := entry.Name()
+		if entry.IsDir() || path.Ext(file) != ".sql" {
+			continue
+		}
+		base, down := strings.CutSuffix(strings.TrimSuffix(file, ".sql"), ".down")
+		prefix, name, ok := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s: name must look like NNN_description.sql", file)
+		}
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %s: version %d is also used by %s", file, version, m.Name)
+		}
+		if down {
+			m.Down = string(content)
+			continue
+		}
+		sum := sha256.Sum256(content)
+		m.Up, m.Checksum = string(content), hex.EncodeToString(sum[:])
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Checksum == "" {
+			return nil, fmt.Errorf("migration %03d_%s has a down file but no up file", m.Version, m.Name)
+		}
+		if m.Down == "" {
+			return nil, fmt.Errorf("migration %03d_%s has no down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies every pending migration in order, each in its own transaction,
+// and returns the ones it applied. It refuses to run if an applied migration
+// no longer matches its file.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			// Re-running the early migrations over an existing schema fails
+			// part way; the operator has to say how far it already is.
+			var existing bool
+			if err := conn.QueryRowContext(ctx, `SELECT to_regclass('counties') IS NOT NULL`).Scan(&existing); err != nil {
+				return err
+			}
+			if existing {
+				return ErrUnrecordedSchema
+			}
+		}
+		for _, mig := range m.migrations {
+			if checksum, ok := done[mig.Version]; ok {
+				if checksum != mig.Checksum {
+					return fmt.Errorf("%w: %03d_%s", ErrChecksumMismatch, mig.Version, mig.Name)
+				}
+				continue
+			}
+			err := execInTx(ctx, conn, mig.Up,
+				`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				mig.Version, mig.Name, mig.Checksum)
+			if err != nil {
+				return fmt.Errorf("migration %03d_%s: %w", mig.Version, mig.Name, err)
+			}
+			log.Info().Int64("version", mig.Version).Str("name", mig.Name).Msg("Applied migration")
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Baseline records every migration up to and including version as applied
+// without running it, for a database whose schema was built by hand, and
+// returns the ones it recorded. Migrations already recorded are left alone.
+func (m *Migrator) Baseline(ctx context.Context, version int64) ([]Migration, error) {
+	var recorded []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		pending, err := baselineMigrations(m.migrations, done, version)
+		if err != nil {
+			return err
+		}
+		for _, mig := range pending {
+			_, err := conn.ExecContext(ctx,
+				`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				mig.Version, mig.Name, mig.Checksum)
+			if err != nil {
+				return fmt.Errorf("migration %03d_%s: %w", mig.Version, mig.Name, err)
+			}
+			log.Info().Int64("version", mig.Version).Str("name", mig.Name).Msg("Recorded migration as applied")
+			recorded = append(recorded, mig)
+		}
+		return nil
+	})
+	return recorded, err
+}
+
+// baselineMigrations picks the migrations up to version that done lacks.
+func baselineMigrations(migrations []Migration, done map[int64]string, version int64) ([]Migration, error) {
+	known := false
+	var pending []Migration
+	for _, mig := range migrations {
+		if mig.Version > version {
+			break
+		}
+		known = known || mig.Version == version
+		if _, ok := done[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+	if !known {
+		return nil, fmt.Errorf("no migration has version %d", version)
+	}
+	return pending, nil
+}
+
+// Down reverts the latest steps applied migrations, newest first, and returns
+// the ones it reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			err := execInTx(ctx, conn, mig.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+			if err != nil {
+				return fmt.Errorf("migration %03d_%s: %w", mig.Version, mig.Name, err)
+			}
+			log.Info().Int64("version", mig.Version).Str("name", mig.Name).Msg("Reverted migration")
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if _, err := m.db.ExecContext(ctx, createMigrationsTable); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.QueryContext(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type row struct {
+		checksum  string
+		appliedAt time.Time
+	}
+	done := map[int64]row{}
+	for rows.Next() {
+		var version int64
+		var r row
+		if err := rows.Scan(&version, &r.checksum, &r.appliedAt); err != nil {
+			return nil, err
+		}
+		done[version] = r
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if r, ok := done[mig.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = &r.appliedAt
+			s.Modified = r.checksum != mig.Checksum
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// locked runs fn on a single connection holding the migration advisory lock.
+// Session-level advisory locks belong to a connection, so everything must go
+// through conn rather than the pool.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	defer func() {
+		// Use a fresh context so the lock is released even if ctx was cancelled.
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			log.Warn().Err(err).Msg("Failed to release migration lock")
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]string, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, checksum FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := map[int64]string{}
+	for rows.Next() {
+		var version int64
+		var checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, err
+		}
+		done[version] = checksum
+	}
+	return done, rows.Err()
+}
+
+// execInTx runs script and then the bookkeeping statement in one transaction.
+func execInTx(ctx context.Context, conn *sql.Conn, script, bookkeeping string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/sangkips/revenue-system/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_add_b.sql":      {Data: []byte("CREATE TABLE b ();")},
+		"002_add_b.down.sql": {Data: []byte("DROP TABLE b;")},
+		"001_add_a.sql":      {Data: []byte("CREATE TABLE a ();")},
+		"001_add_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"migrations.go":      {Data: []byte("package migrations")},
+	}
+
+	got, err := LoadMigrations(fsys)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, int64(1), got[0].Version)
+	assert.Equal(t, "add_a", got[0].Name)
+	assert.Equal(t, "DROP TABLE a;", got[0].Down)
+	assert.Len(t, got[0].Checksum, 64)
+	assert.Equal(t, int64(2), got[1].Version)
+}
+
+func TestLoadMigrationsRejectsBadSets(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing down": {
+			"001_add_a.sql": {Data: []byte("CREATE TABLE a ();")},
+		},
+		"missing up": {
+			"001_add_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		},
+		"duplicate version": {
+			"001_add_a.sql":      {Data: []byte("CREATE TABLE a ();")},
+			"001_add_a.down.sql": {Data: []byte("DROP TABLE a;")},
+			"001_add_b.sql":      {Data: []byte("CREATE TABLE b ();")},
+		},
+		"bad name": {
+			"add_a.sql": {Data: []byte("CREATE TABLE a ();")},
+		},
+	}
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadMigrations(fsys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEmbeddedMigrationsArePaired(t *testing.T) {
+	got, err := LoadMigrations(migrations.FS)
+	require.NoError(t, err)
+	require.NotEmpty(t, got)
+	for i, m := range got {
+		assert.Equal(t, int64(i+1), m.Version, "migration versions should be contiguous")
+	}
+}
+
+func TestBaselineMigrations(t *testing.T) {
+	all := []Migration{{Version: 1, Name: "a"}, {Version: 2, Name: "b"}, {Version: 3, Name: "c"}}
+
+	got, err := baselineMigrations(all, map[int64]string{}, 2)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, int64(1), got[0].Version)
+	assert.Equal(t, int64(2), got[1].Version)
+
+	// Versions already recorded are not recorded again.
+	got, err = baselineMigrations(all, map[int64]string{1: "sum"}, 3)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, int64(2), got[0].Version)
+
+	_, err = baselineMigrations(all, map[int64]string{}, 4)
+	assert.Error(t, err)
+}
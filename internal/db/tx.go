@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog/log"
+)
+
+// DBTX is the query interface generated by sqlc in every domain's models
+// package. Both *sql.DB and *sql.Tx satisfy it, so repositories built on a
+// DBTX can run inside or outside a transaction.
+type DBTX interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+// Transactor runs fn in a transaction that is committed when fn returns nil
+// and rolled back otherwise. Services build their transaction-bound
+// repositories from the DBTX handed to fn.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(tx DBTX) error) error
+}
+
+type TxManager struct {
+	db         *sql.DB
+	opts       *sql.TxOptions
+	maxRetries int
+	backoff    time.Duration
+}
+
+type TxOption func(*TxManager)
+
+// WithIsolation sets the isolation level of every transaction.
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(m *TxManager) {
+		m.opts = &sql.TxOptions{Isolation: level}
+	}
+}
+
+// WithRetries sets how many times a transaction that failed with a
+// serialization failure or deadlock is re-run from the start.
+func WithRetries(maxRetries int, backoff time.Duration) TxOption {
+	return func(m *TxManager) {
+		m.maxRetries = maxRetries
+		m.backoff = backoff
+	}
+}
+
+func NewTxManager(db *sql.DB, opts ...TxOption) *TxManager {
+	m := &TxManager{db: db, maxRetries: 3, backoff: 20 * time.Millisecond}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// WithTx runs fn in a transaction. Because fn may be run again after a
+// serialization failure, it must not have side effects outside the database.
+func (m *TxManager) WithTx(ctx context.Context, fn func(tx DBTX) error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = m.runOnce(ctx, fn)
+		if err == nil || !IsRetryable(err) || attempt >= m.maxRetries {
+			return err
+		}
+
+		log.Warn().Err(err).Int("attempt", attempt+1).Msg("Retrying transaction after serialization failure")
+
+		// Jittered linear backoff keeps competing transactions from colliding again.
+		delay := m.backoff*time.Duration(attempt+1) + time.Duration(rand.Int63n(int64(m.backoff)+1))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (m *TxManager) runOnce(ctx context.Context, fn func(tx DBTX) error) (err error) {
+	tx, err := m.db.BeginTx(ctx, m.opts)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				err = fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+			}
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// IsUniqueViolation reports whether err is a Postgres unique constraint violation.
+func IsUniqueViolation(err error) bool {
+	return hasCode(err, "23505")
+}
+
+// IsExclusionViolation reports whether err is a Postgres exclusion constraint
+// violation, such as two overlapping date ranges.
+func IsExclusionViolation(err error) bool {
+	return hasCode(err, "23P01")
+}
+
+// IsRetryable reports whether err is a serialization failure or deadlock,
+// after which the whole transaction can safely be retried.
+func IsRetryable(err error) bool {
+	return hasCode(err, "40001") || hasCode(err, "40P01")
+}
+
+func hasCode(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
@@ -0,0 +1,24 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryable(t *testing.T) {
+	serialization := fmt.Errorf("update: %w", &pgconn.PgError{Code: "40001"})
+	deadlock := &pgconn.PgError{Code: "40P01"}
+	unique := &pgconn.PgError{Code: "23505"}
+
+	assert.True(t, IsRetryable(serialization))
+	assert.True(t, IsRetryable(deadlock))
+	assert.False(t, IsRetryable(unique))
+	assert.False(t, IsRetryable(errors.New("boom")))
+	assert.True(t, IsUniqueViolation(unique))
+	assert.True(t, IsExclusionViolation(&pgconn.PgError{Code: "23P01"}))
+	assert.False(t, IsExclusionViolation(unique))
+}
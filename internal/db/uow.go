@@ -0,0 +1,25 @@
+package db
+
+import "context"
+
+// UnitOfWork runs a callback against a set of repositories that all share one
+// transaction. R is a struct defined by the calling service holding the
+// repositories of every domain the operation touches, and build constructs it
+// from the transaction, typically by calling each domain's NewRepository.
+type UnitOfWork[R any] struct {
+	tx    Transactor
+	build func(tx DBTX) R
+}
+
+func NewUnitOfWork[R any](tx Transactor, build func(tx DBTX) R) *UnitOfWork[R] {
+	return &UnitOfWork[R]{tx: tx, build: build}
+}
+
+// Do commits everything fn writes through repos, or nothing if fn fails. fn
+// may be re-run on serialization failures and must not have side effects
+// outside the database.
+func (u *UnitOfWork[R]) Do(ctx context.Context, fn func(repos R) error) error {
+	return u.tx.WithTx(ctx, func(tx DBTX) error {
+		return fn(u.build(tx))
+	})
+}
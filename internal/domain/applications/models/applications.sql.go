@@ -10,6 +10,7 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/money"
 )
 
 const createApplication = `-- name: CreateApplication :one
@@ -109,7 +110,7 @@ type CreateBuildingApprovalParams struct {
 	ProjectName          string         `json:"project_name"`
 	PlotParcelNumber     string         `json:"plot_parcel_number"`
 	ProjectType          string         `json:"project_type"`
-	EstimatedProjectCost string         `json:"estimated_project_cost"`
+	EstimatedProjectCost money.Amount   `json:"estimated_project_cost"`
 	ContactEmail         sql.NullString `json:"contact_email"`
 	ContactPhone         sql.NullString `json:"contact_phone"`
 }
@@ -230,37 +231,37 @@ WHERE a.id = $1
 `
 
 type GetApplicationByIDRow struct {
-	ID                        uuid.UUID      `json:"id"`
-	TaxpayerID                uuid.UUID      `json:"taxpayer_id"`
-	Type                      string         `json:"type"`
-	Notes                     sql.NullString `json:"notes"`
-	Status                    string         `json:"status"`
-	SubmissionDate            sql.NullTime   `json:"submission_date"`
-	ApprovalDate              sql.NullTime   `json:"approval_date"`
-	CreatedAt                 sql.NullTime   `json:"created_at"`
-	UpdatedAt                 sql.NullTime   `json:"updated_at"`
-	BusinessName              sql.NullString `json:"business_name"`
-	KraPin                    sql.NullString `json:"kra_pin"`
-	BusinessType              sql.NullString `json:"business_type"`
-	BusinessLocation          sql.NullString `json:"business_location"`
-	NumberOfEmployees         sql.NullInt32  `json:"number_of_employees"`
-	ProjectName               sql.NullString `json:"project_name"`
-	PlotParcelNumber          sql.NullString `json:"plot_parcel_number"`
-	ProjectType               sql.NullString `json:"project_type"`
-	EstimatedProjectCost      sql.NullString `json:"estimated_project_cost"`
-	ContactEmail              sql.NullString `json:"contact_email"`
-	ContactPhone              sql.NullString `json:"contact_phone"`
-	VehicleRegistrationNumber sql.NullString `json:"vehicle_registration_number"`
-	PreferredParkingZone      sql.NullString `json:"preferred_parking_zone"`
-	Duration                  sql.NullString `json:"duration"`
-	ContactEmail_2            sql.NullString `json:"contact_email_2"`
-	ContactPhone_2            sql.NullString `json:"contact_phone_2"`
-	ApplicantName             sql.NullString `json:"applicant_name"`
-	BusinessName_2            sql.NullString `json:"business_name_2"`
-	ContactEmail_3            sql.NullString `json:"contact_email_3"`
-	ContactPhone_3            sql.NullString `json:"contact_phone_3"`
-	TaxpayerEmail             string         `json:"taxpayer_email"`
-	TaxpayerPhone             sql.NullString `json:"taxpayer_phone"`
+	ID                        uuid.UUID        `json:"id"`
+	TaxpayerID                uuid.UUID        `json:"taxpayer_id"`
+	Type                      string           `json:"type"`
+	Notes                     sql.NullString   `json:"notes"`
+	Status                    string           `json:"status"`
+	SubmissionDate            sql.NullTime     `json:"submission_date"`
+	ApprovalDate              sql.NullTime     `json:"approval_date"`
+	CreatedAt                 sql.NullTime     `json:"created_at"`
+	UpdatedAt                 sql.NullTime     `json:"updated_at"`
+	BusinessName              sql.NullString   `json:"business_name"`
+	KraPin                    sql.NullString   `json:"kra_pin"`
+	BusinessType              sql.NullString   `json:"business_type"`
+	BusinessLocation          sql.NullString   `json:"business_location"`
+	NumberOfEmployees         sql.NullInt32    `json:"number_of_employees"`
+	ProjectName               sql.NullString   `json:"project_name"`
+	PlotParcelNumber          sql.NullString   `json:"plot_parcel_number"`
+	ProjectType               sql.NullString   `json:"project_type"`
+	EstimatedProjectCost      money.NullAmount `json:"estimated_project_cost"`
+	ContactEmail              sql.NullString   `json:"contact_email"`
+	ContactPhone              sql.NullString   `json:"contact_phone"`
+	VehicleRegistrationNumber sql.NullString   `json:"vehicle_registration_number"`
+	PreferredParkingZone      sql.NullString   `json:"preferred_parking_zone"`
+	Duration                  sql.NullString   `json:"duration"`
+	ContactEmail_2            sql.NullString   `json:"contact_email_2"`
+	ContactPhone_2            sql.NullString   `json:"contact_phone_2"`
+	ApplicantName             sql.NullString   `json:"applicant_name"`
+	BusinessName_2            sql.NullString   `json:"business_name_2"`
+	ContactEmail_3            sql.NullString   `json:"contact_email_3"`
+	ContactPhone_3            sql.NullString   `json:"contact_phone_3"`
+	TaxpayerEmail             string           `json:"taxpayer_email"`
+	TaxpayerPhone             sql.NullString   `json:"taxpayer_phone"`
 }
 
 func (q *Queries) GetApplicationByID(ctx context.Context, id uuid.UUID) (GetApplicationByIDRow, error) {
@@ -321,37 +322,37 @@ ORDER BY a.created_at DESC
 `
 
 type ListApplicationsByTaxpayerRow struct {
-	ID                        uuid.UUID      `json:"id"`
-	TaxpayerID                uuid.UUID      `json:"taxpayer_id"`
-	Type                      string         `json:"type"`
-	Notes                     sql.NullString `json:"notes"`
-	Status                    string         `json:"status"`
-	SubmissionDate            sql.NullTime   `json:"submission_date"`
-	ApprovalDate              sql.NullTime   `json:"approval_date"`
-	CreatedAt                 sql.NullTime   `json:"created_at"`
-	UpdatedAt                 sql.NullTime   `json:"updated_at"`
-	BusinessName              sql.NullString `json:"business_name"`
-	KraPin                    sql.NullString `json:"kra_pin"`
-	BusinessType              sql.NullString `json:"business_type"`
-	BusinessLocation          sql.NullString `json:"business_location"`
-	NumberOfEmployees         sql.NullInt32  `json:"number_of_employees"`
-	ProjectName               sql.NullString `json:"project_name"`
-	PlotParcelNumber          sql.NullString `json:"plot_parcel_number"`
-	ProjectType               sql.NullString `json:"project_type"`
-	EstimatedProjectCost      sql.NullString `json:"estimated_project_cost"`
-	ContactEmail              sql.NullString `json:"contact_email"`
-	ContactPhone              sql.NullString `json:"contact_phone"`
-	VehicleRegistrationNumber sql.NullString `json:"vehicle_registration_number"`
-	PreferredParkingZone      sql.NullString `json:"preferred_parking_zone"`
-	Duration                  sql.NullString `json:"duration"`
-	ContactEmail_2            sql.NullString `json:"contact_email_2"`
-	ContactPhone_2            sql.NullString `json:"contact_phone_2"`
-	ApplicantName             sql.NullString `json:"applicant_name"`
-	BusinessName_2            sql.NullString `json:"business_name_2"`
-	ContactEmail_3            sql.NullString `json:"contact_email_3"`
-	ContactPhone_3            sql.NullString `json:"contact_phone_3"`
-	TaxpayerEmail             string         `json:"taxpayer_email"`
-	TaxpayerPhone             sql.NullString `json:"taxpayer_phone"`
+	ID                        uuid.UUID        `json:"id"`
+	TaxpayerID                uuid.UUID        `json:"taxpayer_id"`
+	Type                      string           `json:"type"`
+	Notes                     sql.NullString   `json:"notes"`
+	Status                    string           `json:"status"`
+	SubmissionDate            sql.NullTime     `json:"submission_date"`
+	ApprovalDate              sql.NullTime     `json:"approval_date"`
+	CreatedAt                 sql.NullTime     `json:"created_at"`
+	UpdatedAt                 sql.NullTime     `json:"updated_at"`
+	BusinessName              sql.NullString   `json:"business_name"`
+	KraPin                    sql.NullString   `json:"kra_pin"`
+	BusinessType              sql.NullString   `json:"business_type"`
+	BusinessLocation          sql.NullString   `json:"business_location"`
+	NumberOfEmployees         sql.NullInt32    `json:"number_of_employees"`
+	ProjectName               sql.NullString   `json:"project_name"`
+	PlotParcelNumber          sql.NullString   `json:"plot_parcel_number"`
+	ProjectType               sql.NullString   `json:"project_type"`
+	EstimatedProjectCost      money.NullAmount `json:"estimated_project_cost"`
+	ContactEmail              sql.NullString   `json:"contact_email"`
+	ContactPhone              sql.NullString   `json:"contact_phone"`
+	VehicleRegistrationNumber sql.NullString   `json:"vehicle_registration_number"`
+	PreferredParkingZone      sql.NullString   `json:"preferred_parking_zone"`
+	Duration                  sql.NullString   `json:"duration"`
+	ContactEmail_2            sql.NullString   `json:"contact_email_2"`
+	ContactPhone_2            sql.NullString   `json:"contact_phone_2"`
+	ApplicantName             sql.NullString   `json:"applicant_name"`
+	BusinessName_2            sql.NullString   `json:"business_name_2"`
+	ContactEmail_3            sql.NullString   `json:"contact_email_3"`
+	ContactPhone_3            sql.NullString   `json:"contact_phone_3"`
+	TaxpayerEmail             string           `json:"taxpayer_email"`
+	TaxpayerPhone             sql.NullString   `json:"taxpayer_phone"`
 }
 
 func (q *Queries) ListApplicationsByTaxpayer(ctx context.Context, taxpayerID uuid.UUID) ([]ListApplicationsByTaxpayerRow, error) {
@@ -1,31 +1,39 @@
 package assessment
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
-	"github.com/sangkips/revenue-system/internal/domain/assessment/models"
+	"github.com/sangkips/revenue-system/internal/db"
 	"github.com/sangkips/revenue-system/internal/middleware/auth"
+	"github.com/sangkips/revenue-system/internal/tariff"
 )
 
 type Handler struct {
 	svc *Service
 }
 
-func NewHandler(db models.DBTX) *Handler {
-	repo := NewRepository(db)
-	return &Handler{svc: NewService(repo)}
+func NewHandler(conn *sql.DB, opts ...Option) *Handler {
+	repo := NewRepository(conn)
+	uow := db.NewUnitOfWork(db.NewTxManager(conn), func(tx db.DBTX) Repository {
+		return NewRepository(tx)
+	})
+	opts = append([]Option{WithUnitOfWork(uow)}, opts...)
+	return &Handler{svc: NewService(repo, opts...)}
 }
 
 func (h *Handler) RegisterAssessmentRoutes(r chi.Router) {
-	r.Post("/", h.CreateAssessment)
-	r.Get("/{id}", h.GetAssessment)
-	r.Get("/", h.ListAssessments)
-	r.Patch("/{id}", h.UpdateAssessment)
-	r.Delete("/{id}", h.DeleteAssessment)
+	r.With(auth.RequirePermission(auth.PermAssessmentsWrite)).Post("/", h.CreateAssessment)
+	r.With(auth.RequirePermission(auth.PermAssessmentsRead)).Post("/quote", h.QuoteAssessment)
+	r.With(auth.RequirePermission(auth.PermAssessmentsRead)).Get("/{id}", h.GetAssessment)
+	r.With(auth.RequirePermission(auth.PermAssessmentsRead)).Get("/", h.ListAssessments)
+	r.With(auth.RequirePermission(auth.PermAssessmentsWrite)).Patch("/{id}", h.UpdateAssessment)
+	r.With(auth.RequirePermission(auth.PermAssessmentsWrite)).Delete("/{id}", h.DeleteAssessment)
 }
 
 func (h *Handler) CreateAssessment(w http.ResponseWriter, r *http.Request) {
@@ -43,7 +51,11 @@ func (h *Handler) CreateAssessment(w http.ResponseWriter, r *http.Request) {
 	assessment, err := h.svc.CreateAssessment(ctx, req, userID)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create assessment")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if db.IsUniqueViolation(err) {
+			http.Error(w, "an assessment with this assessment_number already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), tariffErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -56,7 +68,7 @@ func (h *Handler) GetAssessment(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	assessment, err := h.svc.GetAssessment(ctx, id)
 	if err != nil {
-		http.Error(w, "Not found", http.StatusNotFound)
+		http.Error(w, "Not found", auth.ErrorStatus(err, http.StatusNotFound))
 		return
 	}
 
@@ -78,7 +90,7 @@ func (h *Handler) ListAssessments(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	assessments, err := h.svc.ListAssessments(ctx, int32(countyID), int32(limit), int32(offset))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	json.NewEncoder(w).Encode(assessments)
@@ -95,7 +107,7 @@ func (h *Handler) UpdateAssessment(w http.ResponseWriter, r *http.Request) {
 
 	assessment, err := h.svc.UpdateAssessment(ctx, id, req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -107,7 +119,7 @@ func (h *Handler) DeleteAssessment(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	ctx := r.Context()
 	if err := h.svc.DeleteAssessment(ctx, id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -126,7 +138,7 @@ func (h *Handler) CreateAssessmentItem(w http.ResponseWriter, r *http.Request) {
 	item, err := h.svc.CreateAssessmentItem(ctx, req)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create assessment item")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -139,7 +151,7 @@ func (h *Handler) ListAssessmentItems(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	items, err := h.svc.ListAssessmentItems(ctx, assessmentID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	json.NewEncoder(w).Encode(items)
@@ -155,8 +167,107 @@ func (h *Handler) DeleteAssessmentItem(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		log.Error().Err(err).Msg("Failed to delete assessment item")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
-}
\ No newline at end of file
+}
+
+// QuoteAssessment prices assessment lines from the tariff without raising an
+// assessment, so a clerk can show the taxpayer the charge first.
+func (h *Handler) QuoteAssessment(w http.ResponseWriter, r *http.Request) {
+	var req QuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	quote, err := h.svc.Quote(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), tariffErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(quote)
+}
+
+// RegisterTariffRoutes mounts the county's rate card.
+func (h *Handler) RegisterTariffRoutes(r chi.Router) {
+	r.With(auth.RequirePermission(auth.PermTariffsManage)).Post("/", h.CreateTariff)
+	r.With(auth.RequirePermission(auth.PermAssessmentsRead)).Get("/", h.ListTariffs)
+	r.With(auth.RequirePermission(auth.PermAssessmentsRead)).Get("/{id}", h.GetTariff)
+	r.With(auth.RequirePermission(auth.PermTariffsManage)).Post("/{id}/end", h.EndTariff)
+}
+
+func (h *Handler) CreateTariff(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		return
+	}
+	var req CreateTariffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	detail, err := h.svc.CreateTariff(r.Context(), req, userID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create tariff")
+		http.Error(w, err.Error(), tariffErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(detail)
+}
+
+// ListTariffs takes optional "county_id" and "financial_year" query
+// parameters.
+func (h *Handler) ListTariffs(w http.ResponseWriter, r *http.Request) {
+	countyID, _ := strconv.ParseInt(r.URL.Query().Get("county_id"), 10, 32)
+	tariffs, err := h.svc.ListTariffs(r.Context(), int32(countyID), r.URL.Query().Get("financial_year"))
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tariffs)
+}
+
+func (h *Handler) GetTariff(w http.ResponseWriter, r *http.Request) {
+	detail, err := h.svc.GetTariff(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), tariffErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
+
+func (h *Handler) EndTariff(w http.ResponseWriter, r *http.Request) {
+	var req EndTariffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	t, err := h.svc.EndTariff(r.Context(), chi.URLParam(r, "id"), req)
+	if err != nil {
+		http.Error(w, err.Error(), tariffErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t)
+}
+
+// tariffErrorStatus maps rate cards and lines the tariff cannot price.
+func tariffErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, ErrTaxpayerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrTariffOverlap):
+		return http.StatusConflict
+	case errors.Is(err, ErrUnknownTariff), errors.Is(err, tariff.ErrInvalidTariff), errors.Is(err, tariff.ErrInvalidInput):
+		return http.StatusUnprocessableEntity
+	}
+	return auth.ErrorStatus(err, fallback)
+}
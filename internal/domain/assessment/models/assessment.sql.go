@@ -7,10 +7,10 @@ package models
 
 import (
 	"context"
-	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/money"
 )
 
 const deleteAssessment = `-- name: DeleteAssessment :exec
@@ -63,8 +63,41 @@ func (q *Queries) GetAssessmentByID(ctx context.Context, id uuid.UUID) (Assessme
 	return i, err
 }
 
+const getAssessmentByNumber = `-- name: GetAssessmentByNumber :one
+SELECT id, county_id, taxpayer_id, revenue_id, assessment_number, assessment_type,
+       financial_year, base_amount, calculated_amount, total_amount, status, due_date,
+       assessed_by, assessed_date, created_at, updated_at
+FROM assessments
+WHERE assessment_number = $1
+`
+
+// Looks up an assessment by the reference taxpayers quote when paying, e.g. a paybill account number
+func (q *Queries) GetAssessmentByNumber(ctx context.Context, assessmentNumber string) (Assessment, error) {
+	row := q.db.QueryRowContext(ctx, getAssessmentByNumber, assessmentNumber)
+	var i Assessment
+	err := row.Scan(
+		&i.ID,
+		&i.CountyID,
+		&i.TaxpayerID,
+		&i.RevenueID,
+		&i.AssessmentNumber,
+		&i.AssessmentType,
+		&i.FinancialYear,
+		&i.BaseAmount,
+		&i.CalculatedAmount,
+		&i.TotalAmount,
+		&i.Status,
+		&i.DueDate,
+		&i.AssessedBy,
+		&i.AssessedDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getAssessmentItemByID = `-- name: GetAssessmentItemByID :one
-SELECT id, assessment_id, item_description, quantity, unit_amount, total_amount, created_at
+SELECT id, assessment_id, item_description, quantity, unit_amount, total_amount, created_at, item_type
 FROM assessment_items
 WHERE id = $1
 `
@@ -80,10 +113,22 @@ func (q *Queries) GetAssessmentItemByID(ctx context.Context, id uuid.UUID) (Asse
 		&i.UnitAmount,
 		&i.TotalAmount,
 		&i.CreatedAt,
+		&i.ItemType,
 	)
 	return i, err
 }
 
+const getTaxpayerCounty = `-- name: GetTaxpayerCounty :one
+SELECT county_id FROM taxpayers WHERE id = $1
+`
+
+func (q *Queries) GetTaxpayerCounty(ctx context.Context, id uuid.UUID) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getTaxpayerCounty, id)
+	var county_id int32
+	err := row.Scan(&county_id)
+	return county_id, err
+}
+
 const insertAssessment = `-- name: InsertAssessment :one
 INSERT INTO assessments (
     county_id, taxpayer_id, revenue_id, assessment_number, assessment_type,
@@ -107,9 +152,9 @@ type InsertAssessmentParams struct {
 	AssessmentNumber string        `json:"assessment_number"`
 	AssessmentType   string        `json:"assessment_type"`
 	FinancialYear    string        `json:"financial_year"`
-	BaseAmount       string        `json:"base_amount"`
-	CalculatedAmount string        `json:"calculated_amount"`
-	TotalAmount      string        `json:"total_amount"`
+	BaseAmount       money.Amount  `json:"base_amount"`
+	CalculatedAmount money.Amount  `json:"calculated_amount"`
+	TotalAmount      money.Amount  `json:"total_amount"`
 	Status           string        `json:"status"`
 	DueDate          time.Time     `json:"due_date"`
 	AssessedBy       uuid.NullUUID `json:"assessed_by"`
@@ -157,20 +202,21 @@ func (q *Queries) InsertAssessment(ctx context.Context, arg InsertAssessmentPara
 
 const insertAssessmentItem = `-- name: InsertAssessmentItem :one
 INSERT INTO assessment_items (
-    assessment_id, item_description, quantity, unit_amount, total_amount
+    assessment_id, item_description, quantity, unit_amount, total_amount, item_type
 )
 VALUES (
-    $1, $2, $3, $4, $5
+    $1, $2, $3, $4, $5, $6
 )
-RETURNING id, assessment_id, item_description, quantity, unit_amount, total_amount, created_at
+RETURNING id, assessment_id, item_description, quantity, unit_amount, total_amount, created_at, item_type
 `
 
 type InsertAssessmentItemParams struct {
-	AssessmentID    uuid.UUID      `json:"assessment_id"`
-	ItemDescription string         `json:"item_description"`
-	Quantity        sql.NullString `json:"quantity"`
-	UnitAmount      string         `json:"unit_amount"`
-	TotalAmount     string         `json:"total_amount"`
+	AssessmentID    uuid.UUID          `json:"assessment_id"`
+	ItemDescription string             `json:"item_description"`
+	Quantity        money.NullQuantity `json:"quantity"`
+	UnitAmount      money.Amount       `json:"unit_amount"`
+	TotalAmount     money.Amount       `json:"total_amount"`
+	ItemType        string             `json:"item_type"`
 }
 
 // Assessment Items Queries
@@ -181,6 +227,7 @@ func (q *Queries) InsertAssessmentItem(ctx context.Context, arg InsertAssessment
 		arg.Quantity,
 		arg.UnitAmount,
 		arg.TotalAmount,
+		arg.ItemType,
 	)
 	var i AssessmentItem
 	err := row.Scan(
@@ -191,12 +238,13 @@ func (q *Queries) InsertAssessmentItem(ctx context.Context, arg InsertAssessment
 		&i.UnitAmount,
 		&i.TotalAmount,
 		&i.CreatedAt,
+		&i.ItemType,
 	)
 	return i, err
 }
 
 const listAssessmentItems = `-- name: ListAssessmentItems :many
-SELECT id, assessment_id, item_description, quantity, unit_amount, total_amount, created_at
+SELECT id, assessment_id, item_description, quantity, unit_amount, total_amount, created_at, item_type
 FROM assessment_items
 WHERE assessment_id = $1
 ORDER BY created_at ASC
@@ -219,6 +267,7 @@ func (q *Queries) ListAssessmentItems(ctx context.Context, assessmentID uuid.UUI
 			&i.UnitAmount,
 			&i.TotalAmount,
 			&i.CreatedAt,
+			&i.ItemType,
 		); err != nil {
 			return nil, err
 		}
@@ -289,12 +338,105 @@ func (q *Queries) ListAssessments(ctx context.Context, arg ListAssessmentsParams
 	return items, nil
 }
 
+const listOpenAssessmentsForTaxpayerForUpdate = `-- name: ListOpenAssessmentsForTaxpayerForUpdate :many
+SELECT id, county_id, taxpayer_id, revenue_id, assessment_number, assessment_type,
+       financial_year, base_amount, calculated_amount, total_amount, status, due_date,
+       assessed_by, assessed_date, created_at, updated_at
+FROM assessments
+WHERE county_id = $1 AND taxpayer_id = $2
+  AND status IN ('pending', 'approved')
+ORDER BY due_date ASC, assessed_date ASC, created_at ASC
+FOR UPDATE
+`
+
+type ListOpenAssessmentsForTaxpayerForUpdateParams struct {
+	CountyID   int32     `json:"county_id"`
+	TaxpayerID uuid.UUID `json:"taxpayer_id"`
+}
+
+// Open assessments of a taxpayer, oldest due first, locked for allocation
+func (q *Queries) ListOpenAssessmentsForTaxpayerForUpdate(ctx context.Context, arg ListOpenAssessmentsForTaxpayerForUpdateParams) ([]Assessment, error) {
+	rows, err := q.db.QueryContext(ctx, listOpenAssessmentsForTaxpayerForUpdate, arg.CountyID, arg.TaxpayerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Assessment
+	for rows.Next() {
+		var i Assessment
+		if err := rows.Scan(
+			&i.ID,
+			&i.CountyID,
+			&i.TaxpayerID,
+			&i.RevenueID,
+			&i.AssessmentNumber,
+			&i.AssessmentType,
+			&i.FinancialYear,
+			&i.BaseAmount,
+			&i.CalculatedAmount,
+			&i.TotalAmount,
+			&i.Status,
+			&i.DueDate,
+			&i.AssessedBy,
+			&i.AssessedDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sumAssessmentItemsByType = `-- name: SumAssessmentItemsByType :many
+SELECT item_type, COALESCE(SUM(total_amount), 0)::decimal AS total
+FROM assessment_items
+WHERE assessment_id = $1
+GROUP BY item_type
+`
+
+type SumAssessmentItemsByTypeRow struct {
+	ItemType string       `json:"item_type"`
+	Total    money.Amount `json:"total"`
+}
+
+// What an assessment's items charge per component; principal not itemised is the rest of total_amount
+func (q *Queries) SumAssessmentItemsByType(ctx context.Context, assessmentID uuid.UUID) ([]SumAssessmentItemsByTypeRow, error) {
+	rows, err := q.db.QueryContext(ctx, sumAssessmentItemsByType, assessmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SumAssessmentItemsByTypeRow
+	for rows.Next() {
+		var i SumAssessmentItemsByTypeRow
+		if err := rows.Scan(&i.ItemType, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateAssessment = `-- name: UpdateAssessment :one
 UPDATE assessments
 SET
-    base_amount = CASE WHEN $1::text = '' THEN base_amount ELSE $1::decimal END,
-    calculated_amount = CASE WHEN $2::text = '' THEN calculated_amount ELSE $2::decimal END,
-    total_amount = CASE WHEN $3::text = '' THEN total_amount ELSE $3::decimal END,
+    base_amount = COALESCE($1::decimal, base_amount),
+    calculated_amount = COALESCE($2::decimal, calculated_amount),
+    total_amount = COALESCE($3::decimal, total_amount),
     status = CASE WHEN $4 = '' THEN status ELSE $4 END,
     due_date = CASE WHEN $5 = '1970-01-01T00:00:00Z'::timestamptz THEN due_date ELSE $5 END,
     updated_at = CURRENT_TIMESTAMP
@@ -305,12 +447,12 @@ RETURNING id, county_id, taxpayer_id, revenue_id, assessment_number, assessment_
 `
 
 type UpdateAssessmentParams struct {
-	BaseAmount       string      `json:"base_amount"`
-	CalculatedAmount string      `json:"calculated_amount"`
-	TotalAmount      string      `json:"total_amount"`
-	Status           interface{} `json:"status"`
-	DueDate          interface{} `json:"due_date"`
-	ID               uuid.UUID   `json:"id"`
+	BaseAmount       money.NullAmount `json:"base_amount"`
+	CalculatedAmount money.NullAmount `json:"calculated_amount"`
+	TotalAmount      money.NullAmount `json:"total_amount"`
+	Status           interface{}      `json:"status"`
+	DueDate          interface{}      `json:"due_date"`
+	ID               uuid.UUID        `json:"id"`
 }
 
 func (q *Queries) UpdateAssessment(ctx context.Context, arg UpdateAssessmentParams) (Assessment, error) {
@@ -343,3 +485,53 @@ func (q *Queries) UpdateAssessment(ctx context.Context, arg UpdateAssessmentPara
 	)
 	return i, err
 }
+
+const getAssessmentForUpdate = `-- name: GetAssessmentForUpdate :one
+SELECT id, county_id, taxpayer_id, revenue_id, assessment_number, assessment_type,
+       financial_year, base_amount, calculated_amount, total_amount, status, due_date,
+       assessed_by, assessed_date, created_at, updated_at
+FROM assessments
+WHERE id = $1
+FOR UPDATE
+`
+
+// Locks the assessment until the surrounding transaction ends so concurrent payments settle it one at a time
+func (q *Queries) GetAssessmentForUpdate(ctx context.Context, id uuid.UUID) (Assessment, error) {
+	row := q.db.QueryRowContext(ctx, getAssessmentForUpdate, id)
+	var i Assessment
+	err := row.Scan(
+		&i.ID,
+		&i.CountyID,
+		&i.TaxpayerID,
+		&i.RevenueID,
+		&i.AssessmentNumber,
+		&i.AssessmentType,
+		&i.FinancialYear,
+		&i.BaseAmount,
+		&i.CalculatedAmount,
+		&i.TotalAmount,
+		&i.Status,
+		&i.DueDate,
+		&i.AssessedBy,
+		&i.AssessedDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const setAssessmentStatus = `-- name: SetAssessmentStatus :exec
+UPDATE assessments
+SET status = $1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2
+`
+
+type SetAssessmentStatusParams struct {
+	Status string    `json:"status"`
+	ID     uuid.UUID `json:"id"`
+}
+
+func (q *Queries) SetAssessmentStatus(ctx context.Context, arg SetAssessmentStatusParams) error {
+	_, err := q.db.ExecContext(ctx, setAssessmentStatus, arg.Status, arg.ID)
+	return err
+}
@@ -13,14 +13,33 @@ import (
 type Querier interface {
 	DeleteAssessment(ctx context.Context, id uuid.UUID) error
 	DeleteAssessmentItem(ctx context.Context, id uuid.UUID) error
+	// Sets the last day a tariff is in effect.
+	EndTariff(ctx context.Context, arg EndTariffParams) (Tariff, error)
 	GetAssessmentByID(ctx context.Context, id uuid.UUID) (Assessment, error)
+	// Looks up an assessment by the reference taxpayers quote when paying, e.g. a paybill account number
+	GetAssessmentByNumber(ctx context.Context, assessmentNumber string) (Assessment, error)
+	// Locks the assessment until the surrounding transaction ends so concurrent payments settle it one at a time
+	GetAssessmentForUpdate(ctx context.Context, id uuid.UUID) (Assessment, error)
 	GetAssessmentItemByID(ctx context.Context, id uuid.UUID) (AssessmentItem, error)
+	// The version of a county's tariff in effect on a day.
+	GetEffectiveTariff(ctx context.Context, arg GetEffectiveTariffParams) (Tariff, error)
+	GetTariffByID(ctx context.Context, id uuid.UUID) (Tariff, error)
+	GetTaxpayerCounty(ctx context.Context, id uuid.UUID) (int32, error)
 	// internal/domains/assessment/queries/assessment.sql
 	InsertAssessment(ctx context.Context, arg InsertAssessmentParams) (Assessment, error)
 	// Assessment Items Queries
 	InsertAssessmentItem(ctx context.Context, arg InsertAssessmentItemParams) (AssessmentItem, error)
+	InsertTariff(ctx context.Context, arg InsertTariffParams) (Tariff, error)
+	InsertTariffBand(ctx context.Context, arg InsertTariffBandParams) error
 	ListAssessmentItems(ctx context.Context, assessmentID uuid.UUID) ([]AssessmentItem, error)
 	ListAssessments(ctx context.Context, arg ListAssessmentsParams) ([]Assessment, error)
+	// Open assessments of a taxpayer, oldest due first, locked for allocation
+	ListOpenAssessmentsForTaxpayerForUpdate(ctx context.Context, arg ListOpenAssessmentsForTaxpayerForUpdateParams) ([]Assessment, error)
+	ListTariffBands(ctx context.Context, tariffID uuid.UUID) ([]TariffBand, error)
+	ListTariffs(ctx context.Context, arg ListTariffsParams) ([]Tariff, error)
+	SetAssessmentStatus(ctx context.Context, arg SetAssessmentStatusParams) error
+	// What an assessment's items charge per component; principal not itemised is the rest of total_amount
+	SumAssessmentItemsByType(ctx context.Context, assessmentID uuid.UUID) ([]SumAssessmentItemsByTypeRow, error)
 	UpdateAssessment(ctx context.Context, arg UpdateAssessmentParams) (Assessment, error)
 }
 
@@ -21,6 +21,14 @@ SELECT id, county_id, taxpayer_id, revenue_id, assessment_number, assessment_typ
 FROM assessments
 WHERE id = @id;
 
+-- Looks up an assessment by the reference taxpayers quote when paying, e.g. a paybill account number
+-- name: GetAssessmentByNumber :one
+SELECT id, county_id, taxpayer_id, revenue_id, assessment_number, assessment_type,
+       financial_year, base_amount, calculated_amount, total_amount, status, due_date,
+       assessed_by, assessed_date, created_at, updated_at
+FROM assessments
+WHERE assessment_number = @assessment_number;
+
 -- name: ListAssessments :many
 SELECT id, county_id, taxpayer_id, revenue_id, assessment_number, assessment_type,
        financial_year, base_amount, calculated_amount, total_amount, status, due_date,
@@ -33,9 +41,9 @@ LIMIT $1 OFFSET $2;
 -- name: UpdateAssessment :one
 UPDATE assessments
 SET
-    base_amount = CASE WHEN @base_amount::text = '' THEN base_amount ELSE @base_amount::decimal END,
-    calculated_amount = CASE WHEN @calculated_amount::text = '' THEN calculated_amount ELSE @calculated_amount::decimal END,
-    total_amount = CASE WHEN @total_amount::text = '' THEN total_amount ELSE @total_amount::decimal END,
+    base_amount = COALESCE(sqlc.narg('base_amount')::decimal, base_amount),
+    calculated_amount = COALESCE(sqlc.narg('calculated_amount')::decimal, calculated_amount),
+    total_amount = COALESCE(sqlc.narg('total_amount')::decimal, total_amount),
     status = CASE WHEN @status = '' THEN status ELSE @status END,
     due_date = CASE WHEN @due_date = '1970-01-01T00:00:00Z'::timestamptz THEN due_date ELSE @due_date END,
     updated_at = CURRENT_TIMESTAMP
@@ -50,23 +58,58 @@ DELETE FROM assessments WHERE id = @id;
 -- Assessment Items Queries
 -- name: InsertAssessmentItem :one
 INSERT INTO assessment_items (
-    assessment_id, item_description, quantity, unit_amount, total_amount
+    assessment_id, item_description, quantity, unit_amount, total_amount, item_type
 )
 VALUES (
-    @assessment_id, @item_description, @quantity, @unit_amount, @total_amount
+    @assessment_id, @item_description, @quantity, @unit_amount, @total_amount, @item_type
 )
-RETURNING id, assessment_id, item_description, quantity, unit_amount, total_amount, created_at;
+RETURNING id, assessment_id, item_description, quantity, unit_amount, total_amount, created_at, item_type;
 
 -- name: ListAssessmentItems :many
-SELECT id, assessment_id, item_description, quantity, unit_amount, total_amount, created_at
+SELECT id, assessment_id, item_description, quantity, unit_amount, total_amount, created_at, item_type
 FROM assessment_items
 WHERE assessment_id = @assessment_id
 ORDER BY created_at ASC;
 
 -- name: GetAssessmentItemByID :one
-SELECT id, assessment_id, item_description, quantity, unit_amount, total_amount, created_at
+SELECT id, assessment_id, item_description, quantity, unit_amount, total_amount, created_at, item_type
 FROM assessment_items
 WHERE id = @id;
 
 -- name: DeleteAssessmentItem :exec
 DELETE FROM assessment_items WHERE id = @id;
+
+-- Locks the assessment until the surrounding transaction ends so concurrent payments settle it one at a time
+-- name: GetAssessmentForUpdate :one
+SELECT id, county_id, taxpayer_id, revenue_id, assessment_number, assessment_type,
+       financial_year, base_amount, calculated_amount, total_amount, status, due_date,
+       assessed_by, assessed_date, created_at, updated_at
+FROM assessments
+WHERE id = @id
+FOR UPDATE;
+
+-- Open assessments of a taxpayer, oldest due first, locked for allocation
+-- name: ListOpenAssessmentsForTaxpayerForUpdate :many
+SELECT id, county_id, taxpayer_id, revenue_id, assessment_number, assessment_type,
+       financial_year, base_amount, calculated_amount, total_amount, status, due_date,
+       assessed_by, assessed_date, created_at, updated_at
+FROM assessments
+WHERE county_id = @county_id AND taxpayer_id = @taxpayer_id
+  AND status IN ('pending', 'approved')
+ORDER BY due_date ASC, assessed_date ASC, created_at ASC
+FOR UPDATE;
+
+-- What an assessment's items charge per component; principal not itemised is the rest of total_amount
+-- name: SumAssessmentItemsByType :many
+SELECT item_type, COALESCE(SUM(total_amount), 0)::decimal AS total
+FROM assessment_items
+WHERE assessment_id = @assessment_id
+GROUP BY item_type;
+
+-- name: SetAssessmentStatus :exec
+UPDATE assessments
+SET status = @status, updated_at = CURRENT_TIMESTAMP
+WHERE id = @id;
+
+-- name: GetTaxpayerCounty :one
+SELECT county_id FROM taxpayers WHERE id = @id;
@@ -0,0 +1,53 @@
+-- Tariff catalogue
+
+-- name: InsertTariff :one
+INSERT INTO tariffs (
+    county_id, code, name, assessment_type, financial_year, method, amount, unit, rate,
+    minimum_amount, maximum_amount, effective_from, effective_to, created_by
+)
+VALUES (
+    @county_id, @code, @name, @assessment_type, @financial_year, @method, @amount, @unit, @rate,
+    @minimum_amount, @maximum_amount, @effective_from, @effective_to, @created_by
+)
+RETURNING id, county_id, code, name, assessment_type, financial_year, method, amount, unit, rate,
+    minimum_amount, maximum_amount, effective_from, effective_to, created_by, created_at, updated_at;
+
+-- name: InsertTariffBand :exec
+INSERT INTO tariff_bands (tariff_id, lower_bound, amount, unit_amount)
+VALUES (@tariff_id, @lower_bound, @amount, @unit_amount);
+
+-- name: GetTariffByID :one
+SELECT id, county_id, code, name, assessment_type, financial_year, method, amount, unit, rate,
+    minimum_amount, maximum_amount, effective_from, effective_to, created_by, created_at, updated_at
+FROM tariffs
+WHERE id = @id;
+
+-- The version of a county's tariff in effect on a day.
+-- name: GetEffectiveTariff :one
+SELECT id, county_id, code, name, assessment_type, financial_year, method, amount, unit, rate,
+    minimum_amount, maximum_amount, effective_from, effective_to, created_by, created_at, updated_at
+FROM tariffs
+WHERE county_id = @county_id AND code = @code
+  AND effective_from <= @on_date AND (effective_to IS NULL OR effective_to >= @on_date);
+
+-- name: ListTariffs :many
+SELECT id, county_id, code, name, assessment_type, financial_year, method, amount, unit, rate,
+    minimum_amount, maximum_amount, effective_from, effective_to, created_by, created_at, updated_at
+FROM tariffs
+WHERE county_id = @county_id
+  AND (sqlc.narg('financial_year')::text IS NULL OR financial_year = sqlc.narg('financial_year'))
+ORDER BY code ASC, effective_from DESC;
+
+-- name: ListTariffBands :many
+SELECT tariff_id, lower_bound, amount, unit_amount
+FROM tariff_bands
+WHERE tariff_id = @tariff_id
+ORDER BY lower_bound ASC;
+
+-- Sets the last day a tariff is in effect.
+-- name: EndTariff :one
+UPDATE tariffs
+SET effective_to = @effective_to, updated_at = CURRENT_TIMESTAMP
+WHERE id = @id
+RETURNING id, county_id, code, name, assessment_type, financial_year, method, amount, unit, rate,
+    minimum_amount, maximum_amount, effective_from, effective_to, created_by, created_at, updated_at;
@@ -2,9 +2,12 @@ package assessment
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/sangkips/revenue-system/internal/domain/assessment/models"
+	"github.com/sangkips/revenue-system/internal/numbering"
 )
 
 
@@ -14,19 +17,37 @@ type Repository interface {
 	ListAssessments(ctx context.Context, params models.ListAssessmentsParams) ([]models.Assessment, error)
 	UpdateAssessment(ctx context.Context, params models.UpdateAssessmentParams) (models.Assessment, error)
 	DeleteAssessment(ctx context.Context, id string) error
+	GetAssessmentByNumber(ctx context.Context, assessmentNumber string) (models.Assessment, error)
+	GetAssessmentForUpdate(ctx context.Context, id uuid.UUID) (models.Assessment, error)
+	SetAssessmentStatus(ctx context.Context, params models.SetAssessmentStatusParams) error
+	ListOpenAssessmentsForTaxpayerForUpdate(ctx context.Context, countyID int32, taxpayerID uuid.UUID) ([]models.Assessment, error)
+	GetTaxpayerCounty(ctx context.Context, taxpayerID uuid.UUID) (int32, error)
+	SumAssessmentItemsByType(ctx context.Context, assessmentID uuid.UUID) ([]models.SumAssessmentItemsByTypeRow, error)
 
 	CreateAssessmentItem(ctx context.Context, item models.InsertAssessmentItemParams) (models.AssessmentItem, error)
 	ListAssessmentItems(ctx context.Context, asessmentID string) ([]models.AssessmentItem, error)
 	DeleteAssessmentItem(ctx context.Context, id string) error
 	GetAssessmentItemByID(ctx context.Context, id string) (models.AssessmentItem, error)
+
+	GetCountyCode(ctx context.Context, countyID int32) (string, error)
+	NextDocumentSequence(ctx context.Context, countyID int32, documentType, financialYear string) (int64, error)
+
+	CreateTariff(ctx context.Context, params models.InsertTariffParams) (models.Tariff, error)
+	CreateTariffBand(ctx context.Context, params models.InsertTariffBandParams) error
+	GetTariffByID(ctx context.Context, id uuid.UUID) (models.Tariff, error)
+	GetEffectiveTariff(ctx context.Context, countyID int32, code string, on time.Time) (models.Tariff, error)
+	ListTariffs(ctx context.Context, params models.ListTariffsParams) ([]models.Tariff, error)
+	ListTariffBands(ctx context.Context, tariffID uuid.UUID) ([]models.TariffBand, error)
+	EndTariff(ctx context.Context, id uuid.UUID, effectiveTo time.Time) (models.Tariff, error)
 }
 
 type repository struct {
-	q *models.Queries
+	q       *models.Queries
+	numbers numbering.Counter
 }
 
 func NewRepository(db models.DBTX) Repository {
-	return &repository{q: models.New(db)}
+	return &repository{q: models.New(db), numbers: numbering.NewCounter(db)}
 }
 
 func (r *repository) CreateAssessment(ctx context.Context, assessment models.InsertAssessmentParams) (models.Assessment, error) {
@@ -84,4 +105,74 @@ func (r *repository) DeleteAssessmentItem(ctx context.Context, id string) error
 		return err
 	}
 	return r.q.DeleteAssessmentItem(ctx, parsedID)
-}
\ No newline at end of file
+}
+
+func (r *repository) GetAssessmentByNumber(ctx context.Context, assessmentNumber string) (models.Assessment, error) {
+	return r.q.GetAssessmentByNumber(ctx, assessmentNumber)
+}
+
+func (r *repository) GetAssessmentForUpdate(ctx context.Context, id uuid.UUID) (models.Assessment, error) {
+	return r.q.GetAssessmentForUpdate(ctx, id)
+}
+
+func (r *repository) SetAssessmentStatus(ctx context.Context, params models.SetAssessmentStatusParams) error {
+	return r.q.SetAssessmentStatus(ctx, params)
+}
+
+func (r *repository) ListOpenAssessmentsForTaxpayerForUpdate(ctx context.Context, countyID int32, taxpayerID uuid.UUID) ([]models.Assessment, error) {
+	return r.q.ListOpenAssessmentsForTaxpayerForUpdate(ctx, models.ListOpenAssessmentsForTaxpayerForUpdateParams{
+		CountyID:   countyID,
+		TaxpayerID: taxpayerID,
+	})
+}
+
+func (r *repository) SumAssessmentItemsByType(ctx context.Context, assessmentID uuid.UUID) ([]models.SumAssessmentItemsByTypeRow, error) {
+	return r.q.SumAssessmentItemsByType(ctx, assessmentID)
+}
+
+func (r *repository) GetTaxpayerCounty(ctx context.Context, taxpayerID uuid.UUID) (int32, error) {
+	return r.q.GetTaxpayerCounty(ctx, taxpayerID)
+}
+
+func (r *repository) GetCountyCode(ctx context.Context, countyID int32) (string, error) {
+	return r.numbers.GetCountyCode(ctx, countyID)
+}
+
+func (r *repository) NextDocumentSequence(ctx context.Context, countyID int32, documentType, financialYear string) (int64, error) {
+	return r.numbers.NextDocumentSequence(ctx, countyID, documentType, financialYear)
+}
+
+func (r *repository) CreateTariff(ctx context.Context, params models.InsertTariffParams) (models.Tariff, error) {
+	return r.q.InsertTariff(ctx, params)
+}
+
+func (r *repository) CreateTariffBand(ctx context.Context, params models.InsertTariffBandParams) error {
+	return r.q.InsertTariffBand(ctx, params)
+}
+
+func (r *repository) GetTariffByID(ctx context.Context, id uuid.UUID) (models.Tariff, error) {
+	return r.q.GetTariffByID(ctx, id)
+}
+
+func (r *repository) GetEffectiveTariff(ctx context.Context, countyID int32, code string, on time.Time) (models.Tariff, error) {
+	return r.q.GetEffectiveTariff(ctx, models.GetEffectiveTariffParams{
+		CountyID: countyID,
+		Code:     code,
+		OnDate:   on,
+	})
+}
+
+func (r *repository) ListTariffs(ctx context.Context, params models.ListTariffsParams) ([]models.Tariff, error) {
+	return r.q.ListTariffs(ctx, params)
+}
+
+func (r *repository) ListTariffBands(ctx context.Context, tariffID uuid.UUID) ([]models.TariffBand, error) {
+	return r.q.ListTariffBands(ctx, tariffID)
+}
+
+func (r *repository) EndTariff(ctx context.Context, id uuid.UUID, effectiveTo time.Time) (models.Tariff, error) {
+	return r.q.EndTariff(ctx, models.EndTariffParams{
+		EffectiveTo: sql.NullTime{Time: effectiveTo, Valid: true},
+		ID:          id,
+	})
+}
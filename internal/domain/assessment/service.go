@@ -8,20 +8,87 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/db"
 	"github.com/sangkips/revenue-system/internal/domain/assessment/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/sangkips/revenue-system/internal/numbering"
+	"github.com/sangkips/revenue-system/internal/tariff"
 )
 
+var ErrTaxpayerNotFound = errors.New("taxpayer not found")
+
 type Service struct {
-	repo Repository
+	repo    Repository
+	uow     *db.UnitOfWork[Repository]
+	numbers numbering.Scheme
+}
+
+// Option configures optional Service features.
+type Option func(*Service)
+
+// WithUnitOfWork lets the service issue assessment numbers in the transaction
+// that inserts the assessment. Without it clients must supply the number.
+func WithUnitOfWork(uow *db.UnitOfWork[Repository]) Option {
+	return func(s *Service) {
+		s.uow = uow
+	}
 }
 
-func NewService(repo Repository) *Service {
-	return &Service{repo: repo}
+// WithNumbering sets the scheme of the assessment numbers issued when a
+// client leaves them out. numbering.DefaultScheme is used otherwise.
+func WithNumbering(scheme numbering.Scheme) Option {
+	return func(s *Service) {
+		s.numbers = scheme
+	}
+}
+
+func NewService(repo Repository, opts ...Option) *Service {
+	s := &Service{repo: repo, numbers: numbering.DefaultScheme}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *Service) CreateAssessment(ctx context.Context, req CreateAssessmentRequest, userID string) (models.Assessment, error) {
-	if req.CountyID == 0 || req.TaxpayerID == "" || req.AssessmentNumber == "" || req.AssessmentType == "" ||
-	req.FinancialYear == "" || req.BaseAmount <= 0 || req.TotalAmount <= 0 {
+	countyID, err := auth.ResolveCounty(ctx, req.CountyID)
+	if err != nil {
+		return models.Assessment{}, err
+	}
+	req.CountyID = countyID
+
+	assessedDate := req.AssessedDate
+	if assessedDate.IsZero() {
+		assessedDate = time.Now()
+	}
+
+	// Assessments raised with lines are priced from the tariff; see tariffs.go.
+	var items []tariff.Charge
+	if len(req.Lines) > 0 {
+		if !req.BaseAmount.IsZero() || !req.CalculatedAmount.IsZero() || !req.TotalAmount.IsZero() {
+			return models.Assessment{}, errors.New("amounts are worked out from the lines; leave base_amount, calculated_amount and total_amount out")
+		}
+		if s.uow == nil {
+			return models.Assessment{}, errors.New("tariffs are not configured")
+		}
+		q, err := quote(ctx, s.repo, req.CountyID, assessedDate, req.Lines)
+		if err != nil {
+			return models.Assessment{}, err
+		}
+		if req.FinancialYear != "" && req.FinancialYear != q.FinancialYear {
+			return models.Assessment{}, fmt.Errorf("the tariffs in effect on the assessed date are those of %s", q.FinancialYear)
+		}
+		req.FinancialYear = q.FinancialYear
+		if req.AssessmentType == "" {
+			req.AssessmentType = q.AssessmentType
+		}
+		req.BaseAmount, req.CalculatedAmount, req.TotalAmount = q.TotalAmount, q.TotalAmount, q.TotalAmount
+		items = q.Items
+	}
+
+	if req.CountyID == 0 || req.TaxpayerID == "" || req.AssessmentType == "" ||
+	req.FinancialYear == "" || !req.BaseAmount.IsPositive() || !req.TotalAmount.IsPositive() {
 		return models.Assessment{}, errors.New("required fields missing or invalid")
 	}
 
@@ -33,11 +100,6 @@ func (s *Service) CreateAssessment(ctx context.Context, req CreateAssessmentRequ
 		return models.Assessment{}, errors.New("user ID is required")
 	}
 
-	assessedDate := req.AssessedDate
-	if assessedDate.IsZero() {
-		assessedDate = time.Now()
-	}
-
 	dueDate := req.DueDate
 	if dueDate.IsZero() {
 		dueDate = time.Now().AddDate(0, 1,0)
@@ -52,6 +114,20 @@ func (s *Service) CreateAssessment(ctx context.Context, req CreateAssessmentRequ
 	if err != nil {
 		return models.Assessment{}, err
 	}
+	// Only a taxpayer of the caller's county can be assessed, and only there.
+	taxpayerCounty, err := s.repo.GetTaxpayerCounty(ctx, taxpayerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Assessment{}, ErrTaxpayerNotFound
+	}
+	if err != nil {
+		return models.Assessment{}, err
+	}
+	if err := auth.AuthorizeCounty(ctx, taxpayerCounty); err != nil {
+		return models.Assessment{}, err
+	}
+	if taxpayerCounty != req.CountyID {
+		return models.Assessment{}, errors.New("taxpayer is not registered in this county")
+	}
 
 	var revenueID uuid.NullUUID
 	if req.RevenueID != "" {
@@ -81,23 +157,78 @@ func (s *Service) CreateAssessment(ctx context.Context, req CreateAssessmentRequ
 		AssessmentNumber: req.AssessmentNumber,
 		AssessmentType:   req.AssessmentType,
 		FinancialYear:    req.FinancialYear,
-		BaseAmount:       fmt.Sprintf("%.2f", req.BaseAmount),
-		CalculatedAmount: fmt.Sprintf("%.2f", req.CalculatedAmount),
-		TotalAmount:      fmt.Sprintf("%.2f", req.TotalAmount),
+		BaseAmount:       req.BaseAmount,
+		CalculatedAmount: req.CalculatedAmount,
+		TotalAmount:      req.TotalAmount,
 		Status:           status,
 		DueDate:          dueDate,
 		AssessedBy:       assessedBy,
 		AssessedDate:     assessedDate,
 	}
 
-	return s.repo.CreateAssessment(ctx, params)
+	if s.uow == nil {
+		if params.AssessmentNumber == "" {
+			return models.Assessment{}, errors.New("assessment numbering is not configured; supply assessment_number")
+		}
+		return s.repo.CreateAssessment(ctx, params)
+	}
+
+	// The number is taken in the insert's transaction so a failed insert
+	// gives it back.
+	var assessment models.Assessment
+	err = s.uow.Do(ctx, func(repo Repository) error {
+		params := params
+		if params.AssessmentNumber == "" {
+			number, err := s.numbers.Next(ctx, repo, params.CountyID, numbering.Assessment, params.AssessedDate)
+			if err != nil {
+				return err
+			}
+			params.AssessmentNumber = number
+		}
+		var err error
+		assessment, err = repo.CreateAssessment(ctx, params)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			_, err := repo.CreateAssessmentItem(ctx, models.InsertAssessmentItemParams{
+				AssessmentID:    assessment.ID,
+				ItemDescription: item.Description,
+				Quantity:        money.NullQuantity{Quantity: item.Quantity, Valid: true},
+				UnitAmount:      item.UnitAmount,
+				TotalAmount:     item.Total,
+				ItemType:        "principal",
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Assessment{}, err
+	}
+	return assessment, nil
 }
 
 func (s *Service) GetAssessment(ctx context.Context, id string) (models.Assessment, error) {
-	return s.repo.GetAssessmentByID(ctx, id)
+	assessment, err := s.repo.GetAssessmentByID(ctx, id)
+	if err != nil {
+		return models.Assessment{}, err
+	}
+
+	if err := auth.AuthorizeCounty(ctx, assessment.CountyID); err != nil {
+		return models.Assessment{}, err
+	}
+	return assessment, nil
 }
 
 func (s *Service) ListAssessments(ctx context.Context, countyID, limit, offset int32) ([]models.Assessment, error) {
+	countyID, err := auth.ResolveCounty(ctx, countyID)
+	if err != nil {
+		return nil, err
+	}
+
 	return s.repo.ListAssessments(ctx, models.ListAssessmentsParams{
 		CountyID: countyID,
 		Limit: limit,
@@ -106,11 +237,11 @@ func (s *Service) ListAssessments(ctx context.Context, countyID, limit, offset i
 }
 
 func (s *Service) UpdateAssessment(ctx context.Context, id string, req UpdateAssessmentRequest) (models.Assessment, error) {
-	if req.BaseAmount != nil && *req.BaseAmount <= 0 {
+	if req.BaseAmount != nil && !req.BaseAmount.IsPositive() {
 		return models.Assessment{}, errors.New("base_amount must be greater than 0")
 	}
 
-	if req.TotalAmount != nil && *req.TotalAmount <= 0 {
+	if req.TotalAmount != nil && !req.TotalAmount.IsPositive() {
 		return models.Assessment{}, errors.New("total_amount must be greater than 0")
 	}
 
@@ -127,23 +258,24 @@ func (s *Service) UpdateAssessment(ctx context.Context, id string, req UpdateAss
 		return models.Assessment{}, err
 	}
 
+	if _, err := s.GetAssessment(ctx, id); err != nil {
+		return models.Assessment{}, err
+	}
+
 	params := models.UpdateAssessmentParams{
 		ID:               assessmentID,
-		BaseAmount:       "",
-		CalculatedAmount: "",
-		TotalAmount:      "",
 		Status:           "",
 		DueDate:          time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
 	}
 
 	if req.BaseAmount != nil {
-		params.BaseAmount = fmt.Sprintf("%.2f", *req.BaseAmount)
+		params.BaseAmount = money.NullAmount{Amount: *req.BaseAmount, Valid: true}
 	}
 	if req.CalculatedAmount != nil {
-		params.CalculatedAmount = fmt.Sprintf("%.2f", *req.CalculatedAmount)
+		params.CalculatedAmount = money.NullAmount{Amount: *req.CalculatedAmount, Valid: true}
 	}
 	if req.TotalAmount != nil {
-		params.TotalAmount = fmt.Sprintf("%.2f", *req.TotalAmount)
+		params.TotalAmount = money.NullAmount{Amount: *req.TotalAmount, Valid: true}
 	}
 	if req.Status != nil {
 		params.Status = *req.Status
@@ -156,32 +288,51 @@ func (s *Service) UpdateAssessment(ctx context.Context, id string, req UpdateAss
 }
 
 func (s *Service) DeleteAssessment(ctx context.Context, id string) error {
+	if _, err := s.GetAssessment(ctx, id); err != nil {
+		return err
+	}
 	return s.repo.DeleteAssessment(ctx, id)
 }
 
 func (s *Service) CreateAssessmentItem(ctx context.Context, req CreateAssessmentItemRequest) (models.AssessmentItem, error) {
-	if req.AssessmentID == "" || req.ItemDescription == "" || req.UnitAmount <= 0 || req.TotalAmount <= 0 {
+	if req.AssessmentID == "" || req.ItemDescription == "" || !req.UnitAmount.IsPositive() || !req.TotalAmount.IsPositive() {
 		return models.AssessmentItem{}, errors.New("required fields missing or invalid")
 	}
 	assessmentUUID, err := uuid.Parse(req.AssessmentID)
 	if err != nil {
 		return models.AssessmentItem{}, errors.New("invalid assessment_id format")
 	}
+	if req.ItemType == "" {
+		req.ItemType = "principal"
+	}
+	if !validItemType(req.ItemType) {
+		return models.AssessmentItem{}, errors.New("invalid item_type: must be 'principal', 'penalty', or 'interest'")
+	}
+	if _, err := s.GetAssessment(ctx, req.AssessmentID); err != nil {
+		return models.AssessmentItem{}, err
+	}
 	params := models.InsertAssessmentItemParams{
 		AssessmentID:     assessmentUUID,
 		ItemDescription:  req.ItemDescription,
-		Quantity:         sql.NullString{String: fmt.Sprintf("%.2f", req.Quantity), Valid: true},
-		UnitAmount:       fmt.Sprintf("%.2f", req.UnitAmount),
-		TotalAmount:      fmt.Sprintf("%.2f", req.TotalAmount),
+		Quantity:         money.NullQuantity{Quantity: req.Quantity, Valid: true},
+		UnitAmount:       req.UnitAmount,
+		TotalAmount:      req.TotalAmount,
+		ItemType:         req.ItemType,
 	}
 	return s.repo.CreateAssessmentItem(ctx, params)
 }
 
 func (s *Service) ListAssessmentItems(ctx context.Context, assessmentID string) ([]models.AssessmentItem, error) {
+	if _, err := s.GetAssessment(ctx, assessmentID); err != nil {
+		return nil, err
+	}
 	return s.repo.ListAssessmentItems(ctx, assessmentID)
 }
 
 func (s *Service) DeleteAssessmentItem(ctx context.Context, assessmentID, itemID string) error {
+	if _, err := s.GetAssessment(ctx, assessmentID); err != nil {
+		return err
+	}
 	item, err := s.repo.GetAssessmentItemByID(ctx, itemID)
 	if err != nil {
 		return errors.New("assessment item not found")
@@ -201,27 +352,36 @@ func validStatus(status string) bool {
 	return status == "pending" || status == "approved" || status == "rejected" || status == "paid"
 }
 
+// validItemType reports whether typ is a component payments are allocated
+// against; see the payments allocation engine.
+func validItemType(typ string) bool {
+	return typ == "principal" || typ == "penalty" || typ == "interest"
+}
+
 
 type CreateAssessmentRequest struct {
 	CountyID        int32     `json:"county_id"`
 	TaxpayerID      string    `json:"taxpayer_id"`
 	RevenueID       string    `json:"revenue_id,omitempty"`
-	AssessmentNumber string   `json:"assessment_number"`
+	AssessmentNumber string   `json:"assessment_number,omitempty"` // issued by the server when empty
 	AssessmentType  string    `json:"assessment_type"`
 	FinancialYear   string    `json:"financial_year"`
-	BaseAmount      float64   `json:"base_amount"`
-	CalculatedAmount float64  `json:"calculated_amount"`
-	TotalAmount     float64   `json:"total_amount"`
+	BaseAmount      money.Amount `json:"base_amount"`
+	CalculatedAmount money.Amount `json:"calculated_amount"`
+	TotalAmount     money.Amount `json:"total_amount"`
 	Status          string    `json:"status,omitempty"`
 	DueDate         time.Time `json:"due_date,omitempty"`
 	AssessedBy      string    `json:"assessed_by,omitempty"`
 	AssessedDate    time.Time `json:"assessed_date,omitempty"`
+	// Lines price the assessment from the county's tariffs in place of the
+	// amounts above.
+	Lines           []QuoteLine `json:"lines,omitempty"`
 }
 
 type UpdateAssessmentRequest struct {
-	BaseAmount      *float64  `json:"base_amount,omitempty"`
-	CalculatedAmount *float64 `json:"calculated_amount,omitempty"`
-	TotalAmount     *float64  `json:"total_amount,omitempty"`
+	BaseAmount      *money.Amount `json:"base_amount,omitempty"`
+	CalculatedAmount *money.Amount `json:"calculated_amount,omitempty"`
+	TotalAmount     *money.Amount `json:"total_amount,omitempty"`
 	Status          *string   `json:"status,omitempty"`
 	DueDate         *time.Time `json:"due_date,omitempty"`
 }
@@ -229,7 +389,8 @@ type UpdateAssessmentRequest struct {
 type CreateAssessmentItemRequest struct {
 	AssessmentID    string   `json:"assessment_id"`
 	ItemDescription string   `json:"item_description"`
-	Quantity        float64  `json:"quantity"`
-	UnitAmount      float64  `json:"unit_amount"`
-	TotalAmount     float64  `json:"total_amount"`
+	Quantity        money.Quantity `json:"quantity"`
+	UnitAmount      money.Amount   `json:"unit_amount"`
+	TotalAmount     money.Amount   `json:"total_amount"`
+	ItemType        string         `json:"item_type,omitempty"` // principal (default), penalty or interest
 }
@@ -0,0 +1,290 @@
+package assessment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/db"
+	"github.com/sangkips/revenue-system/internal/domain/assessment/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/sangkips/revenue-system/internal/tariff"
+)
+
+// Tariffs are the county's rate card. An assessment raised with lines is
+// priced from the tariffs in effect on its assessed date: each line becomes
+// an assessment item and the items add up to the assessment's amounts, so
+// clients no longer work out the charge themselves.
+
+var (
+	ErrTariffOverlap = errors.New("another version of this tariff is in effect over those dates")
+	ErrUnknownTariff = errors.New("no tariff with this code is in effect on the assessed date")
+)
+
+// TariffDetail is a tariff with the bands of a tiered one.
+type TariffDetail struct {
+	models.Tariff
+	Bands []models.TariffBand `json:"bands"`
+}
+
+// Quote is what an assessment with the quoted lines would charge.
+type Quote struct {
+	CountyID       int32           `json:"county_id"`
+	AssessedDate   time.Time       `json:"assessed_date"`
+	FinancialYear  string          `json:"financial_year"`
+	AssessmentType string          `json:"assessment_type,omitempty"` // empty when the tariffs are of different types
+	Items          []tariff.Charge `json:"items"`
+	TotalAmount    money.Amount    `json:"total_amount"`
+}
+
+func (s *Service) CreateTariff(ctx context.Context, req CreateTariffRequest, userID string) (TariffDetail, error) {
+	countyID, err := auth.ResolveCounty(ctx, req.CountyID)
+	if err != nil {
+		return TariffDetail{}, err
+	}
+	if req.AssessmentType == "" || req.EffectiveFrom.IsZero() {
+		return TariffDetail{}, errors.New("required fields missing or invalid")
+	}
+	if req.EffectiveTo != nil && req.EffectiveTo.Before(req.EffectiveFrom) {
+		return TariffDetail{}, errors.New("effective_to must not be before effective_from")
+	}
+	if req.Method != tariff.Tiered && len(req.Bands) > 0 {
+		return TariffDetail{}, errors.New("bands are only for tiered tariffs")
+	}
+	t := tariff.Tariff{
+		Code:    req.Code,
+		Name:    req.Name,
+		Method:  req.Method,
+		Amount:  req.Amount.Amount,
+		Unit:    req.Unit,
+		Rate:    req.Rate.Quantity,
+		Minimum: req.MinimumAmount,
+		Maximum: req.MaximumAmount,
+		Bands:   req.Bands,
+	}
+	if err := t.Validate(); err != nil {
+		return TariffDetail{}, err
+	}
+	if s.uow == nil {
+		return TariffDetail{}, errors.New("tariffs are not configured")
+	}
+	createdBy, err := uuid.Parse(userID)
+	if err != nil {
+		return TariffDetail{}, errors.New("user ID is required")
+	}
+
+	params := models.InsertTariffParams{
+		CountyID:       countyID,
+		Code:           req.Code,
+		Name:           req.Name,
+		AssessmentType: req.AssessmentType,
+		FinancialYear:  req.FinancialYear,
+		Method:         string(req.Method),
+		Amount:         req.Amount,
+		Unit:           sql.NullString{String: req.Unit, Valid: req.Unit != ""},
+		Rate:           req.Rate,
+		MinimumAmount:  req.MinimumAmount,
+		MaximumAmount:  req.MaximumAmount,
+		EffectiveFrom:  req.EffectiveFrom,
+		CreatedBy:      uuid.NullUUID{UUID: createdBy, Valid: true},
+	}
+	if params.FinancialYear == "" {
+		params.FinancialYear = s.numbers.FinancialYear(req.EffectiveFrom)
+	}
+	if req.EffectiveTo != nil {
+		params.EffectiveTo = sql.NullTime{Time: *req.EffectiveTo, Valid: true}
+	}
+
+	var detail TariffDetail
+	err = s.uow.Do(ctx, func(repo Repository) error {
+		var err error
+		detail.Tariff, err = repo.CreateTariff(ctx, params)
+		if err != nil {
+			return err
+		}
+		for _, b := range req.Bands {
+			band := models.InsertTariffBandParams{
+				TariffID:   detail.ID,
+				LowerBound: b.LowerBound,
+				Amount:     b.Amount,
+				UnitAmount: b.UnitAmount,
+			}
+			if err := repo.CreateTariffBand(ctx, band); err != nil {
+				return err
+			}
+			detail.Bands = append(detail.Bands, models.TariffBand(band))
+		}
+		return nil
+	})
+	if db.IsExclusionViolation(err) {
+		return TariffDetail{}, ErrTariffOverlap
+	}
+	if err != nil {
+		return TariffDetail{}, err
+	}
+	return detail, nil
+}
+
+func (s *Service) GetTariff(ctx context.Context, id string) (TariffDetail, error) {
+	tariffID, err := uuid.Parse(id)
+	if err != nil {
+		return TariffDetail{}, sql.ErrNoRows
+	}
+	t, err := s.repo.GetTariffByID(ctx, tariffID)
+	if err != nil {
+		return TariffDetail{}, err
+	}
+	if err := auth.AuthorizeCounty(ctx, t.CountyID); err != nil {
+		return TariffDetail{}, err
+	}
+	bands, err := s.repo.ListTariffBands(ctx, t.ID)
+	if err != nil {
+		return TariffDetail{}, err
+	}
+	return TariffDetail{Tariff: t, Bands: bands}, nil
+}
+
+// ListTariffs returns every version of the county's tariffs, optionally only
+// those of one financial year.
+func (s *Service) ListTariffs(ctx context.Context, countyID int32, financialYear string) ([]models.Tariff, error) {
+	countyID, err := auth.ResolveCounty(ctx, countyID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListTariffs(ctx, models.ListTariffsParams{
+		CountyID:      countyID,
+		FinancialYear: sql.NullString{String: financialYear, Valid: financialYear != ""},
+	})
+}
+
+// EndTariff sets the last day the tariff is in effect, typically the day
+// before the next Finance Act's version of it takes over. Tariffs are never
+// edited in place so that assessments keep the rates they were raised at.
+func (s *Service) EndTariff(ctx context.Context, id string, req EndTariffRequest) (models.Tariff, error) {
+	if req.EffectiveTo.IsZero() {
+		return models.Tariff{}, errors.New("effective_to is required")
+	}
+	t, err := s.GetTariff(ctx, id)
+	if err != nil {
+		return models.Tariff{}, err
+	}
+	if req.EffectiveTo.Before(t.EffectiveFrom) {
+		return models.Tariff{}, errors.New("effective_to must not be before effective_from")
+	}
+	ended, err := s.repo.EndTariff(ctx, t.ID, req.EffectiveTo)
+	if db.IsExclusionViolation(err) {
+		return models.Tariff{}, ErrTariffOverlap
+	}
+	return ended, err
+}
+
+// Quote works out what an assessment with the lines would charge without
+// raising it.
+func (s *Service) Quote(ctx context.Context, req QuoteRequest) (Quote, error) {
+	countyID, err := auth.ResolveCounty(ctx, req.CountyID)
+	if err != nil {
+		return Quote{}, err
+	}
+	assessedDate := req.AssessedDate
+	if assessedDate.IsZero() {
+		assessedDate = time.Now()
+	}
+	return quote(ctx, s.repo, countyID, assessedDate, req.Lines)
+}
+
+// quote charges each line under the county's tariff of that code in effect
+// on the assessed date.
+func quote(ctx context.Context, repo Repository, countyID int32, assessedDate time.Time, lines []QuoteLine) (Quote, error) {
+	if len(lines) == 0 {
+		return Quote{}, errors.New("at least one line is required")
+	}
+	q := Quote{CountyID: countyID, AssessedDate: assessedDate, TotalAmount: money.Zero}
+	for i, line := range lines {
+		t, err := repo.GetEffectiveTariff(ctx, countyID, line.TariffCode, assessedDate)
+		if errors.Is(err, sql.ErrNoRows) {
+			return Quote{}, fmt.Errorf("%w: %q", ErrUnknownTariff, line.TariffCode)
+		}
+		if err != nil {
+			return Quote{}, err
+		}
+		var bands []models.TariffBand
+		if t.Method == string(tariff.Tiered) {
+			if bands, err = repo.ListTariffBands(ctx, t.ID); err != nil {
+				return Quote{}, err
+			}
+		}
+		charge, err := rateCard(t, bands).Charge(tariff.Input{Quantity: line.Quantity, Value: line.Value})
+		if err != nil {
+			return Quote{}, err
+		}
+
+		switch {
+		case i == 0:
+			q.FinancialYear, q.AssessmentType = t.FinancialYear, t.AssessmentType
+		case t.FinancialYear != q.FinancialYear:
+			return Quote{}, errors.New("lines must all be charged under the same financial year's tariffs")
+		case t.AssessmentType != q.AssessmentType:
+			q.AssessmentType = ""
+		}
+		q.Items = append(q.Items, charge)
+		q.TotalAmount = q.TotalAmount.Add(charge.Total)
+	}
+	return q, nil
+}
+
+// rateCard is the calculator's view of a stored tariff.
+func rateCard(t models.Tariff, bands []models.TariffBand) tariff.Tariff {
+	rc := tariff.Tariff{
+		Code:    t.Code,
+		Name:    t.Name,
+		Method:  tariff.Method(t.Method),
+		Amount:  t.Amount.Amount,
+		Unit:    t.Unit.String,
+		Rate:    t.Rate.Quantity,
+		Minimum: t.MinimumAmount,
+		Maximum: t.MaximumAmount,
+	}
+	for _, b := range bands {
+		rc.Bands = append(rc.Bands, tariff.Band{LowerBound: b.LowerBound, Amount: b.Amount, UnitAmount: b.UnitAmount})
+	}
+	return rc
+}
+
+type CreateTariffRequest struct {
+	CountyID       int32              `json:"county_id"`
+	Code           string             `json:"code"`
+	Name           string             `json:"name"`
+	AssessmentType string             `json:"assessment_type"`
+	FinancialYear  string             `json:"financial_year,omitempty"` // that of effective_from when empty
+	Method         tariff.Method      `json:"method"`
+	Amount         money.NullAmount   `json:"amount"`
+	Unit           string             `json:"unit,omitempty"`
+	Rate           money.NullQuantity `json:"rate"`
+	MinimumAmount  money.NullAmount   `json:"minimum_amount"`
+	MaximumAmount  money.NullAmount   `json:"maximum_amount"`
+	Bands          []tariff.Band      `json:"bands,omitempty"`
+	EffectiveFrom  time.Time          `json:"effective_from"`
+	EffectiveTo    *time.Time         `json:"effective_to,omitempty"`
+}
+
+type EndTariffRequest struct {
+	EffectiveTo time.Time `json:"effective_to"`
+}
+
+type QuoteRequest struct {
+	CountyID     int32       `json:"county_id"`
+	AssessedDate time.Time   `json:"assessed_date,omitempty"` // today when empty
+	Lines        []QuoteLine `json:"lines"`
+}
+
+// QuoteLine is one charge of an assessment: the tariff's code and what the
+// taxpayer declares for it.
+type QuoteLine struct {
+	TariffCode string         `json:"tariff_code"`
+	Quantity   money.Quantity `json:"quantity"`
+	Value      money.Amount   `json:"value"`
+}
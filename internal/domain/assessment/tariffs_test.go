@@ -0,0 +1,104 @@
+package assessment
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/assessment/models"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/sangkips/revenue-system/internal/tariff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rateCardRepo serves a fixed rate card; the rest of Repository is unused.
+type rateCardRepo struct {
+	Repository
+	tariffs []models.Tariff
+	bands   map[uuid.UUID][]models.TariffBand
+}
+
+func (r *rateCardRepo) GetEffectiveTariff(ctx context.Context, countyID int32, code string, on time.Time) (models.Tariff, error) {
+	for _, t := range r.tariffs {
+		if t.CountyID == countyID && t.Code == code && !on.Before(t.EffectiveFrom) &&
+			(!t.EffectiveTo.Valid || !on.After(t.EffectiveTo.Time)) {
+			return t, nil
+		}
+	}
+	return models.Tariff{}, sql.ErrNoRows
+}
+
+func (r *rateCardRepo) ListTariffBands(ctx context.Context, tariffID uuid.UUID) ([]models.TariffBand, error) {
+	return r.bands[tariffID], nil
+}
+
+func TestQuote(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse(time.DateOnly, s)
+		require.NoError(t, err)
+		return d
+	}
+	permitID := uuid.New()
+	repo := &rateCardRepo{
+		tariffs: []models.Tariff{
+			{ID: uuid.New(), CountyID: 47, Code: "FIRE", Name: "Fire inspection", AssessmentType: "business_permit", FinancialYear: "2024-25",
+				Method: "flat", Amount: money.NullAmount{Amount: money.MustParse("2000"), Valid: true},
+				EffectiveFrom: date("2024-07-01"), EffectiveTo: sql.NullTime{Time: date("2025-06-30"), Valid: true}},
+			{ID: uuid.New(), CountyID: 47, Code: "FIRE", Name: "Fire inspection", AssessmentType: "business_permit", FinancialYear: "2025-26",
+				Method: "flat", Amount: money.NullAmount{Amount: money.MustParse("2500"), Valid: true},
+				EffectiveFrom: date("2025-07-01")},
+			{ID: permitID, CountyID: 47, Code: "SBP", Name: "Single business permit", AssessmentType: "business_permit", FinancialYear: "2025-26",
+				Method: "tiered", Unit: sql.NullString{String: "employees", Valid: true}, EffectiveFrom: date("2025-07-01")},
+			{ID: uuid.New(), CountyID: 47, Code: "SIGN", Name: "Advertising sign", AssessmentType: "advertising", FinancialYear: "2025-26",
+				Method: "per_unit", Amount: money.NullAmount{Amount: money.MustParse("450"), Valid: true}, Unit: sql.NullString{String: "sq m", Valid: true},
+				EffectiveFrom: date("2025-07-01")},
+		},
+		bands: map[uuid.UUID][]models.TariffBand{
+			permitID: {
+				{TariffID: permitID, LowerBound: money.QuantityOf(0), Amount: money.MustParse("5000")},
+				{TariffID: permitID, LowerBound: money.QuantityOf(6), Amount: money.MustParse("10000")},
+			},
+		},
+	}
+	ctx := context.Background()
+
+	q, err := quote(ctx, repo, 47, date("2025-08-14"), []QuoteLine{
+		{TariffCode: "SBP", Quantity: money.QuantityOf(8)},
+		{TariffCode: "FIRE"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "2025-26", q.FinancialYear)
+	assert.Equal(t, "business_permit", q.AssessmentType)
+	assert.Equal(t, []tariff.Charge{
+		{Code: "SBP", Description: "Single business permit (8.00 employees)", Quantity: money.QuantityOf(1), UnitAmount: money.MustParse("10000"), Total: money.MustParse("10000")},
+		{Code: "FIRE", Description: "Fire inspection", Quantity: money.QuantityOf(1), UnitAmount: money.MustParse("2500"), Total: money.MustParse("2500")},
+	}, q.Items)
+	assert.Equal(t, money.MustParse("12500"), q.TotalAmount)
+
+	// The version in effect on the assessed date prices the line.
+	q, err = quote(ctx, repo, 47, date("2025-06-30"), []QuoteLine{{TariffCode: "FIRE"}})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-25", q.FinancialYear)
+	assert.Equal(t, money.MustParse("2000"), q.TotalAmount)
+
+	// Tariffs of different types leave the assessment type to the client.
+	q, err = quote(ctx, repo, 47, date("2025-08-14"), []QuoteLine{
+		{TariffCode: "FIRE"},
+		{TariffCode: "SIGN", Quantity: money.QuantityOf(3)},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, q.AssessmentType)
+	assert.Equal(t, money.MustParse("3850"), q.TotalAmount)
+
+	_, err = quote(ctx, repo, 47, date("2025-08-14"), []QuoteLine{{TariffCode: "PARKING"}})
+	assert.ErrorIs(t, err, ErrUnknownTariff)
+	_, err = quote(ctx, repo, 1, date("2025-08-14"), []QuoteLine{{TariffCode: "FIRE"}})
+	assert.ErrorIs(t, err, ErrUnknownTariff)
+	_, err = quote(ctx, repo, 47, date("2025-08-14"), []QuoteLine{{TariffCode: "SIGN"}})
+	assert.ErrorIs(t, err, tariff.ErrInvalidInput)
+	_, err = quote(ctx, repo, 47, date("2025-08-14"), nil)
+	assert.Error(t, err)
+}
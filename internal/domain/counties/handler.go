@@ -8,6 +8,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
 	"github.com/sangkips/revenue-system/internal/domain/counties/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
 )
 
 type Handler struct {
@@ -20,11 +21,13 @@ func NewHandler(db models.DBTX) *Handler {
 }
 
 func (h *Handler) RegisterCountyRoutes(r chi.Router) {
-	r.Post("/", h.CreateCounty)
-	r.Get("/{id}", h.GetCounty)
-	r.Get("/", h.ListCounties)
-	r.Patch("/{id}", h.UpdateCounty)
-	r.Delete("/{id}", h.DeleteCounty)
+	r.With(auth.RequirePermission(auth.PermCountiesWrite)).Post("/", h.CreateCounty)
+	r.With(auth.RequirePermission(auth.PermCountiesRead)).Get("/{id}", h.GetCounty)
+	r.With(auth.RequirePermission(auth.PermCountiesRead)).Get("/", h.ListCounties)
+	r.With(auth.RequirePermission(auth.PermCountiesWrite)).Patch("/{id}", h.UpdateCounty)
+	r.With(auth.RequirePermission(auth.PermCountiesWrite)).Delete("/{id}", h.DeleteCounty)
+	r.With(auth.RequirePermission(auth.PermCountiesRead)).Get("/{id}/mfa-policy", h.GetMFAPolicy)
+	r.With(auth.RequirePermission(auth.PermSecurityManage)).Put("/{id}/mfa-policy", h.UpdateMFAPolicy)
 }
 
 func (h *Handler) CreateCounty(w http.ResponseWriter, r *http.Request) {
@@ -40,7 +43,7 @@ func (h *Handler) CreateCounty(w http.ResponseWriter, r *http.Request) {
 	county, err := h.svc.CreateCounty(ctx, req)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create county")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -60,7 +63,7 @@ func (h *Handler) GetCounty(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	county, err := h.svc.GetCounty(ctx, int32(id))
 	if err != nil {
-		http.Error(w, "Not found", http.StatusNotFound)
+		http.Error(w, "Not found", auth.ErrorStatus(err, http.StatusNotFound))
 		return
 	}
 
@@ -80,7 +83,7 @@ func (h *Handler) ListCounties(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	county, err := h.svc.ListCounties(ctx, int32(limit), int32(offset))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -105,7 +108,7 @@ func (h *Handler) UpdateCounty(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	county, err := h.svc.UpdateCounty(ctx, int32(id), req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -124,8 +127,54 @@ func (h *Handler) DeleteCounty(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 	if err := h.svc.DeleteCounty(ctx, int32(id)); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (h *Handler) GetMFAPolicy(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	policy, err := h.svc.GetMFAPolicy(ctx, int32(id))
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+func (h *Handler) UpdateMFAPolicy(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateMFAPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	policy, err := h.svc.UpdateMFAPolicy(ctx, int32(id), req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to update county MFA policy")
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/money"
 )
 
 type Application struct {
@@ -44,9 +45,9 @@ type Assessment struct {
 	AssessmentNumber string        `json:"assessment_number"`
 	AssessmentType   string        `json:"assessment_type"`
 	FinancialYear    string        `json:"financial_year"`
-	BaseAmount       string        `json:"base_amount"`
-	CalculatedAmount string        `json:"calculated_amount"`
-	TotalAmount      string        `json:"total_amount"`
+	BaseAmount       money.Amount  `json:"base_amount"`
+	CalculatedAmount money.Amount  `json:"calculated_amount"`
+	TotalAmount      money.Amount  `json:"total_amount"`
 	Status           string        `json:"status"`
 	DueDate          time.Time     `json:"due_date"`
 	AssessedBy       uuid.NullUUID `json:"assessed_by"`
@@ -56,13 +57,47 @@ type Assessment struct {
 }
 
 type AssessmentItem struct {
-	ID              uuid.UUID      `json:"id"`
-	AssessmentID    uuid.UUID      `json:"assessment_id"`
-	ItemDescription string         `json:"item_description"`
-	Quantity        sql.NullString `json:"quantity"`
-	UnitAmount      string         `json:"unit_amount"`
-	TotalAmount     string         `json:"total_amount"`
-	CreatedAt       sql.NullTime   `json:"created_at"`
+	ID              uuid.UUID          `json:"id"`
+	AssessmentID    uuid.UUID          `json:"assessment_id"`
+	ItemDescription string             `json:"item_description"`
+	Quantity        money.NullQuantity `json:"quantity"`
+	UnitAmount      money.Amount       `json:"unit_amount"`
+	TotalAmount     money.Amount       `json:"total_amount"`
+	CreatedAt       sql.NullTime       `json:"created_at"`
+	ItemType        string             `json:"item_type"`
+}
+
+type BankStatement struct {
+	ID                 uuid.UUID      `json:"id"`
+	CountyID           int32          `json:"county_id"`
+	Format             string         `json:"format"`
+	FileName           sql.NullString `json:"file_name"`
+	FileSha256         string         `json:"file_sha256"`
+	AccountNumber      sql.NullString `json:"account_number"`
+	StatementReference sql.NullString `json:"statement_reference"`
+	LineCount          int32          `json:"line_count"`
+	MatchedCount       int32          `json:"matched_count"`
+	TotalAmount        money.Amount   `json:"total_amount"`
+	ImportedBy         uuid.NullUUID  `json:"imported_by"`
+	ImportedAt         sql.NullTime   `json:"imported_at"`
+}
+
+type BankStatementLine struct {
+	ID          uuid.UUID      `json:"id"`
+	StatementID uuid.UUID      `json:"statement_id"`
+	CountyID    int32          `json:"county_id"`
+	LineNumber  int32          `json:"line_number"`
+	ValueDate   time.Time      `json:"value_date"`
+	Amount      money.Amount   `json:"amount"`
+	Reference   string         `json:"reference"`
+	Description string         `json:"description"`
+	Status      string         `json:"status"`
+	PaymentID   uuid.NullUUID  `json:"payment_id"`
+	MatchMethod sql.NullString `json:"match_method"`
+	ResolvedBy  uuid.NullUUID  `json:"resolved_by"`
+	ResolvedAt  sql.NullTime   `json:"resolved_at"`
+	Note        sql.NullString `json:"note"`
+	CreatedAt   sql.NullTime   `json:"created_at"`
 }
 
 type BuildingApproval struct {
@@ -70,11 +105,34 @@ type BuildingApproval struct {
 	ProjectName          string         `json:"project_name"`
 	PlotParcelNumber     string         `json:"plot_parcel_number"`
 	ProjectType          string         `json:"project_type"`
-	EstimatedProjectCost string         `json:"estimated_project_cost"`
+	EstimatedProjectCost money.Amount   `json:"estimated_project_cost"`
 	ContactEmail         sql.NullString `json:"contact_email"`
 	ContactPhone         sql.NullString `json:"contact_phone"`
 }
 
+type CollectorSession struct {
+	ID              uuid.UUID        `json:"id"`
+	CountyID        int32            `json:"county_id"`
+	CollectorID     uuid.UUID        `json:"collector_id"`
+	CollectionPoint string           `json:"collection_point"`
+	OpeningFloat    money.Amount     `json:"opening_float"`
+	Status          string           `json:"status"`
+	OpenedAt        time.Time        `json:"opened_at"`
+	ClosedAt        sql.NullTime     `json:"closed_at"`
+	ExpectedCash    money.NullAmount `json:"expected_cash"`
+	DeclaredCash    money.NullAmount `json:"declared_cash"`
+	Variance        money.NullAmount `json:"variance"`
+	CloseNote       sql.NullString   `json:"close_note"`
+	ReviewedBy      uuid.NullUUID    `json:"reviewed_by"`
+	ReviewedAt      sql.NullTime     `json:"reviewed_at"`
+	ReviewNote      sql.NullString   `json:"review_note"`
+}
+
+type CollectorSessionPayment struct {
+	SessionID uuid.UUID `json:"session_id"`
+	PaymentID uuid.UUID `json:"payment_id"`
+}
+
 type County struct {
 	ID              int32          `json:"id"`
 	Name            string         `json:"name"`
@@ -84,6 +142,21 @@ type County struct {
 	UpdatedAt       sql.NullTime   `json:"updated_at"`
 }
 
+type CountyMfaPolicy struct {
+	CountyID    int32         `json:"county_id"`
+	MfaRequired bool          `json:"mfa_required"`
+	UpdatedBy   uuid.NullUUID `json:"updated_by"`
+	UpdatedAt   sql.NullTime  `json:"updated_at"`
+}
+
+type DocumentSequence struct {
+	CountyID      int32     `json:"county_id"`
+	DocumentType  string    `json:"document_type"`
+	FinancialYear string    `json:"financial_year"`
+	LastValue     int64     `json:"last_value"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
 type HealthCertificate struct {
 	ApplicationID uuid.UUID      `json:"application_id"`
 	ApplicantName string         `json:"applicant_name"`
@@ -92,13 +165,87 @@ type HealthCertificate struct {
 	ContactPhone  sql.NullString `json:"contact_phone"`
 }
 
+type IdempotencyKey struct {
+	UserID              uuid.UUID      `json:"user_id"`
+	IdempotencyKey      string         `json:"idempotency_key"`
+	RequestHash         string         `json:"request_hash"`
+	ResponseStatus      sql.NullInt32  `json:"response_status"`
+	ResponseContentType sql.NullString `json:"response_content_type"`
+	ResponseBody        []byte         `json:"response_body"`
+	CreatedAt           time.Time      `json:"created_at"`
+	CompletedAt         sql.NullTime   `json:"completed_at"`
+}
+
+type LoginThrottle struct {
+	ThrottleKey   string       `json:"throttle_key"`
+	Failures      int32        `json:"failures"`
+	LockedUntil   sql.NullTime `json:"locked_until"`
+	LastFailureAt time.Time    `json:"last_failure_at"`
+}
+
+type MfaRecoveryCode struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
+type MpesaSuspense struct {
+	ID                 uuid.UUID        `json:"id"`
+	CountyID           sql.NullInt32    `json:"county_id"`
+	Source             string           `json:"source"`
+	Reference          string           `json:"reference"`
+	ResultCode         int32            `json:"result_code"`
+	ResultDesc         sql.NullString   `json:"result_desc"`
+	MpesaReceiptNumber sql.NullString   `json:"mpesa_receipt_number"`
+	Amount             money.NullAmount `json:"amount"`
+	PhoneNumber        sql.NullString   `json:"phone_number"`
+	AccountReference   sql.NullString   `json:"account_reference"`
+	PayerName          sql.NullString   `json:"payer_name"`
+	TransactionDate    sql.NullTime     `json:"transaction_date"`
+	Status             string           `json:"status"`
+	PaymentID          uuid.NullUUID    `json:"payment_id"`
+	ResolvedBy         uuid.NullUUID    `json:"resolved_by"`
+	ResolvedAt         sql.NullTime     `json:"resolved_at"`
+	Note               sql.NullString   `json:"note"`
+	CreatedAt          time.Time        `json:"created_at"`
+}
+
+type NotificationOutbox struct {
+	ID            uuid.UUID      `json:"id"`
+	CountyID      int32          `json:"county_id"`
+	Channel       string         `json:"channel"`
+	Recipient     string         `json:"recipient"`
+	Subject       sql.NullString `json:"subject"`
+	Body          string         `json:"body"`
+	ReceiptID     uuid.NullUUID  `json:"receipt_id"`
+	Status        string         `json:"status"`
+	Attempts      int32          `json:"attempts"`
+	MaxAttempts   int32          `json:"max_attempts"`
+	NextAttemptAt time.Time      `json:"next_attempt_at"`
+	LastError     sql.NullString `json:"last_error"`
+	SentAt        sql.NullTime   `json:"sent_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+type PasswordResetToken struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
 type Payment struct {
 	ID                    uuid.UUID      `json:"id"`
 	CountyID              int32          `json:"county_id"`
 	TaxpayerID            uuid.UUID      `json:"taxpayer_id"`
 	AssessmentID          uuid.NullUUID  `json:"assessment_id"`
 	PaymentNumber         string         `json:"payment_number"`
-	Amount                string         `json:"amount"`
+	Amount                money.Amount   `json:"amount"`
 	PaymentMethod         string         `json:"payment_method"`
 	PaymentChannel        sql.NullString `json:"payment_channel"`
 	ExternalTransactionID sql.NullString `json:"external_transaction_id"`
@@ -126,11 +273,48 @@ type PaymentAllocation struct {
 	ID              uuid.UUID      `json:"id"`
 	PaymentID       uuid.UUID      `json:"payment_id"`
 	AssessmentID    uuid.UUID      `json:"assessment_id"`
-	AllocatedAmount string         `json:"allocated_amount"`
+	AllocatedAmount money.Amount   `json:"allocated_amount"`
 	AllocationType  sql.NullString `json:"allocation_type"`
 	CreatedAt       sql.NullTime   `json:"created_at"`
 }
 
+type PaymentRefund struct {
+	ID          uuid.UUID      `json:"id"`
+	PaymentID   uuid.UUID      `json:"payment_id"`
+	CountyID    int32          `json:"county_id"`
+	Amount      money.Amount   `json:"amount"`
+	Reason      string         `json:"reason"`
+	Status      string         `json:"status"`
+	RequestedBy uuid.UUID      `json:"requested_by"`
+	RequestedAt sql.NullTime   `json:"requested_at"`
+	ReviewedBy  uuid.NullUUID  `json:"reviewed_by"`
+	ReviewedAt  sql.NullTime   `json:"reviewed_at"`
+	ReviewNote  sql.NullString `json:"review_note"`
+}
+
+type PaymentRefundReversal struct {
+	ID             uuid.UUID        `json:"id"`
+	RefundID       uuid.UUID        `json:"refund_id"`
+	PaymentID      uuid.UUID        `json:"payment_id"`
+	ReversalType   string           `json:"reversal_type"`
+	AllocationID   uuid.NullUUID    `json:"allocation_id"`
+	AssessmentID   uuid.NullUUID    `json:"assessment_id"`
+	AllocationType sql.NullString   `json:"allocation_type"`
+	ReceiptID      uuid.NullUUID    `json:"receipt_id"`
+	Amount         money.NullAmount `json:"amount"`
+	CreatedAt      sql.NullTime     `json:"created_at"`
+}
+
+type PaymentStatusHistory struct {
+	ID         uuid.UUID      `json:"id"`
+	PaymentID  uuid.UUID      `json:"payment_id"`
+	FromStatus sql.NullString `json:"from_status"`
+	ToStatus   string         `json:"to_status"`
+	Reason     sql.NullString `json:"reason"`
+	ChangedBy  uuid.NullUUID  `json:"changed_by"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
 type Receipt struct {
 	ID                 uuid.UUID      `json:"id"`
 	PaymentID          uuid.UUID      `json:"payment_id"`
@@ -148,13 +332,49 @@ type Receipt struct {
 	BlockchainVerified sql.NullBool   `json:"blockchain_verified"`
 	QrCodeData         sql.NullString `json:"qr_code_data"`
 	CreatedAt          sql.NullTime   `json:"created_at"`
+	VoidedAt           sql.NullTime   `json:"voided_at"`
+	VoidedBy           uuid.NullUUID  `json:"voided_by"`
+	VoidReason         sql.NullString `json:"void_reason"`
+}
+
+type ReceiptLedgerBlock struct {
+	CountyID          int32     `json:"county_id"`
+	BlockNumber       int64     `json:"block_number"`
+	FirstSequence     int64     `json:"first_sequence"`
+	LastSequence      int64     `json:"last_sequence"`
+	EntryCount        int32     `json:"entry_count"`
+	MerkleRoot        string    `json:"merkle_root"`
+	PreviousBlockHash string    `json:"previous_block_hash"`
+	BlockHash         string    `json:"block_hash"`
+	SealedAt          time.Time `json:"sealed_at"`
+}
+
+type ReceiptLedgerEntry struct {
+	CountyID     int32         `json:"county_id"`
+	Sequence     int64         `json:"sequence"`
+	ReceiptID    uuid.UUID     `json:"receipt_id"`
+	Event        string        `json:"event"`
+	RecordHash   string        `json:"record_hash"`
+	PreviousHash string        `json:"previous_hash"`
+	EntryHash    string        `json:"entry_hash"`
+	BlockNumber  sql.NullInt64 `json:"block_number"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+type ReceiptLedgerHead struct {
+	CountyID        int32     `json:"county_id"`
+	LastSequence    int64     `json:"last_sequence"`
+	LastEntryHash   string    `json:"last_entry_hash"`
+	LastBlockNumber int64     `json:"last_block_number"`
+	LastBlockHash   string    `json:"last_block_hash"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 type Revenue struct {
 	ID              uuid.UUID      `json:"id"`
 	TaxpayerID      uuid.UUID      `json:"taxpayer_id"`
 	CountyID        int32          `json:"county_id"`
-	Amount          string         `json:"amount"`
+	Amount          money.Amount   `json:"amount"`
 	RevenueType     string         `json:"revenue_type"`
 	TransactionDate time.Time      `json:"transaction_date"`
 	Description     sql.NullString `json:"description"`
@@ -171,6 +391,19 @@ type SeasonalParkingTicket struct {
 	ContactPhone              sql.NullString `json:"contact_phone"`
 }
 
+type Session struct {
+	ID                       uuid.UUID      `json:"id"`
+	UserID                   uuid.UUID      `json:"user_id"`
+	RefreshTokenHash         string         `json:"refresh_token_hash"`
+	UserAgent                sql.NullString `json:"user_agent"`
+	IpAddress                sql.NullString `json:"ip_address"`
+	ExpiresAt                time.Time      `json:"expires_at"`
+	RevokedAt                sql.NullTime   `json:"revoked_at"`
+	LastUsedAt               sql.NullTime   `json:"last_used_at"`
+	CreatedAt                sql.NullTime   `json:"created_at"`
+	PreviousRefreshTokenHash sql.NullString `json:"previous_refresh_token_hash"`
+}
+
 type SingleBusinessPermit struct {
 	ApplicationID     uuid.UUID `json:"application_id"`
 	BusinessName      string    `json:"business_name"`
@@ -180,6 +413,33 @@ type SingleBusinessPermit struct {
 	NumberOfEmployees int32     `json:"number_of_employees"`
 }
 
+type Tariff struct {
+	ID             uuid.UUID          `json:"id"`
+	CountyID       int32              `json:"county_id"`
+	Code           string             `json:"code"`
+	Name           string             `json:"name"`
+	AssessmentType string             `json:"assessment_type"`
+	FinancialYear  string             `json:"financial_year"`
+	Method         string             `json:"method"`
+	Amount         money.NullAmount   `json:"amount"`
+	Unit           sql.NullString     `json:"unit"`
+	Rate           money.NullQuantity `json:"rate"`
+	MinimumAmount  money.NullAmount   `json:"minimum_amount"`
+	MaximumAmount  money.NullAmount   `json:"maximum_amount"`
+	EffectiveFrom  time.Time          `json:"effective_from"`
+	EffectiveTo    sql.NullTime       `json:"effective_to"`
+	CreatedBy      uuid.NullUUID      `json:"created_by"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
+
+type TariffBand struct {
+	TariffID   uuid.UUID      `json:"tariff_id"`
+	LowerBound money.Quantity `json:"lower_bound"`
+	Amount     money.Amount   `json:"amount"`
+	UnitAmount money.Amount   `json:"unit_amount"`
+}
+
 type Taxpayer struct {
 	ID           uuid.UUID      `json:"id"`
 	CountyID     int32          `json:"county_id"`
@@ -195,6 +455,26 @@ type Taxpayer struct {
 	UserID       uuid.NullUUID  `json:"user_id"`
 }
 
+type TaxpayerCreditBalance struct {
+	TaxpayerID uuid.UUID    `json:"taxpayer_id"`
+	CountyID   int32        `json:"county_id"`
+	Balance    money.Amount `json:"balance"`
+	UpdatedAt  sql.NullTime `json:"updated_at"`
+}
+
+type TaxpayerCreditLedger struct {
+	ID           uuid.UUID     `json:"id"`
+	CountyID     int32         `json:"county_id"`
+	TaxpayerID   uuid.UUID     `json:"taxpayer_id"`
+	PaymentID    uuid.UUID     `json:"payment_id"`
+	EntryType    string        `json:"entry_type"`
+	Amount       money.Amount  `json:"amount"`
+	BalanceAfter money.Amount  `json:"balance_after"`
+	Description  string        `json:"description"`
+	CreatedBy    uuid.NullUUID `json:"created_by"`
+	CreatedAt    sql.NullTime  `json:"created_at"`
+}
+
 type User struct {
 	ID           uuid.UUID      `json:"id"`
 	CountyID     sql.NullInt32  `json:"county_id"`
@@ -211,3 +491,12 @@ type User struct {
 	CreatedAt    sql.NullTime   `json:"created_at"`
 	UpdatedAt    sql.NullTime   `json:"updated_at"`
 }
+
+type UserMfa struct {
+	UserID       uuid.UUID    `json:"user_id"`
+	Secret       string       `json:"secret"`
+	Enabled      bool         `json:"enabled"`
+	LastUsedStep int64        `json:"last_used_step"`
+	ConfirmedAt  sql.NullTime `json:"confirmed_at"`
+	CreatedAt    sql.NullTime `json:"created_at"`
+}
@@ -11,9 +11,11 @@ import (
 type Querier interface {
 	DeleteCounty(ctx context.Context, id int32) error
 	GetCountyByID(ctx context.Context, id int32) (County, error)
+	GetCountyMFAPolicy(ctx context.Context, countyID int32) (CountyMfaPolicy, error)
 	InsertCounty(ctx context.Context, arg InsertCountyParams) (County, error)
 	ListCounties(ctx context.Context, arg ListCountiesParams) ([]County, error)
 	UpdateCounty(ctx context.Context, arg UpdateCountyParams) (County, error)
+	UpsertCountyMFAPolicy(ctx context.Context, arg UpsertCountyMFAPolicyParams) (CountyMfaPolicy, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,11 @@
+-- name: GetCountyMFAPolicy :one
+SELECT county_id, mfa_required, updated_by, updated_at
+FROM county_mfa_policies
+WHERE county_id = @county_id;
+
+-- name: UpsertCountyMFAPolicy :one
+INSERT INTO county_mfa_policies (county_id, mfa_required, updated_by)
+VALUES (@county_id, @mfa_required, @updated_by)
+ON CONFLICT (county_id) DO UPDATE
+SET mfa_required = EXCLUDED.mfa_required, updated_by = EXCLUDED.updated_by, updated_at = CURRENT_TIMESTAMP
+RETURNING county_id, mfa_required, updated_by, updated_at;
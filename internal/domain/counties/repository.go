@@ -12,6 +12,8 @@ type Repository interface {
 	ListCounties(ctx context.Context, params models.ListCountiesParams) ([]models.County, error)
 	UpdateCounty(ctx context.Context, params models.UpdateCountyParams) (models.County, error)
 	DeleteCounty(ctx context.Context, id int32) error
+	GetCountyMFAPolicy(ctx context.Context, countyID int32) (models.CountyMfaPolicy, error)
+	UpsertCountyMFAPolicy(ctx context.Context, params models.UpsertCountyMFAPolicyParams) (models.CountyMfaPolicy, error)
 }
 
 type repository struct {
@@ -42,3 +44,11 @@ func (r *repository) DeleteCounty(ctx context.Context, id int32) error {
 	
 	return r.q.DeleteCounty(ctx, id)
 }
+
+func (r *repository) GetCountyMFAPolicy(ctx context.Context, countyID int32) (models.CountyMfaPolicy, error) {
+	return r.q.GetCountyMFAPolicy(ctx, countyID)
+}
+
+func (r *repository) UpsertCountyMFAPolicy(ctx context.Context, params models.UpsertCountyMFAPolicyParams) (models.CountyMfaPolicy, error) {
+	return r.q.UpsertCountyMFAPolicy(ctx, params)
+}
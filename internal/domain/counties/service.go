@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"errors"
 
+	"github.com/google/uuid"
 	"github.com/sangkips/revenue-system/internal/domain/counties/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
 )
 
 type Service struct {
@@ -17,6 +19,10 @@ func NewService(repo Repository) *Service {
 }
 
 func (s *Service) CreateCounty(ctx context.Context, req CreateCountyRequest) (models.County, error) {
+	if _, unrestricted, err := auth.CallerCounty(ctx); err != nil || !unrestricted {
+		return models.County{}, auth.ErrCountyForbidden
+	}
+
 	if req.Name == "" || req.Code == "" {
 		return models.County{}, errors.New("name and code are required")
 	}
@@ -41,14 +47,38 @@ type UpdateCountyRequest struct {
 }
 
 func (s *Service) GetCounty(ctx context.Context, id int32) (models.County, error) {
+	if err := auth.AuthorizeCounty(ctx, id); err != nil {
+		return models.County{}, err
+	}
 	return s.repo.GetCountyByID(ctx, id)
 }
 
 func (s *Service) ListCounties(ctx context.Context, limit, offset int32) ([]models.County, error) {
+	countyID, unrestricted, err := auth.CallerCounty(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// County-bound users only ever see their own county.
+	if !unrestricted {
+		if offset > 0 {
+			return []models.County{}, nil
+		}
+		county, err := s.repo.GetCountyByID(ctx, countyID)
+		if err != nil {
+			return nil, err
+		}
+		return []models.County{county}, nil
+	}
+
 	return s.repo.ListCounties(ctx, models.ListCountiesParams{Limit: limit, Offset: offset})
 }
 
 func (s *Service) UpdateCounty(ctx context.Context, id int32, req UpdateCountyRequest) (models.County, error) {
+	if err := auth.AuthorizeCounty(ctx, id); err != nil {
+		return models.County{}, err
+	}
+
 	params := models.UpdateCountyParams{
 		ID:                    id,
 		UpdateName:            req.Name != nil,
@@ -67,5 +97,45 @@ func (s *Service) UpdateCounty(ctx context.Context, id int32, req UpdateCountyRe
 }
 
 func (s *Service) DeleteCounty(ctx context.Context, id int32) error {
+	if err := auth.AuthorizeCounty(ctx, id); err != nil {
+		return err
+	}
 	return s.repo.DeleteCounty(ctx, id)
 }
+
+type UpdateMFAPolicyRequest struct {
+	MFARequired bool `json:"mfa_required"`
+}
+
+// GetMFAPolicy returns the county's MFA policy; counties without a stored policy
+// do not require MFA.
+func (s *Service) GetMFAPolicy(ctx context.Context, countyID int32) (models.CountyMfaPolicy, error) {
+	if err := auth.AuthorizeCounty(ctx, countyID); err != nil {
+		return models.CountyMfaPolicy{}, err
+	}
+
+	policy, err := s.repo.GetCountyMFAPolicy(ctx, countyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.CountyMfaPolicy{CountyID: countyID}, nil
+	}
+	return policy, err
+}
+
+func (s *Service) UpdateMFAPolicy(ctx context.Context, countyID int32, req UpdateMFAPolicyRequest) (models.CountyMfaPolicy, error) {
+	if err := auth.AuthorizeCounty(ctx, countyID); err != nil {
+		return models.CountyMfaPolicy{}, err
+	}
+
+	var updatedBy uuid.NullUUID
+	if userID, ok := ctx.Value(auth.UserIDKey).(string); ok {
+		if parsed, err := uuid.Parse(userID); err == nil {
+			updatedBy = uuid.NullUUID{UUID: parsed, Valid: true}
+		}
+	}
+
+	return s.repo.UpsertCountyMFAPolicy(ctx, models.UpsertCountyMFAPolicyParams{
+		CountyID:    countyID,
+		MfaRequired: req.MFARequired,
+		UpdatedBy:   updatedBy,
+	})
+}
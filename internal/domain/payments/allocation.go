@@ -0,0 +1,284 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	assessmentmodels "github.com/sangkips/revenue-system/internal/domain/assessment/models"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
+	"github.com/sangkips/revenue-system/internal/money"
+)
+
+// AllocationStrategy decides how a completed payment is spread over the
+// taxpayer's open assessments.
+type AllocationStrategy string
+
+const (
+	// AllocateOldestDue settles assessments in due-date order, each one's
+	// penalty, then interest, then principal.
+	AllocateOldestDue AllocationStrategy = "oldest_due"
+	// AllocatePenaltyFirst clears every open penalty, then every interest
+	// charge, then principal, oldest due first within each.
+	AllocatePenaltyFirst AllocationStrategy = "penalty_first"
+	// AllocateTargeted settles only the assessment the payment names. Any
+	// excess is held as the taxpayer's credit.
+	AllocateTargeted AllocationStrategy = "targeted"
+)
+
+func ParseAllocationStrategy(s string) (AllocationStrategy, error) {
+	switch strategy := AllocationStrategy(s); strategy {
+	case AllocateOldestDue, AllocatePenaltyFirst, AllocateTargeted:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("invalid allocation strategy %q: must be 'oldest_due', 'penalty_first', or 'targeted'", s)
+}
+
+// WithAllocationStrategy sets how completed payments are allocated when the
+// caller does not choose. The default is AllocateOldestDue.
+func WithAllocationStrategy(strategy AllocationStrategy) Option {
+	return func(s *Service) {
+		s.strategy = strategy
+	}
+}
+
+var (
+	ErrOverAllocated      = errors.New("allocation exceeds what is available")
+	ErrAssessmentClosed   = errors.New("assessment is closed to payments")
+	ErrAssessmentNotFound = errors.New("assessment not found")
+	ErrNoTargetAssessment = errors.New("targeted allocation needs an assessment_id")
+)
+
+// allocationComponents are the parts of an assessment in the order a payment
+// settles them.
+var allocationComponents = []string{"penalty", "interest", "principal"}
+
+// balance is what one assessment still owes, by allocation type.
+type balance struct {
+	assessmentID uuid.UUID
+	owed         map[string]money.Amount
+}
+
+func (b balance) total() money.Amount {
+	total := money.Zero
+	for _, owed := range b.owed {
+		total = total.Add(owed)
+	}
+	return total
+}
+
+// newBalance works out what is owed per component. Penalty and interest are
+// what the assessment's items of those types charge; principal is the rest of
+// the total. Completed allocations are taken off their own component, and the
+// result never exceeds the total still unpaid.
+func newBalance(a assessmentmodels.Assessment, charged, paid map[string]money.Amount) balance {
+	b := balance{assessmentID: a.ID, owed: map[string]money.Amount{}}
+
+	principal := a.TotalAmount.Sub(charged["penalty"]).Sub(charged["interest"])
+	charged = map[string]money.Amount{
+		"penalty":   charged["penalty"],
+		"interest":  charged["interest"],
+		"principal": money.Max(principal, money.Zero),
+	}
+
+	unpaid := a.TotalAmount
+	for _, typ := range allocationComponents {
+		b.owed[typ] = money.Max(charged[typ].Sub(paid[typ]), money.Zero)
+		unpaid = unpaid.Sub(paid[typ])
+	}
+
+	// Trim from principal upwards until the components fit what is unpaid.
+	excess := b.total().Sub(money.Max(unpaid, money.Zero))
+	for i := len(allocationComponents) - 1; i >= 0 && excess.IsPositive(); i-- {
+		typ := allocationComponents[i]
+		cut := money.Min(excess, b.owed[typ])
+		b.owed[typ] = b.owed[typ].Sub(cut)
+		excess = excess.Sub(cut)
+	}
+	return b
+}
+
+// planAllocations spreads amount over balances, which must be ordered oldest
+// due first, as strategy dictates. Whatever no balance needs is left out.
+func planAllocations(amount money.Amount, balances []balance, strategy AllocationStrategy) []CollectAllocationRequest {
+	var plan []CollectAllocationRequest
+	take := func(b balance, typ string) {
+		n := money.Min(amount, b.owed[typ])
+		if !n.IsPositive() {
+			return
+		}
+		plan = append(plan, CollectAllocationRequest{
+			AssessmentID:    b.assessmentID.String(),
+			AllocatedAmount: n,
+			AllocationType:  typ,
+		})
+		amount = amount.Sub(n)
+	}
+
+	if strategy == AllocatePenaltyFirst {
+		for _, typ := range allocationComponents {
+			for _, b := range balances {
+				take(b, typ)
+			}
+		}
+		return plan
+	}
+	for _, b := range balances {
+		for _, typ := range allocationComponents {
+			take(b, typ)
+		}
+	}
+	return plan
+}
+
+// assessmentBalance loads what a locked assessment still owes.
+func assessmentBalance(ctx context.Context, st Stores, a assessmentmodels.Assessment) (balance, error) {
+	items, err := st.Assessments.SumAssessmentItemsByType(ctx, a.ID)
+	if err != nil {
+		return balance{}, err
+	}
+	charged := map[string]money.Amount{}
+	for _, item := range items {
+		charged[item.ItemType] = item.Total
+	}
+
+	allocations, err := st.Payments.SumCompletedAllocationsByTypeForAssessment(ctx, a.ID)
+	if err != nil {
+		return balance{}, err
+	}
+	paid := map[string]money.Amount{}
+	for _, allocation := range allocations {
+		paid[allocation.AllocationType] = allocation.Total
+	}
+	return newBalance(a, charged, paid), nil
+}
+
+type AllocationResult struct {
+	Allocations        []models.PaymentAllocation `json:"allocations"`
+	SettledAssessments []uuid.UUID                `json:"settled_assessments"`
+	Unallocated        money.Amount               `json:"unallocated"`
+}
+
+// strategyFor resolves the strategy for payment: the one requested, or the
+// service default. A default of targeted falls back to oldest-due for
+// payments that name no assessment.
+func (s *Service) strategyFor(requested string, payment models.Payment) (AllocationStrategy, error) {
+	if requested != "" {
+		return ParseAllocationStrategy(requested)
+	}
+	strategy := s.strategy
+	if strategy == "" || (strategy == AllocateTargeted && !payment.AssessmentID.Valid) {
+		strategy = AllocateOldestDue
+	}
+	return strategy, nil
+}
+
+// autoAllocate distributes the unallocated part of a completed payment over
+// the payer's open assessments; what is left over is held as the payer's
+// credit. Targeted allocation goes to target, or failing that to the
+// payment's own assessment. Money asked for by refunds awaiting review is
+// left where it is until they are decided. The caller must hold the
+// payment's row lock or have created the payment in the same transaction.
+func autoAllocate(ctx context.Context, st Stores, payment models.Payment, strategy AllocationStrategy, target uuid.NullUUID) (AllocationResult, error) {
+	if payment.Status != "completed" {
+		return AllocationResult{}, fmt.Errorf("payment is %s; only completed payments are allocated", payment.Status)
+	}
+	remaining, err := unallocated(ctx, st, payment)
+	if err != nil {
+		return AllocationResult{}, err
+	}
+	pending, err := st.Payments.SumPendingRefundsForPayment(ctx, payment.ID)
+	if err != nil {
+		return AllocationResult{}, err
+	}
+	spendable := remaining.Sub(pending)
+	result := AllocationResult{Unallocated: remaining}
+	if !spendable.IsPositive() {
+		return result, syncPaymentCredit(ctx, st, payment, "")
+	}
+
+	var open []assessmentmodels.Assessment
+	if strategy == AllocateTargeted {
+		if !target.Valid {
+			target = payment.AssessmentID
+		}
+		if !target.Valid {
+			return AllocationResult{}, ErrNoTargetAssessment
+		}
+		a, err := st.Assessments.GetAssessmentForUpdate(ctx, target.UUID)
+		if err != nil {
+			return AllocationResult{}, err
+		}
+		if a.Status == "paid" || a.Status == "rejected" {
+			return AllocationResult{}, fmt.Errorf("%w: assessment %s is %s", ErrAssessmentClosed, a.ID, a.Status)
+		}
+		open = append(open, a)
+	} else {
+		open, err = st.Assessments.ListOpenAssessmentsForTaxpayerForUpdate(ctx, payment.CountyID, payment.TaxpayerID)
+		if err != nil {
+			return AllocationResult{}, err
+		}
+	}
+
+	balances := make([]balance, 0, len(open))
+	for _, a := range open {
+		b, err := assessmentBalance(ctx, st, a)
+		if err != nil {
+			return AllocationResult{}, err
+		}
+		balances = append(balances, b)
+	}
+
+	for _, req := range planAllocations(spendable, balances, strategy) {
+		allocation, settled, err := allocate(ctx, st, payment, req)
+		if err != nil {
+			return AllocationResult{}, err
+		}
+		result.Allocations = append(result.Allocations, allocation)
+		result.Unallocated = result.Unallocated.Sub(allocation.AllocatedAmount)
+		if settled {
+			result.SettledAssessments = append(result.SettledAssessments, allocation.AssessmentID)
+		}
+	}
+
+	if err := syncPaymentCredit(ctx, st, payment, ""); err != nil {
+		return AllocationResult{}, err
+	}
+	return result, nil
+}
+
+// AllocatePayment runs the allocation engine over whatever part of a
+// completed payment is still unallocated, for instance after new assessments
+// are raised against a taxpayer who paid in advance.
+func (s *Service) AllocatePayment(ctx context.Context, id string, strategy string) (AllocationResult, error) {
+	if s.uow == nil {
+		return AllocationResult{}, errors.New("payment allocation is not configured")
+	}
+	paymentID, err := uuid.Parse(id)
+	if err != nil {
+		return AllocationResult{}, err
+	}
+
+	var result AllocationResult
+	err = s.uow.Do(ctx, func(st Stores) error {
+		payment, err := st.Payments.GetPaymentForUpdate(ctx, paymentID)
+		if err != nil {
+			return err
+		}
+		if err := auth.AuthorizeCounty(ctx, payment.CountyID); err != nil {
+			return err
+		}
+		resolved, err := s.strategyFor(strategy, payment)
+		if err != nil {
+			return err
+		}
+		result, err = autoAllocate(ctx, st, payment, resolved, uuid.NullUUID{})
+		return err
+	})
+	if err != nil {
+		return AllocationResult{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,133 @@
+package payments
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	assessmentmodels "github.com/sangkips/revenue-system/internal/domain/assessment/models"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func amounts(penalty, interest, principal string) map[string]money.Amount {
+	return map[string]money.Amount{
+		"penalty":   money.MustParse(penalty),
+		"interest":  money.MustParse(interest),
+		"principal": money.MustParse(principal),
+	}
+}
+
+func TestNewBalance(t *testing.T) {
+	a := assessmentmodels.Assessment{ID: uuid.New(), TotalAmount: money.MustParse("1150")}
+
+	tests := []struct {
+		name    string
+		charged map[string]money.Amount
+		paid    map[string]money.Amount
+		want    map[string]money.Amount
+	}{
+		{
+			name: "no items is all principal",
+			want: amounts("0", "0", "1150"),
+		},
+		{
+			name:    "itemised penalty and interest",
+			charged: map[string]money.Amount{"penalty": money.MustParse("100"), "interest": money.MustParse("50")},
+			want:    amounts("100", "50", "1000"),
+		},
+		{
+			name:    "payments come off their own component",
+			charged: map[string]money.Amount{"penalty": money.MustParse("100"), "interest": money.MustParse("50")},
+			paid:    map[string]money.Amount{"penalty": money.MustParse("100"), "principal": money.MustParse("400")},
+			want:    amounts("0", "50", "600"),
+		},
+		{
+			// Paid as principal before the penalty was itemised.
+			name:    "never more than the unpaid total",
+			charged: map[string]money.Amount{"penalty": money.MustParse("100")},
+			paid:    map[string]money.Amount{"principal": money.MustParse("1100")},
+			want:    amounts("50", "0", "0"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBalance(a, tt.charged, tt.paid)
+			for _, typ := range allocationComponents {
+				assert.Equal(t, tt.want[typ].String(), b.owed[typ].String(), typ)
+			}
+		})
+	}
+}
+
+func TestPlanAllocations(t *testing.T) {
+	older := balance{assessmentID: uuid.New(), owed: amounts("100", "50", "1000")}
+	newer := balance{assessmentID: uuid.New(), owed: amounts("200", "0", "500")}
+
+	type step struct {
+		assessment uuid.UUID
+		typ        string
+		amount     string
+	}
+	tests := []struct {
+		name     string
+		amount   string
+		strategy AllocationStrategy
+		want     []step
+	}{
+		{
+			name:     "oldest due settles one assessment at a time",
+			amount:   "1300",
+			strategy: AllocateOldestDue,
+			want: []step{
+				{older.assessmentID, "penalty", "100.00"},
+				{older.assessmentID, "interest", "50.00"},
+				{older.assessmentID, "principal", "1000.00"},
+				{newer.assessmentID, "penalty", "150.00"},
+			},
+		},
+		{
+			name:     "penalty first clears penalties across assessments",
+			amount:   "400",
+			strategy: AllocatePenaltyFirst,
+			want: []step{
+				{older.assessmentID, "penalty", "100.00"},
+				{newer.assessmentID, "penalty", "200.00"},
+				{older.assessmentID, "interest", "50.00"},
+				{older.assessmentID, "principal", "50.00"},
+			},
+		},
+		{
+			name:     "excess stays unallocated",
+			amount:   "5000",
+			strategy: AllocateOldestDue,
+			want: []step{
+				{older.assessmentID, "penalty", "100.00"},
+				{older.assessmentID, "interest", "50.00"},
+				{older.assessmentID, "principal", "1000.00"},
+				{newer.assessmentID, "penalty", "200.00"},
+				{newer.assessmentID, "principal", "500.00"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := planAllocations(money.MustParse(tt.amount), []balance{older, newer}, tt.strategy)
+			require.Len(t, plan, len(tt.want))
+			for i, want := range tt.want {
+				assert.Equal(t, want.assessment.String(), plan[i].AssessmentID, i)
+				assert.Equal(t, want.typ, plan[i].AllocationType, i)
+				assert.Equal(t, want.amount, plan[i].AllocatedAmount.String(), i)
+			}
+		})
+	}
+}
+
+func TestParseAllocationStrategy(t *testing.T) {
+	strategy, err := ParseAllocationStrategy("penalty_first")
+	require.NoError(t, err)
+	assert.Equal(t, AllocatePenaltyFirst, strategy)
+
+	_, err = ParseAllocationStrategy("newest_first")
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/sangkips/revenue-system/internal/db"
+	assessmentmodels "github.com/sangkips/revenue-system/internal/domain/assessment/models"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/sangkips/revenue-system/internal/mpesa"
+)
+
+// WithPaybill makes C2B validation decline payments sent to any paybill other
+// than shortCode.
+func WithPaybill(shortCode string) Option {
+	return func(s *Service) {
+		s.paybill = shortCode
+	}
+}
+
+var ErrUnknownAccount = errors.New("no assessment matches the account reference")
+
+// C2BRejection is why a paybill payment was declined at validation. Code is
+// the Daraja result code returned to the customer's phone.
+type C2BRejection struct {
+	Code   string
+	Reason string
+}
+
+func (e *C2BRejection) Error() string {
+	return e.Reason
+}
+
+// ValidateC2B decides whether Daraja may accept a paybill payment. The
+// account reference must be the number of an assessment that is still open.
+// Paying more than is owed is allowed; the excess is held as the taxpayer's
+// credit.
+func (s *Service) ValidateC2B(ctx context.Context, p mpesa.C2BPayment) error {
+	if s.uow == nil {
+		return ErrMpesaDisabled
+	}
+	if s.paybill != "" && p.ShortCode != s.paybill {
+		return &C2BRejection{Code: mpesa.C2BInvalidShortCode, Reason: "payment is for another paybill"}
+	}
+	if !p.Amount.IsPositive() {
+		return &C2BRejection{Code: mpesa.C2BInvalidAmount, Reason: "amount must be positive"}
+	}
+
+	return s.uow.Do(ctx, func(st Stores) error {
+		a, err := st.Assessments.GetAssessmentByNumber(ctx, p.BillRefNumber)
+		if errors.Is(err, sql.ErrNoRows) {
+			return &C2BRejection{Code: mpesa.C2BInvalidAccountNumber, Reason: ErrUnknownAccount.Error()}
+		}
+		if err != nil {
+			return err
+		}
+		if a.Status == "paid" || a.Status == "rejected" {
+			return &C2BRejection{Code: mpesa.C2BInvalidAccountNumber, Reason: fmt.Sprintf("assessment is %s and cannot take payments", a.Status)}
+		}
+		return nil
+	})
+}
+
+// ConfirmC2B records a paybill payment Daraja has completed and allocates it
+// to the assessment named in the account reference. Confirmations are keyed
+// on TransID, which becomes the payment's M-Pesa receipt number, so a
+// repeated confirmation returns the payment created the first time. A
+// payment whose account reference matches no assessment is held in M-Pesa
+// suspense and ErrUnknownAccount returned.
+func (s *Service) ConfirmC2B(ctx context.Context, p mpesa.C2BPayment) (models.Payment, error) {
+	if s.uow == nil {
+		return models.Payment{}, ErrMpesaDisabled
+	}
+
+	var payment models.Payment
+	var unknown bool
+	err := s.uow.Do(ctx, func(st Stores) error {
+		unknown = false
+		existing, err := st.Payments.GetPaymentByMpesaReceiptNumber(ctx, p.TransID)
+		if err == nil {
+			payment = existing
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		a, err := st.Assessments.GetAssessmentByNumber(ctx, p.BillRefNumber)
+		if errors.Is(err, sql.ErrNoRows) {
+			// The money has already moved, so it is kept for someone to match.
+			unknown = true
+			return st.Payments.CreateMpesaSuspense(ctx, c2bSuspense(p))
+		}
+		if err != nil {
+			return err
+		}
+
+		if payment, err = createPayment(ctx, st, c2bPaymentParams(p, a), "M-Pesa paybill payment confirmed"); err != nil {
+			return err
+		}
+		return s.allocateToAssessment(ctx, st, payment)
+	})
+	if db.IsUniqueViolation(err) {
+		// A concurrent confirmation for the same TransID won the race.
+		log.Info().Str("trans_id", p.TransID).Msg("Duplicate C2B confirmation")
+		return s.repo.GetPaymentByMpesaReceiptNumber(ctx, p.TransID)
+	}
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if unknown {
+		return models.Payment{}, ErrUnknownAccount
+	}
+	return payment, nil
+}
+
+// c2bSuspense is the suspense entry for a paybill payment whose account
+// reference matches no assessment.
+func c2bSuspense(p mpesa.C2BPayment) models.InsertMpesaSuspenseParams {
+	return models.InsertMpesaSuspenseParams{
+		Source:             "c2b",
+		Reference:          p.TransID,
+		MpesaReceiptNumber: sql.NullString{String: p.TransID, Valid: true},
+		Amount:             money.NullAmount{Amount: p.Amount, Valid: true},
+		PhoneNumber:        sql.NullString{String: p.PhoneNumber, Valid: p.PhoneNumber != ""},
+		AccountReference:   sql.NullString{String: p.BillRefNumber, Valid: p.BillRefNumber != ""},
+		PayerName:          sql.NullString{String: p.PayerName, Valid: p.PayerName != ""},
+		TransactionDate:    sql.NullTime{Time: p.TransTime, Valid: !p.TransTime.IsZero()},
+		Status:             "unmatched",
+	}
+}
+
+// c2bPaymentParams builds the completed payment for a confirmed paybill
+// payment. There is no collector: the taxpayer paid directly.
+func c2bPaymentParams(p mpesa.C2BPayment, a assessmentmodels.Assessment) models.InsertPaymentParams {
+	return models.InsertPaymentParams{
+		CountyID:              a.CountyID,
+		TaxpayerID:            a.TaxpayerID,
+		AssessmentID:          uuid.NullUUID{UUID: a.ID, Valid: true},
+		PaymentNumber:         "MPESA-" + p.TransID,
+		Amount:                p.Amount,
+		PaymentMethod:         "mpesa",
+		PaymentChannel:        sql.NullString{String: "paybill", Valid: true},
+		ExternalTransactionID: sql.NullString{String: p.TransID, Valid: true},
+		MpesaReceiptNumber:    sql.NullString{String: p.TransID, Valid: true},
+		PayerPhoneNumber:      sql.NullString{String: p.PhoneNumber, Valid: p.PhoneNumber != ""},
+		PayerName:             sql.NullString{String: p.PayerName, Valid: p.PayerName != ""},
+		Status:                "completed",
+		Reconciled:            sql.NullBool{Bool: false, Valid: true},
+		PaymentDate:           sql.NullTime{Time: p.TransTime, Valid: true},
+	}
+}
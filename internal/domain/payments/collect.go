@@ -0,0 +1,272 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/db"
+	"github.com/sangkips/revenue-system/internal/domain/assessment"
+	assessmentmodels "github.com/sangkips/revenue-system/internal/domain/assessment/models"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/money"
+)
+
+// Stores are the repositories a collection writes through. NewStores binds
+// them all to the same transaction.
+type Stores struct {
+	Payments    Repository
+	Assessments assessment.Repository
+}
+
+func NewStores(tx db.DBTX) Stores {
+	return Stores{
+		Payments:    NewRepository(tx),
+		Assessments: assessment.NewRepository(tx),
+	}
+}
+
+type CollectAllocationRequest struct {
+	AssessmentID    string       `json:"assessment_id"`
+	AllocatedAmount money.Amount `json:"allocated_amount"`
+	AllocationType  string       `json:"allocation_type,omitempty"`
+}
+
+type CollectPaymentRequest struct {
+	CreatePaymentRequest
+	Allocations []CollectAllocationRequest `json:"allocations"`
+	// AllocationStrategy picks how the allocation engine spreads the payment
+	// when Allocations is empty; see AllocationStrategy.
+	AllocationStrategy string                `json:"allocation_strategy,omitempty"`
+	Receipt            *CreateReceiptRequest `json:"receipt,omitempty"`
+}
+
+type CollectPaymentResult struct {
+	Payment            models.Payment             `json:"payment"`
+	Allocations        []models.PaymentAllocation `json:"allocations"`
+	SettledAssessments []uuid.UUID                `json:"settled_assessments"`
+	Unallocated        money.Amount               `json:"unallocated"`
+	Receipt            *models.Receipt            `json:"receipt,omitempty"`
+}
+
+// CollectPayment records a completed payment, allocates it to assessments and
+// optionally issues a receipt. Allocations are taken as given or, when there
+// are none, worked out by the allocation engine. Either every row is written
+// or none is, and any assessment whose completed allocations now cover its
+// total is marked paid.
+func (s *Service) CollectPayment(ctx context.Context, req CollectPaymentRequest, userID string) (CollectPaymentResult, error) {
+	if s.uow == nil {
+		return CollectPaymentResult{}, errors.New("payment collection is not configured")
+	}
+
+	req.Status = "completed"
+	params, err := s.paymentParams(ctx, req.CreatePaymentRequest, userID)
+	if err != nil {
+		return CollectPaymentResult{}, err
+	}
+
+	allocated := money.Zero
+	for _, a := range req.Allocations {
+		if a.AssessmentID == "" || !a.AllocatedAmount.IsPositive() {
+			return CollectPaymentResult{}, errors.New("allocation fields missing or invalid")
+		}
+		if a.AllocationType != "" && !validAllocationType(a.AllocationType) {
+			return CollectPaymentResult{}, errors.New("invalid allocation_type")
+		}
+		if _, err := uuid.Parse(a.AssessmentID); err != nil {
+			return CollectPaymentResult{}, err
+		}
+		allocated = allocated.Add(a.AllocatedAmount)
+	}
+	if allocated.Cmp(req.Amount) > 0 {
+		return CollectPaymentResult{}, errors.New("allocations exceed the payment amount")
+	}
+	if len(req.Allocations) > 0 && req.AllocationStrategy != "" {
+		return CollectPaymentResult{}, errors.New("allocation_strategy cannot be combined with explicit allocations")
+	}
+
+	var receipt models.InsertReceiptParams
+	if req.Receipt != nil {
+		// The payment ID is not known yet; validate against a placeholder and
+		// fill it in once the payment row exists.
+		req.Receipt.PaymentID = uuid.Nil.String()
+		if receipt, err = receiptParams(*req.Receipt); err != nil {
+			return CollectPaymentResult{}, err
+		}
+	}
+
+	var result CollectPaymentResult
+	err = s.uow.Do(ctx, func(st Stores) error {
+		result = CollectPaymentResult{}
+
+		params, err := s.numberPayment(ctx, st, params)
+		if err != nil {
+			return err
+		}
+		payment, err := createPayment(ctx, st, params, "")
+		if err != nil {
+			return err
+		}
+		result.Payment = payment
+
+		if len(req.Allocations) == 0 {
+			strategy, err := s.strategyFor(req.AllocationStrategy, payment)
+			if err != nil {
+				return err
+			}
+			allocated, err := autoAllocate(ctx, st, payment, strategy, uuid.NullUUID{})
+			if err != nil {
+				return err
+			}
+			result.Allocations = allocated.Allocations
+			result.SettledAssessments = allocated.SettledAssessments
+		}
+		for _, a := range req.Allocations {
+			allocation, settled, err := allocate(ctx, st, payment, a)
+			if err != nil {
+				return err
+			}
+			result.Allocations = append(result.Allocations, allocation)
+			if settled {
+				result.SettledAssessments = append(result.SettledAssessments, allocation.AssessmentID)
+			}
+		}
+		result.Unallocated = payment.Amount
+		for _, allocation := range result.Allocations {
+			result.Unallocated = result.Unallocated.Sub(allocation.AllocatedAmount)
+		}
+		if len(req.Allocations) > 0 {
+			if err := syncPaymentCredit(ctx, st, payment, ""); err != nil {
+				return err
+			}
+		}
+
+		if req.Receipt != nil {
+			issued, err := s.issueReceipt(ctx, st, payment, receipt)
+			if err != nil {
+				return err
+			}
+			result.Receipt = &issued
+		}
+		return nil
+	})
+	if err != nil {
+		return CollectPaymentResult{}, err
+	}
+	if result.Receipt != nil {
+		rendered := s.renderIssued(ctx, *result.Receipt)
+		result.Receipt = &rendered
+	}
+	return result, nil
+}
+
+// allocate locks the assessment, records the allocation against it and marks
+// the assessment paid once fully covered. It reports whether that happened.
+// Neither the payment nor the assessment component may be over-allocated;
+// the caller must hold the payment's row lock or have just created it, and
+// must sync the payment's credit afterwards.
+func allocate(ctx context.Context, st Stores, payment models.Payment, req CollectAllocationRequest) (models.PaymentAllocation, bool, error) {
+	assessmentID, err := uuid.Parse(req.AssessmentID)
+	if err != nil {
+		return models.PaymentAllocation{}, false, err
+	}
+	if req.AllocationType == "" {
+		req.AllocationType = "principal"
+	}
+
+	available, err := unallocated(ctx, st, payment)
+	if err != nil {
+		return models.PaymentAllocation{}, false, err
+	}
+	if req.AllocatedAmount.Cmp(available) > 0 {
+		return models.PaymentAllocation{}, false, fmt.Errorf("%w: payment %s has %s left to allocate", ErrOverAllocated, payment.ID, available)
+	}
+
+	a, err := st.Assessments.GetAssessmentForUpdate(ctx, assessmentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PaymentAllocation{}, false, fmt.Errorf("assessment %s not found", assessmentID)
+		}
+		return models.PaymentAllocation{}, false, err
+	}
+	if a.CountyID != payment.CountyID || a.TaxpayerID != payment.TaxpayerID {
+		return models.PaymentAllocation{}, false, fmt.Errorf("assessment %s does not belong to the paying taxpayer", assessmentID)
+	}
+	if a.Status == "paid" || a.Status == "rejected" {
+		return models.PaymentAllocation{}, false, fmt.Errorf("%w: assessment %s is %s", ErrAssessmentClosed, assessmentID, a.Status)
+	}
+
+	b, err := assessmentBalance(ctx, st, a)
+	if err != nil {
+		return models.PaymentAllocation{}, false, err
+	}
+	if owed := b.owed[req.AllocationType]; req.AllocatedAmount.Cmp(owed) > 0 {
+		return models.PaymentAllocation{}, false, fmt.Errorf("%w: assessment %s owes %s in %s", ErrOverAllocated, assessmentID, owed, req.AllocationType)
+	}
+
+	allocation, err := st.Payments.CreatePaymentAllocation(ctx, models.InsertPaymentAllocationParams{
+		PaymentID:       payment.ID,
+		AssessmentID:    assessmentID,
+		AllocatedAmount: req.AllocatedAmount,
+		AllocationType:  sql.NullString{String: req.AllocationType, Valid: true},
+	})
+	if err != nil {
+		return models.PaymentAllocation{}, false, err
+	}
+
+	paid, err := st.Payments.SumCompletedAllocationsForAssessment(ctx, assessmentID)
+	if err != nil {
+		return models.PaymentAllocation{}, false, err
+	}
+	if paid.Cmp(a.TotalAmount) < 0 {
+		return allocation, false, nil
+	}
+
+	err = st.Assessments.SetAssessmentStatus(ctx, assessmentmodels.SetAssessmentStatusParams{
+		Status: "paid",
+		ID:     assessmentID,
+	})
+	if err != nil {
+		return models.PaymentAllocation{}, false, err
+	}
+	return allocation, true, nil
+}
+
+// unallocate takes amount back from an allocation, deleting it once nothing
+// is left, and reopens the assessment if it no longer covers its total. The
+// caller must hold the payment's row lock and sync its credit afterwards.
+func unallocate(ctx context.Context, st Stores, allocation models.PaymentAllocation, amount money.Amount) error {
+	a, err := st.Assessments.GetAssessmentForUpdate(ctx, allocation.AssessmentID)
+	if err != nil {
+		return err
+	}
+
+	if rest := allocation.AllocatedAmount.Sub(amount); rest.IsPositive() {
+		err = st.Payments.SetPaymentAllocationAmount(ctx, models.SetPaymentAllocationAmountParams{
+			ID:              allocation.ID,
+			AllocatedAmount: rest,
+		})
+	} else {
+		err = st.Payments.DeletePaymentAllocation(ctx, allocation.ID.String())
+	}
+	if err != nil {
+		return err
+	}
+
+	if a.Status != "paid" {
+		return nil
+	}
+	paid, err := st.Payments.SumCompletedAllocationsForAssessment(ctx, a.ID)
+	if err != nil {
+		return err
+	}
+	if paid.Cmp(a.TotalAmount) >= 0 {
+		return nil
+	}
+	return st.Assessments.SetAssessmentStatus(ctx, assessmentmodels.SetAssessmentStatusParams{
+		Status: "approved",
+		ID:     a.ID,
+	})
+}
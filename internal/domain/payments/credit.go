@@ -0,0 +1,354 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
+	"github.com/sangkips/revenue-system/internal/money"
+)
+
+// Credit is whatever part of a completed payment no assessment took. The
+// ledger keeps, for every completed payment,
+//
+//	amount = allocations + credit held + credit refunded
+//
+// so applying credit is just allocating more of the payment it came from.
+
+var (
+	ErrNoCredit           = errors.New("taxpayer has no credit")
+	ErrInsufficientCredit = errors.New("credit balance is too low")
+	ErrTaxpayerNotFound   = errors.New("taxpayer not found")
+)
+
+type TaxpayerBalance struct {
+	TaxpayerID uuid.UUID                     `json:"taxpayer_id"`
+	CountyID   int32                         `json:"county_id"`
+	Balance    money.Amount                  `json:"balance"`
+	Movements  []models.TaxpayerCreditLedger `json:"movements"`
+}
+
+type ApplyCreditRequest struct {
+	Strategy     string `json:"strategy,omitempty"`      // defaults to the configured strategy
+	AssessmentID string `json:"assessment_id,omitempty"` // required for the targeted strategy
+}
+
+type RefundCreditRequest struct {
+	Amount money.Amount `json:"amount"`
+	Reason string       `json:"reason"`
+}
+
+// unallocated is how much of payment can still be allocated: the amount
+// less its allocations and any of its credit already refunded.
+func unallocated(ctx context.Context, st Stores, payment models.Payment) (money.Amount, error) {
+	allocated, err := st.Payments.SumAllocationsForPayment(ctx, payment.ID)
+	if err != nil {
+		return money.Zero, err
+	}
+	credit, err := st.Payments.GetPaymentCreditTotals(ctx, payment.ID)
+	if err != nil {
+		return money.Zero, err
+	}
+	return payment.Amount.Sub(allocated).Sub(credit.Refunded), nil
+}
+
+// syncPaymentCredit brings the credit a completed payment holds in line with
+// what its allocations leave over. A surplus is recorded as an overpayment;
+// credit taken up by new allocations is recorded as applied. An empty
+// description is filled in from the kind of movement.
+func syncPaymentCredit(ctx context.Context, st Stores, payment models.Payment, description string) error {
+	if payment.Status != "completed" {
+		return nil
+	}
+	allocated, err := st.Payments.SumAllocationsForPayment(ctx, payment.ID)
+	if err != nil {
+		return err
+	}
+	credit, err := st.Payments.GetPaymentCreditTotals(ctx, payment.ID)
+	if err != nil {
+		return err
+	}
+
+	delta := payment.Amount.Sub(allocated).Sub(credit.Held)
+	if delta.IsZero() {
+		return nil
+	}
+	entryType, defaultDescription := "overpayment", "paid more than was owed"
+	if delta.IsNegative() {
+		entryType, defaultDescription = "applied", "credit allocated to assessments"
+	}
+	if description == "" {
+		description = defaultDescription
+	}
+	_, err = moveCredit(ctx, st, payment, entryType, delta, description, uuid.NullUUID{})
+	return err
+}
+
+// moveCredit adds a signed amount to the payer's balance and records it
+// against payment.
+func moveCredit(ctx context.Context, st Stores, payment models.Payment, entryType string, amount money.Amount, description string, by uuid.NullUUID) (models.TaxpayerCreditLedger, error) {
+	balance, err := st.Payments.AdjustTaxpayerCreditBalance(ctx, models.AdjustTaxpayerCreditBalanceParams{
+		TaxpayerID: payment.TaxpayerID,
+		CountyID:   payment.CountyID,
+		Amount:     amount,
+	})
+	if err != nil {
+		return models.TaxpayerCreditLedger{}, err
+	}
+	return st.Payments.CreateCreditLedgerEntry(ctx, models.InsertCreditLedgerEntryParams{
+		CountyID:     payment.CountyID,
+		TaxpayerID:   payment.TaxpayerID,
+		PaymentID:    payment.ID,
+		EntryType:    entryType,
+		Amount:       amount,
+		BalanceAfter: balance.Balance,
+		Description:  description,
+		CreatedBy:    by,
+	})
+}
+
+// authorizeTaxpayer checks the caller may act for the taxpayer's county.
+func authorizeTaxpayer(ctx context.Context, repo Repository, taxpayerID uuid.UUID) (int32, error) {
+	countyID, err := repo.GetTaxpayerCounty(ctx, taxpayerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrTaxpayerNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := auth.AuthorizeCounty(ctx, countyID); err != nil {
+		return 0, err
+	}
+	return countyID, nil
+}
+
+// GetTaxpayerBalance returns the taxpayer's credit and its movements, newest
+// first.
+func (s *Service) GetTaxpayerBalance(ctx context.Context, taxpayerID string, limit int32, offset int32) (TaxpayerBalance, error) {
+	id, err := uuid.Parse(taxpayerID)
+	if err != nil {
+		return TaxpayerBalance{}, err
+	}
+	countyID, err := authorizeTaxpayer(ctx, s.repo, id)
+	if err != nil {
+		return TaxpayerBalance{}, err
+	}
+
+	result := TaxpayerBalance{TaxpayerID: id, CountyID: countyID, Balance: money.Zero}
+	balance, err := s.repo.GetTaxpayerCreditBalance(ctx, id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return TaxpayerBalance{}, err
+	}
+	if err == nil {
+		result.Balance = balance.Balance
+	}
+
+	result.Movements, err = s.repo.ListCreditLedgerEntries(ctx, models.ListCreditLedgerEntriesParams{
+		TaxpayerID: id,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		return TaxpayerBalance{}, err
+	}
+	return result, nil
+}
+
+// creditStrategy picks how credit is applied: the requested strategy, else
+// targeted when an assessment is named, else the configured one. Credit
+// belongs to no assessment, so a configured targeted strategy falls back to
+// oldest due and targeting needs an explicit assessment.
+func creditStrategy(configured AllocationStrategy, requested string, targeted bool) (AllocationStrategy, error) {
+	strategy := configured
+	switch {
+	case requested != "":
+		var err error
+		if strategy, err = ParseAllocationStrategy(requested); err != nil {
+			return "", err
+		}
+	case targeted:
+		strategy = AllocateTargeted
+	case strategy == "" || strategy == AllocateTargeted:
+		strategy = AllocateOldestDue
+	}
+	if strategy == AllocateTargeted && !targeted {
+		return "", errors.New("assessment_id is required to apply credit with the targeted strategy")
+	}
+	return strategy, nil
+}
+
+// ApplyCredit allocates the taxpayer's credit to their open assessments
+// through the allocation engine, spending the oldest credit first. Credit
+// asked for by refunds awaiting review is not applied.
+func (s *Service) ApplyCredit(ctx context.Context, taxpayerID string, req ApplyCreditRequest) (AllocationResult, error) {
+	if s.uow == nil {
+		return AllocationResult{}, errors.New("payment allocation is not configured")
+	}
+	id, err := uuid.Parse(taxpayerID)
+	if err != nil {
+		return AllocationResult{}, err
+	}
+	var target uuid.NullUUID
+	if req.AssessmentID != "" {
+		assessmentID, err := uuid.Parse(req.AssessmentID)
+		if err != nil {
+			return AllocationResult{}, err
+		}
+		target = uuid.NullUUID{UUID: assessmentID, Valid: true}
+	}
+
+	strategy, err := creditStrategy(s.strategy, req.Strategy, target.Valid)
+	if err != nil {
+		return AllocationResult{}, err
+	}
+
+	var result AllocationResult
+	err = s.uow.Do(ctx, func(st Stores) error {
+		result = AllocationResult{Unallocated: money.Zero}
+
+		if _, err := authorizeTaxpayer(ctx, st.Payments, id); err != nil {
+			return err
+		}
+		if _, err := st.Payments.GetTaxpayerCreditBalanceForUpdate(ctx, id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNoCredit
+			}
+			return err
+		}
+		credits, err := st.Payments.ListPaymentsWithCredit(ctx, id)
+		if err != nil {
+			return err
+		}
+		if len(credits) == 0 {
+			return ErrNoCredit
+		}
+
+		for _, credit := range credits {
+			payment, err := st.Payments.GetPaymentForUpdate(ctx, credit.PaymentID)
+			if err != nil {
+				return err
+			}
+			applied, err := autoAllocate(ctx, st, payment, strategy, target)
+			if err != nil {
+				return err
+			}
+			result.Allocations = append(result.Allocations, applied.Allocations...)
+			result.SettledAssessments = append(result.SettledAssessments, applied.SettledAssessments...)
+			result.Unallocated = result.Unallocated.Add(applied.Unallocated)
+		}
+		return nil
+	})
+	if err != nil {
+		return AllocationResult{}, err
+	}
+	return result, nil
+}
+
+// RefundCredit asks for credit to be paid back to the taxpayer, oldest
+// credit first. It opens a refund against each payment the credit comes
+// from, which like any refund moves no money until someone other than the
+// requester approves it. Credit already asked for by refunds awaiting review
+// is not available again.
+func (s *Service) RefundCredit(ctx context.Context, taxpayerID string, req RefundCreditRequest, userID string) ([]models.PaymentRefund, error) {
+	if s.uow == nil {
+		return nil, errors.New("taxpayer credit is not configured")
+	}
+	if !req.Amount.IsPositive() || req.Reason == "" {
+		return nil, errors.New("a positive amount and a reason are required")
+	}
+	id, err := uuid.Parse(taxpayerID)
+	if err != nil {
+		return nil, err
+	}
+	requestedBy, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var refunds []models.PaymentRefund
+	err = s.uow.Do(ctx, func(st Stores) error {
+		refunds = nil
+
+		if _, err := authorizeTaxpayer(ctx, st.Payments, id); err != nil {
+			return err
+		}
+		if _, err := st.Payments.GetTaxpayerCreditBalanceForUpdate(ctx, id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNoCredit
+			}
+			return err
+		}
+
+		credits, err := st.Payments.ListPaymentsWithCredit(ctx, id)
+		if err != nil {
+			return err
+		}
+		payments := make(map[uuid.UUID]models.Payment, len(credits))
+		available := money.Zero
+		for i, credit := range credits {
+			payment, err := st.Payments.GetPaymentForUpdate(ctx, credit.PaymentID)
+			if err != nil {
+				return err
+			}
+			pending, err := st.Payments.SumPendingRefundsForPayment(ctx, payment.ID)
+			if err != nil {
+				return err
+			}
+			payments[payment.ID] = payment
+			credits[i].Credit = money.Max(credit.Credit.Sub(pending), money.Zero)
+			available = available.Add(credits[i].Credit)
+		}
+		if req.Amount.Cmp(available) > 0 {
+			return fmt.Errorf("%w: %s available", ErrInsufficientCredit, available)
+		}
+
+		for _, take := range planCreditRefund(req.Amount, credits) {
+			payment := payments[take.paymentID]
+			refund, err := st.Payments.CreatePaymentRefund(ctx, models.InsertPaymentRefundParams{
+				PaymentID:   payment.ID,
+				CountyID:    payment.CountyID,
+				Amount:      take.amount,
+				Reason:      req.Reason,
+				RequestedBy: requestedBy,
+			})
+			if err != nil {
+				return err
+			}
+			refunds = append(refunds, refund)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// creditTake is how much of a credit refund comes out of one payment's credit.
+type creditTake struct {
+	paymentID uuid.UUID
+	amount    money.Amount
+}
+
+// planCreditRefund takes amount out of credits, which must be ordered oldest
+// first, so the oldest credit is paid back first. Anything left over is more
+// than the taxpayer holds.
+func planCreditRefund(amount money.Amount, credits []models.ListPaymentsWithCreditRow) []creditTake {
+	var takes []creditTake
+	for _, credit := range credits {
+		if !amount.IsPositive() {
+			break
+		}
+		if !credit.Credit.IsPositive() {
+			continue
+		}
+		n := money.Min(amount, credit.Credit)
+		takes = append(takes, creditTake{paymentID: credit.PaymentID, amount: n})
+		amount = amount.Sub(n)
+	}
+	return takes
+}
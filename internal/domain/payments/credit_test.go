@@ -0,0 +1,183 @@
+package payments
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// creditRepo keeps one payment's allocations and credit in memory; the rest
+// of Repository is unused.
+type creditRepo struct {
+	Repository
+	allocated money.Amount
+	totals    models.GetPaymentCreditTotalsRow
+	balance   money.Amount
+	pending   money.Amount
+	entries   []models.InsertCreditLedgerEntryParams
+}
+
+func (r *creditRepo) SumAllocationsForPayment(ctx context.Context, paymentID uuid.UUID) (money.Amount, error) {
+	return r.allocated, nil
+}
+
+func (r *creditRepo) GetPaymentCreditTotals(ctx context.Context, paymentID uuid.UUID) (models.GetPaymentCreditTotalsRow, error) {
+	return r.totals, nil
+}
+
+func (r *creditRepo) SumPendingRefundsForPayment(ctx context.Context, paymentID uuid.UUID) (money.Amount, error) {
+	return r.pending, nil
+}
+
+func (r *creditRepo) AdjustTaxpayerCreditBalance(ctx context.Context, params models.AdjustTaxpayerCreditBalanceParams) (models.TaxpayerCreditBalance, error) {
+	r.balance = r.balance.Add(params.Amount)
+	return models.TaxpayerCreditBalance{TaxpayerID: params.TaxpayerID, CountyID: params.CountyID, Balance: r.balance}, nil
+}
+
+func (r *creditRepo) CreateCreditLedgerEntry(ctx context.Context, params models.InsertCreditLedgerEntryParams) (models.TaxpayerCreditLedger, error) {
+	r.entries = append(r.entries, params)
+	r.totals.Held = r.totals.Held.Add(params.Amount)
+	return models.TaxpayerCreditLedger{PaymentID: params.PaymentID, EntryType: params.EntryType, Amount: params.Amount, BalanceAfter: params.BalanceAfter}, nil
+}
+
+func TestSyncPaymentCredit(t *testing.T) {
+	payment := models.Payment{ID: uuid.New(), TaxpayerID: uuid.New(), CountyID: 47, Amount: money.MustParse("1000"), Status: "completed"}
+	repo := &creditRepo{allocated: money.MustParse("600"), balance: money.MustParse("50")}
+	st := Stores{Payments: repo}
+	ctx := context.Background()
+
+	// What the allocations leave over becomes credit.
+	require.NoError(t, syncPaymentCredit(ctx, st, payment, ""))
+	require.Len(t, repo.entries, 1)
+	assert.Equal(t, "overpayment", repo.entries[0].EntryType)
+	assert.Equal(t, "400.00", repo.entries[0].Amount.String())
+	assert.Equal(t, "paid more than was owed", repo.entries[0].Description)
+	assert.Equal(t, "450.00", repo.entries[0].BalanceAfter.String())
+
+	// In line already: nothing is recorded.
+	require.NoError(t, syncPaymentCredit(ctx, st, payment, ""))
+	assert.Len(t, repo.entries, 1)
+
+	// Credit taken up by a new allocation is recorded as applied, negative.
+	repo.allocated = money.MustParse("850")
+	require.NoError(t, syncPaymentCredit(ctx, st, payment, ""))
+	require.Len(t, repo.entries, 2)
+	assert.Equal(t, "applied", repo.entries[1].EntryType)
+	assert.Equal(t, "-250.00", repo.entries[1].Amount.String())
+	assert.Equal(t, "credit allocated to assessments", repo.entries[1].Description)
+	assert.Equal(t, "200.00", repo.entries[1].BalanceAfter.String())
+
+	// An allocation removed puts the money back, with the caller's description.
+	repo.allocated = money.MustParse("700")
+	require.NoError(t, syncPaymentCredit(ctx, st, payment, "allocation removed"))
+	require.Len(t, repo.entries, 3)
+	assert.Equal(t, "overpayment", repo.entries[2].EntryType)
+	assert.Equal(t, "150.00", repo.entries[2].Amount.String())
+	assert.Equal(t, "allocation removed", repo.entries[2].Description)
+	assert.Equal(t, "300.00", repo.totals.Held.String())
+
+	// Only completed payments hold credit.
+	payment.Status = "processing"
+	repo.allocated = money.Zero
+	require.NoError(t, syncPaymentCredit(ctx, st, payment, ""))
+	assert.Len(t, repo.entries, 3)
+}
+
+func TestAutoAllocateLeavesPendingRefunds(t *testing.T) {
+	payment := models.Payment{ID: uuid.New(), TaxpayerID: uuid.New(), CountyID: 47, Amount: money.MustParse("1000"), Status: "completed"}
+	repo := &creditRepo{allocated: money.MustParse("600"), pending: money.MustParse("400")}
+	// No assessment store: the credit is all asked for, so none is looked up.
+	st := Stores{Payments: repo}
+
+	result, err := autoAllocate(context.Background(), st, payment, AllocateOldestDue, uuid.NullUUID{})
+	require.NoError(t, err)
+	assert.Empty(t, result.Allocations)
+	assert.Equal(t, "400.00", result.Unallocated.String())
+	require.Len(t, repo.entries, 1)
+	assert.Equal(t, "overpayment", repo.entries[0].EntryType)
+}
+
+func TestPlanCreditRefund(t *testing.T) {
+	oldest := models.ListPaymentsWithCreditRow{PaymentID: uuid.New(), Credit: money.MustParse("300")}
+	middle := models.ListPaymentsWithCreditRow{PaymentID: uuid.New(), Credit: money.MustParse("200")}
+	newest := models.ListPaymentsWithCreditRow{PaymentID: uuid.New(), Credit: money.MustParse("500")}
+	credits := []models.ListPaymentsWithCreditRow{oldest, middle, newest}
+
+	tests := []struct {
+		name    string
+		amount  string
+		credits []models.ListPaymentsWithCreditRow // credits when empty
+		want    []creditTake
+	}{
+		{name: "oldest covers it", amount: "120", want: []creditTake{
+			{paymentID: oldest.PaymentID, amount: money.MustParse("120")},
+		}},
+		{name: "oldest used up first", amount: "300", want: []creditTake{
+			{paymentID: oldest.PaymentID, amount: money.MustParse("300")},
+		}},
+		{name: "spills into newer credit", amount: "650", want: []creditTake{
+			{paymentID: oldest.PaymentID, amount: money.MustParse("300")},
+			{paymentID: middle.PaymentID, amount: money.MustParse("200")},
+			{paymentID: newest.PaymentID, amount: money.MustParse("150")},
+		}},
+		{name: "credit asked for already is skipped", amount: "250", credits: []models.ListPaymentsWithCreditRow{
+			{PaymentID: oldest.PaymentID, Credit: money.Zero}, middle, newest,
+		}, want: []creditTake{
+			{paymentID: middle.PaymentID, amount: money.MustParse("200")},
+			{paymentID: newest.PaymentID, amount: money.MustParse("50")},
+		}},
+		{name: "everything", amount: "1000", want: []creditTake{
+			{paymentID: oldest.PaymentID, amount: money.MustParse("300")},
+			{paymentID: middle.PaymentID, amount: money.MustParse("200")},
+			{paymentID: newest.PaymentID, amount: money.MustParse("500")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.credits == nil {
+				tt.credits = credits
+			}
+			takes := planCreditRefund(money.MustParse(tt.amount), tt.credits)
+			require.Len(t, takes, len(tt.want))
+			for i, want := range tt.want {
+				assert.Equal(t, want.paymentID, takes[i].paymentID, i)
+				assert.Equal(t, want.amount.String(), takes[i].amount.String(), i)
+			}
+		})
+	}
+}
+
+func TestCreditStrategy(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured AllocationStrategy
+		requested  string
+		targeted   bool
+		want       AllocationStrategy
+		wantErr    bool
+	}{
+		{name: "configured", configured: AllocatePenaltyFirst, want: AllocatePenaltyFirst},
+		{name: "unconfigured falls back to oldest due", want: AllocateOldestDue},
+		{name: "configured targeted falls back to oldest due", configured: AllocateTargeted, want: AllocateOldestDue},
+		{name: "naming an assessment targets it", configured: AllocatePenaltyFirst, targeted: true, want: AllocateTargeted},
+		{name: "requested wins", configured: AllocateTargeted, requested: "penalty_first", targeted: true, want: AllocatePenaltyFirst},
+		{name: "requested targeted needs an assessment", requested: "targeted", wantErr: true},
+		{name: "unknown strategy", requested: "newest_first", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := creditStrategy(tt.configured, tt.requested, tt.targeted)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"text/template"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/notify"
+	"github.com/sangkips/revenue-system/internal/receipt"
+)
+
+// Issued receipts are sent to the payer by SMS and, when the taxpayer has an
+// email address, by email. The messages are written to the notification
+// outbox in the transaction that issues the receipt, so a receipt that is
+// rolled back is never announced; notify.Dispatcher sends them afterwards and
+// marks the receipt's sms_sent and email_sent once they are delivered.
+
+var (
+	receiptSMS = template.Must(template.New("sms").Parse(
+		`{{.County}}: {{.Amount}} received by {{.Method}} on {{.Date}}. Receipt {{.ReceiptNumber}}.{{with .VerifyURL}} Verify: {{.}}{{end}}`))
+
+	receiptEmailSubject = template.Must(template.New("subject").Parse(
+		`Receipt {{.ReceiptNumber}} from {{.County}}`))
+
+	receiptEmail = template.Must(template.New("email").Parse(`Dear {{or .Name "taxpayer"}},
+
+{{.County}} has received your payment of {{.Amount}} by {{.Method}} on {{.Date}}.
+
+Receipt number: {{.ReceiptNumber}}
+{{with .Reference}}Payment reference: {{.}}
+{{end}}{{with .VerifyURL}}
+You can check this receipt at any time at {{.}}
+{{end}}
+Thank you.
+`))
+)
+
+// receiptMessage is what the receipt templates are filled in with.
+type receiptMessage struct {
+	Name          string
+	County        string
+	ReceiptNumber string
+	Reference     string
+	Amount        string
+	Method        string
+	Date          string
+	VerifyURL     string
+}
+
+// WithReceiptDelivery sends every issued receipt to its payer, trying each
+// message up to maxAttempts times.
+func WithReceiptDelivery(maxAttempts int) Option {
+	return func(s *Service) {
+		s.deliveryAttempts = int32(maxAttempts)
+	}
+}
+
+// enqueueReceiptDelivery queues the SMS and email for a receipt just issued.
+// Recipients are checked by the providers when the messages are sent, so a
+// bad phone number fails its message rather than the receipt.
+func (s *Service) enqueueReceiptDelivery(ctx context.Context, st Stores, payment models.Payment, r models.Receipt) error {
+	if s.deliveryAttempts == 0 {
+		return nil
+	}
+	contact, err := st.Payments.GetPaymentContact(ctx, payment.ID)
+	if err != nil {
+		return err
+	}
+	msg := receiptMessage{
+		Name:          contact.Name,
+		County:        contact.CountyName,
+		ReceiptNumber: r.ReceiptNumber,
+		Reference:     paymentReference(payment),
+		Amount:        payment.Amount.Format(),
+		Method:        receipt.MethodName(payment.PaymentMethod),
+		VerifyURL:     s.verificationURL(r.ID),
+	}
+	switch {
+	case payment.PaymentDate.Valid:
+		msg.Date = payment.PaymentDate.Time.Format("02 Jan 2006")
+	case r.CreatedAt.Valid:
+		msg.Date = r.CreatedAt.Time.Format("02 Jan 2006")
+	}
+
+	enqueue := func(channel, recipient string, subject sql.NullString, body string) error {
+		_, err := st.Payments.EnqueueNotification(ctx, models.EnqueueNotificationParams{
+			CountyID:    payment.CountyID,
+			Channel:     channel,
+			Recipient:   recipient,
+			Subject:     subject,
+			Body:        body,
+			ReceiptID:   uuid.NullUUID{UUID: r.ID, Valid: true},
+			MaxAttempts: s.deliveryAttempts,
+		})
+		return err
+	}
+	if contact.PhoneNumber != "" {
+		body, err := render(receiptSMS, msg)
+		if err != nil {
+			return err
+		}
+		if err := enqueue(notify.SMS, contact.PhoneNumber, sql.NullString{}, body); err != nil {
+			return err
+		}
+	}
+	if contact.Email != "" {
+		subject, err := render(receiptEmailSubject, msg)
+		if err != nil {
+			return err
+		}
+		body, err := render(receiptEmail, msg)
+		if err != nil {
+			return err
+		}
+		if err := enqueue(notify.Email, contact.Email, sql.NullString{String: subject, Valid: true}, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func render(t *template.Template, msg receiptMessage) (string, error) {
+	var b strings.Builder
+	if err := t.Execute(&b, msg); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// ListReceiptNotifications returns the messages sent, or still to be sent,
+// for a receipt of the payment.
+func (s *Service) ListReceiptNotifications(ctx context.Context, paymentID, receiptID string) ([]models.NotificationOutbox, error) {
+	r, err := s.GetReceipt(ctx, receiptID)
+	if err != nil {
+		return nil, err
+	}
+	if r.PaymentID.String() != paymentID {
+		return nil, sql.ErrNoRows
+	}
+	return s.repo.ListReceiptNotifications(ctx, r.ID)
+}
@@ -0,0 +1,59 @@
+package payments
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiptTemplates(t *testing.T) {
+	msg := receiptMessage{
+		Name:          "Wanjiru Kamau",
+		County:        "Nairobi City County",
+		ReceiptNumber: "047/RCT/2025-26/000001",
+		Reference:     "SGH1234567",
+		Amount:        "KES 1,500.00",
+		Method:        "M-Pesa",
+		Date:          "14 Aug 2025",
+		VerifyURL:     "https://revenue.example/verify/tokens/abc",
+	}
+
+	sms, err := render(receiptSMS, msg)
+	require.NoError(t, err)
+	assert.Equal(t, "Nairobi City County: KES 1,500.00 received by M-Pesa on 14 Aug 2025. Receipt 047/RCT/2025-26/000001. Verify: https://revenue.example/verify/tokens/abc", sms)
+
+	subject, err := render(receiptEmailSubject, msg)
+	require.NoError(t, err)
+	assert.Equal(t, "Receipt 047/RCT/2025-26/000001 from Nairobi City County", subject)
+
+	email, err := render(receiptEmail, msg)
+	require.NoError(t, err)
+	assert.Equal(t, `Dear Wanjiru Kamau,
+
+Nairobi City County has received your payment of KES 1,500.00 by M-Pesa on 14 Aug 2025.
+
+Receipt number: 047/RCT/2025-26/000001
+Payment reference: SGH1234567
+
+You can check this receipt at any time at https://revenue.example/verify/tokens/abc
+
+Thank you.
+`, email)
+
+	// Without a reference, name or verification link those lines are left out.
+	msg.Name, msg.Reference, msg.VerifyURL = "", "", ""
+	sms, err = render(receiptSMS, msg)
+	require.NoError(t, err)
+	assert.NotContains(t, sms, "Verify")
+	email, err = render(receiptEmail, msg)
+	require.NoError(t, err)
+	assert.Equal(t, `Dear taxpayer,
+
+Nairobi City County has received your payment of KES 1,500.00 by M-Pesa on 14 Aug 2025.
+
+Receipt number: 047/RCT/2025-26/000001
+
+Thank you.
+`, email)
+}
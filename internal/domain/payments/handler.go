@@ -1,47 +1,73 @@
 package payments
 
 import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
+	"github.com/sangkips/revenue-system/internal/db"
 	"github.com/sangkips/revenue-system/internal/domain/payments/models"
 	"github.com/sangkips/revenue-system/internal/middleware/auth"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/sangkips/revenue-system/internal/mpesa"
 )
 
 type Handler struct {
 	svc *Service
 }
 
-func NewHandler(db models.DBTX) *Handler {
-	repo := NewRepository(db)
-	return &Handler{svc: NewService(repo)}
+func NewHandler(conn *sql.DB, opts ...Option) *Handler {
+	repo := NewRepository(conn)
+	uow := db.NewUnitOfWork(db.NewTxManager(conn), NewStores)
+	opts = append([]Option{WithUnitOfWork(uow)}, opts...)
+	return &Handler{svc: NewService(repo, opts...)}
 }
 
 func (h *Handler) RegisterPaymentsRoutes(r chi.Router) {
-	r.Post("/", h.CreatePayment)
-	r.Get("/{id}", h.GetPayment)
-	r.Get("/", h.ListPayments)
-	r.Get("/revenue/{revenue_id}", h.ListPaymentsByRevenueID)
-	r.Patch("/{id}", h.UpdatePayment)
-	r.Delete("/{id}", h.DeletePayment)
+	r.With(auth.RequirePermission(auth.PermPaymentsCollect)).Post("/", h.CreatePayment)
+	r.With(auth.RequirePermission(auth.PermPaymentsCollect)).Post("/collect", h.CollectPayment)
+	r.With(auth.RequirePermission(auth.PermPaymentsCollect)).Post("/mpesa/stk-push", h.InitiateSTKPush)
+	r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/{id}", h.GetPayment)
+	r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/{id}/history", h.GetPaymentHistory)
+	r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/", h.ListPayments)
+	r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/revenue/{revenue_id}", h.ListPaymentsByRevenueID)
+	r.With(auth.RequirePermission(auth.PermPaymentsManage)).Patch("/{id}", h.UpdatePayment)
+	r.With(auth.RequirePermission(auth.PermPaymentsManage)).Delete("/{id}", h.DeletePayment)
 
 	// Payment Allocations sub-routes
 	r.Route("/{id}/allocations", func(r chi.Router) {
-		r.Post("/", h.CreatePaymentAllocation)
-		r.Get("/", h.ListPaymentAllocations)
-		r.Delete("/{allocation_id}", h.DeletePaymentAllocation)
+		r.With(auth.RequirePermission(auth.PermPaymentsCollect)).Post("/", h.CreatePaymentAllocation)
+		r.With(auth.RequirePermission(auth.PermPaymentsCollect)).Post("/auto", h.AllocatePayment)
+		r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/", h.ListPaymentAllocations)
+		r.With(auth.RequirePermission(auth.PermPaymentsManage)).Delete("/{allocation_id}", h.DeletePaymentAllocation)
+	})
+
+	// Refund requests against a payment; review happens under /refunds
+	r.Route("/{id}/refunds", func(r chi.Router) {
+		r.With(auth.RequirePermission(auth.PermRefundsRequest)).Post("/", h.RequestRefund)
+		r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/", h.ListPaymentRefunds)
 	})
 
 	// Receipts sub-routes
 	r.Route("/{id}/receipts", func(r chi.Router) {
-		r.Post("/", h.CreateReceipt)
-		r.Get("/{receipt_id}", h.GetReceipt)
-		r.Get("/", h.ListReceiptsByPayment)
-		r.Patch("/{receipt_id}", h.UpdateReceipt)
-		r.Delete("/{receipt_id}", h.DeleteReceipt)
+		r.With(auth.RequirePermission(auth.PermPaymentsCollect)).Post("/", h.CreateReceipt)
+		r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/{receipt_id}", h.GetReceipt)
+		r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/{receipt_id}/pdf", h.GetReceiptPDF)
+		r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/{receipt_id}/notifications", h.ListReceiptNotifications)
+		r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/", h.ListReceiptsByPayment)
+		r.With(auth.RequirePermission(auth.PermPaymentsManage)).Patch("/{receipt_id}", h.UpdateReceipt)
+		r.With(auth.RequirePermission(auth.PermPaymentsManage)).Delete("/{receipt_id}", h.DeleteReceipt)
 	})
 }
 
@@ -62,8 +88,12 @@ func (h *Handler) CreatePayment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	payment, err := h.svc.CreatePayment(ctx, req, userID)
+	if db.IsUniqueViolation(err) {
+		http.Error(w, "a payment with this payment_number already exists", http.StatusConflict)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), sessionErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -72,6 +102,101 @@ func (h *Handler) CreatePayment(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(payment)
 }
 
+// CollectPayment records a completed payment together with its allocations and
+// optional receipt in one transaction.
+func (h *Handler) CollectPayment(w http.ResponseWriter, r *http.Request) {
+	var req CollectPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "user not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := h.svc.CollectPayment(r.Context(), req, userID)
+	if db.IsUniqueViolation(err) {
+		http.Error(w, "a payment with this payment_number already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("payment_number", req.PaymentNumber).Msg("Failed to collect payment")
+		http.Error(w, err.Error(), ledgerErrorStatus(err, sessionErrorStatus(err, allocationErrorStatus(err, http.StatusBadRequest))))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// InitiateSTKPush sends an M-Pesa payment prompt to the payer's phone. The
+// payment is returned as processing; its final status arrives via the callback.
+func (h *Handler) InitiateSTKPush(w http.ResponseWriter, r *http.Request) {
+	var req STKPushPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "user not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	payment, err := h.svc.InitiateSTKPush(r.Context(), req, userID)
+	if err != nil {
+		var apiErr *mpesa.APIError
+		switch {
+		case errors.Is(err, ErrMpesaDisabled):
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		case errors.As(err, &apiErr):
+			log.Error().Err(err).Str("assessment_id", req.AssessmentID).Msg("STK push rejected")
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		default:
+			http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(payment)
+}
+
+// RegisterMpesaCallbackRoutes mounts the public endpoint Daraja posts STK
+// results to. Daraja cannot authenticate, so the URL carries a shared secret.
+func (h *Handler) RegisterMpesaCallbackRoutes(r chi.Router, token string) {
+	r.Post("/callback/{token}", func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(chi.URLParam(r, "token")), []byte(token)) != 1 {
+			http.NotFound(w, r)
+			return
+		}
+		h.MpesaCallback(w, r)
+	})
+}
+
+func (h *Handler) MpesaCallback(w http.ResponseWriter, r *http.Request) {
+	cb, err := mpesa.ParseCallback(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.svc.HandleSTKCallback(r.Context(), cb); err != nil {
+		log.Error().Err(err).Str("checkout_request_id", cb.CheckoutRequestID).Msg("Failed to apply STK callback")
+		http.Error(w, "callback not applied", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"ResultCode": 0, "ResultDesc": "Accepted"})
+}
+
 func (h *Handler) GetPayment(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
@@ -82,7 +207,7 @@ func (h *Handler) GetPayment(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	payment, err := h.svc.GetPayment(ctx, id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -105,7 +230,7 @@ func (h *Handler) ListPayments(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	payments, err := h.svc.ListPayments(ctx, int32(countyID), int32(limit), int32(offset))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 
@@ -119,7 +244,7 @@ func (h *Handler) ListPaymentsByRevenueID(w http.ResponseWriter, r *http.Request
 	ctx := r.Context()
 	payments, err := h.svc.ListPaymentsByRevenueID(ctx, revenueID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 
@@ -151,7 +276,7 @@ func (h *Handler) UpdatePayment(w http.ResponseWriter, r *http.Request) {
 
 	payment, err := h.svc.UpdatePayment(ctx, id, req, userID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), lifecycleErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -170,12 +295,39 @@ func (h *Handler) DeletePayment(w http.ResponseWriter, r *http.Request) {
 	
 	ctx := r.Context()
 	if err := h.svc.DeletePayment(ctx, id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), lifecycleErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// lifecycleErrorStatus maps changes the payment's status does not allow.
+func lifecycleErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, ErrIllegalTransition), errors.Is(err, ErrPaymentFrozen), errors.Is(err, ErrRefundThroughRequest):
+		return http.StatusConflict
+	case errors.Is(err, ErrReconcileThroughStatements):
+		return http.StatusBadRequest
+	}
+	return auth.ErrorStatus(err, fallback)
+}
+
+// GetPaymentHistory lists every status the payment has been in, with who
+// changed it and why.
+func (h *Handler) GetPaymentHistory(w http.ResponseWriter, r *http.Request) {
+	history, err := h.svc.GetPaymentHistory(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), lifecycleErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
+
 
 // Payment Allocations Handlers
 func (h *Handler) CreatePaymentAllocation(w http.ResponseWriter, r *http.Request) {
@@ -190,7 +342,7 @@ func (h *Handler) CreatePaymentAllocation(w http.ResponseWriter, r *http.Request
 	allocation, err := h.svc.CreatePaymentAllocation(ctx, req)
 	if err != nil {
 		log.Error().Err(err).Str("payment_id", paymentID).Msg("Failed to create allocation")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), allocationErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -198,6 +350,47 @@ func (h *Handler) CreatePaymentAllocation(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(allocation)
 }
 
+type AllocatePaymentRequest struct {
+	Strategy string `json:"strategy,omitempty"` // oldest_due, penalty_first or targeted; defaults to the configured strategy
+}
+
+// AllocatePayment spreads the unallocated part of a completed payment over
+// the payer's open assessments.
+func (h *Handler) AllocatePayment(w http.ResponseWriter, r *http.Request) {
+	paymentID := chi.URLParam(r, "id")
+	var req AllocatePaymentRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := h.svc.AllocatePayment(r.Context(), paymentID, req.Strategy)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "payment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("payment_id", paymentID).Msg("Failed to allocate payment")
+		http.Error(w, err.Error(), allocationErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// allocationErrorStatus reports allocations that would break an invariant as
+// conflicts with the current state rather than malformed requests.
+func allocationErrorStatus(err error, fallback int) int {
+	if errors.Is(err, ErrOverAllocated) || errors.Is(err, ErrAssessmentClosed) {
+		return http.StatusConflict
+	}
+	return auth.ErrorStatus(err, fallback)
+}
+
 func (h *Handler) ListPaymentAllocations(w http.ResponseWriter, r *http.Request) {
 	paymentID := chi.URLParam(r, "id")
 	log.Info().Str("payment_id", paymentID).Msg("Listing payment allocations")
@@ -211,7 +404,7 @@ func (h *Handler) ListPaymentAllocations(w http.ResponseWriter, r *http.Request)
 	allocations, err := h.svc.ListPaymentAllocations(ctx, paymentID)
 	if err != nil {
 		log.Error().Err(err).Str("payment_id", paymentID).Msg("Failed to list payment allocations")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	
@@ -227,7 +420,7 @@ func (h *Handler) DeletePaymentAllocation(w http.ResponseWriter, r *http.Request
 	allocationID := chi.URLParam(r, "allocation_id")
 	ctx := r.Context()
 	if err := h.svc.DeletePaymentAllocation(ctx, allocationID, paymentID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -243,12 +436,19 @@ func (h *Handler) CreateReceipt(w http.ResponseWriter, r *http.Request) {
 	}
 	req.PaymentID = paymentID
 	ctx := r.Context()
-	if err := h.svc.CreateReceipt(ctx, req); err != nil {
+	receipt, err := h.svc.CreateReceipt(ctx, req)
+	if err != nil {
 		log.Error().Err(err).Str("payment_id", paymentID).Msg("Failed to create receipt")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if db.IsUniqueViolation(err) {
+			http.Error(w, "a receipt with this receipt_number already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), ledgerErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(receipt)
 }
 
 func (h *Handler) GetReceipt(w http.ResponseWriter, r *http.Request) {
@@ -256,18 +456,75 @@ func (h *Handler) GetReceipt(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	receipt, err := h.svc.GetReceipt(ctx, receiptID)
 	if err != nil {
-		http.Error(w, "Not found", http.StatusNotFound)
+		http.Error(w, "Not found", auth.ErrorStatus(err, http.StatusNotFound))
 		return
 	}
 	json.NewEncoder(w).Encode(receipt)
 }
 
+// GetReceiptPDF downloads the printable receipt.
+func (h *Handler) GetReceiptPDF(w http.ResponseWriter, r *http.Request) {
+	pdf, receipt, err := h.svc.ReceiptPDF(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "receipt_id"))
+	if err != nil {
+		http.Error(w, err.Error(), receiptPDFErrorStatus(err))
+		return
+	}
+	defer pdf.Close()
+
+	filename := strings.ReplaceAll(receipt.ReceiptNumber, "/", "-") + ".pdf"
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	if receipt.PdfFileSize.Valid {
+		w.Header().Set("Content-Length", strconv.Itoa(int(receipt.PdfFileSize.Int32)))
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, pdf); err != nil {
+		log.Error().Err(err).Str("receipt_id", receipt.ID.String()).Msg("Failed to send receipt PDF")
+	}
+}
+
+// ListReceiptNotifications shows whether a receipt's SMS and email went out.
+func (h *Handler) ListReceiptNotifications(w http.ResponseWriter, r *http.Request) {
+	messages, err := h.svc.ListReceiptNotifications(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "receipt_id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(messages)
+}
+
+func receiptPDFErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, ErrReceiptNotReady):
+		return http.StatusConflict
+	case errors.Is(err, ErrReceiptPDFsDisabled):
+		return http.StatusNotImplemented
+	}
+	return auth.ErrorStatus(err, http.StatusInternalServerError)
+}
+
+// ledgerErrorStatus maps the errors of issuing and changing ledgered
+// receipts, falling back to fallback for anything else.
+func ledgerErrorStatus(err error, fallback int) int {
+	if errors.Is(err, ErrPaymentNotCompleted) || errors.Is(err, ErrReceiptLedgered) {
+		return http.StatusConflict
+	}
+	return auth.ErrorStatus(err, fallback)
+}
+
 func (h *Handler) ListReceiptsByPayment(w http.ResponseWriter, r *http.Request) {
 	paymentID := chi.URLParam(r, "id")
 	ctx := r.Context()
 	receipts, err := h.svc.ListReceiptsByPayment(ctx, paymentID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	json.NewEncoder(w).Encode(receipts)
@@ -282,7 +539,7 @@ func (h *Handler) UpdateReceipt(w http.ResponseWriter, r *http.Request) {
 	}
 	ctx := r.Context()
 	if err := h.svc.UpdateReceipt(ctx, receiptID, req); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), ledgerErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -292,8 +549,739 @@ func (h *Handler) DeleteReceipt(w http.ResponseWriter, r *http.Request) {
 	receiptID := chi.URLParam(r, "receipt_id")
 	ctx := r.Context()
 	if err := h.svc.DeleteReceipt(ctx, receiptID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), ledgerErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// RegisterC2BRoutes mounts the public paybill validation and confirmation
+// endpoints behind guard, which checks the caller's address and signature.
+func (h *Handler) RegisterC2BRoutes(r chi.Router, guard func(http.Handler) http.Handler) {
+	r.Use(guard)
+	r.Post("/validation", h.C2BValidation)
+	r.Post("/confirmation", h.C2BConfirmation)
+}
+
+func (h *Handler) C2BValidation(w http.ResponseWriter, r *http.Request) {
+	resp := mpesa.C2BResponse{ResultCode: mpesa.C2BAccepted, ResultDesc: "Accepted"}
+
+	p, err := mpesa.ParseC2B(r.Body)
+	if err == nil {
+		err = h.svc.ValidateC2B(r.Context(), p)
+	}
+	if err != nil {
+		var rejection *C2BRejection
+		if errors.As(err, &rejection) {
+			resp = mpesa.C2BResponse{ResultCode: rejection.Code, ResultDesc: "Rejected: " + rejection.Reason}
+		} else {
+			log.Error().Err(err).Str("trans_id", p.TransID).Msg("Failed to validate C2B payment")
+			resp = mpesa.C2BResponse{ResultCode: mpesa.C2BOtherError, ResultDesc: "Rejected"}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) C2BConfirmation(w http.ResponseWriter, r *http.Request) {
+	p, err := mpesa.ParseC2B(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payment, err := h.svc.ConfirmC2B(r.Context(), p)
+	switch {
+	case errors.Is(err, ErrUnknownAccount):
+		log.Warn().Str("trans_id", p.TransID).Str("bill_ref_number", p.BillRefNumber).Msg("C2B payment for unknown account held in suspense")
+	case err != nil:
+		log.Error().Err(err).Str("trans_id", p.TransID).Msg("Failed to record C2B payment")
+		http.Error(w, "confirmation not applied", http.StatusInternalServerError)
+		return
+	default:
+		log.Info().Str("trans_id", p.TransID).Str("payment_id", payment.ID.String()).Msg("Recorded C2B payment")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mpesa.C2BResponse{ResultCode: mpesa.C2BAccepted, ResultDesc: "Success"})
+}
+
+// maxStatementSize bounds an uploaded bank statement; a month of county
+// collections fits comfortably.
+const maxStatementSize = 10 << 20
+
+// MaxStatementRequestSize bounds a statement import request: the file plus
+// room for the other form fields.
+const MaxStatementRequestSize = maxStatementSize + 1<<20
+
+// RegisterReconciliationRoutes mounts bank statement import, the queue of
+// statement lines awaiting manual resolution and the M-Pesa suspense queue.
+func (h *Handler) RegisterReconciliationRoutes(r chi.Router) {
+	r.With(auth.RequirePermission(auth.PermPaymentsReconcile)).Post("/statements", h.ImportStatement)
+	r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/statements", h.ListBankStatements)
+	r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/statements/{id}", h.GetBankStatement)
+	r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/unmatched", h.ListUnmatchedStatementLines)
+	r.With(auth.RequirePermission(auth.PermPaymentsReconcile)).Post("/lines/{id}/match", h.MatchStatementLine)
+	r.With(auth.RequirePermission(auth.PermPaymentsReconcile)).Post("/lines/{id}/ignore", h.IgnoreStatementLine)
+	r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/mpesa-suspense", h.ListMpesaSuspense)
+	r.With(auth.RequirePermission(auth.PermPaymentsReconcile)).Post("/mpesa-suspense/{id}/match", h.MatchMpesaSuspense)
+	r.With(auth.RequirePermission(auth.PermPaymentsReconcile)).Post("/mpesa-suspense/{id}/ignore", h.IgnoreMpesaSuspense)
+}
+
+// ImportStatement takes a multipart upload with the statement in "file" and
+// optional "format", "county_id", "amount_tolerance" and "date_window_days"
+// fields overriding the configured matching tolerance.
+func (h *Handler) ImportStatement(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxStatementRequestSize)
+	if err := r.ParseMultipartForm(maxStatementSize); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "statement file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := ImportStatementRequest{
+		Format:   r.FormValue("format"),
+		FileName: header.Filename,
+		Data:     data,
+	}
+	if v := r.FormValue("county_id"); v != "" {
+		countyID, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			http.Error(w, "invalid county_id", http.StatusBadRequest)
+			return
+		}
+		req.CountyID = int32(countyID)
+	}
+	if v := r.FormValue("amount_tolerance"); v != "" {
+		tolerance, err := money.Parse(v)
+		if err != nil {
+			http.Error(w, "invalid amount_tolerance: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.AmountTolerance = &tolerance
+	}
+	if v := r.FormValue("date_window_days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "invalid date_window_days", http.StatusBadRequest)
+			return
+		}
+		req.DateWindowDays = &days
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "user not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := h.svc.ImportStatement(r.Context(), req, userID)
+	if errors.Is(err, ErrStatementImported) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("file_name", req.FileName).Msg("Failed to import bank statement")
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *Handler) ListBankStatements(w http.ResponseWriter, r *http.Request) {
+	countyID, _ := strconv.ParseInt(r.URL.Query().Get("county_id"), 10, 32)
+	limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 32)
+	offset, _ := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 32)
+	if limit == 0 {
+		limit = 10
+	}
+
+	statements, err := h.svc.ListBankStatements(r.Context(), int32(countyID), int32(limit), int32(offset))
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(statements)
+}
+
+func (h *Handler) GetBankStatement(w http.ResponseWriter, r *http.Request) {
+	statement, err := h.svc.GetBankStatement(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "statement not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(statement)
+}
+
+func (h *Handler) ListUnmatchedStatementLines(w http.ResponseWriter, r *http.Request) {
+	countyID, _ := strconv.ParseInt(r.URL.Query().Get("county_id"), 10, 32)
+	limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 32)
+	offset, _ := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 32)
+	if limit == 0 {
+		limit = 50
+	}
+
+	lines, err := h.svc.ListUnmatchedStatementLines(r.Context(), int32(countyID), int32(limit), int32(offset))
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(lines)
+}
+
+func (h *Handler) ListMpesaSuspense(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 32)
+	offset, _ := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 32)
+	if limit == 0 {
+		limit = 50
+	}
+
+	entries, err := h.svc.ListMpesaSuspense(r.Context(), int32(limit), int32(offset))
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+func (h *Handler) MatchStatementLine(w http.ResponseWriter, r *http.Request) {
+	var req MatchStatementLineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.resolveQueueEntry(w, r, "statement line", func(userID string) (any, error) {
+		return h.svc.MatchStatementLine(r.Context(), chi.URLParam(r, "id"), req, userID)
+	})
+}
+
+func (h *Handler) IgnoreStatementLine(w http.ResponseWriter, r *http.Request) {
+	var req IgnoreStatementLineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.resolveQueueEntry(w, r, "statement line", func(userID string) (any, error) {
+		return h.svc.IgnoreStatementLine(r.Context(), chi.URLParam(r, "id"), req, userID)
+	})
+}
+
+func (h *Handler) MatchMpesaSuspense(w http.ResponseWriter, r *http.Request) {
+	var req MatchMpesaSuspenseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.resolveQueueEntry(w, r, "M-Pesa suspense entry", func(userID string) (any, error) {
+		return h.svc.MatchMpesaSuspense(r.Context(), chi.URLParam(r, "id"), req, userID)
+	})
+}
+
+func (h *Handler) IgnoreMpesaSuspense(w http.ResponseWriter, r *http.Request) {
+	var req IgnoreMpesaSuspenseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.resolveQueueEntry(w, r, "M-Pesa suspense entry", func(userID string) (any, error) {
+		return h.svc.IgnoreMpesaSuspense(r.Context(), chi.URLParam(r, "id"), req, userID)
+	})
+}
+
+// resolveQueueEntry runs resolve for the caller against an entry of one of the
+// reconciliation queues; what names the kind of entry for a 404.
+func (h *Handler) resolveQueueEntry(w http.ResponseWriter, r *http.Request, what string, resolve func(userID string) (any, error)) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "user not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	entry, err := resolve(userID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, what+" not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrLineResolved), errors.Is(err, ErrSuspenseResolved):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// RegisterTaxpayerBalanceRoutes mounts a taxpayer's credit balance under the
+// taxpayers router.
+func (h *Handler) RegisterTaxpayerBalanceRoutes(r chi.Router) {
+	r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/{id}/balance", h.GetTaxpayerBalance)
+	r.With(auth.RequirePermission(auth.PermPaymentsCollect)).Post("/{id}/balance/apply", h.ApplyCredit)
+	r.With(auth.RequirePermission(auth.PermRefundsRequest)).Post("/{id}/balance/refunds", h.RefundCredit)
+}
+
+func (h *Handler) GetTaxpayerBalance(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 32)
+	offset, _ := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 32)
+	if limit == 0 {
+		limit = 50
+	}
+
+	balance, err := h.svc.GetTaxpayerBalance(r.Context(), chi.URLParam(r, "id"), int32(limit), int32(offset))
+	if errors.Is(err, ErrTaxpayerNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(balance)
+}
+
+// ApplyCredit allocates the taxpayer's credit to their open assessments.
+func (h *Handler) ApplyCredit(w http.ResponseWriter, r *http.Request) {
+	var req ApplyCreditRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := h.svc.ApplyCredit(r.Context(), chi.URLParam(r, "id"), req)
+	switch {
+	case errors.Is(err, ErrTaxpayerNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, ErrNoCredit):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), allocationErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// RefundCredit requests credit be paid back to the taxpayer. The refunds it
+// opens are approved through the refund queue.
+func (h *Handler) RefundCredit(w http.ResponseWriter, r *http.Request) {
+	var req RefundCreditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "user not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	refunds, err := h.svc.RefundCredit(r.Context(), chi.URLParam(r, "id"), req, userID)
+	switch {
+	case errors.Is(err, ErrTaxpayerNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, ErrNoCredit), errors.Is(err, ErrInsufficientCredit):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(refunds)
+}
+
+// RegisterRefundRoutes mounts the review side of refunds: the approval queue
+// and the approve and reject actions.
+func (h *Handler) RegisterRefundRoutes(r chi.Router) {
+	r.With(auth.RequirePermission(auth.PermRefundsApprove)).Get("/pending", h.ListPendingRefunds)
+	r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/{id}", h.GetRefund)
+	r.With(auth.RequirePermission(auth.PermRefundsApprove)).Post("/{id}/approve", h.ApproveRefund)
+	r.With(auth.RequirePermission(auth.PermRefundsApprove)).Post("/{id}/reject", h.RejectRefund)
+}
+
+// refundErrorStatus maps refund workflow errors to their HTTP status.
+func refundErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, ErrSelfApproval):
+		return http.StatusForbidden
+	case errors.Is(err, ErrPaymentNotRefundable), errors.Is(err, ErrRefundExceedsPayment), errors.Is(err, ErrRefundReviewed):
+		return http.StatusConflict
+	}
+	return auth.ErrorStatus(err, fallback)
+}
+
+func (h *Handler) RequestRefund(w http.ResponseWriter, r *http.Request) {
+	var req RequestRefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "user not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	refund, err := h.svc.RequestRefund(r.Context(), chi.URLParam(r, "id"), req, userID)
+	if err != nil {
+		http.Error(w, err.Error(), refundErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(refund)
+}
+
+func (h *Handler) ListPaymentRefunds(w http.ResponseWriter, r *http.Request) {
+	refunds, err := h.svc.ListPaymentRefunds(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), refundErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(refunds)
+}
+
+func (h *Handler) ListPendingRefunds(w http.ResponseWriter, r *http.Request) {
+	countyID, _ := strconv.ParseInt(r.URL.Query().Get("county_id"), 10, 32)
+	limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 32)
+	offset, _ := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 32)
+	if limit == 0 {
+		limit = 50
+	}
+
+	refunds, err := h.svc.ListPendingRefunds(r.Context(), int32(countyID), int32(limit), int32(offset))
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(refunds)
+}
+
+func (h *Handler) GetRefund(w http.ResponseWriter, r *http.Request) {
+	refund, err := h.svc.GetRefund(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), refundErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(refund)
+}
+
+func (h *Handler) ApproveRefund(w http.ResponseWriter, r *http.Request) {
+	h.reviewRefund(w, r, func(req ReviewRefundRequest, userID string) (any, error) {
+		return h.svc.ApproveRefund(r.Context(), chi.URLParam(r, "id"), req, userID)
+	})
+}
+
+func (h *Handler) RejectRefund(w http.ResponseWriter, r *http.Request) {
+	h.reviewRefund(w, r, func(req ReviewRefundRequest, userID string) (any, error) {
+		return h.svc.RejectRefund(r.Context(), chi.URLParam(r, "id"), req, userID)
+	})
+}
+
+func (h *Handler) reviewRefund(w http.ResponseWriter, r *http.Request, review func(req ReviewRefundRequest, userID string) (any, error)) {
+	var req ReviewRefundRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "user not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := review(req, userID)
+	if err != nil {
+		http.Error(w, err.Error(), refundErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *Handler) RegisterCollectorSessionRoutes(r chi.Router) {
+	r.With(auth.RequirePermission(auth.PermPaymentsCollect)).Post("/", h.OpenSession)
+	r.With(auth.RequirePermission(auth.PermPaymentsCollect)).Get("/current", h.CurrentSession)
+	r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/", h.ListSessions)
+	r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/z-reports", h.ListZReports)
+	r.With(auth.RequirePermission(auth.PermPaymentsRead)).Get("/{id}", h.GetZReport)
+	r.With(auth.RequirePermission(auth.PermPaymentsCollect)).Post("/{id}/close", h.CloseSession)
+	r.With(auth.RequirePermission(auth.PermSessionsApprove)).Post("/{id}/approve", h.ApproveSession)
+	r.With(auth.RequirePermission(auth.PermSessionsApprove)).Post("/{id}/reject", h.RejectSession)
+}
+
+// sessionErrorStatus maps collector session errors to their HTTP status.
+func sessionErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, ErrTaxpayerNotFound), errors.Is(err, ErrAssessmentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrNotSessionCollector), errors.Is(err, ErrSessionSelfApproval):
+		return http.StatusForbidden
+	case errors.Is(err, ErrNoOpenSession), errors.Is(err, ErrSessionAlreadyOpen), errors.Is(err, ErrSessionNotOpen), errors.Is(err, ErrSessionReviewed):
+		return http.StatusConflict
+	}
+	return auth.ErrorStatus(err, fallback)
+}
+
+func (h *Handler) OpenSession(w http.ResponseWriter, r *http.Request) {
+	var req OpenSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "user not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	session, err := h.svc.OpenSession(r.Context(), req, userID)
+	if err != nil {
+		http.Error(w, err.Error(), sessionErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+// CurrentSession returns the caller's open session with its takings so far.
+func (h *Handler) CurrentSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "user not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	report, err := h.svc.CurrentSession(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), sessionErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	countyID, _ := strconv.ParseInt(r.URL.Query().Get("county_id"), 10, 32)
+	limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 32)
+	offset, _ := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 32)
+	if limit == 0 {
+		limit = 50
+	}
+
+	sessions, err := h.svc.ListSessions(r.Context(), int32(countyID), r.URL.Query().Get("status"), int32(limit), int32(offset))
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// ListZReports returns the end-of-day totals per collector for ?date=
+// (YYYY-MM-DD, today by default).
+func (h *Handler) ListZReports(w http.ResponseWriter, r *http.Request) {
+	countyID, _ := strconv.ParseInt(r.URL.Query().Get("county_id"), 10, 32)
+	day := time.Now()
+	if date := r.URL.Query().Get("date"); date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			http.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		day = parsed
+	}
+
+	reports, err := h.svc.ListZReports(r.Context(), int32(countyID), day)
+	if err != nil {
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reports)
+}
+
+func (h *Handler) GetZReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.svc.GetZReport(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), sessionErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *Handler) CloseSession(w http.ResponseWriter, r *http.Request) {
+	var req CloseSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "user not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	report, err := h.svc.CloseSession(r.Context(), chi.URLParam(r, "id"), req, userID)
+	if err != nil {
+		http.Error(w, err.Error(), sessionErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *Handler) ApproveSession(w http.ResponseWriter, r *http.Request) {
+	h.reviewSession(w, r, h.svc.ApproveSession)
+}
+
+func (h *Handler) RejectSession(w http.ResponseWriter, r *http.Request) {
+	h.reviewSession(w, r, h.svc.RejectSession)
+}
+
+func (h *Handler) reviewSession(w http.ResponseWriter, r *http.Request, review func(ctx context.Context, id string, req ReviewSessionRequest, userID string) (models.CollectorSession, error)) {
+	var req ReviewSessionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(string)
+	if !ok {
+		http.Error(w, "user not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	session, err := review(r.Context(), chi.URLParam(r, "id"), req, userID)
+	if err != nil {
+		http.Error(w, err.Error(), sessionErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(session)
+}
+
+// RegisterVerificationRoutes mounts the public receipt checks. They need no
+// login, so the caller should rate limit them. Receipt numbers contain
+// slashes and are matched by the rest of the path.
+func (h *Handler) RegisterVerificationRoutes(r chi.Router) {
+	r.Get("/receipts/*", h.VerifyReceipt)
+	r.Get("/tokens/{token}", h.VerifyReceiptToken)
+}
+
+func (h *Handler) VerifyReceipt(w http.ResponseWriter, r *http.Request) {
+	number, err := url.PathUnescape(chi.URLParam(r, "*"))
+	if err != nil {
+		http.Error(w, "invalid receipt number", http.StatusBadRequest)
+		return
+	}
+	verification, err := h.svc.VerifyReceipt(r.Context(), number)
+	writeVerification(w, verification, err)
+}
+
+func (h *Handler) VerifyReceiptToken(w http.ResponseWriter, r *http.Request) {
+	verification, err := h.svc.VerifyReceiptToken(r.Context(), chi.URLParam(r, "token"))
+	writeVerification(w, verification, err)
+}
+
+func writeVerification(w http.ResponseWriter, verification ReceiptVerification, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "no receipt with this number was issued", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrInvalidReceiptToken):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		log.Error().Err(err).Msg("Failed to verify receipt")
+		http.Error(w, "receipt verification is unavailable", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(verification)
+}
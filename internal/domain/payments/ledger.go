@@ -0,0 +1,618 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/sangkips/revenue-system/internal/db"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/ledger"
+)
+
+// Every receipt is recorded in its county's receipt ledger when it is issued
+// and again when it is voided. receipts.blockchain_hash is the hash of the
+// entry that issued the receipt and payments.blockchain_hash that of the
+// payment's first receipt. Sealing groups entries into blocks and fills in
+// block_number and blockchain_verified. VerifyLedger recomputes the chain
+// from the receipts as they are now, so any edited or deleted receipt, entry
+// or block is reported.
+
+var (
+	ErrPaymentNotCompleted = errors.New("receipts are issued once the payment completes")
+	ErrReceiptLedgered     = errors.New("the receipt is recorded in the receipt ledger")
+	ErrLedgerTampered      = errors.New("the receipt ledger failed verification")
+)
+
+const (
+	// maxBlockEntries caps the entries sealed into one block.
+	maxBlockEntries = 1000
+	// ledgerPageSize is how many entries verification reads at a time.
+	ledgerPageSize = 1000
+)
+
+// NewLedgerService returns a Service for the ledger jobs that run outside a
+// request: sealing, verification and backfill.
+func NewLedgerService(conn *sql.DB) *Service {
+	uow := db.NewUnitOfWork(db.NewTxManager(conn), NewStores)
+	return NewService(NewRepository(conn), WithUnitOfWork(uow))
+}
+
+// paymentReference is the reference the money came in under.
+func paymentReference(p models.Payment) string {
+	for _, ref := range []sql.NullString{p.MpesaReceiptNumber, p.BankReference, p.ChequeNumber, p.ExternalTransactionID} {
+		if ref.Valid && ref.String != "" {
+			return ref.String
+		}
+	}
+	return ""
+}
+
+func issuedRecord(receiptID uuid.UUID, number string, receiptType sql.NullString, payment models.Payment) ledger.Record {
+	return ledger.Record{
+		Event:         ledger.Issued,
+		ReceiptID:     receiptID.String(),
+		ReceiptNumber: number,
+		ReceiptType:   receiptType.String,
+		PaymentID:     payment.ID.String(),
+		PaymentNumber: payment.PaymentNumber,
+		CountyID:      payment.CountyID,
+		TaxpayerID:    payment.TaxpayerID.String(),
+		Amount:        payment.Amount.String(),
+		PaymentMethod: payment.PaymentMethod,
+		Reference:     paymentReference(payment),
+	}
+}
+
+func voidedRecord(receipt models.Receipt, countyID int32) ledger.Record {
+	record := ledger.Record{
+		Event:         ledger.Voided,
+		ReceiptID:     receipt.ID.String(),
+		ReceiptNumber: receipt.ReceiptNumber,
+		PaymentID:     receipt.PaymentID.String(),
+		CountyID:      countyID,
+		VoidReason:    receipt.VoidReason.String,
+	}
+	if receipt.VoidedBy.Valid {
+		record.VoidedBy = receipt.VoidedBy.UUID.String()
+	}
+	return record
+}
+
+// appendLedger adds an entry for record to the end of a county's chain. The
+// head row stays locked until the caller's transaction ends, so entries are
+// appended one at a time.
+func appendLedger(ctx context.Context, st Stores, countyID int32, receiptID uuid.UUID, record ledger.Record) (models.ReceiptLedgerEntry, error) {
+	head, err := st.Payments.LockReceiptLedger(ctx, countyID)
+	if err != nil {
+		return models.ReceiptLedgerEntry{}, err
+	}
+	sequence := head.LastSequence + 1
+	recordHash := record.Hash()
+	entry, err := st.Payments.InsertReceiptLedgerEntry(ctx, models.InsertReceiptLedgerEntryParams{
+		CountyID:     countyID,
+		Sequence:     sequence,
+		ReceiptID:    receiptID,
+		Event:        record.Event,
+		RecordHash:   recordHash,
+		PreviousHash: head.LastEntryHash,
+		EntryHash:    ledger.EntryHash(countyID, sequence, head.LastEntryHash, recordHash),
+	})
+	if err != nil {
+		return models.ReceiptLedgerEntry{}, err
+	}
+	err = st.Payments.AdvanceReceiptLedger(ctx, models.AdvanceReceiptLedgerParams{
+		CountyID:      countyID,
+		LastSequence:  entry.Sequence,
+		LastEntryHash: entry.EntryHash,
+	})
+	return entry, err
+}
+
+// issueReceipt numbers a receipt of a completed payment, records it in the
+// ledger and inserts it, all in the caller's transaction.
+func (s *Service) issueReceipt(ctx context.Context, st Stores, payment models.Payment, params models.InsertReceiptParams) (models.Receipt, error) {
+	if payment.Status != "completed" {
+		return models.Receipt{}, fmt.Errorf("%w: the payment is %s", ErrPaymentNotCompleted, payment.Status)
+	}
+	params, err := s.numberReceipt(ctx, st, payment.CountyID, params)
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	params.ID = uuid.New()
+	params.PaymentID = payment.ID
+
+	entry, err := appendLedger(ctx, st, payment.CountyID, params.ID, issuedRecord(params.ID, params.ReceiptNumber, params.ReceiptType, payment))
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	params.BlockchainHash = entry.EntryHash
+	params.BlockNumber = sql.NullInt64{}
+	params.BlockchainVerified = sql.NullBool{Bool: false, Valid: true}
+
+	receipt, err := st.Payments.CreateReceipt(ctx, params)
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	err = st.Payments.SetPaymentLedgerHash(ctx, models.SetPaymentLedgerHashParams{
+		ID:             payment.ID,
+		BlockchainHash: sql.NullString{String: entry.EntryHash, Valid: true},
+	})
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	return receipt, s.enqueueReceiptDelivery(ctx, st, payment, receipt)
+}
+
+// ledgerVoid records that a receipt has been voided. The caller voids it
+// first in the same transaction.
+func ledgerVoid(ctx context.Context, st Stores, countyID int32, id uuid.UUID) error {
+	receipt, err := st.Payments.GetReceiptByID(ctx, id.String())
+	if err != nil {
+		return err
+	}
+	_, err = appendLedger(ctx, st, countyID, receipt.ID, voidedRecord(receipt, countyID))
+	return err
+}
+
+// LedgerProblem is one discrepancy found by verification.
+type LedgerProblem struct {
+	Sequence  int64  `json:"sequence,omitempty"`
+	Block     int64  `json:"block,omitempty"`
+	ReceiptID string `json:"receipt_id,omitempty"`
+	Problem   string `json:"problem"`
+}
+
+// LedgerReport is the outcome of verifying one county's chain.
+type LedgerReport struct {
+	CountyID int32           `json:"county_id"`
+	Entries  int64           `json:"entries"`
+	Blocks   int64           `json:"blocks"`
+	Problems []LedgerProblem `json:"problems,omitempty"`
+}
+
+// OK reports whether the chain verified cleanly.
+func (r LedgerReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// chainCheck walks a county's entries in order, checking each against the
+// entry before it and against the receipt it records, and collects the entry
+// hashes of every block so the blocks can be checked at the end.
+type chainCheck struct {
+	report   LedgerReport
+	sequence int64
+	previous string
+	blocks   map[int64][]string
+	voids    map[uuid.UUID]bool
+}
+
+func newChainCheck(countyID int32) *chainCheck {
+	return &chainCheck{
+		report:   LedgerReport{CountyID: countyID},
+		previous: ledger.GenesisHash,
+		blocks:   map[int64][]string{},
+		voids:    map[uuid.UUID]bool{},
+	}
+}
+
+// startAfter checks a chain that is already known up to entry sequence,
+// whose hash is previous.
+func (c *chainCheck) startAfter(sequence int64, previous string) {
+	c.sequence, c.previous = sequence, previous
+}
+
+func (c *chainCheck) problem(p LedgerProblem) {
+	c.report.Problems = append(c.report.Problems, p)
+}
+
+func (c *chainCheck) entry(e models.ListReceiptLedgerEntriesRow) {
+	c.report.Entries++
+	at := func(format string, args ...any) {
+		c.problem(LedgerProblem{Sequence: e.Sequence, ReceiptID: e.ReceiptID.String(), Problem: fmt.Sprintf(format, args...)})
+	}
+
+	if e.Sequence != c.sequence+1 {
+		at("entries %d to %d are missing", c.sequence+1, e.Sequence-1)
+	}
+	if e.PreviousHash != c.previous {
+		at("entry does not link to the entry before it")
+	}
+	if ledger.EntryHash(c.report.CountyID, e.Sequence, e.PreviousHash, e.RecordHash) != e.EntryHash {
+		at("entry hash does not match the entry")
+	}
+	c.sequence, c.previous = e.Sequence, e.EntryHash
+	if e.BlockNumber.Valid {
+		c.blocks[e.BlockNumber.Int64] = append(c.blocks[e.BlockNumber.Int64], e.EntryHash)
+	}
+
+	if !e.ReceiptFound {
+		at("receipt %s has been deleted", e.ReceiptID)
+		return
+	}
+	if entryRecord(e).Hash() != e.RecordHash {
+		at("receipt %s has been changed since it was %s", e.ReceiptNumber, e.Event)
+	}
+	switch e.Event {
+	case ledger.Issued:
+		if e.ReceiptHash != e.EntryHash {
+			at("blockchain_hash of receipt %s does not match its entry", e.ReceiptNumber)
+		}
+		if e.VoidedAt.Valid && !c.voids[e.ReceiptID] {
+			c.voids[e.ReceiptID] = false
+		}
+	case ledger.Voided:
+		if !e.VoidedAt.Valid {
+			at("receipt %s is no longer voided", e.ReceiptNumber)
+		}
+		c.voids[e.ReceiptID] = true
+	}
+}
+
+// entryRecord rebuilds the record of an entry from its receipt as it is now.
+func entryRecord(e models.ListReceiptLedgerEntriesRow) ledger.Record {
+	receipt := models.Receipt{
+		ID:            e.ReceiptID,
+		PaymentID:     e.PaymentID.UUID,
+		ReceiptNumber: e.ReceiptNumber,
+		ReceiptType:   e.ReceiptType,
+		VoidedBy:      e.VoidedBy,
+		VoidReason:    e.VoidReason,
+	}
+	if e.Event == ledger.Voided {
+		return voidedRecord(receipt, e.PaymentCountyID)
+	}
+	return issuedRecord(receipt.ID, receipt.ReceiptNumber, receipt.ReceiptType, models.Payment{
+		ID:                    e.PaymentID.UUID,
+		CountyID:              e.PaymentCountyID,
+		TaxpayerID:            e.TaxpayerID.UUID,
+		PaymentNumber:         e.PaymentNumber,
+		Amount:                e.Amount.Amount,
+		PaymentMethod:         e.PaymentMethod,
+		MpesaReceiptNumber:    e.MpesaReceiptNumber,
+		BankReference:         e.BankReference,
+		ChequeNumber:          e.ChequeNumber,
+		ExternalTransactionID: e.ExternalTransactionID,
+	})
+}
+
+// finish checks the blocks, which must cover the chain from the start in
+// order, and the head, which must point at the last entry and block.
+func (c *chainCheck) finish(head models.ReceiptLedgerHead, blocks []models.ReceiptLedgerBlock) LedgerReport {
+	for id, voided := range c.voids {
+		if !voided {
+			c.problem(LedgerProblem{ReceiptID: id.String(), Problem: "receipt was voided outside the ledger"})
+		}
+	}
+
+	var number, last int64
+	previous := ledger.GenesisHash
+	for _, b := range blocks {
+		c.report.Blocks++
+		at := func(format string, args ...any) {
+			c.problem(LedgerProblem{Block: b.BlockNumber, Problem: fmt.Sprintf(format, args...)})
+		}
+		if b.BlockNumber != number+1 {
+			at("blocks %d to %d are missing", number+1, b.BlockNumber-1)
+		}
+		if b.FirstSequence != last+1 {
+			at("block starts at entry %d instead of %d", b.FirstSequence, last+1)
+		}
+		if b.PreviousBlockHash != previous {
+			at("block does not link to the block before it")
+		}
+		hashes := c.blocks[b.BlockNumber]
+		if int64(len(hashes)) != b.LastSequence-b.FirstSequence+1 || int(b.EntryCount) != len(hashes) {
+			at("block seals %d entries but %d are in the ledger", b.EntryCount, len(hashes))
+		}
+		if root, err := ledger.MerkleRoot(hashes); err != nil || root != b.MerkleRoot {
+			at("Merkle root does not match the block's entries")
+		}
+		header := ledger.Block{
+			CountyID:          c.report.CountyID,
+			Number:            b.BlockNumber,
+			FirstSequence:     b.FirstSequence,
+			LastSequence:      b.LastSequence,
+			MerkleRoot:        b.MerkleRoot,
+			PreviousBlockHash: b.PreviousBlockHash,
+		}
+		if header.Hash() != b.BlockHash {
+			at("block hash does not match the block")
+		}
+		delete(c.blocks, b.BlockNumber)
+		number, last, previous = b.BlockNumber, b.LastSequence, b.BlockHash
+	}
+	for n, hashes := range c.blocks {
+		c.problem(LedgerProblem{Block: n, Problem: fmt.Sprintf("%d entries are sealed into a block that does not exist", len(hashes))})
+	}
+
+	if head.LastSequence != c.sequence || head.LastEntryHash != c.previous {
+		c.problem(LedgerProblem{Sequence: c.sequence, Problem: fmt.Sprintf("the chain ends at entry %d but should end at entry %d", c.sequence, head.LastSequence)})
+	}
+	if head.LastBlockNumber != number || head.LastBlockHash != previous {
+		c.problem(LedgerProblem{Block: number, Problem: fmt.Sprintf("the last block is %d but should be %d", number, head.LastBlockNumber)})
+	}
+	return c.report
+}
+
+// VerifyLedger checks the chain of a county, or of every county when
+// countyID is 0, against the receipts as they are now.
+func (s *Service) VerifyLedger(ctx context.Context, countyID int32) ([]LedgerReport, error) {
+	counties, err := s.repo.ListReceiptLedgerCounties(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if countyID != 0 {
+		counties = []int32{countyID}
+	}
+	unledgered, err := s.repo.ListUnledgeredReceipts(ctx, sql.NullInt32{Int32: countyID, Valid: countyID != 0})
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []LedgerReport
+	for _, county := range counties {
+		report, err := s.verifyCounty(ctx, county)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	for _, r := range unledgered {
+		i := 0
+		for i < len(reports) && reports[i].CountyID != r.CountyID {
+			i++
+		}
+		if i == len(reports) {
+			reports = append(reports, LedgerReport{CountyID: r.CountyID})
+		}
+		reports[i].Problems = append(reports[i].Problems, LedgerProblem{
+			ReceiptID: r.ID.String(),
+			Problem:   fmt.Sprintf("receipt %s is not in the ledger", r.ReceiptNumber),
+		})
+	}
+	return reports, nil
+}
+
+// verifyCounty checks a county's chain up to its head as read at the start,
+// so receipts issued meanwhile are left for the next run.
+func (s *Service) verifyCounty(ctx context.Context, countyID int32) (LedgerReport, error) {
+	head, err := s.repo.GetReceiptLedgerHead(ctx, countyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		head = models.ReceiptLedgerHead{CountyID: countyID, LastEntryHash: ledger.GenesisHash, LastBlockHash: ledger.GenesisHash}
+	} else if err != nil {
+		return LedgerReport{}, err
+	}
+	blocks, err := s.repo.ListReceiptLedgerBlocks(ctx, countyID)
+	if err != nil {
+		return LedgerReport{}, err
+	}
+	for i, b := range blocks {
+		if b.BlockNumber > head.LastBlockNumber {
+			blocks = blocks[:i]
+			break
+		}
+	}
+
+	check := newChainCheck(countyID)
+	after := int64(0)
+	for after < head.LastSequence {
+		entries, err := s.repo.ListReceiptLedgerEntries(ctx, models.ListReceiptLedgerEntriesParams{
+			CountyID:      countyID,
+			AfterSequence: after,
+			Limit:         ledgerPageSize,
+		})
+		if err != nil {
+			return LedgerReport{}, err
+		}
+		for _, e := range entries {
+			if e.Sequence > head.LastSequence {
+				break
+			}
+			if e.BlockNumber.Int64 > head.LastBlockNumber {
+				e.BlockNumber = sql.NullInt64{}
+			}
+			check.entry(e)
+		}
+		if len(entries) < ledgerPageSize {
+			break
+		}
+		after = entries[len(entries)-1].Sequence
+	}
+	return check.finish(head, blocks), nil
+}
+
+// SealLedger seals the unsealed entries of every county into blocks and
+// returns the blocks made. Entries are checked before they are sealed; a
+// county whose pending entries fail is left unsealed and reported with
+// ErrLedgerTampered once the other counties are done.
+func (s *Service) SealLedger(ctx context.Context) ([]models.ReceiptLedgerBlock, error) {
+	if s.uow == nil {
+		return nil, errors.New("the receipt ledger is not configured")
+	}
+	counties, err := s.repo.ListCountiesWithUnsealedReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var sealed []models.ReceiptLedgerBlock
+	var tampered error
+	for _, county := range counties {
+		for {
+			block, more, err := s.sealBlock(ctx, county)
+			if errors.Is(err, ErrLedgerTampered) {
+				tampered = errors.Join(tampered, err)
+				break
+			}
+			if err != nil {
+				return sealed, err
+			}
+			if block.BlockNumber != 0 {
+				sealed = append(sealed, block)
+			}
+			if !more {
+				break
+			}
+		}
+	}
+	return sealed, tampered
+}
+
+// sealBlock seals up to maxBlockEntries of a county's unsealed entries into
+// the next block, reporting whether more are left.
+func (s *Service) sealBlock(ctx context.Context, countyID int32) (models.ReceiptLedgerBlock, bool, error) {
+	var block models.ReceiptLedgerBlock
+	var more bool
+	err := s.uow.Do(ctx, func(st Stores) error {
+		head, err := st.Payments.LockReceiptLedger(ctx, countyID)
+		if err != nil {
+			return err
+		}
+		entries, err := st.Payments.ListReceiptLedgerEntries(ctx, models.ListReceiptLedgerEntriesParams{
+			CountyID:     countyID,
+			UnsealedOnly: true,
+			Limit:        maxBlockEntries,
+		})
+		if err != nil || len(entries) == 0 {
+			return err
+		}
+		more = len(entries) == maxBlockEntries
+
+		first, last := entries[0], entries[len(entries)-1]
+		check := newChainCheck(countyID)
+		check.startAfter(first.Sequence-1, first.PreviousHash)
+		hashes := make([]string, 0, len(entries))
+		for _, e := range entries {
+			check.entry(e)
+			hashes = append(hashes, e.EntryHash)
+		}
+		if !check.report.OK() {
+			p := check.report.Problems[0]
+			return fmt.Errorf("%w: county %d entry %d: %s", ErrLedgerTampered, countyID, p.Sequence, p.Problem)
+		}
+
+		root, err := ledger.MerkleRoot(hashes)
+		if err != nil {
+			return err
+		}
+		header := ledger.Block{
+			CountyID:          countyID,
+			Number:            head.LastBlockNumber + 1,
+			FirstSequence:     first.Sequence,
+			LastSequence:      last.Sequence,
+			MerkleRoot:        root,
+			PreviousBlockHash: head.LastBlockHash,
+		}
+		block, err = st.Payments.InsertReceiptLedgerBlock(ctx, models.InsertReceiptLedgerBlockParams{
+			CountyID:          countyID,
+			BlockNumber:       header.Number,
+			FirstSequence:     header.FirstSequence,
+			LastSequence:      header.LastSequence,
+			EntryCount:        int32(len(entries)),
+			MerkleRoot:        root,
+			PreviousBlockHash: header.PreviousBlockHash,
+			BlockHash:         header.Hash(),
+		})
+		if err != nil {
+			return err
+		}
+
+		number := sql.NullInt64{Int64: block.BlockNumber, Valid: true}
+		err = st.Payments.SealReceiptLedgerEntries(ctx, models.SealReceiptLedgerEntriesParams{
+			CountyID:      countyID,
+			BlockNumber:   number,
+			FirstSequence: block.FirstSequence,
+			LastSequence:  block.LastSequence,
+		})
+		if err != nil {
+			return err
+		}
+		if err := st.Payments.MarkReceiptsSealed(ctx, models.MarkReceiptsSealedParams{CountyID: countyID, BlockNumber: number}); err != nil {
+			return err
+		}
+		if err := st.Payments.MarkPaymentsSealed(ctx, models.MarkPaymentsSealedParams{CountyID: countyID, BlockNumber: number}); err != nil {
+			return err
+		}
+		return st.Payments.AdvanceReceiptLedgerBlock(ctx, models.AdvanceReceiptLedgerBlockParams{
+			CountyID:        countyID,
+			LastBlockNumber: block.BlockNumber,
+			LastBlockHash:   block.BlockHash,
+		})
+	})
+	if err != nil {
+		return models.ReceiptLedgerBlock{}, false, err
+	}
+	return block, more, nil
+}
+
+// SealLedgerEvery seals the ledger every interval until ctx is done.
+func (s *Service) SealLedgerEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			blocks, err := s.SealLedger(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to seal the receipt ledger")
+			}
+			if len(blocks) > 0 {
+				log.Info().Int("blocks", len(blocks)).Msg("Sealed receipt ledger blocks")
+			}
+		}
+	}
+}
+
+// BackfillLedger records receipts issued before the ledger existed, oldest
+// first, including their voids. It returns how many receipts it recorded.
+func (s *Service) BackfillLedger(ctx context.Context) (int, error) {
+	if s.uow == nil {
+		return 0, errors.New("the receipt ledger is not configured")
+	}
+	pending, err := s.repo.ListUnledgeredReceipts(ctx, sql.NullInt32{})
+	if err != nil {
+		return 0, err
+	}
+	for i, r := range pending {
+		err := s.uow.Do(ctx, func(st Stores) error {
+			if n, err := st.Payments.CountReceiptLedgerEntries(ctx, r.ID); err != nil || n > 0 {
+				return err
+			}
+			receipt, err := st.Payments.GetReceiptByID(ctx, r.ID.String())
+			if err != nil {
+				return err
+			}
+			payment, err := st.Payments.GetPaymentForUpdate(ctx, receipt.PaymentID)
+			if err != nil {
+				return err
+			}
+			entry, err := appendLedger(ctx, st, payment.CountyID, receipt.ID, issuedRecord(receipt.ID, receipt.ReceiptNumber, receipt.ReceiptType, payment))
+			if err != nil {
+				return err
+			}
+			err = st.Payments.SetReceiptLedgerHash(ctx, models.SetReceiptLedgerHashParams{ID: receipt.ID, BlockchainHash: entry.EntryHash})
+			if err != nil {
+				return err
+			}
+			err = st.Payments.SetPaymentLedgerHash(ctx, models.SetPaymentLedgerHashParams{
+				ID:             payment.ID,
+				BlockchainHash: sql.NullString{String: entry.EntryHash, Valid: true},
+			})
+			if err != nil || !receipt.VoidedAt.Valid {
+				return err
+			}
+			_, err = appendLedger(ctx, st, payment.CountyID, receipt.ID, voidedRecord(receipt, payment.CountyID))
+			return err
+		})
+		if err != nil {
+			return i, fmt.Errorf("receipt %s: %w", r.ReceiptNumber, err)
+		}
+	}
+	return len(pending), nil
+}
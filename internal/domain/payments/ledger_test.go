@@ -0,0 +1,248 @@
+package payments
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/ledger"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testChain issues n receipts in county 47, seals the first sealed of them
+// into block 1 and returns the entries with the head and blocks to match.
+func testChain(t *testing.T, n, sealed int) ([]models.ListReceiptLedgerEntriesRow, models.ReceiptLedgerHead, []models.ReceiptLedgerBlock) {
+	t.Helper()
+	const county = 47
+	previous := ledger.GenesisHash
+	var entries []models.ListReceiptLedgerEntriesRow
+	for i := 1; i <= n; i++ {
+		payment := models.Payment{
+			ID:                 uuid.New(),
+			CountyID:           county,
+			TaxpayerID:         uuid.New(),
+			PaymentNumber:      fmt.Sprintf("047/PAY/2025-26/%06d", i),
+			Amount:             money.MustParse("1500"),
+			PaymentMethod:      "mpesa",
+			MpesaReceiptNumber: sql.NullString{String: fmt.Sprintf("SGH%07d", i), Valid: true},
+		}
+		receiptID := uuid.New()
+		number := fmt.Sprintf("047/RCT/2025-26/%06d", i)
+		receiptType := sql.NullString{String: "official", Valid: true}
+		recordHash := issuedRecord(receiptID, number, receiptType, payment).Hash()
+		entryHash := ledger.EntryHash(county, int64(i), previous, recordHash)
+		entries = append(entries, models.ListReceiptLedgerEntriesRow{
+			Sequence:           int64(i),
+			ReceiptID:          receiptID,
+			Event:              ledger.Issued,
+			RecordHash:         recordHash,
+			PreviousHash:       previous,
+			EntryHash:          entryHash,
+			ReceiptFound:       true,
+			ReceiptNumber:      number,
+			ReceiptType:        receiptType,
+			ReceiptHash:        entryHash,
+			PaymentID:          uuid.NullUUID{UUID: payment.ID, Valid: true},
+			PaymentNumber:      payment.PaymentNumber,
+			PaymentCountyID:    county,
+			TaxpayerID:         uuid.NullUUID{UUID: payment.TaxpayerID, Valid: true},
+			Amount:             money.NullAmount{Amount: payment.Amount, Valid: true},
+			PaymentMethod:      payment.PaymentMethod,
+			MpesaReceiptNumber: payment.MpesaReceiptNumber,
+		})
+		previous = entryHash
+	}
+	head := models.ReceiptLedgerHead{
+		CountyID:        county,
+		LastSequence:    int64(n),
+		LastEntryHash:   previous,
+		LastBlockHash:   ledger.GenesisHash,
+		LastBlockNumber: 0,
+	}
+
+	var blocks []models.ReceiptLedgerBlock
+	if sealed > 0 {
+		var hashes []string
+		for i := range entries[:sealed] {
+			entries[i].BlockNumber = sql.NullInt64{Int64: 1, Valid: true}
+			hashes = append(hashes, entries[i].EntryHash)
+		}
+		root, err := ledger.MerkleRoot(hashes)
+		require.NoError(t, err)
+		header := ledger.Block{CountyID: county, Number: 1, FirstSequence: 1, LastSequence: int64(sealed), MerkleRoot: root, PreviousBlockHash: ledger.GenesisHash}
+		blocks = append(blocks, models.ReceiptLedgerBlock{
+			CountyID:          county,
+			BlockNumber:       1,
+			FirstSequence:     1,
+			LastSequence:      int64(sealed),
+			EntryCount:        int32(sealed),
+			MerkleRoot:        root,
+			PreviousBlockHash: ledger.GenesisHash,
+			BlockHash:         header.Hash(),
+		})
+		head.LastBlockNumber, head.LastBlockHash = 1, header.Hash()
+	}
+	return entries, head, blocks
+}
+
+func verifyChain(entries []models.ListReceiptLedgerEntriesRow, head models.ReceiptLedgerHead, blocks []models.ReceiptLedgerBlock) LedgerReport {
+	check := newChainCheck(head.CountyID)
+	for _, e := range entries {
+		check.entry(e)
+	}
+	return check.finish(head, blocks)
+}
+
+func problems(report LedgerReport) []string {
+	var out []string
+	for _, p := range report.Problems {
+		out = append(out, p.Problem)
+	}
+	return out
+}
+
+func TestVerifyLedgerIntact(t *testing.T) {
+	entries, head, blocks := testChain(t, 5, 3)
+	report := verifyChain(entries, head, blocks)
+	assert.True(t, report.OK(), problems(report))
+	assert.EqualValues(t, 5, report.Entries)
+	assert.EqualValues(t, 1, report.Blocks)
+}
+
+func TestVerifyLedgerDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(entries []models.ListReceiptLedgerEntriesRow, blocks []models.ReceiptLedgerBlock) []models.ListReceiptLedgerEntriesRow
+		want   string
+	}{
+		{
+			name: "edited amount",
+			tamper: func(e []models.ListReceiptLedgerEntriesRow, _ []models.ReceiptLedgerBlock) []models.ListReceiptLedgerEntriesRow {
+				e[1].Amount.Amount = money.MustParse("150")
+				return e
+			},
+			want: "has been changed since it was issued",
+		},
+		{
+			name: "edited receipt number",
+			tamper: func(e []models.ListReceiptLedgerEntriesRow, _ []models.ReceiptLedgerBlock) []models.ListReceiptLedgerEntriesRow {
+				e[3].ReceiptNumber = "047/RCT/2025-26/999999"
+				return e
+			},
+			want: "has been changed since it was issued",
+		},
+		{
+			name: "deleted receipt",
+			tamper: func(e []models.ListReceiptLedgerEntriesRow, _ []models.ReceiptLedgerBlock) []models.ListReceiptLedgerEntriesRow {
+				e[2].ReceiptFound = false
+				return e
+			},
+			want: "has been deleted",
+		},
+		{
+			name: "deleted entry",
+			tamper: func(e []models.ListReceiptLedgerEntriesRow, _ []models.ReceiptLedgerBlock) []models.ListReceiptLedgerEntriesRow {
+				return append(e[:3:3], e[4:]...)
+			},
+			want: "entries 4 to 4 are missing",
+		},
+		{
+			name: "truncated chain",
+			tamper: func(e []models.ListReceiptLedgerEntriesRow, _ []models.ReceiptLedgerBlock) []models.ListReceiptLedgerEntriesRow {
+				return e[:4]
+			},
+			want: "the chain ends at entry 4 but should end at entry 5",
+		},
+		{
+			name: "rewritten record hash",
+			tamper: func(e []models.ListReceiptLedgerEntriesRow, _ []models.ReceiptLedgerBlock) []models.ListReceiptLedgerEntriesRow {
+				e[4].RecordHash = ledger.Record{ReceiptID: "forged"}.Hash()
+				return e
+			},
+			want: "entry hash does not match the entry",
+		},
+		{
+			name: "replaced blockchain_hash",
+			tamper: func(e []models.ListReceiptLedgerEntriesRow, _ []models.ReceiptLedgerBlock) []models.ListReceiptLedgerEntriesRow {
+				e[0].ReceiptHash = ledger.GenesisHash
+				return e
+			},
+			want: "does not match its entry",
+		},
+		{
+			name: "voided outside the ledger",
+			tamper: func(e []models.ListReceiptLedgerEntriesRow, _ []models.ReceiptLedgerBlock) []models.ListReceiptLedgerEntriesRow {
+				e[2].VoidedAt = sql.NullTime{Valid: true}
+				return e
+			},
+			want: "receipt was voided outside the ledger",
+		},
+		{
+			name: "forged block root",
+			tamper: func(e []models.ListReceiptLedgerEntriesRow, b []models.ReceiptLedgerBlock) []models.ListReceiptLedgerEntriesRow {
+				b[0].MerkleRoot = ledger.GenesisHash
+				return e
+			},
+			want: "Merkle root does not match the block's entries",
+		},
+		{
+			name: "entry moved out of its block",
+			tamper: func(e []models.ListReceiptLedgerEntriesRow, _ []models.ReceiptLedgerBlock) []models.ListReceiptLedgerEntriesRow {
+				e[1].BlockNumber = sql.NullInt64{}
+				return e
+			},
+			want: "block seals 3 entries but 2 are in the ledger",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, head, blocks := testChain(t, 5, 3)
+			entries = tt.tamper(entries, blocks)
+			report := verifyChain(entries, head, blocks)
+			require.False(t, report.OK())
+			found := false
+			for _, p := range problems(report) {
+				if strings.Contains(p, tt.want) {
+					found = true
+				}
+			}
+			assert.True(t, found, "want %q in %q", tt.want, problems(report))
+		})
+	}
+}
+
+func TestVerifyLedgerVoids(t *testing.T) {
+	entries, head, blocks := testChain(t, 2, 0)
+	voidedBy := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	issued := &entries[1]
+	issued.VoidedAt = sql.NullTime{Valid: true}
+	issued.VoidedBy = voidedBy
+	issued.VoidReason = sql.NullString{String: "payment refunded: duplicate", Valid: true}
+
+	record := voidedRecord(models.Receipt{
+		ID:            issued.ReceiptID,
+		PaymentID:     issued.PaymentID.UUID,
+		ReceiptNumber: issued.ReceiptNumber,
+		VoidedBy:      voidedBy,
+		VoidReason:    issued.VoidReason,
+	}, 47).Hash()
+	void := *issued
+	void.Sequence, void.Event, void.RecordHash, void.PreviousHash = 3, ledger.Voided, record, head.LastEntryHash
+	void.EntryHash = ledger.EntryHash(47, 3, head.LastEntryHash, record)
+	entries = append(entries, void)
+	head.LastSequence, head.LastEntryHash = 3, void.EntryHash
+
+	report := verifyChain(entries, head, blocks)
+	assert.True(t, report.OK(), problems(report))
+
+	// Un-voiding the receipt afterwards is caught.
+	for i := range entries {
+		entries[i].VoidedAt = sql.NullTime{}
+	}
+	assert.Contains(t, problems(verifyChain(entries, head, blocks)), "receipt 047/RCT/2025-26/000002 is no longer voided")
+}
@@ -10,6 +10,7 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/money"
 )
 
 const deletePayment = `-- name: DeletePayment :exec
@@ -39,6 +40,73 @@ func (q *Queries) DeleteReceipt(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const getPaymentAllocationByID = `-- name: GetPaymentAllocationByID :one
+SELECT id, payment_id, assessment_id, allocated_amount, allocation_type, created_at
+FROM payment_allocations
+WHERE id = $1
+`
+
+func (q *Queries) GetPaymentAllocationByID(ctx context.Context, id uuid.UUID) (PaymentAllocation, error) {
+	row := q.db.QueryRowContext(ctx, getPaymentAllocationByID, id)
+	var i PaymentAllocation
+	err := row.Scan(
+		&i.ID,
+		&i.PaymentID,
+		&i.AssessmentID,
+		&i.AllocatedAmount,
+		&i.AllocationType,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPaymentByExternalTransactionIDForUpdate = `-- name: GetPaymentByExternalTransactionIDForUpdate :one
+SELECT id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
+    payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
+    status, collected_by, created_at, updated_at, mpesa_receipt_number, bank_reference,
+    cheque_number, failure_reason, collection_point, gps_coordinates, blockchain_hash,
+    block_number, reconciled, reconciliation_date, reconciled_by
+FROM payments
+WHERE external_transaction_id = $1
+FOR UPDATE
+`
+
+// Locks the payment awaiting an external confirmation, e.g. an STK callback
+func (q *Queries) GetPaymentByExternalTransactionIDForUpdate(ctx context.Context, externalTransactionID sql.NullString) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, getPaymentByExternalTransactionIDForUpdate, externalTransactionID)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.CountyID,
+		&i.TaxpayerID,
+		&i.AssessmentID,
+		&i.PaymentNumber,
+		&i.Amount,
+		&i.PaymentMethod,
+		&i.PaymentChannel,
+		&i.ExternalTransactionID,
+		&i.PayerPhoneNumber,
+		&i.PayerName,
+		&i.PaymentDate,
+		&i.Status,
+		&i.CollectedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.MpesaReceiptNumber,
+		&i.BankReference,
+		&i.ChequeNumber,
+		&i.FailureReason,
+		&i.CollectionPoint,
+		&i.GpsCoordinates,
+		&i.BlockchainHash,
+		&i.BlockNumber,
+		&i.Reconciled,
+		&i.ReconciliationDate,
+		&i.ReconciledBy,
+	)
+	return i, err
+}
+
 const getPaymentByID = `-- name: GetPaymentByID :one
 SELECT id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
        payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
@@ -84,10 +152,104 @@ func (q *Queries) GetPaymentByID(ctx context.Context, id uuid.UUID) (Payment, er
 	return i, err
 }
 
+const getPaymentByMpesaReceiptNumber = `-- name: GetPaymentByMpesaReceiptNumber :one
+SELECT id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
+    payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
+    status, collected_by, created_at, updated_at, mpesa_receipt_number, bank_reference,
+    cheque_number, failure_reason, collection_point, gps_coordinates, blockchain_hash,
+    block_number, reconciled, reconciliation_date, reconciled_by
+FROM payments
+WHERE mpesa_receipt_number = $1
+`
+
+// M-Pesa receipt numbers are unique, so a repeated confirmation finds the payment it already created
+func (q *Queries) GetPaymentByMpesaReceiptNumber(ctx context.Context, mpesaReceiptNumber sql.NullString) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, getPaymentByMpesaReceiptNumber, mpesaReceiptNumber)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.CountyID,
+		&i.TaxpayerID,
+		&i.AssessmentID,
+		&i.PaymentNumber,
+		&i.Amount,
+		&i.PaymentMethod,
+		&i.PaymentChannel,
+		&i.ExternalTransactionID,
+		&i.PayerPhoneNumber,
+		&i.PayerName,
+		&i.PaymentDate,
+		&i.Status,
+		&i.CollectedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.MpesaReceiptNumber,
+		&i.BankReference,
+		&i.ChequeNumber,
+		&i.FailureReason,
+		&i.CollectionPoint,
+		&i.GpsCoordinates,
+		&i.BlockchainHash,
+		&i.BlockNumber,
+		&i.Reconciled,
+		&i.ReconciliationDate,
+		&i.ReconciledBy,
+	)
+	return i, err
+}
+
+const getPaymentForUpdate = `-- name: GetPaymentForUpdate :one
+SELECT id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
+    payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
+    status, collected_by, created_at, updated_at, mpesa_receipt_number, bank_reference,
+    cheque_number, failure_reason, collection_point, gps_coordinates, blockchain_hash,
+    block_number, reconciled, reconciliation_date, reconciled_by
+FROM payments
+WHERE id = $1
+FOR UPDATE
+`
+
+// Locks the payment so concurrent allocations cannot exceed its amount
+func (q *Queries) GetPaymentForUpdate(ctx context.Context, id uuid.UUID) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, getPaymentForUpdate, id)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.CountyID,
+		&i.TaxpayerID,
+		&i.AssessmentID,
+		&i.PaymentNumber,
+		&i.Amount,
+		&i.PaymentMethod,
+		&i.PaymentChannel,
+		&i.ExternalTransactionID,
+		&i.PayerPhoneNumber,
+		&i.PayerName,
+		&i.PaymentDate,
+		&i.Status,
+		&i.CollectedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.MpesaReceiptNumber,
+		&i.BankReference,
+		&i.ChequeNumber,
+		&i.FailureReason,
+		&i.CollectionPoint,
+		&i.GpsCoordinates,
+		&i.BlockchainHash,
+		&i.BlockNumber,
+		&i.Reconciled,
+		&i.ReconciliationDate,
+		&i.ReconciledBy,
+	)
+	return i, err
+}
+
 const getReceiptByID = `-- name: GetReceiptByID :one
 SELECT id, payment_id, receipt_number, receipt_type, pdf_file_path, pdf_file_size,
        pdf_generated, sms_sent, sms_sent_at, email_sent, email_sent_at,
-       blockchain_hash, block_number, blockchain_verified, qr_code_data, created_at
+       blockchain_hash, block_number, blockchain_verified, qr_code_data, created_at,
+       voided_at, voided_by, void_reason
 FROM receipts
 WHERE id = $1
 `
@@ -112,6 +274,9 @@ func (q *Queries) GetReceiptByID(ctx context.Context, id uuid.UUID) (Receipt, er
 		&i.BlockchainVerified,
 		&i.QrCodeData,
 		&i.CreatedAt,
+		&i.VoidedAt,
+		&i.VoidedBy,
+		&i.VoidReason,
 	)
 	return i, err
 }
@@ -122,7 +287,7 @@ INSERT INTO payments (
     payment_channel, external_transaction_id, mpesa_receipt_number, bank_reference,
     cheque_number, payer_phone_number, payer_name, status, collected_by,
     failure_reason, collection_point, gps_coordinates, blockchain_hash, block_number,
-    reconciled, reconciliation_date, reconciled_by
+    reconciled, reconciliation_date, reconciled_by, payment_date
 )
 VALUES (
     $1, $2, $3, $4, $5, $6,
@@ -131,7 +296,8 @@ VALUES (
     $16, $17, 
     CASE WHEN $18 = '' THEN NULL ELSE $18::point END, 
     $19, $20,
-    $21, $22, $23
+    $21, $22, $23,
+    COALESCE($24, CURRENT_TIMESTAMP)
 )
 RETURNING id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
     payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
@@ -145,7 +311,7 @@ type InsertPaymentParams struct {
 	TaxpayerID            uuid.UUID      `json:"taxpayer_id"`
 	AssessmentID          uuid.NullUUID  `json:"assessment_id"`
 	PaymentNumber         string         `json:"payment_number"`
-	Amount                string         `json:"amount"`
+	Amount                money.Amount   `json:"amount"`
 	PaymentMethod         string         `json:"payment_method"`
 	PaymentChannel        sql.NullString `json:"payment_channel"`
 	ExternalTransactionID sql.NullString `json:"external_transaction_id"`
@@ -164,6 +330,7 @@ type InsertPaymentParams struct {
 	Reconciled            sql.NullBool   `json:"reconciled"`
 	ReconciliationDate    sql.NullTime   `json:"reconciliation_date"`
 	ReconciledBy          uuid.NullUUID  `json:"reconciled_by"`
+	PaymentDate           sql.NullTime   `json:"payment_date"`
 }
 
 // internal/domains/payments/queries/payments.sql
@@ -192,6 +359,7 @@ func (q *Queries) InsertPayment(ctx context.Context, arg InsertPaymentParams) (P
 		arg.Reconciled,
 		arg.ReconciliationDate,
 		arg.ReconciledBy,
+		arg.PaymentDate,
 	)
 	var i Payment
 	err := row.Scan(
@@ -239,7 +407,7 @@ RETURNING id, payment_id, assessment_id, allocated_amount, allocation_type, crea
 type InsertPaymentAllocationParams struct {
 	PaymentID       uuid.UUID      `json:"payment_id"`
 	AssessmentID    uuid.UUID      `json:"assessment_id"`
-	AllocatedAmount string         `json:"allocated_amount"`
+	AllocatedAmount money.Amount   `json:"allocated_amount"`
 	AllocationType  sql.NullString `json:"allocation_type"`
 }
 
@@ -263,20 +431,25 @@ func (q *Queries) InsertPaymentAllocation(ctx context.Context, arg InsertPayment
 	return i, err
 }
 
-const insertReceipt = `-- name: InsertReceipt :exec
+const insertReceipt = `-- name: InsertReceipt :one
 INSERT INTO receipts (
-    payment_id, receipt_number, receipt_type, pdf_file_path, pdf_file_size,
+    id, payment_id, receipt_number, receipt_type, pdf_file_path, pdf_file_size,
     pdf_generated, sms_sent, sms_sent_at, email_sent, email_sent_at,
     blockchain_hash, block_number, blockchain_verified, qr_code_data
 )
 VALUES (
-    $1, $2, $3, $4, $5,
-    $6, $7, $8, $9, $10,
-    $11, $12, $13, $14
+    $1, $2, $3, $4, $5, $6,
+    $7, $8, $9, $10, $11,
+    $12, $13, $14, $15
 )
+RETURNING id, payment_id, receipt_number, receipt_type, pdf_file_path, pdf_file_size,
+    pdf_generated, sms_sent, sms_sent_at, email_sent, email_sent_at,
+    blockchain_hash, block_number, blockchain_verified, qr_code_data, created_at,
+    voided_at, voided_by, void_reason
 `
 
 type InsertReceiptParams struct {
+	ID                 uuid.UUID      `json:"id"`
 	PaymentID          uuid.UUID      `json:"payment_id"`
 	ReceiptNumber      string         `json:"receipt_number"`
 	ReceiptType        sql.NullString `json:"receipt_type"`
@@ -294,8 +467,9 @@ type InsertReceiptParams struct {
 }
 
 // Receipts Queries
-func (q *Queries) InsertReceipt(ctx context.Context, arg InsertReceiptParams) error {
-	_, err := q.db.ExecContext(ctx, insertReceipt,
+func (q *Queries) InsertReceipt(ctx context.Context, arg InsertReceiptParams) (Receipt, error) {
+	row := q.db.QueryRowContext(ctx, insertReceipt,
+		arg.ID,
 		arg.PaymentID,
 		arg.ReceiptNumber,
 		arg.ReceiptType,
@@ -311,7 +485,29 @@ func (q *Queries) InsertReceipt(ctx context.Context, arg InsertReceiptParams) er
 		arg.BlockchainVerified,
 		arg.QrCodeData,
 	)
-	return err
+	var i Receipt
+	err := row.Scan(
+		&i.ID,
+		&i.PaymentID,
+		&i.ReceiptNumber,
+		&i.ReceiptType,
+		&i.PdfFilePath,
+		&i.PdfFileSize,
+		&i.PdfGenerated,
+		&i.SmsSent,
+		&i.SmsSentAt,
+		&i.EmailSent,
+		&i.EmailSentAt,
+		&i.BlockchainHash,
+		&i.BlockNumber,
+		&i.BlockchainVerified,
+		&i.QrCodeData,
+		&i.CreatedAt,
+		&i.VoidedAt,
+		&i.VoidedBy,
+		&i.VoidReason,
+	)
+	return i, err
 }
 
 const listPaymentAllocations = `-- name: ListPaymentAllocations :many
@@ -485,7 +681,8 @@ func (q *Queries) ListPaymentsByRevenueID(ctx context.Context, assessmentID uuid
 const listReceiptsByPayment = `-- name: ListReceiptsByPayment :many
 SELECT id, payment_id, receipt_number, receipt_type, pdf_file_path, pdf_file_size,
        pdf_generated, sms_sent, sms_sent_at, email_sent, email_sent_at,
-       blockchain_hash, block_number, blockchain_verified, qr_code_data, created_at
+       blockchain_hash, block_number, blockchain_verified, qr_code_data, created_at,
+       voided_at, voided_by, void_reason
 FROM receipts
 WHERE payment_id = $1
 ORDER BY created_at ASC
@@ -517,6 +714,9 @@ func (q *Queries) ListReceiptsByPayment(ctx context.Context, paymentID uuid.UUID
 			&i.BlockchainVerified,
 			&i.QrCodeData,
 			&i.CreatedAt,
+			&i.VoidedAt,
+			&i.VoidedBy,
+			&i.VoidReason,
 		); err != nil {
 			return nil, err
 		}
@@ -531,6 +731,196 @@ func (q *Queries) ListReceiptsByPayment(ctx context.Context, paymentID uuid.UUID
 	return items, nil
 }
 
+const setPaymentExternalTransactionID = `-- name: SetPaymentExternalTransactionID :one
+UPDATE payments
+SET external_transaction_id = $1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2
+RETURNING id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
+    payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
+    status, collected_by, created_at, updated_at, mpesa_receipt_number, bank_reference,
+    cheque_number, failure_reason, collection_point, gps_coordinates, blockchain_hash,
+    block_number, reconciled, reconciliation_date, reconciled_by
+`
+
+type SetPaymentExternalTransactionIDParams struct {
+	ExternalTransactionID sql.NullString `json:"external_transaction_id"`
+	ID                    uuid.UUID      `json:"id"`
+}
+
+// Saves the CheckoutRequestID Daraja gave an STK push on the payment it is for
+func (q *Queries) SetPaymentExternalTransactionID(ctx context.Context, arg SetPaymentExternalTransactionIDParams) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, setPaymentExternalTransactionID,
+		arg.ExternalTransactionID,
+		arg.ID,
+	)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.CountyID,
+		&i.TaxpayerID,
+		&i.AssessmentID,
+		&i.PaymentNumber,
+		&i.Amount,
+		&i.PaymentMethod,
+		&i.PaymentChannel,
+		&i.ExternalTransactionID,
+		&i.PayerPhoneNumber,
+		&i.PayerName,
+		&i.PaymentDate,
+		&i.Status,
+		&i.CollectedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.MpesaReceiptNumber,
+		&i.BankReference,
+		&i.ChequeNumber,
+		&i.FailureReason,
+		&i.CollectionPoint,
+		&i.GpsCoordinates,
+		&i.BlockchainHash,
+		&i.BlockNumber,
+		&i.Reconciled,
+		&i.ReconciliationDate,
+		&i.ReconciledBy,
+	)
+	return i, err
+}
+
+const setPaymentOutcome = `-- name: SetPaymentOutcome :one
+UPDATE payments
+SET
+    status = $1,
+    mpesa_receipt_number = COALESCE($2, mpesa_receipt_number),
+    payer_phone_number = COALESCE($3, payer_phone_number),
+    payment_date = COALESCE($4, payment_date),
+    failure_reason = $5,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $6
+RETURNING id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
+    payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
+    status, collected_by, created_at, updated_at, mpesa_receipt_number, bank_reference,
+    cheque_number, failure_reason, collection_point, gps_coordinates, blockchain_hash,
+    block_number, reconciled, reconciliation_date, reconciled_by
+`
+
+type SetPaymentOutcomeParams struct {
+	Status             string         `json:"status"`
+	MpesaReceiptNumber sql.NullString `json:"mpesa_receipt_number"`
+	PayerPhoneNumber   sql.NullString `json:"payer_phone_number"`
+	PaymentDate        sql.NullTime   `json:"payment_date"`
+	FailureReason      sql.NullString `json:"failure_reason"`
+	ID                 uuid.UUID      `json:"id"`
+}
+
+func (q *Queries) SetPaymentOutcome(ctx context.Context, arg SetPaymentOutcomeParams) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, setPaymentOutcome,
+		arg.Status,
+		arg.MpesaReceiptNumber,
+		arg.PayerPhoneNumber,
+		arg.PaymentDate,
+		arg.FailureReason,
+		arg.ID,
+	)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.CountyID,
+		&i.TaxpayerID,
+		&i.AssessmentID,
+		&i.PaymentNumber,
+		&i.Amount,
+		&i.PaymentMethod,
+		&i.PaymentChannel,
+		&i.ExternalTransactionID,
+		&i.PayerPhoneNumber,
+		&i.PayerName,
+		&i.PaymentDate,
+		&i.Status,
+		&i.CollectedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.MpesaReceiptNumber,
+		&i.BankReference,
+		&i.ChequeNumber,
+		&i.FailureReason,
+		&i.CollectionPoint,
+		&i.GpsCoordinates,
+		&i.BlockchainHash,
+		&i.BlockNumber,
+		&i.Reconciled,
+		&i.ReconciliationDate,
+		&i.ReconciledBy,
+	)
+	return i, err
+}
+
+const sumAllocationsForPayment = `-- name: SumAllocationsForPayment :one
+SELECT COALESCE(SUM(allocated_amount), 0)::numeric AS total
+FROM payment_allocations
+WHERE payment_id = $1
+`
+
+// How much of a payment has been allocated so far
+func (q *Queries) SumAllocationsForPayment(ctx context.Context, paymentID uuid.UUID) (money.Amount, error) {
+	row := q.db.QueryRowContext(ctx, sumAllocationsForPayment, paymentID)
+	var total money.Amount
+	err := row.Scan(&total)
+	return total, err
+}
+
+const sumCompletedAllocationsByTypeForAssessment = `-- name: SumCompletedAllocationsByTypeForAssessment :many
+SELECT COALESCE(pa.allocation_type, 'principal')::text AS allocation_type,
+    COALESCE(SUM(pa.allocated_amount), 0)::numeric AS total
+FROM payment_allocations pa
+JOIN payments p ON p.id = pa.payment_id
+WHERE pa.assessment_id = $1 AND p.status = 'completed'
+GROUP BY 1
+`
+
+type SumCompletedAllocationsByTypeForAssessmentRow struct {
+	AllocationType string       `json:"allocation_type"`
+	Total          money.Amount `json:"total"`
+}
+
+// Settled per component; allocations recorded without a type count as principal
+func (q *Queries) SumCompletedAllocationsByTypeForAssessment(ctx context.Context, assessmentID uuid.UUID) ([]SumCompletedAllocationsByTypeForAssessmentRow, error) {
+	rows, err := q.db.QueryContext(ctx, sumCompletedAllocationsByTypeForAssessment, assessmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SumCompletedAllocationsByTypeForAssessmentRow
+	for rows.Next() {
+		var i SumCompletedAllocationsByTypeForAssessmentRow
+		if err := rows.Scan(&i.AllocationType, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const sumCompletedAllocationsForAssessment = `-- name: SumCompletedAllocationsForAssessment :one
+SELECT COALESCE(SUM(pa.allocated_amount), 0)::numeric AS total
+FROM payment_allocations pa
+JOIN payments p ON p.id = pa.payment_id
+WHERE pa.assessment_id = $1 AND p.status = 'completed'
+`
+
+// Total settled against an assessment by completed payments
+func (q *Queries) SumCompletedAllocationsForAssessment(ctx context.Context, assessmentID uuid.UUID) (money.Amount, error) {
+	row := q.db.QueryRowContext(ctx, sumCompletedAllocationsForAssessment, assessmentID)
+	var total money.Amount
+	err := row.Scan(&total)
+	return total, err
+}
+
 const updatePayment = `-- name: UpdatePayment :one
 UPDATE payments
 SET 
@@ -552,13 +942,11 @@ SET
         WHEN $14 IS NULL THEN gps_coordinates
         ELSE $14::point 
     END,
-    blockchain_hash = COALESCE($15, blockchain_hash),
-    block_number = COALESCE($16, block_number),
-    reconciled = COALESCE($17, reconciled),
-    reconciliation_date = COALESCE($18, reconciliation_date),
-    reconciled_by = COALESCE($19, reconciled_by),
+    reconciled = COALESCE($15, reconciled),
+    reconciliation_date = COALESCE($16, reconciliation_date),
+    reconciled_by = COALESCE($17, reconciled_by),
     updated_at = CURRENT_TIMESTAMP
-WHERE id = $20
+WHERE id = $18
 RETURNING id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
     payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
     status, collected_by, created_at, updated_at, mpesa_receipt_number, bank_reference,
@@ -567,7 +955,7 @@ RETURNING id, county_id, taxpayer_id, assessment_id, payment_number, amount, pay
 `
 
 type UpdatePaymentParams struct {
-	Amount                string         `json:"amount"`
+	Amount                money.Amount   `json:"amount"`
 	PaymentMethod         string         `json:"payment_method"`
 	PaymentChannel        sql.NullString `json:"payment_channel"`
 	ExternalTransactionID sql.NullString `json:"external_transaction_id"`
@@ -581,8 +969,6 @@ type UpdatePaymentParams struct {
 	CollectedBy           uuid.NullUUID  `json:"collected_by"`
 	CollectionPoint       sql.NullString `json:"collection_point"`
 	GpsCoordinates        interface{}    `json:"gps_coordinates"`
-	BlockchainHash        sql.NullString `json:"blockchain_hash"`
-	BlockNumber           sql.NullInt64  `json:"block_number"`
 	Reconciled            sql.NullBool   `json:"reconciled"`
 	ReconciliationDate    sql.NullTime   `json:"reconciliation_date"`
 	ReconciledBy          uuid.NullUUID  `json:"reconciled_by"`
@@ -605,8 +991,6 @@ func (q *Queries) UpdatePayment(ctx context.Context, arg UpdatePaymentParams) (P
 		arg.CollectedBy,
 		arg.CollectionPoint,
 		arg.GpsCoordinates,
-		arg.BlockchainHash,
-		arg.BlockNumber,
 		arg.Reconciled,
 		arg.ReconciliationDate,
 		arg.ReconciledBy,
@@ -655,22 +1039,20 @@ SET
     sms_sent = COALESCE($5, sms_sent),
     sms_sent_at = COALESCE($6, sms_sent_at),
     email_sent = COALESCE($7, email_sent),
-    email_sent_at = COALESCE($8, email_sent_at),
-    blockchain_verified = COALESCE($9, blockchain_verified)
-WHERE id = $10
+    email_sent_at = COALESCE($8, email_sent_at)
+WHERE id = $9
 `
 
 type UpdateReceiptParams struct {
-	ReceiptType        sql.NullString `json:"receipt_type"`
-	PdfFilePath        sql.NullString `json:"pdf_file_path"`
-	PdfFileSize        sql.NullInt32  `json:"pdf_file_size"`
-	PdfGenerated       sql.NullBool   `json:"pdf_generated"`
-	SmsSent            sql.NullBool   `json:"sms_sent"`
-	SmsSentAt          sql.NullTime   `json:"sms_sent_at"`
-	EmailSent          sql.NullBool   `json:"email_sent"`
-	EmailSentAt        sql.NullTime   `json:"email_sent_at"`
-	BlockchainVerified sql.NullBool   `json:"blockchain_verified"`
-	ID                 uuid.UUID      `json:"id"`
+	ReceiptType  sql.NullString `json:"receipt_type"`
+	PdfFilePath  sql.NullString `json:"pdf_file_path"`
+	PdfFileSize  sql.NullInt32  `json:"pdf_file_size"`
+	PdfGenerated sql.NullBool   `json:"pdf_generated"`
+	SmsSent      sql.NullBool   `json:"sms_sent"`
+	SmsSentAt    sql.NullTime   `json:"sms_sent_at"`
+	EmailSent    sql.NullBool   `json:"email_sent"`
+	EmailSentAt  sql.NullTime   `json:"email_sent_at"`
+	ID           uuid.UUID      `json:"id"`
 }
 
 func (q *Queries) UpdateReceipt(ctx context.Context, arg UpdateReceiptParams) error {
@@ -683,7 +1065,6 @@ func (q *Queries) UpdateReceipt(ctx context.Context, arg UpdateReceiptParams) er
 		arg.SmsSentAt,
 		arg.EmailSent,
 		arg.EmailSentAt,
-		arg.BlockchainVerified,
 		arg.ID,
 	)
 	return err
@@ -6,28 +6,153 @@ package models
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/money"
 )
 
 type Querier interface {
+	// Adds a signed amount to the balance, creating it on the first movement
+	AdjustTaxpayerCreditBalance(ctx context.Context, arg AdjustTaxpayerCreditBalanceParams) (TaxpayerCreditBalance, error)
+	AdvanceReceiptLedger(ctx context.Context, arg AdvanceReceiptLedgerParams) error
+	AdvanceReceiptLedgerBlock(ctx context.Context, arg AdvanceReceiptLedgerBlockParams) error
+	// Takes up to limit due messages and leases them until lease_until, so a
+	// dispatcher that dies mid-send only delays them. Concurrent dispatchers
+	// skip each other's messages.
+	ClaimNotifications(ctx context.Context, arg ClaimNotificationsParams) ([]NotificationOutbox, error)
+	CloseCollectorSession(ctx context.Context, arg CloseCollectorSessionParams) (CollectorSession, error)
+	CountReceiptLedgerEntries(ctx context.Context, receiptID uuid.UUID) (int64, error)
 	DeletePayment(ctx context.Context, id uuid.UUID) error
 	DeletePaymentAllocation(ctx context.Context, id uuid.UUID) error
 	DeleteReceipt(ctx context.Context, id uuid.UUID) error
+	EnqueueNotification(ctx context.Context, arg EnqueueNotificationParams) (NotificationOutbox, error)
+	FailNotification(ctx context.Context, arg FailNotificationParams) error
+	GetAssessmentOwner(ctx context.Context, id uuid.UUID) (GetAssessmentOwnerRow, error)
+	GetBankStatementByID(ctx context.Context, id uuid.UUID) (BankStatement, error)
+	GetBankStatementLineForUpdate(ctx context.Context, id uuid.UUID) (BankStatementLine, error)
+	GetCollectorSession(ctx context.Context, id uuid.UUID) (CollectorSession, error)
+	GetCollectorSessionForUpdate(ctx context.Context, id uuid.UUID) (CollectorSession, error)
+	GetMpesaSuspenseForUpdate(ctx context.Context, id uuid.UUID) (MpesaSuspense, error)
+	// The collector's open session, share-locked so it cannot be closed while a payment is being added to it
+	GetOpenCollectorSession(ctx context.Context, collectorID uuid.UUID) (CollectorSession, error)
+	GetPaymentAllocationByID(ctx context.Context, id uuid.UUID) (PaymentAllocation, error)
+	// Locks the payment awaiting an external confirmation, e.g. an STK callback
+	GetPaymentByExternalTransactionIDForUpdate(ctx context.Context, externalTransactionID sql.NullString) (Payment, error)
 	GetPaymentByID(ctx context.Context, id uuid.UUID) (Payment, error)
+	// M-Pesa receipt numbers are unique, so a repeated confirmation finds the payment it already created
+	GetPaymentByMpesaReceiptNumber(ctx context.Context, mpesaReceiptNumber sql.NullString) (Payment, error)
+	// Who a payment's receipts go to: the number that paid, else the
+	// taxpayer's, and the taxpayer's email.
+	GetPaymentContact(ctx context.Context, id uuid.UUID) (GetPaymentContactRow, error)
+	// What a payment has put into credit, and how much of that was refunded
+	GetPaymentCreditTotals(ctx context.Context, paymentID uuid.UUID) (GetPaymentCreditTotalsRow, error)
+	// Locks the payment so concurrent allocations cannot exceed its amount
+	GetPaymentForUpdate(ctx context.Context, id uuid.UUID) (Payment, error)
+	GetPaymentRefund(ctx context.Context, id uuid.UUID) (PaymentRefund, error)
+	// Locks the refund so it is reviewed once
+	GetPaymentRefundForUpdate(ctx context.Context, id uuid.UUID) (PaymentRefund, error)
 	GetReceiptByID(ctx context.Context, id uuid.UUID) (Receipt, error)
+	GetReceiptDocument(ctx context.Context, id uuid.UUID) (GetReceiptDocumentRow, error)
+	GetReceiptLedgerHead(ctx context.Context, countyID int32) (ReceiptLedgerHead, error)
+	// Looks a receipt up by ID or by number for public verification.
+	GetReceiptVerification(ctx context.Context, arg GetReceiptVerificationParams) (GetReceiptVerificationRow, error)
+	GetTaxpayerCounty(ctx context.Context, id uuid.UUID) (int32, error)
+	GetTaxpayerCreditBalance(ctx context.Context, taxpayerID uuid.UUID) (TaxpayerCreditBalance, error)
+	// Locks the balance so credit is applied or refunded one movement at a time
+	GetTaxpayerCreditBalanceForUpdate(ctx context.Context, taxpayerID uuid.UUID) (TaxpayerCreditBalance, error)
+	// Locks the STK result that arrived before its checkout request was saved on the payment.
+	GetUnappliedSTKResultForUpdate(ctx context.Context, reference string) (MpesaSuspense, error)
+	InsertBankStatement(ctx context.Context, arg InsertBankStatementParams) (BankStatement, error)
+	InsertBankStatementLine(ctx context.Context, arg InsertBankStatementLineParams) (BankStatementLine, error)
+	InsertCollectorSession(ctx context.Context, arg InsertCollectorSessionParams) (CollectorSession, error)
+	InsertCollectorSessionPayment(ctx context.Context, arg InsertCollectorSessionPaymentParams) error
+	InsertCreditLedgerEntry(ctx context.Context, arg InsertCreditLedgerEntryParams) (TaxpayerCreditLedger, error)
+	// Stores an M-Pesa result no payment was waiting for. Retries of a result already stored are ignored.
+	InsertMpesaSuspense(ctx context.Context, arg InsertMpesaSuspenseParams) error
 	// internal/domains/payments/queries/payments.sql
 	InsertPayment(ctx context.Context, arg InsertPaymentParams) (Payment, error)
 	// Payment Allocations Queries
 	InsertPaymentAllocation(ctx context.Context, arg InsertPaymentAllocationParams) (PaymentAllocation, error)
+	InsertPaymentRefund(ctx context.Context, arg InsertPaymentRefundParams) (PaymentRefund, error)
+	InsertPaymentStatusChange(ctx context.Context, arg InsertPaymentStatusChangeParams) (PaymentStatusHistory, error)
 	// Receipts Queries
-	InsertReceipt(ctx context.Context, arg InsertReceiptParams) error
+	InsertReceipt(ctx context.Context, arg InsertReceiptParams) (Receipt, error)
+	InsertReceiptLedgerBlock(ctx context.Context, arg InsertReceiptLedgerBlockParams) (ReceiptLedgerBlock, error)
+	InsertReceiptLedgerEntry(ctx context.Context, arg InsertReceiptLedgerEntryParams) (ReceiptLedgerEntry, error)
+	InsertRefundReversal(ctx context.Context, arg InsertRefundReversalParams) (PaymentRefundReversal, error)
+	ListBankStatementLines(ctx context.Context, statementID uuid.UUID) ([]BankStatementLine, error)
+	ListBankStatements(ctx context.Context, arg ListBankStatementsParams) ([]BankStatement, error)
+	ListCollectorSessions(ctx context.Context, arg ListCollectorSessionsParams) ([]CollectorSession, error)
+	ListCountiesWithUnsealedReceipts(ctx context.Context) ([]int32, error)
+	ListCreditLedgerEntries(ctx context.Context, arg ListCreditLedgerEntriesParams) ([]TaxpayerCreditLedger, error)
 	ListPaymentAllocations(ctx context.Context, paymentID uuid.UUID) ([]PaymentAllocation, error)
+	// Newest allocations first, which is the order a refund unwinds them
+	ListPaymentAllocationsForUpdate(ctx context.Context, paymentID uuid.UUID) ([]PaymentAllocation, error)
+	ListPaymentRefunds(ctx context.Context, paymentID uuid.UUID) ([]PaymentRefund, error)
+	ListPaymentStatusHistory(ctx context.Context, paymentID uuid.UUID) ([]PaymentStatusHistory, error)
 	ListPayments(ctx context.Context, arg ListPaymentsParams) ([]Payment, error)
 	ListPaymentsByRevenueID(ctx context.Context, assessmentID uuid.NullUUID) ([]Payment, error)
+	// Payments still holding credit for a taxpayer, oldest first, so credit is used first in, first out
+	ListPaymentsWithCredit(ctx context.Context, taxpayerID uuid.UUID) ([]ListPaymentsWithCreditRow, error)
+	ListPendingRefunds(ctx context.Context, arg ListPendingRefundsParams) ([]PaymentRefund, error)
+	ListReceiptLedgerBlocks(ctx context.Context, countyID int32) ([]ReceiptLedgerBlock, error)
+	ListReceiptLedgerCounties(ctx context.Context) ([]int32, error)
+	ListReceiptLedgerEntries(ctx context.Context, arg ListReceiptLedgerEntriesParams) ([]ListReceiptLedgerEntriesRow, error)
+	ListReceiptLedgerEntriesByReceipt(ctx context.Context, receiptID uuid.UUID) ([]ListReceiptLedgerEntriesByReceiptRow, error)
+	ListReceiptLines(ctx context.Context, paymentID uuid.UUID) ([]ListReceiptLinesRow, error)
+	ListReceiptNotifications(ctx context.Context, receiptID uuid.NullUUID) ([]NotificationOutbox, error)
 	ListReceiptsByPayment(ctx context.Context, paymentID uuid.UUID) ([]Receipt, error)
+	// Completed payments that no statement line has settled yet
+	ListReconciliationCandidates(ctx context.Context, arg ListReconciliationCandidatesParams) ([]Payment, error)
+	ListRefundReversals(ctx context.Context, refundID uuid.UUID) ([]PaymentRefundReversal, error)
+	ListUnledgeredReceipts(ctx context.Context, countyID sql.NullInt32) ([]ListUnledgeredReceiptsRow, error)
+	// The M-Pesa suspense queue, oldest first.
+	ListUnmatchedMpesaSuspense(ctx context.Context, arg ListUnmatchedMpesaSuspenseParams) ([]MpesaSuspense, error)
+	// The manual reconciliation queue, oldest first
+	ListUnmatchedStatementLines(ctx context.Context, arg ListUnmatchedStatementLinesParams) ([]BankStatementLine, error)
+	ListUnrenderedReceipts(ctx context.Context, paymentID uuid.UUID) ([]Receipt, error)
+	// Closed sessions opened in a period, one row per collector and collection point
+	ListZReports(ctx context.Context, arg ListZReportsParams) ([]ListZReportsRow, error)
+	// Serialises the STK callback for a checkout request with saving that checkout
+	// request on its payment, whichever comes first, until the transaction ends.
+	LockMpesaReference(ctx context.Context, reference string) error
+	// Returns the tip of a county's receipt ledger, creating it on first use. The row
+	// stays locked until the surrounding transaction ends, so entries are appended
+	// one at a time.
+	LockReceiptLedger(ctx context.Context, countyID int32) (ReceiptLedgerHead, error)
+	// Marks a message sent and records the delivery on its receipt.
+	MarkNotificationSent(ctx context.Context, id uuid.UUID) error
+	MarkPaymentReconciled(ctx context.Context, arg MarkPaymentReconciledParams) (Payment, error)
+	MarkPaymentsSealed(ctx context.Context, arg MarkPaymentsSealedParams) error
+	MarkReceiptsSealed(ctx context.Context, arg MarkReceiptsSealedParams) error
+	ResolveBankStatementLine(ctx context.Context, arg ResolveBankStatementLineParams) (BankStatementLine, error)
+	ResolveMpesaSuspense(ctx context.Context, arg ResolveMpesaSuspenseParams) (MpesaSuspense, error)
+	RetryNotification(ctx context.Context, arg RetryNotificationParams) error
+	ReviewCollectorSession(ctx context.Context, arg ReviewCollectorSessionParams) (CollectorSession, error)
+	ReviewPaymentRefund(ctx context.Context, arg ReviewPaymentRefundParams) (PaymentRefund, error)
+	SealReceiptLedgerEntries(ctx context.Context, arg SealReceiptLedgerEntriesParams) error
+	SetBankStatementMatchedCount(ctx context.Context, id uuid.UUID) (BankStatement, error)
+	SetPaymentAllocationAmount(ctx context.Context, arg SetPaymentAllocationAmountParams) error
+	// Saves the CheckoutRequestID Daraja gave an STK push on the payment it is for
+	SetPaymentExternalTransactionID(ctx context.Context, arg SetPaymentExternalTransactionIDParams) (Payment, error)
+	SetPaymentLedgerHash(ctx context.Context, arg SetPaymentLedgerHashParams) error
+	SetPaymentOutcome(ctx context.Context, arg SetPaymentOutcomeParams) (Payment, error)
+	SetReceiptLedgerHash(ctx context.Context, arg SetReceiptLedgerHashParams) error
+	SetReceiptPDF(ctx context.Context, arg SetReceiptPDFParams) (Receipt, error)
+	// How much of a payment has been allocated so far
+	SumAllocationsForPayment(ctx context.Context, paymentID uuid.UUID) (money.Amount, error)
+	// Settled per component; allocations recorded without a type count as principal
+	SumCompletedAllocationsByTypeForAssessment(ctx context.Context, assessmentID uuid.UUID) ([]SumCompletedAllocationsByTypeForAssessmentRow, error)
+	// Total settled against an assessment by completed payments
+	SumCompletedAllocationsForAssessment(ctx context.Context, assessmentID uuid.UUID) (money.Amount, error)
+	// Refunds requested against a payment but not yet reviewed
+	SumPendingRefundsForPayment(ctx context.Context, paymentID uuid.UUID) (money.Amount, error)
+	// Completed payments of a session per payment method
+	SumSessionPaymentsByMethod(ctx context.Context, sessionID uuid.UUID) ([]SumSessionPaymentsByMethodRow, error)
 	UpdatePayment(ctx context.Context, arg UpdatePaymentParams) (Payment, error)
 	UpdateReceipt(ctx context.Context, arg UpdateReceiptParams) error
+	VoidReceipt(ctx context.Context, arg VoidReceiptParams) error
 }
 
 var _ Querier = (*Queries)(nil)
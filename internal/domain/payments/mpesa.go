@@ -0,0 +1,321 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	assessmentmodels "github.com/sangkips/revenue-system/internal/domain/assessment/models"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/sangkips/revenue-system/internal/mpesa"
+)
+
+// STKPusher initiates Lipa na M-Pesa Online prompts. *mpesa.Client implements it.
+type STKPusher interface {
+	STKPush(ctx context.Context, req mpesa.STKPushRequest) (mpesa.STKPushResponse, error)
+}
+
+// WithMpesa enables STK Push payments through client.
+func WithMpesa(client STKPusher) Option {
+	return func(s *Service) {
+		s.mpesa = client
+	}
+}
+
+var ErrMpesaDisabled = errors.New("M-Pesa payments are not configured")
+
+type STKPushPaymentRequest struct {
+	AssessmentID string        `json:"assessment_id"`
+	PhoneNumber  string        `json:"phone_number"`
+	Amount       *money.Amount `json:"amount,omitempty"` // defaults to the outstanding balance
+}
+
+// InitiateSTKPush prompts the payer's phone for the assessment's outstanding
+// balance (or a part of it). The payment is recorded as processing before the
+// prompt is sent, so the money always has a payment to land on, and is
+// completed or failed when Daraja posts the result to the callback.
+func (s *Service) InitiateSTKPush(ctx context.Context, req STKPushPaymentRequest, userID string) (models.Payment, error) {
+	if s.mpesa == nil || s.uow == nil {
+		return models.Payment{}, ErrMpesaDisabled
+	}
+	if req.AssessmentID == "" || req.PhoneNumber == "" {
+		return models.Payment{}, errors.New("assessment_id and phone_number are required")
+	}
+	phone, err := mpesa.NormalizePhone(req.PhoneNumber)
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	var a assessmentmodels.Assessment
+	var outstanding money.Amount
+	err = s.uow.Do(ctx, func(st Stores) error {
+		var err error
+		if a, err = st.Assessments.GetAssessmentByID(ctx, req.AssessmentID); err != nil {
+			return err
+		}
+		paid, err := st.Payments.SumCompletedAllocationsForAssessment(ctx, a.ID)
+		if err != nil {
+			return err
+		}
+		outstanding = a.TotalAmount.Sub(paid)
+		return nil
+	})
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if err := auth.AuthorizeCounty(ctx, a.CountyID); err != nil {
+		return models.Payment{}, err
+	}
+	if a.Status == "paid" || a.Status == "rejected" {
+		return models.Payment{}, fmt.Errorf("assessment is %s and cannot take payments", a.Status)
+	}
+
+	amount := outstanding
+	if req.Amount != nil {
+		amount = *req.Amount
+	}
+	if !amount.IsPositive() || amount.Cmp(outstanding) > 0 {
+		return models.Payment{}, fmt.Errorf("amount must be positive and at most the outstanding balance of %s", outstanding)
+	}
+
+	params, err := s.paymentParams(ctx, CreatePaymentRequest{
+		CountyID:         a.CountyID,
+		TaxpayerID:       a.TaxpayerID.String(),
+		AssessmentID:     a.ID.String(),
+		Amount:           amount,
+		PaymentMethod:    "mpesa",
+		PaymentChannel:   "stk_push",
+		PayerPhoneNumber: phone,
+		Status:           "processing",
+	}, userID)
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	var payment models.Payment
+	err = s.uow.Do(ctx, func(st Stores) error {
+		params, err := s.numberPayment(ctx, st, params)
+		if err != nil {
+			return err
+		}
+		payment, err = createPayment(ctx, st, params, "M-Pesa STK push requested")
+		return err
+	})
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	push, err := s.mpesa.STKPush(ctx, mpesa.STKPushRequest{
+		PhoneNumber:      phone,
+		Amount:           amount,
+		AccountReference: a.AssessmentNumber,
+		Description:      "County revenue",
+	})
+	if err != nil {
+		s.failSTKPush(ctx, payment, err)
+		return models.Payment{}, err
+	}
+
+	completed := false
+	err = s.uow.Do(ctx, func(st Stores) error {
+		completed = false
+		if err := st.Payments.LockMpesaReference(ctx, push.CheckoutRequestID); err != nil {
+			return err
+		}
+		saved, err := st.Payments.SetPaymentCheckoutRequest(ctx, payment.ID, push.CheckoutRequestID)
+		if err != nil {
+			return err
+		}
+
+		// Daraja can post the result before the checkout request is saved; it
+		// is then waiting in suspense.
+		early, err := st.Payments.GetUnappliedSTKResultForUpdate(ctx, push.CheckoutRequestID)
+		if errors.Is(err, sql.ErrNoRows) {
+			payment = saved
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		applied, ok, err := s.applySTKResult(ctx, st, saved, stkResult(early))
+		if err != nil {
+			return err
+		}
+		_, err = st.Payments.ResolveMpesaSuspense(ctx, models.ResolveMpesaSuspenseParams{
+			Status:    "matched",
+			CountyID:  sql.NullInt32{Int32: applied.CountyID, Valid: true},
+			PaymentID: uuid.NullUUID{UUID: applied.ID, Valid: true},
+			Note:      sql.NullString{String: "Applied when the checkout request was saved", Valid: true},
+			ID:        early.ID,
+		})
+		if err != nil {
+			return err
+		}
+		payment, completed = applied, ok
+		return nil
+	})
+	if err != nil {
+		// The prompt is already on the payer's phone. Its result will find no
+		// payment and be held in suspense for someone to match.
+		log.Error().Err(err).Str("payment_id", payment.ID.String()).Str("checkout_request_id", push.CheckoutRequestID).
+			Msg("Failed to save STK checkout request on payment")
+		return payment, nil
+	}
+	if completed {
+		s.renderReceipts(ctx, payment.ID)
+	}
+	return payment, nil
+}
+
+// failSTKPush fails a payment whose prompt Daraja refused.
+func (s *Service) failSTKPush(ctx context.Context, payment models.Payment, pushErr error) {
+	err := s.uow.Do(ctx, func(st Stores) error {
+		locked, err := st.Payments.GetPaymentForUpdate(ctx, payment.ID)
+		if err != nil {
+			return err
+		}
+		_, err = setStatus(ctx, st, locked, models.SetPaymentOutcomeParams{
+			Status:        "failed",
+			FailureReason: sql.NullString{String: pushErr.Error(), Valid: true},
+		}, uuid.NullUUID{}, "M-Pesa STK push failed")
+		return err
+	})
+	if err != nil {
+		log.Error().Err(err).Str("payment_id", payment.ID.String()).Msg("Failed to fail payment after STK push error")
+	}
+}
+
+// HandleSTKCallback applies an STK Push result to the payment waiting on it.
+// A result no payment is waiting for is held in M-Pesa suspense rather than
+// dropped, so the money can still be matched. Callbacks for payments that are
+// no longer processing are ignored, so Daraja retries are harmless.
+func (s *Service) HandleSTKCallback(ctx context.Context, cb mpesa.Callback) error {
+	if s.uow == nil {
+		return ErrMpesaDisabled
+	}
+
+	var completed uuid.NullUUID
+	err := s.uow.Do(ctx, func(st Stores) error {
+		completed = uuid.NullUUID{}
+		if err := st.Payments.LockMpesaReference(ctx, cb.CheckoutRequestID); err != nil {
+			return err
+		}
+		payment, err := st.Payments.GetPaymentByExternalTransactionIDForUpdate(ctx, cb.CheckoutRequestID)
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn().Str("checkout_request_id", cb.CheckoutRequestID).Msg("STK callback for unknown checkout request held in suspense")
+			return st.Payments.CreateMpesaSuspense(ctx, stkSuspense(cb))
+		}
+		if err != nil {
+			return err
+		}
+		if payment.Status != "processing" {
+			return nil
+		}
+
+		payment, ok, err := s.applySTKResult(ctx, st, payment, cb)
+		if ok {
+			completed = uuid.NullUUID{UUID: payment.ID, Valid: true}
+		}
+		return err
+	})
+	if err == nil && completed.Valid {
+		s.renderReceipts(ctx, completed.UUID)
+	}
+	return err
+}
+
+// applySTKResult settles a processing payment with the result of its STK
+// push and reports whether the payment completed. A successful result for a
+// different amount leaves the payment processing with the mismatch as its
+// failure reason, for someone to reconcile by hand.
+func (s *Service) applySTKResult(ctx context.Context, st Stores, payment models.Payment, cb mpesa.Callback) (models.Payment, bool, error) {
+	if !cb.Succeeded() {
+		status := "failed"
+		if cb.ResultCode == mpesa.ResultCancelled {
+			status = "cancelled"
+		}
+		payment, err := setStatus(ctx, st, payment, models.SetPaymentOutcomeParams{
+			Status:        status,
+			FailureReason: sql.NullString{String: cb.ResultDesc, Valid: true},
+		}, uuid.NullUUID{}, cb.ResultDesc)
+		return payment, false, err
+	}
+
+	outcome := models.SetPaymentOutcomeParams{
+		Status:             "completed",
+		MpesaReceiptNumber: sql.NullString{String: cb.ReceiptNumber, Valid: true},
+		PayerPhoneNumber:   sql.NullString{String: cb.PhoneNumber, Valid: cb.PhoneNumber != ""},
+		PaymentDate:        sql.NullTime{Time: cb.TransactionDate, Valid: !cb.TransactionDate.IsZero()},
+	}
+	if cb.Amount.Cmp(payment.Amount) != 0 {
+		reason := fmt.Sprintf("callback amount %s does not match payment amount %s", cb.Amount, payment.Amount)
+		log.Warn().Str("payment_id", payment.ID.String()).Str("checkout_request_id", cb.CheckoutRequestID).Msg(reason)
+		outcome.Status = "processing"
+		outcome.FailureReason = sql.NullString{String: reason, Valid: true}
+		payment, err := setStatus(ctx, st, payment, outcome, uuid.NullUUID{}, reason)
+		return payment, false, err
+	}
+
+	payment, err := setStatus(ctx, st, payment, outcome, uuid.NullUUID{}, "M-Pesa payment "+cb.ReceiptNumber+" confirmed")
+	if err != nil {
+		return models.Payment{}, false, err
+	}
+	if !payment.AssessmentID.Valid {
+		return payment, true, nil
+	}
+	return payment, true, s.allocateToAssessment(ctx, st, payment)
+}
+
+// stkSuspense is the suspense entry for an STK result no payment was waiting
+// for. Only a successful result carries money to match; others are stored
+// already ignored so a late checkout request can still pick them up.
+func stkSuspense(cb mpesa.Callback) models.InsertMpesaSuspenseParams {
+	params := models.InsertMpesaSuspenseParams{
+		Source:             "stk_callback",
+		Reference:          cb.CheckoutRequestID,
+		ResultCode:         int32(cb.ResultCode),
+		ResultDesc:         sql.NullString{String: cb.ResultDesc, Valid: cb.ResultDesc != ""},
+		MpesaReceiptNumber: sql.NullString{String: cb.ReceiptNumber, Valid: cb.ReceiptNumber != ""},
+		Amount:             money.NullAmount{Amount: cb.Amount, Valid: cb.Amount.IsPositive()},
+		PhoneNumber:        sql.NullString{String: cb.PhoneNumber, Valid: cb.PhoneNumber != ""},
+		TransactionDate:    sql.NullTime{Time: cb.TransactionDate, Valid: !cb.TransactionDate.IsZero()},
+		Status:             "unmatched",
+	}
+	if !cb.Succeeded() {
+		params.Status = "ignored"
+		params.Note = sql.NullString{String: "No money was paid", Valid: true}
+	}
+	return params
+}
+
+// stkResult rebuilds the STK result held in a suspense entry.
+func stkResult(e models.MpesaSuspense) mpesa.Callback {
+	return mpesa.Callback{
+		CheckoutRequestID: e.Reference,
+		ResultCode:        int(e.ResultCode),
+		ResultDesc:        e.ResultDesc.String,
+		Amount:            e.Amount.Amount,
+		ReceiptNumber:     e.MpesaReceiptNumber.String,
+		TransactionDate:   e.TransactionDate.Time,
+		PhoneNumber:       e.PhoneNumber.String,
+	}
+}
+
+// allocateToAssessment applies payment to the assessment it was made
+// against: penalty, then interest, then principal, never more than is owed,
+// with any excess held as credit. An assessment that has been settled or
+// rejected in the meantime is left alone and the payment stays unallocated.
+func (s *Service) allocateToAssessment(ctx context.Context, st Stores, payment models.Payment) error {
+	_, err := autoAllocate(ctx, st, payment, AllocateTargeted, uuid.NullUUID{})
+	if errors.Is(err, ErrAssessmentClosed) {
+		log.Warn().Str("payment_id", payment.ID.String()).Str("assessment_id", payment.AssessmentID.UUID.String()).
+			Msg("Assessment closed before payment completed; payment left unallocated")
+		return nil
+	}
+	return err
+}
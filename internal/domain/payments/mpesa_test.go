@@ -0,0 +1,83 @@
+package payments
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	assessmentmodels "github.com/sangkips/revenue-system/internal/domain/assessment/models"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/sangkips/revenue-system/internal/mpesa"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSTKSuspenseRoundTrip(t *testing.T) {
+	paid := mpesa.Callback{
+		CheckoutRequestID: "ws_CO_191220191020363925",
+		ResultCode:        mpesa.ResultSuccess,
+		ResultDesc:        "The service request is processed successfully.",
+		Amount:            money.MustParse("1500"),
+		ReceiptNumber:     "NLJ7RT61SV",
+		TransactionDate:   time.Date(2025, 8, 14, 10, 20, 36, 0, time.UTC),
+		PhoneNumber:       "254708374149",
+	}
+	entry := stkSuspense(paid)
+	assert.Equal(t, "stk_callback", entry.Source)
+	assert.Equal(t, paid.CheckoutRequestID, entry.Reference)
+	assert.Equal(t, "unmatched", entry.Status)
+	assert.Equal(t, money.NullAmount{Amount: paid.Amount, Valid: true}, entry.Amount)
+	assert.Equal(t, paid, stkResult(models.MpesaSuspense{
+		Reference:          entry.Reference,
+		ResultCode:         entry.ResultCode,
+		ResultDesc:         entry.ResultDesc,
+		MpesaReceiptNumber: entry.MpesaReceiptNumber,
+		Amount:             entry.Amount,
+		PhoneNumber:        entry.PhoneNumber,
+		TransactionDate:    entry.TransactionDate,
+	}))
+
+	// A cancelled prompt carries no money, so there is nothing to match.
+	cancelled := stkSuspense(mpesa.Callback{
+		CheckoutRequestID: "ws_CO_191220191020363926",
+		ResultCode:        mpesa.ResultCancelled,
+		ResultDesc:        "Request cancelled by user",
+	})
+	assert.Equal(t, "ignored", cancelled.Status)
+	assert.False(t, cancelled.Amount.Valid)
+	assert.False(t, cancelled.MpesaReceiptNumber.Valid)
+}
+
+func TestSuspensePaymentParams(t *testing.T) {
+	a := assessmentmodels.Assessment{ID: uuid.New(), CountyID: 47, TaxpayerID: uuid.New()}
+	p := mpesa.C2BPayment{
+		TransID:       "RKTQDM7W6S",
+		TransTime:     time.Date(2025, 8, 14, 10, 20, 36, 0, time.UTC),
+		Amount:        money.MustParse("2500"),
+		BillRefNumber: "ASM-2025-00O12",
+		PhoneNumber:   "254708374149",
+		PayerName:     "JOHN DOE",
+	}
+	entry := c2bSuspense(p)
+	assert.Equal(t, "c2b", entry.Source)
+	assert.Equal(t, "ASM-2025-00O12", entry.AccountReference.String)
+
+	// Once matched, the paybill payment is recorded as if the account
+	// reference had been right.
+	held := models.MpesaSuspense{
+		Source:             entry.Source,
+		Reference:          entry.Reference,
+		MpesaReceiptNumber: entry.MpesaReceiptNumber,
+		Amount:             entry.Amount,
+		PhoneNumber:        entry.PhoneNumber,
+		PayerName:          entry.PayerName,
+		TransactionDate:    entry.TransactionDate,
+	}
+	assert.Equal(t, c2bPaymentParams(p, a), suspensePaymentParams(held, a))
+
+	held.Source, held.Reference = "stk_callback", "ws_CO_191220191020363925"
+	params := suspensePaymentParams(held, a)
+	assert.Equal(t, "stk_push", params.PaymentChannel.String)
+	assert.Equal(t, "ws_CO_191220191020363925", params.ExternalTransactionID.String)
+	assert.Equal(t, "RKTQDM7W6S", params.MpesaReceiptNumber.String)
+}
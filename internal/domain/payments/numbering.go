@@ -0,0 +1,51 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/numbering"
+)
+
+var errNumberingUnavailable = errors.New("document numbering is not configured; supply the number")
+
+// WithNumbering sets the scheme of the payment and receipt numbers issued
+// when a client leaves them out. numbering.DefaultScheme is used otherwise.
+func WithNumbering(scheme numbering.Scheme) Option {
+	return func(s *Service) {
+		s.numbers = scheme
+	}
+}
+
+// numberPayment fills in a payment number the client left out. It must run in
+// the transaction that inserts the payment so a rollback returns the number.
+// The series is that of the financial year the payment is recorded in; the
+// payment date comes from the client and cannot pick the year.
+func (s *Service) numberPayment(ctx context.Context, st Stores, params models.InsertPaymentParams) (models.InsertPaymentParams, error) {
+	if params.PaymentNumber != "" {
+		return params, nil
+	}
+	number, err := s.numbers.Next(ctx, st.Payments, params.CountyID, numbering.Payment, time.Now())
+	if err != nil {
+		return params, err
+	}
+	params.PaymentNumber = number
+	return params, nil
+}
+
+// numberReceipt fills in a receipt number the client left out, in the
+// series of the paying county. Like numberPayment it must share the insert's
+// transaction.
+func (s *Service) numberReceipt(ctx context.Context, st Stores, countyID int32, params models.InsertReceiptParams) (models.InsertReceiptParams, error) {
+	if params.ReceiptNumber != "" {
+		return params, nil
+	}
+	number, err := s.numbers.Next(ctx, st.Payments, countyID, numbering.Receipt, time.Now())
+	if err != nil {
+		return params, err
+	}
+	params.ReceiptNumber = number
+	return params, nil
+}
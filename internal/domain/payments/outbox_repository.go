@@ -0,0 +1,60 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/notify"
+)
+
+// outboxRepository is the notification outbox drained by notify.Dispatcher.
+type outboxRepository struct {
+	q *models.Queries
+}
+
+func NewOutboxRepository(db models.DBTX) notify.Store {
+	return &outboxRepository{q: models.New(db)}
+}
+
+func (r *outboxRepository) ClaimNotifications(ctx context.Context, claim notify.Claim) ([]notify.Outgoing, error) {
+	rows, err := r.q.ClaimNotifications(ctx, models.ClaimNotificationsParams{
+		LeaseUntil: claim.LeaseUntil,
+		Limit:      int32(claim.Limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	batch := make([]notify.Outgoing, 0, len(rows))
+	for _, row := range rows {
+		batch = append(batch, notify.Outgoing{
+			ID:          row.ID,
+			Channel:     row.Channel,
+			Message:     notify.Message{To: row.Recipient, Subject: row.Subject.String, Body: row.Body},
+			Attempts:    int(row.Attempts),
+			MaxAttempts: int(row.MaxAttempts),
+		})
+	}
+	return batch, nil
+}
+
+// MarkNotificationSent also records the delivery on the message's receipt.
+func (r *outboxRepository) MarkNotificationSent(ctx context.Context, id uuid.UUID) error {
+	return r.q.MarkNotificationSent(ctx, id)
+}
+
+func (r *outboxRepository) RetryNotification(ctx context.Context, retry notify.Retry) error {
+	return r.q.RetryNotification(ctx, models.RetryNotificationParams{
+		NextAttemptAt: retry.NextAttemptAt,
+		LastError:     sql.NullString{String: retry.LastError, Valid: true},
+		ID:            retry.ID,
+	})
+}
+
+func (r *outboxRepository) FailNotification(ctx context.Context, failure notify.Failure) error {
+	return r.q.FailNotification(ctx, models.FailNotificationParams{
+		LastError: sql.NullString{String: failure.LastError, Valid: true},
+		ID:        failure.ID,
+	})
+}
@@ -0,0 +1,62 @@
+-- Taxpayer credit ledger
+
+-- name: GetTaxpayerCounty :one
+SELECT county_id FROM taxpayers WHERE id = @id;
+
+-- name: GetAssessmentOwner :one
+SELECT county_id, taxpayer_id FROM assessments WHERE id = @id;
+
+-- name: GetTaxpayerCreditBalance :one
+SELECT taxpayer_id, county_id, balance, updated_at
+FROM taxpayer_credit_balances
+WHERE taxpayer_id = @taxpayer_id;
+
+-- Locks the balance so credit is applied or refunded one movement at a time
+-- name: GetTaxpayerCreditBalanceForUpdate :one
+SELECT taxpayer_id, county_id, balance, updated_at
+FROM taxpayer_credit_balances
+WHERE taxpayer_id = @taxpayer_id
+FOR UPDATE;
+
+-- Adds a signed amount to the balance, creating it on the first movement
+-- name: AdjustTaxpayerCreditBalance :one
+INSERT INTO taxpayer_credit_balances (taxpayer_id, county_id, balance)
+VALUES (@taxpayer_id, @county_id, @amount)
+ON CONFLICT (taxpayer_id) DO UPDATE
+SET balance = taxpayer_credit_balances.balance + EXCLUDED.balance,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING taxpayer_id, county_id, balance, updated_at;
+
+-- name: InsertCreditLedgerEntry :one
+INSERT INTO taxpayer_credit_ledger (
+    county_id, taxpayer_id, payment_id, entry_type, amount, balance_after, description, created_by
+)
+VALUES (
+    @county_id, @taxpayer_id, @payment_id, @entry_type, @amount, @balance_after, @description, @created_by
+)
+RETURNING id, county_id, taxpayer_id, payment_id, entry_type, amount, balance_after,
+    description, created_by, created_at;
+
+-- name: ListCreditLedgerEntries :many
+SELECT id, county_id, taxpayer_id, payment_id, entry_type, amount, balance_after,
+    description, created_by, created_at
+FROM taxpayer_credit_ledger
+WHERE taxpayer_id = @taxpayer_id
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- What a payment has put into credit, and how much of that was refunded
+-- name: GetPaymentCreditTotals :one
+SELECT COALESCE(SUM(amount) FILTER (WHERE entry_type <> 'refund'), 0)::numeric AS held,
+    COALESCE(-SUM(amount) FILTER (WHERE entry_type = 'refund'), 0)::numeric AS refunded
+FROM taxpayer_credit_ledger
+WHERE payment_id = @payment_id;
+
+-- Payments still holding credit for a taxpayer, oldest first, so credit is used first in, first out
+-- name: ListPaymentsWithCredit :many
+SELECT payment_id, SUM(amount)::numeric AS credit
+FROM taxpayer_credit_ledger
+WHERE taxpayer_id = @taxpayer_id
+GROUP BY payment_id
+HAVING SUM(amount) > 0
+ORDER BY MIN(created_at), payment_id;
@@ -0,0 +1,135 @@
+-- Receipt ledger
+
+-- name: LockReceiptLedger :one
+INSERT INTO receipt_ledger_heads (county_id)
+VALUES (@county_id)
+ON CONFLICT (county_id) DO UPDATE SET county_id = EXCLUDED.county_id
+RETURNING county_id, last_sequence, last_entry_hash, last_block_number, last_block_hash, updated_at;
+
+-- name: GetReceiptLedgerHead :one
+SELECT county_id, last_sequence, last_entry_hash, last_block_number, last_block_hash, updated_at
+FROM receipt_ledger_heads
+WHERE county_id = @county_id;
+
+-- name: AdvanceReceiptLedger :exec
+UPDATE receipt_ledger_heads
+SET last_sequence = @last_sequence, last_entry_hash = @last_entry_hash, updated_at = CURRENT_TIMESTAMP
+WHERE county_id = @county_id;
+
+-- name: AdvanceReceiptLedgerBlock :exec
+UPDATE receipt_ledger_heads
+SET last_block_number = @last_block_number, last_block_hash = @last_block_hash, updated_at = CURRENT_TIMESTAMP
+WHERE county_id = @county_id;
+
+-- name: InsertReceiptLedgerEntry :one
+INSERT INTO receipt_ledger_entries (county_id, sequence, receipt_id, event, record_hash, previous_hash, entry_hash)
+VALUES (@county_id, @sequence, @receipt_id, @event, @record_hash, @previous_hash, @entry_hash)
+RETURNING county_id, sequence, receipt_id, event, record_hash, previous_hash, entry_hash, block_number, created_at;
+
+-- name: CountReceiptLedgerEntries :one
+SELECT COUNT(*) FROM receipt_ledger_entries WHERE receipt_id = @receipt_id;
+
+-- name: SetReceiptLedgerHash :exec
+UPDATE receipts SET blockchain_hash = @blockchain_hash WHERE id = @id;
+
+-- name: SetPaymentLedgerHash :exec
+UPDATE payments SET blockchain_hash = @blockchain_hash
+WHERE id = @id AND blockchain_hash IS NULL;
+
+-- name: ListReceiptLedgerEntries :many
+SELECT e.sequence, e.receipt_id, e.event, e.record_hash, e.previous_hash, e.entry_hash, e.block_number,
+       (r.id IS NOT NULL)::boolean AS receipt_found,
+       COALESCE(r.receipt_number, '')::text AS receipt_number,
+       r.receipt_type,
+       COALESCE(r.blockchain_hash, '')::text AS receipt_hash,
+       r.voided_at, r.voided_by, r.void_reason,
+       r.payment_id,
+       COALESCE(p.payment_number, '')::text AS payment_number,
+       COALESCE(p.county_id, 0)::int AS payment_county_id,
+       p.taxpayer_id, p.amount, COALESCE(p.payment_method, '')::text AS payment_method,
+       p.mpesa_receipt_number, p.bank_reference, p.cheque_number, p.external_transaction_id
+FROM receipt_ledger_entries e
+LEFT JOIN receipts r ON r.id = e.receipt_id
+LEFT JOIN payments p ON p.id = r.payment_id
+WHERE e.county_id = @county_id
+  AND e.sequence > @after_sequence
+  AND (NOT @unsealed_only::boolean OR e.block_number IS NULL)
+ORDER BY e.sequence ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListReceiptLedgerCounties :many
+SELECT county_id FROM receipt_ledger_heads ORDER BY county_id;
+
+-- name: ListCountiesWithUnsealedReceipts :many
+SELECT DISTINCT county_id FROM receipt_ledger_entries
+WHERE block_number IS NULL
+ORDER BY county_id;
+
+-- name: ListUnledgeredReceipts :many
+SELECT r.id, r.receipt_number, p.county_id
+FROM receipts r
+JOIN payments p ON p.id = r.payment_id
+WHERE (sqlc.narg('county_id')::int IS NULL OR p.county_id = sqlc.narg('county_id'))
+  AND NOT EXISTS (
+      SELECT 1 FROM receipt_ledger_entries e
+      WHERE e.receipt_id = r.id AND e.event = 'issued'
+  )
+ORDER BY r.created_at ASC, r.id ASC;
+
+-- name: InsertReceiptLedgerBlock :one
+INSERT INTO receipt_ledger_blocks (
+    county_id, block_number, first_sequence, last_sequence, entry_count,
+    merkle_root, previous_block_hash, block_hash
+)
+VALUES (
+    @county_id, @block_number, @first_sequence, @last_sequence, @entry_count,
+    @merkle_root, @previous_block_hash, @block_hash
+)
+RETURNING county_id, block_number, first_sequence, last_sequence, entry_count,
+    merkle_root, previous_block_hash, block_hash, sealed_at;
+
+-- name: ListReceiptLedgerBlocks :many
+SELECT county_id, block_number, first_sequence, last_sequence, entry_count,
+       merkle_root, previous_block_hash, block_hash, sealed_at
+FROM receipt_ledger_blocks
+WHERE county_id = @county_id
+ORDER BY block_number ASC;
+
+-- name: SealReceiptLedgerEntries :exec
+UPDATE receipt_ledger_entries
+SET block_number = @block_number
+WHERE county_id = @county_id
+  AND sequence BETWEEN @first_sequence AND @last_sequence
+  AND block_number IS NULL;
+
+-- name: MarkReceiptsSealed :exec
+UPDATE receipts r
+SET block_number = e.block_number, blockchain_verified = TRUE
+FROM receipt_ledger_entries e
+WHERE e.county_id = @county_id AND e.block_number = @block_number
+  AND e.event = 'issued' AND r.id = e.receipt_id;
+
+-- name: MarkPaymentsSealed :exec
+UPDATE payments p
+SET block_number = e.block_number
+FROM receipt_ledger_entries e
+WHERE e.county_id = @county_id AND e.block_number = @block_number
+  AND e.event = 'issued' AND p.blockchain_hash = e.entry_hash;
+
+-- name: ListReceiptLedgerEntriesByReceipt :many
+SELECT e.sequence, e.receipt_id, e.event, e.record_hash, e.previous_hash, e.entry_hash, e.block_number,
+       (r.id IS NOT NULL)::boolean AS receipt_found,
+       COALESCE(r.receipt_number, '')::text AS receipt_number,
+       r.receipt_type,
+       COALESCE(r.blockchain_hash, '')::text AS receipt_hash,
+       r.voided_at, r.voided_by, r.void_reason,
+       r.payment_id,
+       COALESCE(p.payment_number, '')::text AS payment_number,
+       COALESCE(p.county_id, 0)::int AS payment_county_id,
+       p.taxpayer_id, p.amount, COALESCE(p.payment_method, '')::text AS payment_method,
+       p.mpesa_receipt_number, p.bank_reference, p.cheque_number, p.external_transaction_id
+FROM receipt_ledger_entries e
+LEFT JOIN receipts r ON r.id = e.receipt_id
+LEFT JOIN payments p ON p.id = r.payment_id
+WHERE e.receipt_id = @receipt_id
+ORDER BY e.sequence ASC;
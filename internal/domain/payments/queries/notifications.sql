@@ -0,0 +1,68 @@
+-- Notification outbox
+
+-- name: EnqueueNotification :one
+INSERT INTO notification_outbox (county_id, channel, recipient, subject, body, receipt_id, max_attempts)
+VALUES (@county_id, @channel, @recipient, @subject, @body, @receipt_id, @max_attempts)
+RETURNING id, county_id, channel, recipient, subject, body, receipt_id, status, attempts,
+    max_attempts, next_attempt_at, last_error, sent_at, created_at, updated_at;
+
+-- Takes up to limit due messages and leases them until lease_until, so a
+-- dispatcher that dies mid-send only delays them. Concurrent dispatchers
+-- skip each other's messages.
+-- name: ClaimNotifications :many
+UPDATE notification_outbox
+SET attempts = attempts + 1, next_attempt_at = @lease_until, updated_at = CURRENT_TIMESTAMP
+WHERE id IN (
+    SELECT id FROM notification_outbox
+    WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+    ORDER BY next_attempt_at ASC
+    LIMIT sqlc.arg('limit')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, county_id, channel, recipient, subject, body, receipt_id, status, attempts,
+    max_attempts, next_attempt_at, last_error, sent_at, created_at, updated_at;
+
+-- Marks a message sent and records the delivery on its receipt.
+-- name: MarkNotificationSent :exec
+WITH sent AS (
+    UPDATE notification_outbox
+    SET status = 'sent', sent_at = CURRENT_TIMESTAMP, last_error = NULL, updated_at = CURRENT_TIMESTAMP
+    WHERE id = @id AND status = 'pending'
+    RETURNING receipt_id, channel, sent_at
+)
+UPDATE receipts r
+SET sms_sent = CASE WHEN sent.channel = 'sms' THEN TRUE ELSE r.sms_sent END,
+    sms_sent_at = CASE WHEN sent.channel = 'sms' THEN sent.sent_at ELSE r.sms_sent_at END,
+    email_sent = CASE WHEN sent.channel = 'email' THEN TRUE ELSE r.email_sent END,
+    email_sent_at = CASE WHEN sent.channel = 'email' THEN sent.sent_at ELSE r.email_sent_at END
+FROM sent
+WHERE r.id = sent.receipt_id;
+
+-- name: RetryNotification :exec
+UPDATE notification_outbox
+SET next_attempt_at = @next_attempt_at, last_error = @last_error, updated_at = CURRENT_TIMESTAMP
+WHERE id = @id AND status = 'pending';
+
+-- name: FailNotification :exec
+UPDATE notification_outbox
+SET status = 'failed', last_error = @last_error, updated_at = CURRENT_TIMESTAMP
+WHERE id = @id AND status = 'pending';
+
+-- name: ListReceiptNotifications :many
+SELECT id, county_id, channel, recipient, subject, body, receipt_id, status, attempts,
+    max_attempts, next_attempt_at, last_error, sent_at, created_at, updated_at
+FROM notification_outbox
+WHERE receipt_id = @receipt_id
+ORDER BY created_at ASC;
+
+-- Who a payment's receipts go to: the number that paid, else the
+-- taxpayer's, and the taxpayer's email.
+-- name: GetPaymentContact :one
+SELECT COALESCE(NULLIF(p.payer_phone_number, ''), t.phone_number, '')::text AS phone_number,
+       COALESCE(t.email, '')::text AS email,
+       COALESCE(NULLIF(p.payer_name, ''), NULLIF(t.business_name, ''), TRIM(CONCAT(t.first_name, ' ', t.last_name)))::text AS name,
+       c.name AS county_name
+FROM payments p
+JOIN taxpayers t ON t.id = p.taxpayer_id
+JOIN counties c ON c.id = p.county_id
+WHERE p.id = @id;
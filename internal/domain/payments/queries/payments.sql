@@ -5,7 +5,7 @@ INSERT INTO payments (
     payment_channel, external_transaction_id, mpesa_receipt_number, bank_reference,
     cheque_number, payer_phone_number, payer_name, status, collected_by,
     failure_reason, collection_point, gps_coordinates, blockchain_hash, block_number,
-    reconciled, reconciliation_date, reconciled_by
+    reconciled, reconciliation_date, reconciled_by, payment_date
 )
 VALUES (
     @county_id, @taxpayer_id, @assessment_id, @payment_number, @amount, @payment_method,
@@ -14,7 +14,8 @@ VALUES (
     @failure_reason, @collection_point, 
     CASE WHEN @gps_coordinates = '' THEN NULL ELSE @gps_coordinates::point END, 
     @blockchain_hash, @block_number,
-    @reconciled, @reconciliation_date, @reconciled_by
+    @reconciled, @reconciliation_date, @reconciled_by,
+    COALESCE(sqlc.narg('payment_date'), CURRENT_TIMESTAMP)
 )
 RETURNING id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
     payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
@@ -63,8 +64,6 @@ SET
         WHEN @gps_coordinates IS NULL THEN gps_coordinates
         ELSE @gps_coordinates::point 
     END,
-    blockchain_hash = COALESCE(@blockchain_hash, blockchain_hash),
-    block_number = COALESCE(@block_number, block_number),
     reconciled = COALESCE(@reconciled, reconciled),
     reconciliation_date = COALESCE(@reconciliation_date, reconciliation_date),
     reconciled_by = COALESCE(@reconciled_by, reconciled_by),
@@ -99,6 +98,11 @@ VALUES (
 )
 RETURNING id, payment_id, assessment_id, allocated_amount, allocation_type, created_at;
 
+-- name: GetPaymentAllocationByID :one
+SELECT id, payment_id, assessment_id, allocated_amount, allocation_type, created_at
+FROM payment_allocations
+WHERE id = @id;
+
 -- name: ListPaymentAllocations :many
 SELECT id, payment_id, assessment_id, allocated_amount, allocation_type, created_at
 FROM payment_allocations
@@ -108,30 +112,69 @@ ORDER BY created_at ASC;
 -- name: DeletePaymentAllocation :exec
 DELETE FROM payment_allocations WHERE id = @id;
 
+-- Total settled against an assessment by completed payments
+-- name: SumCompletedAllocationsForAssessment :one
+SELECT COALESCE(SUM(pa.allocated_amount), 0)::numeric AS total
+FROM payment_allocations pa
+JOIN payments p ON p.id = pa.payment_id
+WHERE pa.assessment_id = @assessment_id AND p.status = 'completed';
+
+-- Settled per component; allocations recorded without a type count as principal
+-- name: SumCompletedAllocationsByTypeForAssessment :many
+SELECT COALESCE(pa.allocation_type, 'principal')::text AS allocation_type,
+    COALESCE(SUM(pa.allocated_amount), 0)::numeric AS total
+FROM payment_allocations pa
+JOIN payments p ON p.id = pa.payment_id
+WHERE pa.assessment_id = @assessment_id AND p.status = 'completed'
+GROUP BY 1;
+
+-- How much of a payment has been allocated so far
+-- name: SumAllocationsForPayment :one
+SELECT COALESCE(SUM(allocated_amount), 0)::numeric AS total
+FROM payment_allocations
+WHERE payment_id = @payment_id;
+
+-- Locks the payment so concurrent allocations cannot exceed its amount
+-- name: GetPaymentForUpdate :one
+SELECT id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
+    payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
+    status, collected_by, created_at, updated_at, mpesa_receipt_number, bank_reference,
+    cheque_number, failure_reason, collection_point, gps_coordinates, blockchain_hash,
+    block_number, reconciled, reconciliation_date, reconciled_by
+FROM payments
+WHERE id = @id
+FOR UPDATE;
+
 -- Receipts Queries
--- name: InsertReceipt :exec
+-- name: InsertReceipt :one
 INSERT INTO receipts (
-    payment_id, receipt_number, receipt_type, pdf_file_path, pdf_file_size,
+    id, payment_id, receipt_number, receipt_type, pdf_file_path, pdf_file_size,
     pdf_generated, sms_sent, sms_sent_at, email_sent, email_sent_at,
     blockchain_hash, block_number, blockchain_verified, qr_code_data
 )
 VALUES (
-    @payment_id, @receipt_number, @receipt_type, @pdf_file_path, @pdf_file_size,
+    @id, @payment_id, @receipt_number, @receipt_type, @pdf_file_path, @pdf_file_size,
     @pdf_generated, @sms_sent, @sms_sent_at, @email_sent, @email_sent_at,
     @blockchain_hash, @block_number, @blockchain_verified, @qr_code_data
-);
+)
+RETURNING id, payment_id, receipt_number, receipt_type, pdf_file_path, pdf_file_size,
+    pdf_generated, sms_sent, sms_sent_at, email_sent, email_sent_at,
+    blockchain_hash, block_number, blockchain_verified, qr_code_data, created_at,
+    voided_at, voided_by, void_reason;
 
 -- name: GetReceiptByID :one
 SELECT id, payment_id, receipt_number, receipt_type, pdf_file_path, pdf_file_size,
        pdf_generated, sms_sent, sms_sent_at, email_sent, email_sent_at,
-       blockchain_hash, block_number, blockchain_verified, qr_code_data, created_at
+       blockchain_hash, block_number, blockchain_verified, qr_code_data, created_at,
+       voided_at, voided_by, void_reason
 FROM receipts
 WHERE id = @id;
 
 -- name: ListReceiptsByPayment :many
 SELECT id, payment_id, receipt_number, receipt_type, pdf_file_path, pdf_file_size,
        pdf_generated, sms_sent, sms_sent_at, email_sent, email_sent_at,
-       blockchain_hash, block_number, blockchain_verified, qr_code_data, created_at
+       blockchain_hash, block_number, blockchain_verified, qr_code_data, created_at,
+       voided_at, voided_by, void_reason
 FROM receipts
 WHERE payment_id = @payment_id
 ORDER BY created_at ASC;
@@ -146,9 +189,57 @@ SET
     sms_sent = COALESCE(@sms_sent, sms_sent),
     sms_sent_at = COALESCE(@sms_sent_at, sms_sent_at),
     email_sent = COALESCE(@email_sent, email_sent),
-    email_sent_at = COALESCE(@email_sent_at, email_sent_at),
-    blockchain_verified = COALESCE(@blockchain_verified, blockchain_verified)
+    email_sent_at = COALESCE(@email_sent_at, email_sent_at)
 WHERE id = @id;
 
 -- name: DeleteReceipt :exec
-DELETE FROM receipts WHERE id = @id;
\ No newline at end of file
+DELETE FROM receipts WHERE id = @id;
+-- M-Pesa STK Push
+-- name: GetPaymentByExternalTransactionIDForUpdate :one
+-- Locks the payment awaiting an external confirmation, e.g. an STK callback
+SELECT id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
+    payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
+    status, collected_by, created_at, updated_at, mpesa_receipt_number, bank_reference,
+    cheque_number, failure_reason, collection_point, gps_coordinates, blockchain_hash,
+    block_number, reconciled, reconciliation_date, reconciled_by
+FROM payments
+WHERE external_transaction_id = @external_transaction_id
+FOR UPDATE;
+
+-- name: SetPaymentOutcome :one
+UPDATE payments
+SET
+    status = @status,
+    mpesa_receipt_number = COALESCE(sqlc.narg('mpesa_receipt_number'), mpesa_receipt_number),
+    payer_phone_number = COALESCE(sqlc.narg('payer_phone_number'), payer_phone_number),
+    payment_date = COALESCE(sqlc.narg('payment_date'), payment_date),
+    failure_reason = sqlc.narg('failure_reason'),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = @id
+RETURNING id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
+    payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
+    status, collected_by, created_at, updated_at, mpesa_receipt_number, bank_reference,
+    cheque_number, failure_reason, collection_point, gps_coordinates, blockchain_hash,
+    block_number, reconciled, reconciliation_date, reconciled_by;
+
+-- M-Pesa C2B paybill
+-- name: GetPaymentByMpesaReceiptNumber :one
+-- M-Pesa receipt numbers are unique, so a repeated confirmation finds the payment it already created
+SELECT id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
+    payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
+    status, collected_by, created_at, updated_at, mpesa_receipt_number, bank_reference,
+    cheque_number, failure_reason, collection_point, gps_coordinates, blockchain_hash,
+    block_number, reconciled, reconciliation_date, reconciled_by
+FROM payments
+WHERE mpesa_receipt_number = @mpesa_receipt_number;
+
+-- Saves the CheckoutRequestID Daraja gave an STK push on the payment it is for
+-- name: SetPaymentExternalTransactionID :one
+UPDATE payments
+SET external_transaction_id = @external_transaction_id, updated_at = CURRENT_TIMESTAMP
+WHERE id = @id
+RETURNING id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
+    payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
+    status, collected_by, created_at, updated_at, mpesa_receipt_number, bank_reference,
+    cheque_number, failure_reason, collection_point, gps_coordinates, blockchain_hash,
+    block_number, reconciled, reconciliation_date, reconciled_by;
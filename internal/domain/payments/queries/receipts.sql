@@ -0,0 +1,57 @@
+-- Receipt documents
+
+-- name: GetReceiptDocument :one
+SELECT r.id, r.receipt_number, r.receipt_type, r.qr_code_data, r.created_at,
+       p.id AS payment_id, p.county_id, p.payment_number, p.amount, p.payment_method,
+       p.payment_date, p.status AS payment_status, p.payer_name, p.collection_point,
+       COALESCE(p.mpesa_receipt_number, p.bank_reference, p.cheque_number, p.external_transaction_id, '')::text AS reference,
+       c.name AS county_name, c.code AS county_code,
+       COALESCE(NULLIF(t.business_name, ''), TRIM(CONCAT(t.first_name, ' ', t.last_name)))::text AS taxpayer_name,
+       t.national_id,
+       COALESCE(TRIM(CONCAT(u.first_name, ' ', u.last_name)), '')::text AS collector_name
+FROM receipts r
+JOIN payments p ON p.id = r.payment_id
+JOIN counties c ON c.id = p.county_id
+JOIN taxpayers t ON t.id = p.taxpayer_id
+LEFT JOIN users u ON u.id = p.collected_by
+WHERE r.id = @id;
+
+-- name: ListReceiptLines :many
+SELECT a.assessment_number, a.assessment_type, a.financial_year, pa.allocated_amount
+FROM payment_allocations pa
+JOIN assessments a ON a.id = pa.assessment_id
+WHERE pa.payment_id = @payment_id
+ORDER BY pa.created_at ASC, a.assessment_number ASC;
+
+-- name: ListUnrenderedReceipts :many
+SELECT id, payment_id, receipt_number, receipt_type, pdf_file_path, pdf_file_size,
+       pdf_generated, sms_sent, sms_sent_at, email_sent, email_sent_at,
+       blockchain_hash, block_number, blockchain_verified, qr_code_data, created_at,
+       voided_at, voided_by, void_reason
+FROM receipts
+WHERE payment_id = @payment_id AND pdf_generated IS NOT TRUE AND voided_at IS NULL
+ORDER BY created_at ASC;
+
+-- name: SetReceiptPDF :one
+UPDATE receipts
+SET pdf_file_path = @pdf_file_path,
+    pdf_file_size = @pdf_file_size,
+    pdf_generated = TRUE,
+    qr_code_data = @qr_code_data
+WHERE id = @id
+RETURNING id, payment_id, receipt_number, receipt_type, pdf_file_path, pdf_file_size,
+    pdf_generated, sms_sent, sms_sent_at, email_sent, email_sent_at,
+    blockchain_hash, block_number, blockchain_verified, qr_code_data, created_at,
+    voided_at, voided_by, void_reason;
+
+-- name: GetReceiptVerification :one
+-- Looks a receipt up by ID or by number for public verification.
+SELECT r.id, r.receipt_number, r.receipt_type, r.created_at, r.voided_at,
+       p.status AS payment_status, p.amount, p.payment_method, p.payment_date,
+       c.name AS county_name,
+       COALESCE(NULLIF(t.business_name, ''), TRIM(CONCAT(t.first_name, ' ', t.last_name)))::text AS taxpayer_name
+FROM receipts r
+JOIN payments p ON p.id = r.payment_id
+JOIN counties c ON c.id = p.county_id
+JOIN taxpayers t ON t.id = p.taxpayer_id
+WHERE r.id = sqlc.narg('id') OR r.receipt_number = sqlc.narg('receipt_number');
@@ -0,0 +1,113 @@
+-- name: InsertBankStatement :one
+INSERT INTO bank_statements (
+    county_id, format, file_name, file_sha256, account_number, statement_reference,
+    line_count, total_amount, imported_by
+)
+VALUES (
+    @county_id, @format, @file_name, @file_sha256, @account_number, @statement_reference,
+    @line_count, @total_amount, @imported_by
+)
+RETURNING id, county_id, format, file_name, file_sha256, account_number, statement_reference,
+    line_count, matched_count, total_amount, imported_by, imported_at;
+
+-- name: SetBankStatementMatchedCount :one
+UPDATE bank_statements
+SET matched_count = (
+    SELECT COUNT(*) FROM bank_statement_lines
+    WHERE statement_id = @id AND status = 'matched'
+)
+WHERE id = @id
+RETURNING id, county_id, format, file_name, file_sha256, account_number, statement_reference,
+    line_count, matched_count, total_amount, imported_by, imported_at;
+
+-- name: GetBankStatementByID :one
+SELECT id, county_id, format, file_name, file_sha256, account_number, statement_reference,
+    line_count, matched_count, total_amount, imported_by, imported_at
+FROM bank_statements
+WHERE id = @id;
+
+-- name: ListBankStatements :many
+SELECT id, county_id, format, file_name, file_sha256, account_number, statement_reference,
+    line_count, matched_count, total_amount, imported_by, imported_at
+FROM bank_statements
+WHERE county_id = @county_id
+ORDER BY imported_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: InsertBankStatementLine :one
+INSERT INTO bank_statement_lines (
+    statement_id, county_id, line_number, value_date, amount, reference, description
+)
+VALUES (
+    @statement_id, @county_id, @line_number, @value_date, @amount, @reference, @description
+)
+RETURNING id, statement_id, county_id, line_number, value_date, amount, reference, description,
+    status, payment_id, match_method, resolved_by, resolved_at, note, created_at;
+
+-- name: ListBankStatementLines :many
+SELECT id, statement_id, county_id, line_number, value_date, amount, reference, description,
+    status, payment_id, match_method, resolved_by, resolved_at, note, created_at
+FROM bank_statement_lines
+WHERE statement_id = @statement_id
+ORDER BY line_number;
+
+-- name: ListUnmatchedStatementLines :many
+-- The manual reconciliation queue, oldest first
+SELECT id, statement_id, county_id, line_number, value_date, amount, reference, description,
+    status, payment_id, match_method, resolved_by, resolved_at, note, created_at
+FROM bank_statement_lines
+WHERE county_id = @county_id AND status = 'unmatched'
+ORDER BY value_date, statement_id, line_number
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetBankStatementLineForUpdate :one
+SELECT id, statement_id, county_id, line_number, value_date, amount, reference, description,
+    status, payment_id, match_method, resolved_by, resolved_at, note, created_at
+FROM bank_statement_lines
+WHERE id = @id
+FOR UPDATE;
+
+-- name: ResolveBankStatementLine :one
+UPDATE bank_statement_lines
+SET
+    status = @status,
+    payment_id = sqlc.narg('payment_id'),
+    match_method = sqlc.narg('match_method'),
+    resolved_by = sqlc.narg('resolved_by'),
+    resolved_at = CURRENT_TIMESTAMP,
+    note = sqlc.narg('note')
+WHERE id = @id
+RETURNING id, statement_id, county_id, line_number, value_date, amount, reference, description,
+    status, payment_id, match_method, resolved_by, resolved_at, note, created_at;
+
+-- name: ListReconciliationCandidates :many
+-- Completed payments that no statement line has settled yet
+SELECT id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
+    payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
+    status, collected_by, created_at, updated_at, mpesa_receipt_number, bank_reference,
+    cheque_number, failure_reason, collection_point, gps_coordinates, blockchain_hash,
+    block_number, reconciled, reconciliation_date, reconciled_by
+FROM payments
+WHERE county_id = @county_id
+  AND status = 'completed'
+  AND reconciled IS NOT TRUE
+  AND amount BETWEEN sqlc.arg('min_amount')::numeric AND sqlc.arg('max_amount')::numeric
+  AND payment_date >= sqlc.arg('from_date')::timestamptz
+  AND payment_date < sqlc.arg('to_date')::timestamptz
+  AND NOT EXISTS (SELECT 1 FROM bank_statement_lines l WHERE l.payment_id = payments.id)
+ORDER BY payment_date;
+
+-- name: MarkPaymentReconciled :one
+UPDATE payments
+SET
+    reconciled = TRUE,
+    reconciliation_date = CURRENT_TIMESTAMP,
+    reconciled_by = sqlc.narg('reconciled_by'),
+    bank_reference = COALESCE(bank_reference, sqlc.narg('bank_reference')),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = @id
+RETURNING id, county_id, taxpayer_id, assessment_id, payment_number, amount, payment_method,
+    payment_channel, external_transaction_id, payer_phone_number, payer_name, payment_date,
+    status, collected_by, created_at, updated_at, mpesa_receipt_number, bank_reference,
+    cheque_number, failure_reason, collection_point, gps_coordinates, blockchain_hash,
+    block_number, reconciled, reconciliation_date, reconciled_by;
@@ -0,0 +1,91 @@
+-- Payment refunds
+
+-- name: InsertPaymentRefund :one
+INSERT INTO payment_refunds (payment_id, county_id, amount, reason, requested_by)
+VALUES (@payment_id, @county_id, @amount, @reason, @requested_by)
+RETURNING id, payment_id, county_id, amount, reason, status, requested_by, requested_at,
+    reviewed_by, reviewed_at, review_note;
+
+-- name: GetPaymentRefund :one
+SELECT id, payment_id, county_id, amount, reason, status, requested_by, requested_at,
+    reviewed_by, reviewed_at, review_note
+FROM payment_refunds
+WHERE id = @id;
+
+-- Locks the refund so it is reviewed once
+-- name: GetPaymentRefundForUpdate :one
+SELECT id, payment_id, county_id, amount, reason, status, requested_by, requested_at,
+    reviewed_by, reviewed_at, review_note
+FROM payment_refunds
+WHERE id = @id
+FOR UPDATE;
+
+-- name: ListPaymentRefunds :many
+SELECT id, payment_id, county_id, amount, reason, status, requested_by, requested_at,
+    reviewed_by, reviewed_at, review_note
+FROM payment_refunds
+WHERE payment_id = @payment_id
+ORDER BY requested_at ASC, id ASC;
+
+-- name: ListPendingRefunds :many
+SELECT id, payment_id, county_id, amount, reason, status, requested_by, requested_at,
+    reviewed_by, reviewed_at, review_note
+FROM payment_refunds
+WHERE county_id = @county_id AND status = 'pending'
+ORDER BY requested_at ASC, id ASC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- Refunds requested against a payment but not yet reviewed
+-- name: SumPendingRefundsForPayment :one
+SELECT COALESCE(SUM(amount), 0)::numeric AS total
+FROM payment_refunds
+WHERE payment_id = @payment_id AND status = 'pending';
+
+-- name: ReviewPaymentRefund :one
+UPDATE payment_refunds
+SET
+    status = @status,
+    reviewed_by = @reviewed_by,
+    reviewed_at = CURRENT_TIMESTAMP,
+    review_note = sqlc.narg('review_note')
+WHERE id = @id
+RETURNING id, payment_id, county_id, amount, reason, status, requested_by, requested_at,
+    reviewed_by, reviewed_at, review_note;
+
+-- name: InsertRefundReversal :one
+INSERT INTO payment_refund_reversals (
+    refund_id, payment_id, reversal_type, allocation_id, assessment_id, allocation_type, receipt_id, amount
+)
+VALUES (
+    @refund_id, @payment_id, @reversal_type, @allocation_id, @assessment_id, @allocation_type, @receipt_id, @amount
+)
+RETURNING id, refund_id, payment_id, reversal_type, allocation_id, assessment_id, allocation_type,
+    receipt_id, amount, created_at;
+
+-- name: ListRefundReversals :many
+SELECT id, refund_id, payment_id, reversal_type, allocation_id, assessment_id, allocation_type,
+    receipt_id, amount, created_at
+FROM payment_refund_reversals
+WHERE refund_id = @refund_id
+ORDER BY created_at ASC, id ASC;
+
+-- Newest allocations first, which is the order a refund unwinds them
+-- name: ListPaymentAllocationsForUpdate :many
+SELECT id, payment_id, assessment_id, allocated_amount, allocation_type, created_at
+FROM payment_allocations
+WHERE payment_id = @payment_id
+ORDER BY created_at DESC, id DESC
+FOR UPDATE;
+
+-- name: SetPaymentAllocationAmount :exec
+UPDATE payment_allocations
+SET allocated_amount = @allocated_amount
+WHERE id = @id;
+
+-- name: VoidReceipt :exec
+UPDATE receipts
+SET
+    voided_at = CURRENT_TIMESTAMP,
+    voided_by = @voided_by,
+    void_reason = @void_reason
+WHERE id = @id AND voided_at IS NULL;
@@ -0,0 +1,88 @@
+-- Collector sessions
+
+-- name: InsertCollectorSession :one
+INSERT INTO collector_sessions (county_id, collector_id, collection_point, opening_float)
+VALUES (@county_id, @collector_id, @collection_point, @opening_float)
+RETURNING id, county_id, collector_id, collection_point, opening_float, status, opened_at,
+    closed_at, expected_cash, declared_cash, variance, close_note, reviewed_by, reviewed_at, review_note;
+
+-- name: GetCollectorSession :one
+SELECT id, county_id, collector_id, collection_point, opening_float, status, opened_at,
+    closed_at, expected_cash, declared_cash, variance, close_note, reviewed_by, reviewed_at, review_note
+FROM collector_sessions
+WHERE id = @id;
+
+-- name: GetCollectorSessionForUpdate :one
+SELECT id, county_id, collector_id, collection_point, opening_float, status, opened_at,
+    closed_at, expected_cash, declared_cash, variance, close_note, reviewed_by, reviewed_at, review_note
+FROM collector_sessions
+WHERE id = @id
+FOR UPDATE;
+
+-- The collector's open session, share-locked so it cannot be closed while a payment is being added to it
+-- name: GetOpenCollectorSession :one
+SELECT id, county_id, collector_id, collection_point, opening_float, status, opened_at,
+    closed_at, expected_cash, declared_cash, variance, close_note, reviewed_by, reviewed_at, review_note
+FROM collector_sessions
+WHERE collector_id = @collector_id AND status = 'open'
+FOR SHARE;
+
+-- name: InsertCollectorSessionPayment :exec
+INSERT INTO collector_session_payments (session_id, payment_id)
+VALUES (@session_id, @payment_id);
+
+-- Completed payments of a session per payment method
+-- name: SumSessionPaymentsByMethod :many
+SELECT p.payment_method, COUNT(*)::int AS payments, COALESCE(SUM(p.amount), 0)::numeric AS total
+FROM collector_session_payments csp
+JOIN payments p ON p.id = csp.payment_id
+WHERE csp.session_id = @session_id AND p.status = 'completed'
+GROUP BY p.payment_method
+ORDER BY p.payment_method;
+
+-- name: CloseCollectorSession :one
+UPDATE collector_sessions
+SET status = 'closed',
+    closed_at = CURRENT_TIMESTAMP,
+    expected_cash = @expected_cash,
+    declared_cash = @declared_cash,
+    variance = @variance,
+    close_note = @close_note
+WHERE id = @id AND status = 'open'
+RETURNING id, county_id, collector_id, collection_point, opening_float, status, opened_at,
+    closed_at, expected_cash, declared_cash, variance, close_note, reviewed_by, reviewed_at, review_note;
+
+-- name: ReviewCollectorSession :one
+UPDATE collector_sessions
+SET status = @status,
+    reviewed_by = @reviewed_by,
+    reviewed_at = CURRENT_TIMESTAMP,
+    review_note = @review_note
+WHERE id = @id AND status = 'closed'
+RETURNING id, county_id, collector_id, collection_point, opening_float, status, opened_at,
+    closed_at, expected_cash, declared_cash, variance, close_note, reviewed_by, reviewed_at, review_note;
+
+-- name: ListCollectorSessions :many
+SELECT id, county_id, collector_id, collection_point, opening_float, status, opened_at,
+    closed_at, expected_cash, declared_cash, variance, close_note, reviewed_by, reviewed_at, review_note
+FROM collector_sessions
+WHERE county_id = @county_id
+    AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status'))
+ORDER BY opened_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- Closed sessions opened in a period, one row per collector and collection point
+-- name: ListZReports :many
+SELECT collector_id, collection_point,
+    COUNT(*)::int AS sessions,
+    COUNT(*) FILTER (WHERE status = 'closed')::int AS awaiting_approval,
+    COUNT(*) FILTER (WHERE status = 'rejected')::int AS rejected,
+    COALESCE(SUM(opening_float), 0)::numeric AS opening_float,
+    COALESCE(SUM(expected_cash), 0)::numeric AS expected_cash,
+    COALESCE(SUM(declared_cash), 0)::numeric AS declared_cash,
+    COALESCE(SUM(variance), 0)::numeric AS variance
+FROM collector_sessions
+WHERE county_id = @county_id AND status <> 'open'
+    AND opened_at >= @opened_from AND opened_at < @opened_to
+GROUP BY collector_id, collection_point
+ORDER BY collection_point, collector_id;
@@ -0,0 +1,12 @@
+-- Payment status history
+
+-- name: InsertPaymentStatusChange :one
+INSERT INTO payment_status_history (payment_id, from_status, to_status, reason, changed_by)
+VALUES (@payment_id, @from_status, @to_status, @reason, @changed_by)
+RETURNING id, payment_id, from_status, to_status, reason, changed_by, created_at;
+
+-- name: ListPaymentStatusHistory :many
+SELECT id, payment_id, from_status, to_status, reason, changed_by, created_at
+FROM payment_status_history
+WHERE payment_id = @payment_id
+ORDER BY created_at ASC, id ASC;
@@ -0,0 +1,60 @@
+-- M-Pesa suspense
+
+-- Stores an M-Pesa result no payment was waiting for. Retries of a result already stored are ignored.
+-- name: InsertMpesaSuspense :exec
+INSERT INTO mpesa_suspense (
+    source, reference, result_code, result_desc, mpesa_receipt_number, amount, phone_number,
+    account_reference, payer_name, transaction_date, status, note, resolved_at
+)
+VALUES (
+    @source, @reference, @result_code, @result_desc, @mpesa_receipt_number, @amount, @phone_number,
+    @account_reference, @payer_name, @transaction_date, @status, @note,
+    CASE WHEN @status::text = 'unmatched' THEN NULL ELSE CURRENT_TIMESTAMP END
+)
+ON CONFLICT (source, reference) DO NOTHING;
+
+-- name: GetMpesaSuspenseForUpdate :one
+SELECT id, county_id, source, reference, result_code, result_desc, mpesa_receipt_number, amount,
+    phone_number, account_reference, payer_name, transaction_date, status, payment_id,
+    resolved_by, resolved_at, note, created_at
+FROM mpesa_suspense
+WHERE id = @id
+FOR UPDATE;
+
+-- Serialises the STK callback for a checkout request with saving that checkout
+-- request on its payment, whichever comes first, until the transaction ends.
+-- name: LockMpesaReference :exec
+SELECT pg_advisory_xact_lock(hashtextextended(@reference::text, 0));
+
+-- Locks the STK result that arrived before its checkout request was saved on the payment.
+-- name: GetUnappliedSTKResultForUpdate :one
+SELECT id, county_id, source, reference, result_code, result_desc, mpesa_receipt_number, amount,
+    phone_number, account_reference, payer_name, transaction_date, status, payment_id,
+    resolved_by, resolved_at, note, created_at
+FROM mpesa_suspense
+WHERE source = 'stk_callback' AND reference = @reference AND status <> 'matched'
+FOR UPDATE;
+
+-- The M-Pesa suspense queue, oldest first.
+-- name: ListUnmatchedMpesaSuspense :many
+SELECT id, county_id, source, reference, result_code, result_desc, mpesa_receipt_number, amount,
+    phone_number, account_reference, payer_name, transaction_date, status, payment_id,
+    resolved_by, resolved_at, note, created_at
+FROM mpesa_suspense
+WHERE status = 'unmatched'
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ResolveMpesaSuspense :one
+UPDATE mpesa_suspense
+SET
+    status = @status,
+    county_id = COALESCE(sqlc.narg('county_id'), county_id),
+    payment_id = sqlc.narg('payment_id'),
+    resolved_by = sqlc.narg('resolved_by'),
+    resolved_at = CURRENT_TIMESTAMP,
+    note = COALESCE(sqlc.narg('note'), note)
+WHERE id = @id
+RETURNING id, county_id, source, reference, result_code, result_desc, mpesa_receipt_number, amount,
+    phone_number, account_reference, payer_name, transaction_date, status, payment_id,
+    resolved_by, resolved_at, note, created_at;
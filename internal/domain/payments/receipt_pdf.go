@@ -0,0 +1,182 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/filestore"
+	"github.com/sangkips/revenue-system/internal/receipt"
+)
+
+var (
+	ErrReceiptPDFsDisabled = errors.New("receipt PDFs are not configured")
+	ErrReceiptNotReady     = errors.New("the receipt PDF is issued once the payment completes")
+)
+
+// WithReceiptPDFs renders a PDF for every receipt of a completed payment and
+// keeps it in files.
+func WithReceiptPDFs(renderer *receipt.Renderer, files filestore.Store) Option {
+	return func(s *Service) {
+		s.pdfs = renderer
+		s.files = files
+	}
+}
+
+// renderReceipts renders the receipts of a payment that has just completed.
+// It runs after the payment is committed, so a failure is only logged; the
+// PDF is rendered again when it is first downloaded.
+func (s *Service) renderReceipts(ctx context.Context, paymentID uuid.UUID) {
+	if s.pdfs == nil {
+		return
+	}
+	receipts, err := s.repo.ListUnrenderedReceipts(ctx, paymentID)
+	if err != nil {
+		log.Error().Err(err).Str("payment_id", paymentID.String()).Msg("Failed to list receipts to render")
+		return
+	}
+	for _, r := range receipts {
+		s.renderIssued(ctx, r)
+	}
+}
+
+// renderIssued renders a receipt that has just been issued when its payment
+// is already completed, returning it with its PDF fields set.
+func (s *Service) renderIssued(ctx context.Context, r models.Receipt) models.Receipt {
+	if s.pdfs == nil {
+		return r
+	}
+	rendered, err := s.renderReceipt(ctx, r.ID)
+	if errors.Is(err, ErrReceiptNotReady) {
+		return r
+	}
+	if err != nil {
+		log.Error().Err(err).Str("receipt_id", r.ID.String()).Msg("Failed to render receipt PDF")
+		return r
+	}
+	return rendered
+}
+
+// renderReceipt lays out a receipt of a completed payment, stores the PDF and
+// records where it is.
+func (s *Service) renderReceipt(ctx context.Context, id uuid.UUID) (models.Receipt, error) {
+	row, err := s.repo.GetReceiptDocument(ctx, id)
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	if row.PaymentStatus != "completed" {
+		return models.Receipt{}, ErrReceiptNotReady
+	}
+	lines, err := s.repo.ListReceiptLines(ctx, row.PaymentID)
+	if err != nil {
+		return models.Receipt{}, err
+	}
+
+	doc := receiptDocument(row, lines)
+	if url := s.verificationURL(row.ID); url != "" && !row.QrCodeData.Valid {
+		doc.QRData = url
+	}
+	pdf, err := s.pdfs.Render(doc)
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	key := receiptKey(row.CountyID, row.ID)
+	size, err := s.files.Put(ctx, key, bytes.NewReader(pdf))
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	return s.repo.SetReceiptPDF(ctx, models.SetReceiptPDFParams{
+		ID:          row.ID,
+		PdfFilePath: sql.NullString{String: key, Valid: true},
+		PdfFileSize: sql.NullInt32{Int32: int32(size), Valid: true},
+		QrCodeData:  sql.NullString{String: doc.QRData, Valid: true},
+	})
+}
+
+// ReceiptPDF opens the PDF of a receipt, rendering it first if that has not
+// happened yet. The caller closes the file.
+func (s *Service) ReceiptPDF(ctx context.Context, paymentID, receiptID string) (io.ReadCloser, models.Receipt, error) {
+	if s.pdfs == nil {
+		return nil, models.Receipt{}, ErrReceiptPDFsDisabled
+	}
+	r, err := s.GetReceipt(ctx, receiptID)
+	if err != nil {
+		return nil, models.Receipt{}, err
+	}
+	if r.PaymentID.String() != paymentID {
+		return nil, models.Receipt{}, sql.ErrNoRows
+	}
+
+	if r.PdfGenerated.Bool && r.PdfFilePath.Valid {
+		f, err := s.files.Open(ctx, r.PdfFilePath.String)
+		if !errors.Is(err, filestore.ErrNotFound) {
+			return f, r, err
+		}
+		log.Warn().Str("receipt_id", r.ID.String()).Str("path", r.PdfFilePath.String).Msg("Receipt PDF missing from the file store; rendering it again")
+	}
+	if r.VoidedAt.Valid {
+		return nil, models.Receipt{}, fmt.Errorf("%w: the receipt has been voided", ErrReceiptNotReady)
+	}
+
+	if r, err = s.renderReceipt(ctx, r.ID); err != nil {
+		return nil, models.Receipt{}, err
+	}
+	f, err := s.files.Open(ctx, r.PdfFilePath.String)
+	return f, r, err
+}
+
+func receiptDocument(row models.GetReceiptDocumentRow, lines []models.ListReceiptLinesRow) receipt.Document {
+	doc := receipt.Document{
+		CountyName:      row.CountyName,
+		CountyCode:      row.CountyCode,
+		ReceiptNumber:   row.ReceiptNumber,
+		ReceiptType:     row.ReceiptType.String,
+		IssuedAt:        row.CreatedAt.Time,
+		PaymentNumber:   row.PaymentNumber,
+		PaymentMethod:   row.PaymentMethod,
+		Reference:       row.Reference,
+		PaidAt:          row.PaymentDate.Time,
+		Amount:          row.Amount,
+		TaxpayerName:    row.TaxpayerName,
+		TaxpayerID:      row.NationalID,
+		PayerName:       row.PayerName.String,
+		Collector:       row.CollectorName,
+		CollectionPoint: row.CollectionPoint.String,
+		QRData:          row.QrCodeData.String,
+	}
+	for _, l := range lines {
+		doc.Lines = append(doc.Lines, receipt.Line{
+			Reference:   l.AssessmentNumber,
+			Description: strings.ReplaceAll(l.AssessmentType, "_", " ") + " " + l.FinancialYear,
+			Amount:      l.AllocatedAmount,
+		})
+	}
+	if doc.QRData == "" {
+		doc.QRData = receiptQRData(doc)
+	}
+	return doc
+}
+
+// receiptQRData is what the QR code on a receipt reads back: enough for a
+// clerk to find and check the receipt.
+func receiptQRData(doc receipt.Document) string {
+	return strings.Join([]string{
+		"County Government of " + doc.CountyName,
+		"Receipt " + doc.ReceiptNumber,
+		"Payment " + doc.PaymentNumber,
+		doc.Amount.Format(),
+		doc.IssuedAt.Format("2006-01-02"),
+	}, "\n")
+}
+
+// receiptKey is where a receipt's PDF is kept in the file store.
+func receiptKey(countyID int32, receiptID uuid.UUID) string {
+	return fmt.Sprintf("receipts/%d/%s.pdf", countyID, receiptID)
+}
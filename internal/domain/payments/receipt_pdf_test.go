@@ -0,0 +1,50 @@
+package payments
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiptDocument(t *testing.T) {
+	row := models.GetReceiptDocumentRow{
+		ID:            uuid.New(),
+		ReceiptNumber: "047/RCT/2025-26/000123",
+		ReceiptType:   sql.NullString{String: "official", Valid: true},
+		CreatedAt:     sql.NullTime{Time: time.Date(2025, 8, 14, 10, 30, 0, 0, time.UTC), Valid: true},
+		CountyID:      47,
+		PaymentNumber: "047/PAY/2025-26/000456",
+		Amount:        money.MustParse("12500"),
+		PaymentMethod: "mpesa",
+		Reference:     "SGH7K2L9PQ",
+		CountyName:    "Nairobi",
+		CountyCode:    "047",
+		TaxpayerName:  "Wanjiku Traders Ltd",
+		CollectorName: "Achieng Otieno",
+	}
+	lines := []models.ListReceiptLinesRow{
+		{AssessmentNumber: "047/ASM/2025-26/000010", AssessmentType: "business_permit", FinancialYear: "2025-26", AllocatedAmount: money.MustParse("10000")},
+	}
+
+	doc := receiptDocument(row, lines)
+	assert.Equal(t, "official", doc.ReceiptType)
+	require.Len(t, doc.Lines, 1)
+	assert.Equal(t, "business permit 2025-26", doc.Lines[0].Description)
+	assert.Contains(t, doc.QRData, "Receipt 047/RCT/2025-26/000123")
+	assert.Contains(t, doc.QRData, "KES 12,500.00")
+
+	// QR data recorded with the receipt is kept.
+	row.QrCodeData = sql.NullString{String: "issued", Valid: true}
+	assert.Equal(t, "issued", receiptDocument(row, lines).QRData)
+}
+
+func TestReceiptKey(t *testing.T) {
+	id := uuid.MustParse("3f2a9c1e-0000-4000-8000-000000000001")
+	assert.Equal(t, "receipts/47/3f2a9c1e-0000-4000-8000-000000000001.pdf", receiptKey(47, id))
+}
@@ -0,0 +1,362 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/bankstatement"
+	"github.com/sangkips/revenue-system/internal/db"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
+	"github.com/sangkips/revenue-system/internal/money"
+)
+
+// WithMatchTolerance sets how far statement lines may differ from payments
+// and still be matched automatically. Imports may narrow or widen it.
+func WithMatchTolerance(tol bankstatement.Tolerance) Option {
+	return func(s *Service) {
+		s.tolerance = tol
+	}
+}
+
+var (
+	ErrStatementImported = errors.New("this statement has already been imported")
+	ErrLineResolved      = errors.New("statement line has already been resolved")
+	// ErrReconcileThroughStatements rejects setting the reconciliation fields
+	// of a payment by hand.
+	ErrReconcileThroughStatements = errors.New("payments are reconciled by matching bank statement lines under /reconciliation, not by updating them")
+)
+
+type ImportStatementRequest struct {
+	CountyID        int32
+	Format          string // detected from the file when empty
+	FileName        string
+	Data            []byte
+	AmountTolerance *money.Amount
+	DateWindowDays  *int
+}
+
+type ImportStatementResult struct {
+	Statement models.BankStatement       `json:"statement"`
+	Lines     []models.BankStatementLine `json:"lines"`
+}
+
+type MatchStatementLineRequest struct {
+	PaymentID string `json:"payment_id"`
+	Note      string `json:"note,omitempty"`
+}
+
+type IgnoreStatementLineRequest struct {
+	Note string `json:"note"` // why the line needs no payment, e.g. "interest credit"
+}
+
+// ImportStatement stores a bank statement and matches each credit against
+// the county's completed, unreconciled payments. Matched payments are marked
+// reconciled; the remaining lines wait in the unmatched queue. Importing the
+// same file twice is refused.
+func (s *Service) ImportStatement(ctx context.Context, req ImportStatementRequest, userID string) (ImportStatementResult, error) {
+	if s.uow == nil {
+		return ImportStatementResult{}, errors.New("reconciliation is not configured")
+	}
+	countyID, err := auth.ResolveCounty(ctx, req.CountyID)
+	if err != nil {
+		return ImportStatementResult{}, err
+	}
+
+	format := bankstatement.Format("")
+	if req.Format != "" {
+		format, err = bankstatement.ParseFormat(req.Format)
+	} else {
+		format, err = bankstatement.DetectFormat(req.FileName, req.Data)
+	}
+	if err != nil {
+		return ImportStatementResult{}, err
+	}
+	st, err := bankstatement.Parse(format, bytes.NewReader(req.Data))
+	if err != nil {
+		return ImportStatementResult{}, err
+	}
+
+	tol := s.tolerance
+	if req.AmountTolerance != nil {
+		if req.AmountTolerance.IsNegative() {
+			return ImportStatementResult{}, errors.New("amount_tolerance must not be negative")
+		}
+		tol.Amount = *req.AmountTolerance
+	}
+	if req.DateWindowDays != nil {
+		if *req.DateWindowDays < 0 {
+			return ImportStatementResult{}, errors.New("date_window_days must not be negative")
+		}
+		tol.Days = *req.DateWindowDays
+	}
+
+	sum := sha256.Sum256(req.Data)
+	importedBy := nullUUID(userID)
+
+	var result ImportStatementResult
+	err = s.uow.Do(ctx, func(stores Stores) error {
+		result = ImportStatementResult{}
+
+		statement, err := stores.Payments.CreateBankStatement(ctx, models.InsertBankStatementParams{
+			CountyID:           countyID,
+			Format:             string(st.Format),
+			FileName:           sql.NullString{String: req.FileName, Valid: req.FileName != ""},
+			FileSha256:         hex.EncodeToString(sum[:]),
+			AccountNumber:      sql.NullString{String: st.AccountNumber, Valid: st.AccountNumber != ""},
+			StatementReference: sql.NullString{String: st.Reference, Valid: st.Reference != ""},
+			LineCount:          int32(len(st.Lines)),
+			TotalAmount:        st.Total(),
+			ImportedBy:         importedBy,
+		})
+		if err != nil {
+			return err
+		}
+
+		taken := map[uuid.UUID]bool{} // payments matched earlier in this statement
+		for _, l := range st.Lines {
+			line, err := stores.Payments.CreateBankStatementLine(ctx, models.InsertBankStatementLineParams{
+				StatementID: statement.ID,
+				CountyID:    countyID,
+				LineNumber:  int32(l.Number),
+				ValueDate:   l.ValueDate,
+				Amount:      l.Amount,
+				Reference:   l.Reference,
+				Description: l.Description,
+			})
+			if err != nil {
+				return err
+			}
+
+			payment, kind, err := matchLine(ctx, stores.Payments, countyID, l, tol, taken)
+			if err != nil {
+				return err
+			}
+			if kind != bankstatement.MatchNone {
+				taken[payment.ID] = true
+				if line, err = reconcileLine(ctx, stores.Payments, line, payment, string(kind), "", importedBy); err != nil {
+					return err
+				}
+			}
+			result.Lines = append(result.Lines, line)
+		}
+
+		result.Statement, err = stores.Payments.RefreshBankStatementMatchedCount(ctx, statement.ID)
+		return err
+	})
+	if db.IsUniqueViolation(err) {
+		return ImportStatementResult{}, ErrStatementImported
+	}
+	if err != nil {
+		return ImportStatementResult{}, err
+	}
+	return result, nil
+}
+
+// matchLine finds the payment a statement line settles, skipping payments
+// already taken by earlier lines of the same statement.
+func matchLine(ctx context.Context, repo Repository, countyID int32, l bankstatement.Line, tol bankstatement.Tolerance, taken map[uuid.UUID]bool) (models.Payment, bankstatement.MatchKind, error) {
+	lo, hi := tol.AmountRange(l.Amount)
+	from, to := tol.DateRange(l.ValueDate)
+	payments, err := repo.ListReconciliationCandidates(ctx, models.ListReconciliationCandidatesParams{
+		CountyID:  countyID,
+		MinAmount: lo,
+		MaxAmount: hi,
+		FromDate:  from,
+		ToDate:    to,
+	})
+	if err != nil {
+		return models.Payment{}, bankstatement.MatchNone, err
+	}
+
+	var open []models.Payment
+	var candidates []bankstatement.Candidate
+	for _, p := range payments {
+		if taken[p.ID] {
+			continue
+		}
+		open = append(open, p)
+		candidates = append(candidates, bankstatement.Candidate{
+			Amount: p.Amount,
+			Date:   p.PaymentDate.Time,
+			References: []string{
+				p.PaymentNumber,
+				p.BankReference.String,
+				p.MpesaReceiptNumber.String,
+				p.ExternalTransactionID.String,
+				p.ChequeNumber.String,
+			},
+		})
+	}
+
+	i, kind := bankstatement.Match(l, candidates, tol)
+	if kind == bankstatement.MatchNone {
+		return models.Payment{}, kind, nil
+	}
+	return open[i], kind, nil
+}
+
+// reconcileLine ties line to payment and marks the payment reconciled. The
+// statement reference is kept as the payment's bank reference if it has none.
+func reconcileLine(ctx context.Context, repo Repository, line models.BankStatementLine, payment models.Payment, method, note string, userID uuid.NullUUID) (models.BankStatementLine, error) {
+	_, err := repo.MarkPaymentReconciled(ctx, models.MarkPaymentReconciledParams{
+		ReconciledBy:  userID,
+		BankReference: sql.NullString{String: line.Reference, Valid: line.Reference != ""},
+		ID:            payment.ID,
+	})
+	if err != nil {
+		return models.BankStatementLine{}, err
+	}
+	return repo.ResolveBankStatementLine(ctx, models.ResolveBankStatementLineParams{
+		Status:      "matched",
+		PaymentID:   uuid.NullUUID{UUID: payment.ID, Valid: true},
+		MatchMethod: sql.NullString{String: method, Valid: true},
+		ResolvedBy:  userID,
+		Note:        sql.NullString{String: note, Valid: note != ""},
+		ID:          line.ID,
+	})
+}
+
+// MatchStatementLine resolves an unmatched line by hand against a completed
+// payment of the same county that no other line has settled.
+func (s *Service) MatchStatementLine(ctx context.Context, lineID string, req MatchStatementLineRequest, userID string) (models.BankStatementLine, error) {
+	if s.uow == nil {
+		return models.BankStatementLine{}, errors.New("reconciliation is not configured")
+	}
+	if req.PaymentID == "" {
+		return models.BankStatementLine{}, errors.New("payment_id is required")
+	}
+
+	var resolved models.BankStatementLine
+	err := s.uow.Do(ctx, func(st Stores) error {
+		line, err := s.lockUnmatchedLine(ctx, st, lineID)
+		if err != nil {
+			return err
+		}
+
+		payment, err := st.Payments.GetPaymentByID(ctx, req.PaymentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("payment %s not found", req.PaymentID)
+		}
+		if err != nil {
+			return err
+		}
+		if payment.CountyID != line.CountyID {
+			return auth.ErrCountyForbidden
+		}
+		if payment.Status != "completed" {
+			return fmt.Errorf("payment is %s; only completed payments can be reconciled", payment.Status)
+		}
+		if payment.Reconciled.Bool {
+			return errors.New("payment has already been reconciled")
+		}
+
+		resolved, err = reconcileLine(ctx, st.Payments, line, payment, "manual", req.Note, nullUUID(userID))
+		if err != nil {
+			return err
+		}
+		_, err = st.Payments.RefreshBankStatementMatchedCount(ctx, line.StatementID)
+		return err
+	})
+	if db.IsUniqueViolation(err) {
+		return models.BankStatementLine{}, errors.New("payment is already matched to another statement line")
+	}
+	return resolved, err
+}
+
+// IgnoreStatementLine takes a line that no payment accounts for, such as
+// interest or a transfer between county accounts, out of the queue.
+func (s *Service) IgnoreStatementLine(ctx context.Context, lineID string, req IgnoreStatementLineRequest, userID string) (models.BankStatementLine, error) {
+	if s.uow == nil {
+		return models.BankStatementLine{}, errors.New("reconciliation is not configured")
+	}
+	if req.Note == "" {
+		return models.BankStatementLine{}, errors.New("note is required when ignoring a statement line")
+	}
+
+	var resolved models.BankStatementLine
+	err := s.uow.Do(ctx, func(st Stores) error {
+		line, err := s.lockUnmatchedLine(ctx, st, lineID)
+		if err != nil {
+			return err
+		}
+		resolved, err = st.Payments.ResolveBankStatementLine(ctx, models.ResolveBankStatementLineParams{
+			Status:     "ignored",
+			ResolvedBy: nullUUID(userID),
+			Note:       sql.NullString{String: req.Note, Valid: true},
+			ID:         line.ID,
+		})
+		return err
+	})
+	return resolved, err
+}
+
+func (s *Service) lockUnmatchedLine(ctx context.Context, st Stores, lineID string) (models.BankStatementLine, error) {
+	line, err := st.Payments.GetBankStatementLineForUpdate(ctx, lineID)
+	if err != nil {
+		return models.BankStatementLine{}, err
+	}
+	if err := auth.AuthorizeCounty(ctx, line.CountyID); err != nil {
+		return models.BankStatementLine{}, err
+	}
+	if line.Status != "unmatched" {
+		return models.BankStatementLine{}, ErrLineResolved
+	}
+	return line, nil
+}
+
+// ListUnmatchedStatementLines is the queue of statement credits still
+// waiting for a person to match or ignore them, oldest first.
+func (s *Service) ListUnmatchedStatementLines(ctx context.Context, countyID int32, limit int32, offset int32) ([]models.BankStatementLine, error) {
+	countyID, err := auth.ResolveCounty(ctx, countyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListUnmatchedStatementLines(ctx, models.ListUnmatchedStatementLinesParams{
+		CountyID: countyID,
+		Limit:    limit,
+		Offset:   offset,
+	})
+}
+
+func (s *Service) ListBankStatements(ctx context.Context, countyID int32, limit int32, offset int32) ([]models.BankStatement, error) {
+	countyID, err := auth.ResolveCounty(ctx, countyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListBankStatements(ctx, models.ListBankStatementsParams{
+		CountyID: countyID,
+		Limit:    limit,
+		Offset:   offset,
+	})
+}
+
+// GetBankStatement returns a statement with all of its lines.
+func (s *Service) GetBankStatement(ctx context.Context, id string) (ImportStatementResult, error) {
+	statement, err := s.repo.GetBankStatementByID(ctx, id)
+	if err != nil {
+		return ImportStatementResult{}, err
+	}
+	if err := auth.AuthorizeCounty(ctx, statement.CountyID); err != nil {
+		return ImportStatementResult{}, err
+	}
+
+	lines, err := s.repo.ListBankStatementLines(ctx, statement.ID)
+	if err != nil {
+		return ImportStatementResult{}, err
+	}
+	return ImportStatementResult{Statement: statement, Lines: lines}, nil
+}
+
+func nullUUID(id string) uuid.NullUUID {
+	parsed, err := uuid.Parse(id)
+	return uuid.NullUUID{UUID: parsed, Valid: err == nil}
+}
@@ -0,0 +1,370 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
+	"github.com/sangkips/revenue-system/internal/money"
+)
+
+// A refund gives back part or all of a completed payment. It is requested by
+// one user and approved by another; only approval moves money. The refund is
+// taken from the credit the payment still holds first, then by cutting back
+// its allocations newest first, which reopens any assessment they had
+// settled. A payment refunded in full is marked refunded and its receipts are
+// voided. Everything undone is recorded against the refund.
+
+var (
+	ErrPaymentNotRefundable = errors.New("only completed payments can be refunded")
+	ErrRefundExceedsPayment = errors.New("refund exceeds what is left of the payment")
+	ErrRefundReviewed       = errors.New("refund has already been reviewed")
+	ErrSelfApproval         = errors.New("a refund must be reviewed by someone other than its requester")
+	ErrRefundThroughRequest = errors.New("payments are refunded through a refund request")
+)
+
+type RequestRefundRequest struct {
+	Amount money.Amount `json:"amount"`
+	Reason string       `json:"reason"`
+}
+
+type ReviewRefundRequest struct {
+	Note string `json:"note,omitempty"` // required to reject
+}
+
+type RefundDetail struct {
+	Refund    models.PaymentRefund           `json:"refund"`
+	Reversals []models.PaymentRefundReversal `json:"reversals"`
+}
+
+// allocationCut is how much a refund takes back from one allocation.
+type allocationCut struct {
+	allocation models.PaymentAllocation
+	amount     money.Amount
+}
+
+// planRefund splits amount between the credit a payment holds and its
+// allocations, which must be ordered newest first. It returns what comes out
+// of credit and the cut to each allocation; anything left over is more than
+// the payment can give back.
+func planRefund(amount, credit money.Amount, allocations []models.PaymentAllocation) (money.Amount, []allocationCut) {
+	fromCredit := money.Min(amount, money.Max(credit, money.Zero))
+	amount = amount.Sub(fromCredit)
+
+	var cuts []allocationCut
+	for _, allocation := range allocations {
+		if !amount.IsPositive() {
+			break
+		}
+		n := money.Min(amount, allocation.AllocatedAmount)
+		cuts = append(cuts, allocationCut{allocation: allocation, amount: n})
+		amount = amount.Sub(n)
+	}
+	return fromCredit, cuts
+}
+
+// refundable is how much of a locked payment can still be refunded: its
+// amount less what has been paid back, and less refunds awaiting review when
+// pending is set.
+func refundable(ctx context.Context, st Stores, payment models.Payment, pending bool) (money.Amount, error) {
+	credit, err := st.Payments.GetPaymentCreditTotals(ctx, payment.ID)
+	if err != nil {
+		return money.Zero, err
+	}
+	left := payment.Amount.Sub(credit.Refunded)
+	if pending {
+		requested, err := st.Payments.SumPendingRefundsForPayment(ctx, payment.ID)
+		if err != nil {
+			return money.Zero, err
+		}
+		left = left.Sub(requested)
+	}
+	return left, nil
+}
+
+// RequestRefund opens a refund against a completed payment for approval.
+func (s *Service) RequestRefund(ctx context.Context, paymentID string, req RequestRefundRequest, userID string) (models.PaymentRefund, error) {
+	if s.uow == nil {
+		return models.PaymentRefund{}, errors.New("refunds are not configured")
+	}
+	if !req.Amount.IsPositive() || req.Reason == "" {
+		return models.PaymentRefund{}, errors.New("a positive amount and a reason are required")
+	}
+	id, err := uuid.Parse(paymentID)
+	if err != nil {
+		return models.PaymentRefund{}, err
+	}
+	requestedBy, err := uuid.Parse(userID)
+	if err != nil {
+		return models.PaymentRefund{}, err
+	}
+
+	var refund models.PaymentRefund
+	err = s.uow.Do(ctx, func(st Stores) error {
+		payment, err := st.Payments.GetPaymentForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := auth.AuthorizeCounty(ctx, payment.CountyID); err != nil {
+			return err
+		}
+		if payment.Status != "completed" {
+			return fmt.Errorf("%w: payment is %s", ErrPaymentNotRefundable, payment.Status)
+		}
+		left, err := refundable(ctx, st, payment, true)
+		if err != nil {
+			return err
+		}
+		if req.Amount.Cmp(left) > 0 {
+			return fmt.Errorf("%w: %s can still be refunded", ErrRefundExceedsPayment, money.Max(left, money.Zero))
+		}
+
+		refund, err = st.Payments.CreatePaymentRefund(ctx, models.InsertPaymentRefundParams{
+			PaymentID:   payment.ID,
+			CountyID:    payment.CountyID,
+			Amount:      req.Amount,
+			Reason:      req.Reason,
+			RequestedBy: requestedBy,
+		})
+		return err
+	})
+	if err != nil {
+		return models.PaymentRefund{}, err
+	}
+	return refund, nil
+}
+
+// lockPendingRefund locks a refund for review by userID.
+func lockPendingRefund(ctx context.Context, st Stores, id string, userID uuid.UUID) (models.PaymentRefund, error) {
+	refund, err := st.Payments.GetPaymentRefundForUpdate(ctx, id)
+	if err != nil {
+		return models.PaymentRefund{}, err
+	}
+	if err := auth.AuthorizeCounty(ctx, refund.CountyID); err != nil {
+		return models.PaymentRefund{}, err
+	}
+	if refund.Status != "pending" {
+		return models.PaymentRefund{}, fmt.Errorf("%w: it was %s", ErrRefundReviewed, refund.Status)
+	}
+	if refund.RequestedBy == userID {
+		return models.PaymentRefund{}, ErrSelfApproval
+	}
+	return refund, nil
+}
+
+// ApproveRefund pays the refund out of the payment, reversing whatever that
+// takes, and records the approval.
+func (s *Service) ApproveRefund(ctx context.Context, id string, req ReviewRefundRequest, userID string) (RefundDetail, error) {
+	if s.uow == nil {
+		return RefundDetail{}, errors.New("refunds are not configured")
+	}
+	reviewer, err := uuid.Parse(userID)
+	if err != nil {
+		return RefundDetail{}, err
+	}
+
+	var detail RefundDetail
+	err = s.uow.Do(ctx, func(st Stores) error {
+		detail = RefundDetail{}
+
+		refund, err := lockPendingRefund(ctx, st, id, reviewer)
+		if err != nil {
+			return err
+		}
+		payment, err := st.Payments.GetPaymentForUpdate(ctx, refund.PaymentID)
+		if err != nil {
+			return err
+		}
+		if payment.Status != "completed" {
+			return fmt.Errorf("%w: payment is %s", ErrPaymentNotRefundable, payment.Status)
+		}
+		left, err := refundable(ctx, st, payment, false)
+		if err != nil {
+			return err
+		}
+		if refund.Amount.Cmp(left) > 0 {
+			return fmt.Errorf("%w: %s can still be refunded", ErrRefundExceedsPayment, left)
+		}
+
+		credit, err := st.Payments.GetPaymentCreditTotals(ctx, payment.ID)
+		if err != nil {
+			return err
+		}
+		allocations, err := st.Payments.ListPaymentAllocationsForUpdate(ctx, payment.ID)
+		if err != nil {
+			return err
+		}
+		fromCredit, cuts := planRefund(refund.Amount, credit.Held.Sub(credit.Refunded), allocations)
+
+		reverse := func(params models.InsertRefundReversalParams) error {
+			params.RefundID, params.PaymentID = refund.ID, payment.ID
+			reversal, err := st.Payments.CreateRefundReversal(ctx, params)
+			if err != nil {
+				return err
+			}
+			detail.Reversals = append(detail.Reversals, reversal)
+			return nil
+		}
+
+		if fromCredit.IsPositive() {
+			err := reverse(models.InsertRefundReversalParams{
+				ReversalType: "credit",
+				Amount:       money.NullAmount{Amount: fromCredit, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		for _, cut := range cuts {
+			if err := unallocate(ctx, st, cut.allocation, cut.amount); err != nil {
+				return err
+			}
+			err := reverse(models.InsertRefundReversalParams{
+				ReversalType:   "allocation",
+				AllocationID:   uuid.NullUUID{UUID: cut.allocation.ID, Valid: true},
+				AssessmentID:   uuid.NullUUID{UUID: cut.allocation.AssessmentID, Valid: true},
+				AllocationType: cut.allocation.AllocationType,
+				Amount:         money.NullAmount{Amount: cut.amount, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// What the reversed allocations released passes through the payer's
+		// credit on its way out, so the ledger shows the whole movement.
+		if len(cuts) > 0 {
+			if err := syncPaymentCredit(ctx, st, payment, "allocations reversed for refund"); err != nil {
+				return err
+			}
+		}
+		if _, err := moveCredit(ctx, st, payment, "refund", refund.Amount.Neg(), refund.Reason, uuid.NullUUID{UUID: reviewer, Valid: true}); err != nil {
+			return err
+		}
+
+		if refund.Amount.Cmp(left) == 0 {
+			_, err := setStatus(ctx, st, payment, models.SetPaymentOutcomeParams{
+				Status: "refunded",
+			}, uuid.NullUUID{UUID: reviewer, Valid: true}, "refund approved: "+refund.Reason)
+			if err != nil {
+				return err
+			}
+			receipts, err := st.Payments.ListReceiptsByPayment(ctx, payment.ID.String())
+			if err != nil {
+				return err
+			}
+			for _, receipt := range receipts {
+				if receipt.VoidedAt.Valid {
+					continue
+				}
+				err := st.Payments.VoidReceipt(ctx, models.VoidReceiptParams{
+					ID:         receipt.ID,
+					VoidedBy:   uuid.NullUUID{UUID: reviewer, Valid: true},
+					VoidReason: sql.NullString{String: "payment refunded: " + refund.Reason, Valid: true},
+				})
+				if err != nil {
+					return err
+				}
+				if err := ledgerVoid(ctx, st, payment.CountyID, receipt.ID); err != nil {
+					return err
+				}
+				err = reverse(models.InsertRefundReversalParams{
+					ReversalType: "receipt",
+					ReceiptID:    uuid.NullUUID{UUID: receipt.ID, Valid: true},
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		detail.Refund, err = st.Payments.ReviewPaymentRefund(ctx, models.ReviewPaymentRefundParams{
+			ID:         refund.ID,
+			Status:     "approved",
+			ReviewedBy: uuid.NullUUID{UUID: reviewer, Valid: true},
+			ReviewNote: sql.NullString{String: req.Note, Valid: req.Note != ""},
+		})
+		return err
+	})
+	if err != nil {
+		return RefundDetail{}, err
+	}
+	return detail, nil
+}
+
+// RejectRefund closes a refund request without moving any money.
+func (s *Service) RejectRefund(ctx context.Context, id string, req ReviewRefundRequest, userID string) (models.PaymentRefund, error) {
+	if s.uow == nil {
+		return models.PaymentRefund{}, errors.New("refunds are not configured")
+	}
+	if req.Note == "" {
+		return models.PaymentRefund{}, errors.New("a note explaining the rejection is required")
+	}
+	reviewer, err := uuid.Parse(userID)
+	if err != nil {
+		return models.PaymentRefund{}, err
+	}
+
+	var refund models.PaymentRefund
+	err = s.uow.Do(ctx, func(st Stores) error {
+		pending, err := lockPendingRefund(ctx, st, id, reviewer)
+		if err != nil {
+			return err
+		}
+		refund, err = st.Payments.ReviewPaymentRefund(ctx, models.ReviewPaymentRefundParams{
+			ID:         pending.ID,
+			Status:     "rejected",
+			ReviewedBy: uuid.NullUUID{UUID: reviewer, Valid: true},
+			ReviewNote: sql.NullString{String: req.Note, Valid: true},
+		})
+		return err
+	})
+	if err != nil {
+		return models.PaymentRefund{}, err
+	}
+	return refund, nil
+}
+
+// GetRefund returns a refund with everything its approval reversed.
+func (s *Service) GetRefund(ctx context.Context, id string) (RefundDetail, error) {
+	refund, err := s.repo.GetPaymentRefund(ctx, id)
+	if err != nil {
+		return RefundDetail{}, err
+	}
+	if err := auth.AuthorizeCounty(ctx, refund.CountyID); err != nil {
+		return RefundDetail{}, err
+	}
+
+	reversals, err := s.repo.ListRefundReversals(ctx, refund.ID)
+	if err != nil {
+		return RefundDetail{}, err
+	}
+	return RefundDetail{Refund: refund, Reversals: reversals}, nil
+}
+
+func (s *Service) ListPaymentRefunds(ctx context.Context, paymentID string) ([]models.PaymentRefund, error) {
+	payment, err := s.GetPayment(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListPaymentRefunds(ctx, payment.ID)
+}
+
+// ListPendingRefunds is the county's queue of refunds awaiting approval,
+// oldest first.
+func (s *Service) ListPendingRefunds(ctx context.Context, countyID int32, limit int32, offset int32) ([]models.PaymentRefund, error) {
+	countyID, err := auth.ResolveCounty(ctx, countyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListPendingRefunds(ctx, models.ListPendingRefundsParams{
+		CountyID: countyID,
+		Limit:    limit,
+		Offset:   offset,
+	})
+}
@@ -0,0 +1,42 @@
+package payments
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanRefund(t *testing.T) {
+	newer := models.PaymentAllocation{ID: uuid.New(), AllocatedAmount: money.MustParse("300")}
+	older := models.PaymentAllocation{ID: uuid.New(), AllocatedAmount: money.MustParse("500")}
+
+	tests := []struct {
+		name       string
+		amount     string
+		credit     string
+		fromCredit string
+		cuts       []string
+	}{
+		{name: "credit covers it", amount: "150", credit: "200", fromCredit: "150.00"},
+		{name: "credit then newest allocation", amount: "350", credit: "200", fromCredit: "200.00", cuts: []string{"150.00"}},
+		{name: "whole payment", amount: "1000", credit: "200", fromCredit: "200.00", cuts: []string{"300.00", "500.00"}},
+		{name: "no credit", amount: "400", credit: "0", fromCredit: "0.00", cuts: []string{"300.00", "100.00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fromCredit, cuts := planRefund(money.MustParse(tt.amount), money.MustParse(tt.credit), []models.PaymentAllocation{newer, older})
+			assert.Equal(t, tt.fromCredit, fromCredit.String())
+			require.Len(t, cuts, len(tt.cuts))
+			for i, want := range tt.cuts {
+				assert.Equal(t, want, cuts[i].amount.String(), i)
+			}
+			if len(cuts) > 0 {
+				assert.Equal(t, newer.ID, cuts[0].allocation.ID)
+			}
+		})
+	}
+}
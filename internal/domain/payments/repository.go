@@ -2,9 +2,12 @@ package payments
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/sangkips/revenue-system/internal/numbering"
 )
 
 type Repository interface {
@@ -17,23 +20,131 @@ type Repository interface {
 
 	// Payment Allocations
 	CreatePaymentAllocation(ctx context.Context, allocation models.InsertPaymentAllocationParams) (models.PaymentAllocation, error)
+	GetPaymentAllocationByID(ctx context.Context, id string) (models.PaymentAllocation, error)
 	ListPaymentAllocations(ctx context.Context, paymentID string) ([]models.PaymentAllocation, error)
 	DeletePaymentAllocation(ctx context.Context, id string) error
+	SumCompletedAllocationsForAssessment(ctx context.Context, assessmentID uuid.UUID) (money.Amount, error)
+	SumCompletedAllocationsByTypeForAssessment(ctx context.Context, assessmentID uuid.UUID) ([]models.SumCompletedAllocationsByTypeForAssessmentRow, error)
+	SumAllocationsForPayment(ctx context.Context, paymentID uuid.UUID) (money.Amount, error)
+	GetPaymentForUpdate(ctx context.Context, id uuid.UUID) (models.Payment, error)
+
+	// M-Pesa
+	GetPaymentByExternalTransactionIDForUpdate(ctx context.Context, externalTransactionID string) (models.Payment, error)
+	SetPaymentOutcome(ctx context.Context, params models.SetPaymentOutcomeParams) (models.Payment, error)
+	GetPaymentByMpesaReceiptNumber(ctx context.Context, receiptNumber string) (models.Payment, error)
+	SetPaymentCheckoutRequest(ctx context.Context, id uuid.UUID, checkoutRequestID string) (models.Payment, error)
+
+	// M-Pesa suspense
+	CreateMpesaSuspense(ctx context.Context, params models.InsertMpesaSuspenseParams) error
+	GetMpesaSuspenseForUpdate(ctx context.Context, id string) (models.MpesaSuspense, error)
+	GetUnappliedSTKResultForUpdate(ctx context.Context, checkoutRequestID string) (models.MpesaSuspense, error)
+	LockMpesaReference(ctx context.Context, reference string) error
+	ListUnmatchedMpesaSuspense(ctx context.Context, params models.ListUnmatchedMpesaSuspenseParams) ([]models.MpesaSuspense, error)
+	ResolveMpesaSuspense(ctx context.Context, params models.ResolveMpesaSuspenseParams) (models.MpesaSuspense, error)
+
+	// Reconciliation
+	CreateBankStatement(ctx context.Context, params models.InsertBankStatementParams) (models.BankStatement, error)
+	GetBankStatementByID(ctx context.Context, id string) (models.BankStatement, error)
+	ListBankStatements(ctx context.Context, params models.ListBankStatementsParams) ([]models.BankStatement, error)
+	RefreshBankStatementMatchedCount(ctx context.Context, id uuid.UUID) (models.BankStatement, error)
+	CreateBankStatementLine(ctx context.Context, params models.InsertBankStatementLineParams) (models.BankStatementLine, error)
+	ListBankStatementLines(ctx context.Context, statementID uuid.UUID) ([]models.BankStatementLine, error)
+	ListUnmatchedStatementLines(ctx context.Context, params models.ListUnmatchedStatementLinesParams) ([]models.BankStatementLine, error)
+	GetBankStatementLineForUpdate(ctx context.Context, id string) (models.BankStatementLine, error)
+	ResolveBankStatementLine(ctx context.Context, params models.ResolveBankStatementLineParams) (models.BankStatementLine, error)
+	ListReconciliationCandidates(ctx context.Context, params models.ListReconciliationCandidatesParams) ([]models.Payment, error)
+	MarkPaymentReconciled(ctx context.Context, params models.MarkPaymentReconciledParams) (models.Payment, error)
+
+	// Taxpayer credit
+	GetTaxpayerCounty(ctx context.Context, taxpayerID uuid.UUID) (int32, error)
+	GetAssessmentOwner(ctx context.Context, assessmentID uuid.UUID) (models.GetAssessmentOwnerRow, error)
+	GetTaxpayerCreditBalance(ctx context.Context, taxpayerID uuid.UUID) (models.TaxpayerCreditBalance, error)
+	GetTaxpayerCreditBalanceForUpdate(ctx context.Context, taxpayerID uuid.UUID) (models.TaxpayerCreditBalance, error)
+	AdjustTaxpayerCreditBalance(ctx context.Context, params models.AdjustTaxpayerCreditBalanceParams) (models.TaxpayerCreditBalance, error)
+	CreateCreditLedgerEntry(ctx context.Context, params models.InsertCreditLedgerEntryParams) (models.TaxpayerCreditLedger, error)
+	ListCreditLedgerEntries(ctx context.Context, params models.ListCreditLedgerEntriesParams) ([]models.TaxpayerCreditLedger, error)
+	GetPaymentCreditTotals(ctx context.Context, paymentID uuid.UUID) (models.GetPaymentCreditTotalsRow, error)
+	ListPaymentsWithCredit(ctx context.Context, taxpayerID uuid.UUID) ([]models.ListPaymentsWithCreditRow, error)
+
+	// Refunds
+	CreatePaymentRefund(ctx context.Context, params models.InsertPaymentRefundParams) (models.PaymentRefund, error)
+	GetPaymentRefund(ctx context.Context, id string) (models.PaymentRefund, error)
+	GetPaymentRefundForUpdate(ctx context.Context, id string) (models.PaymentRefund, error)
+	ListPaymentRefunds(ctx context.Context, paymentID uuid.UUID) ([]models.PaymentRefund, error)
+	ListPendingRefunds(ctx context.Context, params models.ListPendingRefundsParams) ([]models.PaymentRefund, error)
+	SumPendingRefundsForPayment(ctx context.Context, paymentID uuid.UUID) (money.Amount, error)
+	ReviewPaymentRefund(ctx context.Context, params models.ReviewPaymentRefundParams) (models.PaymentRefund, error)
+	CreateRefundReversal(ctx context.Context, params models.InsertRefundReversalParams) (models.PaymentRefundReversal, error)
+	ListRefundReversals(ctx context.Context, refundID uuid.UUID) ([]models.PaymentRefundReversal, error)
+	ListPaymentAllocationsForUpdate(ctx context.Context, paymentID uuid.UUID) ([]models.PaymentAllocation, error)
+	SetPaymentAllocationAmount(ctx context.Context, params models.SetPaymentAllocationAmountParams) error
+	VoidReceipt(ctx context.Context, params models.VoidReceiptParams) error
 
 	// Receipts
-	CreateReceipt(ctx context.Context, receipt models.InsertReceiptParams) error
+	CreateReceipt(ctx context.Context, receipt models.InsertReceiptParams) (models.Receipt, error)
 	GetReceiptByID(ctx context.Context, id string) (models.Receipt, error)
 	ListReceiptsByPayment(ctx context.Context, paymentID string) ([]models.Receipt, error)
 	UpdateReceipt(ctx context.Context, params models.UpdateReceiptParams) error
 	DeleteReceipt(ctx context.Context, id string) error
+	GetReceiptDocument(ctx context.Context, id uuid.UUID) (models.GetReceiptDocumentRow, error)
+	GetReceiptVerification(ctx context.Context, params models.GetReceiptVerificationParams) (models.GetReceiptVerificationRow, error)
+	ListReceiptLines(ctx context.Context, paymentID uuid.UUID) ([]models.ListReceiptLinesRow, error)
+	ListUnrenderedReceipts(ctx context.Context, paymentID uuid.UUID) ([]models.Receipt, error)
+	SetReceiptPDF(ctx context.Context, params models.SetReceiptPDFParams) (models.Receipt, error)
+
+	// Receipt ledger
+	AdvanceReceiptLedger(ctx context.Context, params models.AdvanceReceiptLedgerParams) error
+	AdvanceReceiptLedgerBlock(ctx context.Context, params models.AdvanceReceiptLedgerBlockParams) error
+	CountReceiptLedgerEntries(ctx context.Context, receiptID uuid.UUID) (int64, error)
+	GetReceiptLedgerHead(ctx context.Context, countyID int32) (models.ReceiptLedgerHead, error)
+	InsertReceiptLedgerBlock(ctx context.Context, params models.InsertReceiptLedgerBlockParams) (models.ReceiptLedgerBlock, error)
+	InsertReceiptLedgerEntry(ctx context.Context, params models.InsertReceiptLedgerEntryParams) (models.ReceiptLedgerEntry, error)
+	ListCountiesWithUnsealedReceipts(ctx context.Context) ([]int32, error)
+	ListReceiptLedgerBlocks(ctx context.Context, countyID int32) ([]models.ReceiptLedgerBlock, error)
+	ListReceiptLedgerCounties(ctx context.Context) ([]int32, error)
+	ListReceiptLedgerEntries(ctx context.Context, params models.ListReceiptLedgerEntriesParams) ([]models.ListReceiptLedgerEntriesRow, error)
+	ListReceiptLedgerEntriesByReceipt(ctx context.Context, receiptID uuid.UUID) ([]models.ListReceiptLedgerEntriesByReceiptRow, error)
+	ListUnledgeredReceipts(ctx context.Context, countyID sql.NullInt32) ([]models.ListUnledgeredReceiptsRow, error)
+	LockReceiptLedger(ctx context.Context, countyID int32) (models.ReceiptLedgerHead, error)
+	MarkPaymentsSealed(ctx context.Context, params models.MarkPaymentsSealedParams) error
+	MarkReceiptsSealed(ctx context.Context, params models.MarkReceiptsSealedParams) error
+	SealReceiptLedgerEntries(ctx context.Context, params models.SealReceiptLedgerEntriesParams) error
+	SetPaymentLedgerHash(ctx context.Context, params models.SetPaymentLedgerHashParams) error
+	SetReceiptLedgerHash(ctx context.Context, params models.SetReceiptLedgerHashParams) error
+
+	// Receipt delivery
+	EnqueueNotification(ctx context.Context, params models.EnqueueNotificationParams) (models.NotificationOutbox, error)
+	GetPaymentContact(ctx context.Context, paymentID uuid.UUID) (models.GetPaymentContactRow, error)
+	ListReceiptNotifications(ctx context.Context, receiptID uuid.UUID) ([]models.NotificationOutbox, error)
+
+	// Collector sessions
+	CreateCollectorSession(ctx context.Context, params models.InsertCollectorSessionParams) (models.CollectorSession, error)
+	GetCollectorSession(ctx context.Context, id string) (models.CollectorSession, error)
+	GetCollectorSessionForUpdate(ctx context.Context, id string) (models.CollectorSession, error)
+	GetOpenCollectorSession(ctx context.Context, collectorID uuid.UUID) (models.CollectorSession, error)
+	AddSessionPayment(ctx context.Context, sessionID, paymentID uuid.UUID) error
+	SumSessionPaymentsByMethod(ctx context.Context, sessionID uuid.UUID) ([]models.SumSessionPaymentsByMethodRow, error)
+	CloseCollectorSession(ctx context.Context, params models.CloseCollectorSessionParams) (models.CollectorSession, error)
+	ReviewCollectorSession(ctx context.Context, params models.ReviewCollectorSessionParams) (models.CollectorSession, error)
+	ListCollectorSessions(ctx context.Context, params models.ListCollectorSessionsParams) ([]models.CollectorSession, error)
+	ListZReports(ctx context.Context, params models.ListZReportsParams) ([]models.ListZReportsRow, error)
+
+	// Status history
+	RecordPaymentStatusChange(ctx context.Context, params models.InsertPaymentStatusChangeParams) (models.PaymentStatusHistory, error)
+	ListPaymentStatusHistory(ctx context.Context, paymentID uuid.UUID) ([]models.PaymentStatusHistory, error)
+
+	// Numbering
+	GetCountyCode(ctx context.Context, countyID int32) (string, error)
+	NextDocumentSequence(ctx context.Context, countyID int32, documentType, financialYear string) (int64, error)
 }
 
 type repository struct {
-	q *models.Queries
+	q       *models.Queries
+	numbers numbering.Counter
 }
 
 func NewRepository(db models.DBTX) Repository {
-	return &repository{q: models.New(db)}
+	return &repository{q: models.New(db), numbers: numbering.NewCounter(db)}
 }
 
 func (r *repository) CreatePayment(ctx context.Context, payment models.InsertPaymentParams) (models.Payment, error) {
@@ -82,6 +193,14 @@ func (r *repository) CreatePaymentAllocation(ctx context.Context, allocation mod
 	return r.q.InsertPaymentAllocation(ctx, allocation)
 }
 
+func (r *repository) GetPaymentAllocationByID(ctx context.Context, id string) (models.PaymentAllocation, error) {
+	parseID, err := uuid.Parse(id)
+	if err != nil {
+		return models.PaymentAllocation{}, err
+	}
+	return r.q.GetPaymentAllocationByID(ctx, parseID)
+}
+
 func (r *repository) ListPaymentAllocations(ctx context.Context, paymentID string) ([]models.PaymentAllocation, error) {
 	parseID, err := uuid.Parse(paymentID)
 	if err != nil {
@@ -98,8 +217,219 @@ func (r *repository) DeletePaymentAllocation(ctx context.Context, id string) err
 	return r.q.DeletePaymentAllocation(ctx, parseID)
 }
 
+func (r *repository) SumCompletedAllocationsForAssessment(ctx context.Context, assessmentID uuid.UUID) (money.Amount, error) {
+	return r.q.SumCompletedAllocationsForAssessment(ctx, assessmentID)
+}
+
+func (r *repository) SumCompletedAllocationsByTypeForAssessment(ctx context.Context, assessmentID uuid.UUID) ([]models.SumCompletedAllocationsByTypeForAssessmentRow, error) {
+	return r.q.SumCompletedAllocationsByTypeForAssessment(ctx, assessmentID)
+}
+
+func (r *repository) SumAllocationsForPayment(ctx context.Context, paymentID uuid.UUID) (money.Amount, error) {
+	return r.q.SumAllocationsForPayment(ctx, paymentID)
+}
+
+func (r *repository) GetPaymentForUpdate(ctx context.Context, id uuid.UUID) (models.Payment, error) {
+	return r.q.GetPaymentForUpdate(ctx, id)
+}
+
+// M-Pesa
+func (r *repository) GetPaymentByExternalTransactionIDForUpdate(ctx context.Context, externalTransactionID string) (models.Payment, error) {
+	return r.q.GetPaymentByExternalTransactionIDForUpdate(ctx, sql.NullString{String: externalTransactionID, Valid: true})
+}
+
+func (r *repository) SetPaymentOutcome(ctx context.Context, params models.SetPaymentOutcomeParams) (models.Payment, error) {
+	return r.q.SetPaymentOutcome(ctx, params)
+}
+
+func (r *repository) GetPaymentByMpesaReceiptNumber(ctx context.Context, receiptNumber string) (models.Payment, error) {
+	return r.q.GetPaymentByMpesaReceiptNumber(ctx, sql.NullString{String: receiptNumber, Valid: true})
+}
+
+func (r *repository) SetPaymentCheckoutRequest(ctx context.Context, id uuid.UUID, checkoutRequestID string) (models.Payment, error) {
+	return r.q.SetPaymentExternalTransactionID(ctx, models.SetPaymentExternalTransactionIDParams{
+		ExternalTransactionID: sql.NullString{String: checkoutRequestID, Valid: true},
+		ID:                    id,
+	})
+}
+
+func (r *repository) CreateMpesaSuspense(ctx context.Context, params models.InsertMpesaSuspenseParams) error {
+	return r.q.InsertMpesaSuspense(ctx, params)
+}
+
+func (r *repository) GetMpesaSuspenseForUpdate(ctx context.Context, id string) (models.MpesaSuspense, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return models.MpesaSuspense{}, err
+	}
+	return r.q.GetMpesaSuspenseForUpdate(ctx, parsedID)
+}
+
+func (r *repository) GetUnappliedSTKResultForUpdate(ctx context.Context, checkoutRequestID string) (models.MpesaSuspense, error) {
+	return r.q.GetUnappliedSTKResultForUpdate(ctx, checkoutRequestID)
+}
+
+func (r *repository) LockMpesaReference(ctx context.Context, reference string) error {
+	return r.q.LockMpesaReference(ctx, reference)
+}
+
+func (r *repository) ListUnmatchedMpesaSuspense(ctx context.Context, params models.ListUnmatchedMpesaSuspenseParams) ([]models.MpesaSuspense, error) {
+	return r.q.ListUnmatchedMpesaSuspense(ctx, params)
+}
+
+func (r *repository) ResolveMpesaSuspense(ctx context.Context, params models.ResolveMpesaSuspenseParams) (models.MpesaSuspense, error) {
+	return r.q.ResolveMpesaSuspense(ctx, params)
+}
+
+// Reconciliation
+func (r *repository) CreateBankStatement(ctx context.Context, params models.InsertBankStatementParams) (models.BankStatement, error) {
+	return r.q.InsertBankStatement(ctx, params)
+}
+
+func (r *repository) GetBankStatementByID(ctx context.Context, id string) (models.BankStatement, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return models.BankStatement{}, err
+	}
+	return r.q.GetBankStatementByID(ctx, parsedID)
+}
+
+func (r *repository) ListBankStatements(ctx context.Context, params models.ListBankStatementsParams) ([]models.BankStatement, error) {
+	return r.q.ListBankStatements(ctx, params)
+}
+
+func (r *repository) RefreshBankStatementMatchedCount(ctx context.Context, id uuid.UUID) (models.BankStatement, error) {
+	return r.q.SetBankStatementMatchedCount(ctx, id)
+}
+
+func (r *repository) CreateBankStatementLine(ctx context.Context, params models.InsertBankStatementLineParams) (models.BankStatementLine, error) {
+	return r.q.InsertBankStatementLine(ctx, params)
+}
+
+func (r *repository) ListBankStatementLines(ctx context.Context, statementID uuid.UUID) ([]models.BankStatementLine, error) {
+	return r.q.ListBankStatementLines(ctx, statementID)
+}
+
+func (r *repository) ListUnmatchedStatementLines(ctx context.Context, params models.ListUnmatchedStatementLinesParams) ([]models.BankStatementLine, error) {
+	return r.q.ListUnmatchedStatementLines(ctx, params)
+}
+
+func (r *repository) GetBankStatementLineForUpdate(ctx context.Context, id string) (models.BankStatementLine, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return models.BankStatementLine{}, err
+	}
+	return r.q.GetBankStatementLineForUpdate(ctx, parsedID)
+}
+
+func (r *repository) ResolveBankStatementLine(ctx context.Context, params models.ResolveBankStatementLineParams) (models.BankStatementLine, error) {
+	return r.q.ResolveBankStatementLine(ctx, params)
+}
+
+func (r *repository) ListReconciliationCandidates(ctx context.Context, params models.ListReconciliationCandidatesParams) ([]models.Payment, error) {
+	return r.q.ListReconciliationCandidates(ctx, params)
+}
+
+func (r *repository) MarkPaymentReconciled(ctx context.Context, params models.MarkPaymentReconciledParams) (models.Payment, error) {
+	return r.q.MarkPaymentReconciled(ctx, params)
+}
+
+// Taxpayer credit
+func (r *repository) GetTaxpayerCounty(ctx context.Context, taxpayerID uuid.UUID) (int32, error) {
+	return r.q.GetTaxpayerCounty(ctx, taxpayerID)
+}
+
+func (r *repository) GetAssessmentOwner(ctx context.Context, assessmentID uuid.UUID) (models.GetAssessmentOwnerRow, error) {
+	return r.q.GetAssessmentOwner(ctx, assessmentID)
+}
+
+func (r *repository) GetTaxpayerCreditBalance(ctx context.Context, taxpayerID uuid.UUID) (models.TaxpayerCreditBalance, error) {
+	return r.q.GetTaxpayerCreditBalance(ctx, taxpayerID)
+}
+
+func (r *repository) GetTaxpayerCreditBalanceForUpdate(ctx context.Context, taxpayerID uuid.UUID) (models.TaxpayerCreditBalance, error) {
+	return r.q.GetTaxpayerCreditBalanceForUpdate(ctx, taxpayerID)
+}
+
+func (r *repository) AdjustTaxpayerCreditBalance(ctx context.Context, params models.AdjustTaxpayerCreditBalanceParams) (models.TaxpayerCreditBalance, error) {
+	return r.q.AdjustTaxpayerCreditBalance(ctx, params)
+}
+
+func (r *repository) CreateCreditLedgerEntry(ctx context.Context, params models.InsertCreditLedgerEntryParams) (models.TaxpayerCreditLedger, error) {
+	return r.q.InsertCreditLedgerEntry(ctx, params)
+}
+
+func (r *repository) ListCreditLedgerEntries(ctx context.Context, params models.ListCreditLedgerEntriesParams) ([]models.TaxpayerCreditLedger, error) {
+	return r.q.ListCreditLedgerEntries(ctx, params)
+}
+
+func (r *repository) GetPaymentCreditTotals(ctx context.Context, paymentID uuid.UUID) (models.GetPaymentCreditTotalsRow, error) {
+	return r.q.GetPaymentCreditTotals(ctx, paymentID)
+}
+
+func (r *repository) ListPaymentsWithCredit(ctx context.Context, taxpayerID uuid.UUID) ([]models.ListPaymentsWithCreditRow, error) {
+	return r.q.ListPaymentsWithCredit(ctx, taxpayerID)
+}
+
+// Refunds
+func (r *repository) CreatePaymentRefund(ctx context.Context, params models.InsertPaymentRefundParams) (models.PaymentRefund, error) {
+	return r.q.InsertPaymentRefund(ctx, params)
+}
+
+func (r *repository) GetPaymentRefund(ctx context.Context, id string) (models.PaymentRefund, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return models.PaymentRefund{}, err
+	}
+	return r.q.GetPaymentRefund(ctx, parsedID)
+}
+
+func (r *repository) GetPaymentRefundForUpdate(ctx context.Context, id string) (models.PaymentRefund, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return models.PaymentRefund{}, err
+	}
+	return r.q.GetPaymentRefundForUpdate(ctx, parsedID)
+}
+
+func (r *repository) ListPaymentRefunds(ctx context.Context, paymentID uuid.UUID) ([]models.PaymentRefund, error) {
+	return r.q.ListPaymentRefunds(ctx, paymentID)
+}
+
+func (r *repository) ListPendingRefunds(ctx context.Context, params models.ListPendingRefundsParams) ([]models.PaymentRefund, error) {
+	return r.q.ListPendingRefunds(ctx, params)
+}
+
+func (r *repository) SumPendingRefundsForPayment(ctx context.Context, paymentID uuid.UUID) (money.Amount, error) {
+	return r.q.SumPendingRefundsForPayment(ctx, paymentID)
+}
+
+func (r *repository) ReviewPaymentRefund(ctx context.Context, params models.ReviewPaymentRefundParams) (models.PaymentRefund, error) {
+	return r.q.ReviewPaymentRefund(ctx, params)
+}
+
+func (r *repository) CreateRefundReversal(ctx context.Context, params models.InsertRefundReversalParams) (models.PaymentRefundReversal, error) {
+	return r.q.InsertRefundReversal(ctx, params)
+}
+
+func (r *repository) ListRefundReversals(ctx context.Context, refundID uuid.UUID) ([]models.PaymentRefundReversal, error) {
+	return r.q.ListRefundReversals(ctx, refundID)
+}
+
+func (r *repository) ListPaymentAllocationsForUpdate(ctx context.Context, paymentID uuid.UUID) ([]models.PaymentAllocation, error) {
+	return r.q.ListPaymentAllocationsForUpdate(ctx, paymentID)
+}
+
+func (r *repository) SetPaymentAllocationAmount(ctx context.Context, params models.SetPaymentAllocationAmountParams) error {
+	return r.q.SetPaymentAllocationAmount(ctx, params)
+}
+
+func (r *repository) VoidReceipt(ctx context.Context, params models.VoidReceiptParams) error {
+	return r.q.VoidReceipt(ctx, params)
+}
+
 // Receipts
-func (r *repository) CreateReceipt(ctx context.Context, receipt models.InsertReceiptParams) error {
+func (r *repository) CreateReceipt(ctx context.Context, receipt models.InsertReceiptParams) (models.Receipt, error) {
 	return r.q.InsertReceipt(ctx, receipt)
 }
 
@@ -129,4 +459,175 @@ func (r *repository) DeleteReceipt(ctx context.Context, id string) error {
 		return err
 	}
 	return r.q.DeleteReceipt(ctx, parseID)
-}
\ No newline at end of file
+}
+
+func (r *repository) GetReceiptDocument(ctx context.Context, id uuid.UUID) (models.GetReceiptDocumentRow, error) {
+	return r.q.GetReceiptDocument(ctx, id)
+}
+
+func (r *repository) GetReceiptVerification(ctx context.Context, params models.GetReceiptVerificationParams) (models.GetReceiptVerificationRow, error) {
+	return r.q.GetReceiptVerification(ctx, params)
+}
+
+func (r *repository) ListReceiptLines(ctx context.Context, paymentID uuid.UUID) ([]models.ListReceiptLinesRow, error) {
+	return r.q.ListReceiptLines(ctx, paymentID)
+}
+
+func (r *repository) ListUnrenderedReceipts(ctx context.Context, paymentID uuid.UUID) ([]models.Receipt, error) {
+	return r.q.ListUnrenderedReceipts(ctx, paymentID)
+}
+
+func (r *repository) SetReceiptPDF(ctx context.Context, params models.SetReceiptPDFParams) (models.Receipt, error) {
+	return r.q.SetReceiptPDF(ctx, params)
+}
+
+func (r *repository) CreateCollectorSession(ctx context.Context, params models.InsertCollectorSessionParams) (models.CollectorSession, error) {
+	return r.q.InsertCollectorSession(ctx, params)
+}
+
+func (r *repository) GetCollectorSession(ctx context.Context, id string) (models.CollectorSession, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return models.CollectorSession{}, err
+	}
+	return r.q.GetCollectorSession(ctx, parsedID)
+}
+
+func (r *repository) GetCollectorSessionForUpdate(ctx context.Context, id string) (models.CollectorSession, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return models.CollectorSession{}, err
+	}
+	return r.q.GetCollectorSessionForUpdate(ctx, parsedID)
+}
+
+func (r *repository) GetOpenCollectorSession(ctx context.Context, collectorID uuid.UUID) (models.CollectorSession, error) {
+	return r.q.GetOpenCollectorSession(ctx, collectorID)
+}
+
+func (r *repository) AddSessionPayment(ctx context.Context, sessionID, paymentID uuid.UUID) error {
+	return r.q.InsertCollectorSessionPayment(ctx, models.InsertCollectorSessionPaymentParams{
+		SessionID: sessionID,
+		PaymentID: paymentID,
+	})
+}
+
+func (r *repository) SumSessionPaymentsByMethod(ctx context.Context, sessionID uuid.UUID) ([]models.SumSessionPaymentsByMethodRow, error) {
+	return r.q.SumSessionPaymentsByMethod(ctx, sessionID)
+}
+
+func (r *repository) CloseCollectorSession(ctx context.Context, params models.CloseCollectorSessionParams) (models.CollectorSession, error) {
+	return r.q.CloseCollectorSession(ctx, params)
+}
+
+func (r *repository) ReviewCollectorSession(ctx context.Context, params models.ReviewCollectorSessionParams) (models.CollectorSession, error) {
+	return r.q.ReviewCollectorSession(ctx, params)
+}
+
+func (r *repository) ListCollectorSessions(ctx context.Context, params models.ListCollectorSessionsParams) ([]models.CollectorSession, error) {
+	return r.q.ListCollectorSessions(ctx, params)
+}
+
+func (r *repository) ListZReports(ctx context.Context, params models.ListZReportsParams) ([]models.ListZReportsRow, error) {
+	return r.q.ListZReports(ctx, params)
+}
+
+func (r *repository) RecordPaymentStatusChange(ctx context.Context, params models.InsertPaymentStatusChangeParams) (models.PaymentStatusHistory, error) {
+	return r.q.InsertPaymentStatusChange(ctx, params)
+}
+
+func (r *repository) ListPaymentStatusHistory(ctx context.Context, paymentID uuid.UUID) ([]models.PaymentStatusHistory, error) {
+	return r.q.ListPaymentStatusHistory(ctx, paymentID)
+}
+
+func (r *repository) GetCountyCode(ctx context.Context, countyID int32) (string, error) {
+	return r.numbers.GetCountyCode(ctx, countyID)
+}
+
+func (r *repository) NextDocumentSequence(ctx context.Context, countyID int32, documentType, financialYear string) (int64, error) {
+	return r.numbers.NextDocumentSequence(ctx, countyID, documentType, financialYear)
+}
+
+func (r *repository) AdvanceReceiptLedger(ctx context.Context, params models.AdvanceReceiptLedgerParams) error {
+	return r.q.AdvanceReceiptLedger(ctx, params)
+}
+
+func (r *repository) AdvanceReceiptLedgerBlock(ctx context.Context, params models.AdvanceReceiptLedgerBlockParams) error {
+	return r.q.AdvanceReceiptLedgerBlock(ctx, params)
+}
+
+func (r *repository) CountReceiptLedgerEntries(ctx context.Context, receiptID uuid.UUID) (int64, error) {
+	return r.q.CountReceiptLedgerEntries(ctx, receiptID)
+}
+
+func (r *repository) GetReceiptLedgerHead(ctx context.Context, countyID int32) (models.ReceiptLedgerHead, error) {
+	return r.q.GetReceiptLedgerHead(ctx, countyID)
+}
+
+func (r *repository) InsertReceiptLedgerBlock(ctx context.Context, params models.InsertReceiptLedgerBlockParams) (models.ReceiptLedgerBlock, error) {
+	return r.q.InsertReceiptLedgerBlock(ctx, params)
+}
+
+func (r *repository) InsertReceiptLedgerEntry(ctx context.Context, params models.InsertReceiptLedgerEntryParams) (models.ReceiptLedgerEntry, error) {
+	return r.q.InsertReceiptLedgerEntry(ctx, params)
+}
+
+func (r *repository) ListCountiesWithUnsealedReceipts(ctx context.Context) ([]int32, error) {
+	return r.q.ListCountiesWithUnsealedReceipts(ctx)
+}
+
+func (r *repository) ListReceiptLedgerBlocks(ctx context.Context, countyID int32) ([]models.ReceiptLedgerBlock, error) {
+	return r.q.ListReceiptLedgerBlocks(ctx, countyID)
+}
+
+func (r *repository) ListReceiptLedgerCounties(ctx context.Context) ([]int32, error) {
+	return r.q.ListReceiptLedgerCounties(ctx)
+}
+
+func (r *repository) ListReceiptLedgerEntries(ctx context.Context, params models.ListReceiptLedgerEntriesParams) ([]models.ListReceiptLedgerEntriesRow, error) {
+	return r.q.ListReceiptLedgerEntries(ctx, params)
+}
+
+func (r *repository) ListReceiptLedgerEntriesByReceipt(ctx context.Context, receiptID uuid.UUID) ([]models.ListReceiptLedgerEntriesByReceiptRow, error) {
+	return r.q.ListReceiptLedgerEntriesByReceipt(ctx, receiptID)
+}
+
+func (r *repository) ListUnledgeredReceipts(ctx context.Context, countyID sql.NullInt32) ([]models.ListUnledgeredReceiptsRow, error) {
+	return r.q.ListUnledgeredReceipts(ctx, countyID)
+}
+
+func (r *repository) LockReceiptLedger(ctx context.Context, countyID int32) (models.ReceiptLedgerHead, error) {
+	return r.q.LockReceiptLedger(ctx, countyID)
+}
+
+func (r *repository) MarkPaymentsSealed(ctx context.Context, params models.MarkPaymentsSealedParams) error {
+	return r.q.MarkPaymentsSealed(ctx, params)
+}
+
+func (r *repository) MarkReceiptsSealed(ctx context.Context, params models.MarkReceiptsSealedParams) error {
+	return r.q.MarkReceiptsSealed(ctx, params)
+}
+
+func (r *repository) SealReceiptLedgerEntries(ctx context.Context, params models.SealReceiptLedgerEntriesParams) error {
+	return r.q.SealReceiptLedgerEntries(ctx, params)
+}
+
+func (r *repository) SetPaymentLedgerHash(ctx context.Context, params models.SetPaymentLedgerHashParams) error {
+	return r.q.SetPaymentLedgerHash(ctx, params)
+}
+
+func (r *repository) SetReceiptLedgerHash(ctx context.Context, params models.SetReceiptLedgerHashParams) error {
+	return r.q.SetReceiptLedgerHash(ctx, params)
+}
+
+func (r *repository) EnqueueNotification(ctx context.Context, params models.EnqueueNotificationParams) (models.NotificationOutbox, error) {
+	return r.q.EnqueueNotification(ctx, params)
+}
+
+func (r *repository) GetPaymentContact(ctx context.Context, paymentID uuid.UUID) (models.GetPaymentContactRow, error) {
+	return r.q.GetPaymentContact(ctx, paymentID)
+}
+
+func (r *repository) ListReceiptNotifications(ctx context.Context, receiptID uuid.UUID) ([]models.NotificationOutbox, error) {
+	return r.q.ListReceiptNotifications(ctx, uuid.NullUUID{UUID: receiptID, Valid: true})
+}
@@ -8,32 +8,115 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/bankstatement"
+	"github.com/sangkips/revenue-system/internal/db"
 	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/filestore"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/sangkips/revenue-system/internal/numbering"
+	"github.com/sangkips/revenue-system/internal/receipt"
 )
 
 type Service struct {
-	repo Repository
+	repo      Repository
+	uow       *db.UnitOfWork[Stores]
+	mpesa     STKPusher
+	paybill   string
+	tolerance bankstatement.Tolerance
+	strategy  AllocationStrategy
+	numbers   numbering.Scheme
+	pdfs      *receipt.Renderer
+	files     filestore.Store
+	verifyKey []byte
+	verifyURL string
+
+	deliveryAttempts int32
 }
 
-func NewService(repo Repository) *Service {
-	return &Service{repo: repo}
+// Option configures optional Service features.
+type Option func(*Service)
+
+// WithUnitOfWork enables operations that span payments and assessments in a
+// single transaction, such as CollectPayment.
+func WithUnitOfWork(uow *db.UnitOfWork[Stores]) Option {
+	return func(s *Service) {
+		s.uow = uow
+	}
+}
+
+func NewService(repo Repository, opts ...Option) *Service {
+	s := &Service{repo: repo, numbers: numbering.DefaultScheme}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *Service) CreatePayment(ctx context.Context, req CreatePaymentRequest, userID string) (models.Payment, error) {
-	if req.CountyID == 0 || req.TaxpayerID == "" || req.PaymentNumber == "" || req.Amount <= 0 || req.PaymentMethod == "" {
-		return models.Payment{}, errors.New("required fields missing or invalid")
+	params, err := s.paymentParams(ctx, req, userID)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if s.uow == nil {
+		return models.Payment{}, errors.New("payment recording is not configured")
 	}
 
-	if req.Status != "" && !validStatus(req.Status) {
-		return models.Payment{}, errors.New("invalid status value: must be 'pending', 'processing', 'completed', 'failed', or 'cancelled'")
+	// The number is issued, the history started and a payment recorded as
+	// already completed allocated in the transaction that inserts it.
+	var payment models.Payment
+	err = s.uow.Do(ctx, func(st Stores) error {
+		params, err := s.numberPayment(ctx, st, params)
+		if err != nil {
+			return err
+		}
+		if payment, err = createPayment(ctx, st, params, ""); err != nil {
+			return err
+		}
+		if payment.Status != "completed" {
+			return nil
+		}
+		return s.allocateCompleted(ctx, st, payment)
+	})
+	if err != nil {
+		return models.Payment{}, err
+	}
+	return payment, nil
+}
+
+// allocateCompleted runs the allocation engine with the default strategy for
+// a payment that has just become completed.
+func (s *Service) allocateCompleted(ctx context.Context, st Stores, payment models.Payment) error {
+	strategy, err := s.strategyFor("", payment)
+	if err != nil {
+		return err
+	}
+	_, err = autoAllocate(ctx, st, payment, strategy, uuid.NullUUID{})
+	return err
+}
+
+// paymentParams validates req and builds the insert parameters for a new payment.
+func (s *Service) paymentParams(ctx context.Context, req CreatePaymentRequest, userID string) (models.InsertPaymentParams, error) {
+	countyID, err := auth.ResolveCounty(ctx, req.CountyID)
+	if err != nil {
+		return models.InsertPaymentParams{}, err
+	}
+	req.CountyID = countyID
+
+	if req.CountyID == 0 || req.TaxpayerID == "" || !req.Amount.IsPositive() || req.PaymentMethod == "" {
+		return models.InsertPaymentParams{}, errors.New("required fields missing or invalid")
+	}
+
+	if req.Status != "" && !initialStatus(req.Status) {
+		return models.InsertPaymentParams{}, errors.New("invalid status value: a new payment must be 'pending', 'processing', or 'completed'")
 	}
 
 	if req.PaymentMethod != "" && !validPaymentMethod(req.PaymentMethod) {
-		return models.Payment{}, errors.New("invalid payment_method: must be 'mpesa', 'bank_transfer', 'card', 'cheque', or 'cash'")
+		return models.InsertPaymentParams{}, errors.New("invalid payment_method: must be 'mpesa', 'bank_transfer', 'card', 'cheque', or 'cash'")
 	}
 
 	if userID == "" {
-		return models.Payment{}, errors.New("user ID is required")
+		return models.InsertPaymentParams{}, errors.New("user ID is required")
 	}
 
 	status := req.Status
@@ -43,7 +126,17 @@ func (s *Service) CreatePayment(ctx context.Context, req CreatePaymentRequest, u
 
 	taxpayerID, err := uuid.Parse(req.TaxpayerID)
 	if err != nil {
-		return models.Payment{}, err
+		return models.InsertPaymentParams{}, err
+	}
+
+	// The payer, and the assessment if one is named, must belong to the
+	// caller's county and to the county the payment is recorded in.
+	taxpayerCounty, err := authorizeTaxpayer(ctx, s.repo, taxpayerID)
+	if err != nil {
+		return models.InsertPaymentParams{}, err
+	}
+	if taxpayerCounty != req.CountyID {
+		return models.InsertPaymentParams{}, errors.New("taxpayer is not registered in this county")
 	}
 
 	paymentDate := req.PaymentDate
@@ -56,7 +149,20 @@ func (s *Service) CreatePayment(ctx context.Context, req CreatePaymentRequest, u
 	if req.AssessmentID != "" {
 		parsedAssessmentID, err := uuid.Parse(req.AssessmentID)
 		if err != nil {
-			return models.Payment{}, err
+			return models.InsertPaymentParams{}, err
+		}
+		owner, err := s.repo.GetAssessmentOwner(ctx, parsedAssessmentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.InsertPaymentParams{}, ErrAssessmentNotFound
+		}
+		if err != nil {
+			return models.InsertPaymentParams{}, err
+		}
+		if err := auth.AuthorizeCounty(ctx, owner.CountyID); err != nil {
+			return models.InsertPaymentParams{}, err
+		}
+		if owner.TaxpayerID != taxpayerID {
+			return models.InsertPaymentParams{}, errors.New("assessment belongs to a different taxpayer")
 		}
 		assessmentID = uuid.NullUUID{UUID: parsedAssessmentID, Valid: true}
 	}
@@ -66,7 +172,7 @@ func (s *Service) CreatePayment(ctx context.Context, req CreatePaymentRequest, u
 		TaxpayerID:           taxpayerID,
 		AssessmentID:         assessmentID,
 		PaymentNumber:        req.PaymentNumber,
-		Amount:               fmt.Sprintf("%.2f",req.Amount),
+		Amount:               req.Amount,
 		PaymentMethod:        req.PaymentMethod,
 		PaymentChannel:       sql.NullString{String: req.PaymentChannel, Valid: req.PaymentChannel != ""},
 		ExternalTransactionID: sql.NullString{String: req.ExternalTransactionID, Valid: req.ExternalTransactionID != ""},
@@ -92,8 +198,6 @@ func (s *Service) CreatePayment(ctx context.Context, req CreatePaymentRequest, u
 			}
 			return req.GPSCoordinates
 		}(),
-		BlockchainHash:       sql.NullString{String: req.BlockchainHash, Valid: req.BlockchainHash != ""},
-		BlockNumber:          sql.NullInt64{Int64: req.BlockNumber, Valid: req.BlockNumber != 0},
 		Reconciled:           sql.NullBool{Bool: req.Reconciled, Valid: true},
 		ReconciliationDate:   sql.NullTime{Time: req.ReconciliationDate, Valid: !req.ReconciliationDate.IsZero()},
 		ReconciledBy:         func() uuid.NullUUID {
@@ -104,16 +208,30 @@ func (s *Service) CreatePayment(ctx context.Context, req CreatePaymentRequest, u
 			}
 			return uuid.NullUUID{Valid: false}
 		}(),
+		PaymentDate:          sql.NullTime{Time: paymentDate, Valid: true},
 	}
-	return s.repo.CreatePayment(ctx, params)
+	return params, nil
 }
 
 
 func (s *Service) GetPayment(ctx context.Context, id string) (models.Payment, error) {
-	return s.repo.GetPaymentByID(ctx, id)
+	payment, err := s.repo.GetPaymentByID(ctx, id)
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	if err := auth.AuthorizeCounty(ctx, payment.CountyID); err != nil {
+		return models.Payment{}, err
+	}
+	return payment, nil
 }
 
 func (s *Service) ListPayments(ctx context.Context, countyID int32, limit int32, offset int32) ([]models.Payment, error) {
+	countyID, err := auth.ResolveCounty(ctx, countyID)
+	if err != nil {
+		return nil, err
+	}
+
 	return s.repo.ListPayments(ctx, models.ListPaymentsParams{
 		CountyID: countyID,
 		Limit:    limit,
@@ -122,35 +240,112 @@ func (s *Service) ListPayments(ctx context.Context, countyID int32, limit int32,
 }
 
 func (s *Service) ListPaymentsByRevenueID(ctx context.Context, revenueID string) ([]models.Payment, error) {
-	return s.repo.ListPaymentsByRevenueID(ctx, revenueID)
+	payments, err := s.repo.ListPaymentsByRevenueID(ctx, revenueID)
+	if err != nil {
+		return nil, err
+	}
+
+	scoped := make([]models.Payment, 0, len(payments))
+	for _, payment := range payments {
+		if err := auth.AuthorizeCounty(ctx, payment.CountyID); err != nil {
+			return nil, err
+		}
+		scoped = append(scoped, payment)
+	}
+	return scoped, nil
 }
 
 func (s *Service) UpdatePayment(ctx context.Context, id string, req UpdatePaymentRequest, userID string) (models.Payment, error) {
 	if req.Status != nil && !validStatus(*req.Status) {
-		return models.Payment{}, errors.New("invalid status value: must be 'pending', 'processing', 'completed', 'failed', or 'cancelled'")
+		return models.Payment{}, errors.New("invalid status value: must be 'pending', 'processing', 'completed', 'failed', 'cancelled', or 'refunded'")
+	}
+	if req.Status != nil && *req.Status == "refunded" {
+		return models.Payment{}, ErrRefundThroughRequest
+	}
+	if req.Reconciled != nil || req.ReconciliationDate != nil || req.ReconciledBy != nil {
+		return models.Payment{}, ErrReconcileThroughStatements
 	}
 
 	if req.PaymentMethod != nil && !validPaymentMethod(*req.PaymentMethod) {
 		return models.Payment{}, errors.New("invalid payment_method: must be 'mpesa', 'bank_transfer', 'card', 'cheque', or 'cash'")
 	}
 
+	if req.Amount != nil && !req.Amount.IsPositive() {
+		return models.Payment{}, errors.New("amount must be greater than 0")
+	}
+
 	if userID == "" {
 		return models.Payment{}, errors.New("user ID is required")
 	}
+	actor, err := uuid.Parse(userID)
+	if err != nil {
+		return models.Payment{}, err
+	}
 
 	paymentID, err := uuid.Parse(id)
 	if err != nil {
 		return models.Payment{}, err
 	}
 
-	// Get current payment to use existing values for fields not being updated
-	current, err := s.repo.GetPaymentByID(ctx, id)
+	if s.uow == nil {
+		return models.Payment{}, errors.New("payment updates are not configured")
+	}
+
+	var payment models.Payment
+	err = s.uow.Do(ctx, func(st Stores) error {
+		// Lock the payment so its status is checked against what it is now.
+		current, err := st.Payments.GetPaymentForUpdate(ctx, paymentID)
+		if err != nil {
+			return err
+		}
+		if err := auth.AuthorizeCounty(ctx, current.CountyID); err != nil {
+			return err
+		}
+		if err := checkFrozen(current, req); err != nil {
+			return err
+		}
+		if req.PaymentMethod != nil && *req.PaymentMethod == "cash" && current.PaymentMethod != "cash" {
+			return fmt.Errorf("%w; record a new cash payment instead of changing the method", ErrNoOpenSession)
+		}
+
+		params, err := updateParams(current, req)
+		if err != nil {
+			return err
+		}
+		if err := checkTransition(current.Status, params.Status); err != nil {
+			return err
+		}
+
+		if payment, err = st.Payments.UpdatePayment(ctx, params); err != nil {
+			return err
+		}
+		reason := params.FailureReason.String
+		if req.StatusReason != nil {
+			reason = *req.StatusReason
+		}
+		if err := recordStatus(ctx, st, payment, current.Status, uuid.NullUUID{UUID: actor, Valid: true}, reason); err != nil {
+			return err
+		}
+
+		// Completing a payment allocates it like any other collection.
+		if payment.Status != "completed" || current.Status == "completed" {
+			return nil
+		}
+		return s.allocateCompleted(ctx, st, payment)
+	})
 	if err != nil {
 		return models.Payment{}, err
 	}
+	if payment.Status == "completed" {
+		s.renderReceipts(ctx, payment.ID)
+	}
+	return payment, nil
+}
 
+// updateParams applies the fields set in req on top of current.
+func updateParams(current models.Payment, req UpdatePaymentRequest) (models.UpdatePaymentParams, error) {
 	params := models.UpdatePaymentParams{
-		ID:                    paymentID,
+		ID:                    current.ID,
 		Amount:                current.Amount,
 		PaymentMethod:         current.PaymentMethod,
 		PaymentChannel:        current.PaymentChannel,
@@ -165,15 +360,13 @@ func (s *Service) UpdatePayment(ctx context.Context, id string, req UpdatePaymen
 		FailureReason:         current.FailureReason,
 		CollectionPoint:       current.CollectionPoint,
 		GpsCoordinates:        current.GpsCoordinates,
-		BlockchainHash:        current.BlockchainHash,
-		BlockNumber:           current.BlockNumber,
 		Reconciled:            current.Reconciled,
 		ReconciliationDate:    current.ReconciliationDate,
 		ReconciledBy:          current.ReconciledBy,
 	}
 
 	if req.Amount != nil {
-		params.Amount = fmt.Sprintf("%.2f", *req.Amount)
+		params.Amount = *req.Amount
 	}
 	if req.PaymentMethod != nil {
 		params.PaymentMethod = *req.PaymentMethod
@@ -196,10 +389,25 @@ func (s *Service) UpdatePayment(ctx context.Context, id string, req UpdatePaymen
 	if req.CollectedBy != nil {
 		collectedByUUID, err := uuid.Parse(*req.CollectedBy)
 		if err != nil {
-			return models.Payment{}, err
+			return models.UpdatePaymentParams{}, err
 		}
 		params.CollectedBy = uuid.NullUUID{UUID: collectedByUUID, Valid: true}
 	}
+	if req.MpesaReceiptNumber != nil {
+		params.MpesaReceiptNumber = sql.NullString{String: *req.MpesaReceiptNumber, Valid: true}
+	}
+	if req.BankReference != nil {
+		params.BankReference = sql.NullString{String: *req.BankReference, Valid: true}
+	}
+	if req.ChequeNumber != nil {
+		params.ChequeNumber = sql.NullString{String: *req.ChequeNumber, Valid: true}
+	}
+	if req.FailureReason != nil {
+		params.FailureReason = sql.NullString{String: *req.FailureReason, Valid: true}
+	}
+	if req.CollectionPoint != nil {
+		params.CollectionPoint = sql.NullString{String: *req.CollectionPoint, Valid: true}
+	}
 	if req.GPSCoordinates != nil {
 		if *req.GPSCoordinates == "" {
 			params.GpsCoordinates = nil
@@ -207,18 +415,24 @@ func (s *Service) UpdatePayment(ctx context.Context, id string, req UpdatePaymen
 			params.GpsCoordinates = *req.GPSCoordinates
 		}
 	}
-
-	return s.repo.UpdatePayment(ctx, params)
+	return params, nil
 }
 
 func (s *Service) DeletePayment(ctx context.Context, id string) error {
+	payment, err := s.GetPayment(ctx, id)
+	if err != nil {
+		return err
+	}
+	if frozen(payment.Status) {
+		return fmt.Errorf("%w: a %s payment cannot be deleted", ErrPaymentFrozen, payment.Status)
+	}
 	return s.repo.DeletePayment(ctx, id)
 }
 
 
 // Payment Allocations
 func (s *Service) CreatePaymentAllocation(ctx context.Context, req CreatePaymentAllocationRequest) (models.PaymentAllocation, error) {
-	if req.PaymentID == "" || req.AssessmentID == "" || req.AllocatedAmount <= 0 {
+	if req.PaymentID == "" || req.AssessmentID == "" || !req.AllocatedAmount.IsPositive() {
 		return models.PaymentAllocation{}, errors.New("required fields missing or invalid")
 	}
 	if req.AllocationType != "" && !validAllocationType(req.AllocationType) {
@@ -228,66 +442,155 @@ func (s *Service) CreatePaymentAllocation(ctx context.Context, req CreatePayment
 	if err != nil {
 		return models.PaymentAllocation{}, err
 	}
-	assessmentUUID, err := uuid.Parse(req.AssessmentID)
-	if err != nil {
+	if _, err := uuid.Parse(req.AssessmentID); err != nil {
 		return models.PaymentAllocation{}, err
 	}
-	
-	params := models.InsertPaymentAllocationParams{
-		PaymentID:        paymentUUID,
-		AssessmentID:     assessmentUUID,
-		AllocatedAmount:  fmt.Sprintf("%.2f", req.AllocatedAmount),
-		AllocationType:   sql.NullString{String: req.AllocationType, Valid: req.AllocationType != ""},
+	if s.uow == nil {
+		return models.PaymentAllocation{}, errors.New("payment allocation is not configured")
+	}
+
+	// The payment lock serialises allocations so together they never exceed it.
+	var allocation models.PaymentAllocation
+	err = s.uow.Do(ctx, func(st Stores) error {
+		payment, err := st.Payments.GetPaymentForUpdate(ctx, paymentUUID)
+		if err != nil {
+			return err
+		}
+		if err := auth.AuthorizeCounty(ctx, payment.CountyID); err != nil {
+			return err
+		}
+		allocation, _, err = allocate(ctx, st, payment, CollectAllocationRequest{
+			AssessmentID:    req.AssessmentID,
+			AllocatedAmount: req.AllocatedAmount,
+			AllocationType:  req.AllocationType,
+		})
+		if err != nil {
+			return err
+		}
+		return syncPaymentCredit(ctx, st, payment, "")
+	})
+	if err != nil {
+		return models.PaymentAllocation{}, err
 	}
-	return s.repo.CreatePaymentAllocation(ctx, params)
+	return allocation, nil
 }
 
 func (s *Service) ListPaymentAllocations(ctx context.Context, paymentID string) ([]models.PaymentAllocation, error) {
+	if _, err := s.GetPayment(ctx, paymentID); err != nil {
+		return nil, err
+	}
 	return s.repo.ListPaymentAllocations(ctx, paymentID)
 }
 
 func (s *Service) DeletePaymentAllocation(ctx context.Context, id string, paymentID string) error {
-	return s.repo.DeletePaymentAllocation(ctx, id)
+	paymentUUID, err := uuid.Parse(paymentID)
+	if err != nil {
+		return err
+	}
+	if s.uow == nil {
+		return errors.New("payment allocation is not configured")
+	}
+
+	// What the allocation released goes back to the payer as credit, and an
+	// assessment it had settled is reopened.
+	return s.uow.Do(ctx, func(st Stores) error {
+		payment, err := st.Payments.GetPaymentForUpdate(ctx, paymentUUID)
+		if err != nil {
+			return err
+		}
+		if err := auth.AuthorizeCounty(ctx, payment.CountyID); err != nil {
+			return err
+		}
+
+		allocation, err := st.Payments.GetPaymentAllocationByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if allocation.PaymentID != payment.ID {
+			return errors.New("allocation does not belong to the specified payment")
+		}
+		if err := unallocate(ctx, st, allocation, allocation.AllocatedAmount); err != nil {
+			return err
+		}
+		return syncPaymentCredit(ctx, st, payment, "allocation removed")
+	})
 }
 
 
 // Receipts
-func (s *Service) CreateReceipt(ctx context.Context, req CreateReceiptRequest) error {
-	if req.PaymentID == "" || req.ReceiptNumber == "" || req.ReceiptType == "" {
-		return errors.New("required fields missing or invalid")
+func (s *Service) CreateReceipt(ctx context.Context, req CreateReceiptRequest) (models.Receipt, error) {
+	params, err := receiptParams(req)
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	payment, err := s.GetPayment(ctx, req.PaymentID)
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	if s.uow == nil {
+		return models.Receipt{}, errors.New("the receipt ledger is not configured")
+	}
+
+	var receipt models.Receipt
+	err = s.uow.Do(ctx, func(st Stores) error {
+		// The lock keeps the payment from being refunded while it is receipted.
+		locked, err := st.Payments.GetPaymentForUpdate(ctx, payment.ID)
+		if err != nil {
+			return err
+		}
+		receipt, err = s.issueReceipt(ctx, st, locked, params)
+		return err
+	})
+	if err != nil {
+		return models.Receipt{}, err
+	}
+	return s.renderIssued(ctx, receipt), nil
+}
+
+// receiptParams validates req and builds the insert parameters for a receipt.
+func receiptParams(req CreateReceiptRequest) (models.InsertReceiptParams, error) {
+	if req.PaymentID == "" || req.ReceiptType == "" {
+		return models.InsertReceiptParams{}, errors.New("required fields missing or invalid")
 	}
 	if !validReceiptType(req.ReceiptType) {
-		return errors.New("invalid receipt_type")
+		return models.InsertReceiptParams{}, errors.New("invalid receipt_type")
 	}
 	paymentUUID, err := uuid.Parse(req.PaymentID)
 	if err != nil {
-		return err
+		return models.InsertReceiptParams{}, err
 	}
 	
 	params := models.InsertReceiptParams{
 		PaymentID:          paymentUUID,
 		ReceiptNumber:      req.ReceiptNumber,
 		ReceiptType:        sql.NullString{String: req.ReceiptType, Valid: req.ReceiptType != ""},
-		PdfFilePath:        sql.NullString{String: req.PDFFilePath, Valid: req.PDFFilePath != ""},
-		PdfFileSize:        sql.NullInt32{Int32: int32(req.PDFFileSize), Valid: req.PDFFileSize != 0},
-		PdfGenerated:       sql.NullBool{Bool: req.PDFGenerated, Valid: true},
+		PdfGenerated:       sql.NullBool{Bool: false, Valid: true}, // rendered once the payment completes
 		SmsSent:            sql.NullBool{Bool: req.SMSSent, Valid: true},
 		SmsSentAt:          sql.NullTime{Time: req.SMSSentAt, Valid: !req.SMSSentAt.IsZero()},
 		EmailSent:          sql.NullBool{Bool: req.EmailSent, Valid: true},
 		EmailSentAt:        sql.NullTime{Time: req.EmailSentAt, Valid: !req.EmailSentAt.IsZero()},
-		BlockchainHash:     req.BlockchainHash,  // Required, non-null per schema
-		BlockNumber:        sql.NullInt64{Int64: req.BlockNumber, Valid: req.BlockNumber != 0},
-		BlockchainVerified: sql.NullBool{Bool: req.BlockchainVerified, Valid: true},
 		QrCodeData:         sql.NullString{String: req.QRCodeData, Valid: req.QRCodeData != ""},
 	}
-	return s.repo.CreateReceipt(ctx, params)
+	return params, nil
 }
 
 func (s *Service) GetReceipt(ctx context.Context, id string) (models.Receipt, error) {
-	return s.repo.GetReceiptByID(ctx, id)
+	receipt, err := s.repo.GetReceiptByID(ctx, id)
+	if err != nil {
+		return models.Receipt{}, err
+	}
+
+	if _, err := s.GetPayment(ctx, receipt.PaymentID.String()); err != nil {
+		return models.Receipt{}, err
+	}
+	return receipt, nil
 }
 
 func (s *Service) ListReceiptsByPayment(ctx context.Context, paymentID string) ([]models.Receipt, error) {
+	if _, err := s.GetPayment(ctx, paymentID); err != nil {
+		return nil, err
+	}
 	return s.repo.ListReceiptsByPayment(ctx, paymentID)
 }
 
@@ -299,32 +602,26 @@ func (s *Service) UpdateReceipt(ctx context.Context, id string, req UpdateReceip
 	if err != nil {
 		return err
 	}
+	current, err := s.GetReceipt(ctx, id)
+	if err != nil {
+		return err
+	}
+	if req.ReceiptType != nil && *req.ReceiptType != current.ReceiptType.String {
+		return fmt.Errorf("%w: its receipt_type cannot change", ErrReceiptLedgered)
+	}
 	
 	params := models.UpdateReceiptParams{
 		ID:                 receiptUUID,
 		ReceiptType:        sql.NullString{Valid: req.ReceiptType != nil, String: ""},
-		PdfFilePath:        sql.NullString{Valid: req.PDFFilePath != nil, String: ""},
-		PdfFileSize:        sql.NullInt32{Valid: req.PDFFileSize != nil, Int32: 0},
-		PdfGenerated:       sql.NullBool{Valid: req.PDFGenerated != nil, Bool: false},
 		SmsSent:            sql.NullBool{Valid: req.SMSSent != nil, Bool: false},
 		SmsSentAt:          sql.NullTime{Valid: req.SMSSentAt != nil, Time: time.Time{}},
 		EmailSent:          sql.NullBool{Valid: req.EmailSent != nil, Bool: false},
 		EmailSentAt:        sql.NullTime{Valid: req.EmailSentAt != nil, Time: time.Time{}},
-		BlockchainVerified: sql.NullBool{Valid: req.BlockchainVerified != nil, Bool: false},
 	}
 	// Set values if provided (similar to other fields)
 	if req.ReceiptType != nil {
 		params.ReceiptType = sql.NullString{Valid: true, String: *req.ReceiptType}
 	}
-	if req.PDFFilePath != nil {
-		params.PdfFilePath = sql.NullString{Valid: true, String: *req.PDFFilePath}
-	}
-	if req.PDFFileSize != nil {
-		params.PdfFileSize = sql.NullInt32{Valid: true, Int32: int32(*req.PDFFileSize)}
-	}
-	if req.PDFGenerated != nil {
-		params.PdfGenerated = sql.NullBool{Valid: true, Bool: *req.PDFGenerated}
-	}
 	if req.SMSSent != nil {
 		params.SmsSent = sql.NullBool{Valid: true, Bool: *req.SMSSent}
 	}
@@ -339,6 +636,17 @@ func (s *Service) UpdateReceipt(ctx context.Context, id string, req UpdateReceip
 }
 
 func (s *Service) DeleteReceipt(ctx context.Context, id string) error {
+	receipt, err := s.GetReceipt(ctx, id)
+	if err != nil {
+		return err
+	}
+	entries, err := s.repo.CountReceiptLedgerEntries(ctx, receipt.ID)
+	if err != nil {
+		return err
+	}
+	if entries > 0 {
+		return fmt.Errorf("%w: void it by refunding the payment instead", ErrReceiptLedgered)
+	}
 	return s.repo.DeleteReceipt(ctx, id)
 }
 
@@ -347,7 +655,7 @@ func validPaymentMethod(method string) bool {
 }
 
 func validStatus(status string) bool {
-	return status == "pending" || status == "processing" || status == "completed" || status == "failed" || status == "cancelled"
+	return status == "pending" || status == "processing" || status == "completed" || status == "failed" || status == "cancelled" || status == "refunded"
 }
 
 func validReceiptType(typ string) bool {
@@ -362,8 +670,8 @@ type CreatePaymentRequest struct {
 	CountyID               int32   `json:"county_id"`
 	TaxpayerID             string  `json:"taxpayer_id"`
 	AssessmentID           string  `json:"assessment_id,omitempty"`
-	PaymentNumber          string  `json:"payment_number"`
-	Amount                 float64 `json:"amount"`
+	PaymentNumber          string  `json:"payment_number,omitempty"` // issued by the server when empty
+	Amount                 money.Amount `json:"amount"`
 	PaymentMethod          string  `json:"payment_method"`
 	PaymentChannel         string  `json:"payment_channel,omitempty"`
 	ExternalTransactionID  string  `json:"external_transaction_id,omitempty"`
@@ -377,15 +685,13 @@ type CreatePaymentRequest struct {
 	FailureReason          string  `json:"failure_reason,omitempty"`
 	CollectionPoint        string  `json:"collection_point,omitempty"`
 	GPSCoordinates         string  `json:"gps_coordinates,omitempty"`
-	BlockchainHash         string  `json:"blockchain_hash,omitempty"`
-	BlockNumber            int64   `json:"block_number,omitempty"`
 	Reconciled             bool    `json:"reconciled,omitempty"`
 	ReconciliationDate     time.Time `json:"reconciliation_date,omitempty"`
 	ReconciledBy           string  `json:"reconciled_by,omitempty"`
 }
 
 type UpdatePaymentRequest struct {
-	Amount                 *float64 `json:"amount,omitempty"`
+	Amount                 *money.Amount `json:"amount,omitempty"`
 	PaymentMethod          *string  `json:"payment_method,omitempty"`
 	PaymentChannel         *string  `json:"payment_channel,omitempty"`
 	ExternalTransactionID  *string  `json:"external_transaction_id,omitempty"`
@@ -395,11 +701,12 @@ type UpdatePaymentRequest struct {
 	PayerPhoneNumber       *string  `json:"payer_phone_number,omitempty"`
 	PayerName              *string  `json:"payer_name,omitempty"`
 	Status                 *string  `json:"status,omitempty"`
+	StatusReason           *string  `json:"status_reason,omitempty"` // recorded in the status history
 	FailureReason          *string  `json:"failure_reason,omitempty"`
 	CollectionPoint        *string  `json:"collection_point,omitempty"`
 	GPSCoordinates         *string  `json:"gps_coordinates,omitempty"`
-	BlockchainHash         *string  `json:"blockchain_hash,omitempty"`
-	BlockNumber            *int64   `json:"block_number,omitempty"`
+	// The reconciliation fields are only read to reject them; see
+	// ErrReconcileThroughStatements.
 	Reconciled             *bool    `json:"reconciled,omitempty"`
 	ReconciliationDate     *time.Time `json:"reconciliation_date,omitempty"`
 	ReconciledBy           *string  `json:"reconciled_by,omitempty"`
@@ -409,35 +716,25 @@ type UpdatePaymentRequest struct {
 type CreatePaymentAllocationRequest struct {
 	PaymentID        string  `json:"payment_id"`
 	AssessmentID     string  `json:"assessment_id"`
-	AllocatedAmount  float64 `json:"allocated_amount"`
+	AllocatedAmount  money.Amount `json:"allocated_amount"`
 	AllocationType   string  `json:"allocation_type,omitempty"`
 }
 
 type CreateReceiptRequest struct {
 	PaymentID          string  `json:"payment_id"`
-	ReceiptNumber      string  `json:"receipt_number"`
+	ReceiptNumber      string  `json:"receipt_number,omitempty"` // issued by the server when empty
 	ReceiptType        string  `json:"receipt_type"`
-	PDFFilePath        string  `json:"pdf_file_path,omitempty"`
-	PDFFileSize        int     `json:"pdf_file_size,omitempty"`
-	PDFGenerated       bool    `json:"pdf_generated,omitempty"`
 	SMSSent            bool    `json:"sms_sent,omitempty"`
 	SMSSentAt          time.Time `json:"sms_sent_at,omitempty"`
 	EmailSent          bool    `json:"email_sent,omitempty"`
 	EmailSentAt        time.Time `json:"email_sent_at,omitempty"`
-	BlockchainHash     string  `json:"blockchain_hash"`
-	BlockNumber        int64   `json:"block_number,omitempty"`
-	BlockchainVerified bool    `json:"blockchain_verified,omitempty"`
 	QRCodeData         string  `json:"qr_code_data,omitempty"`
 }
 
 type UpdateReceiptRequest struct {
 	ReceiptType        *string  `json:"receipt_type,omitempty"`
-	PDFFilePath        *string  `json:"pdf_file_path,omitempty"`
-	PDFFileSize        *int     `json:"pdf_file_size,omitempty"`
-	PDFGenerated       *bool    `json:"pdf_generated,omitempty"`
 	SMSSent            *bool    `json:"sms_sent,omitempty"`
 	SMSSentAt          *time.Time `json:"sms_sent_at,omitempty"`
 	EmailSent          *bool    `json:"email_sent,omitempty"`
 	EmailSentAt        *time.Time `json:"email_sent_at,omitempty"`
-	BlockchainVerified *bool    `json:"blockchain_verified,omitempty"`
 }
\ No newline at end of file
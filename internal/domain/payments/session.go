@@ -0,0 +1,299 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/db"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
+	"github.com/sangkips/revenue-system/internal/money"
+)
+
+// A collector session is one shift of a collector at a collection point. It
+// opens with the float in the till. Every payment the collector records while
+// it is open belongs to it, and cash can only be taken during one. Closing
+// counts the till: the collector declares the cash on hand, which is set
+// against the float plus the completed cash payments. The resulting Z-report
+// is approved or rejected by a supervisor other than the collector.
+
+var (
+	ErrNoOpenSession       = errors.New("cash payments can only be recorded during an open collector session")
+	ErrSessionAlreadyOpen  = errors.New("collector already has an open session")
+	ErrSessionNotOpen      = errors.New("collector session is not open")
+	ErrNotSessionCollector = errors.New("only the collector who opened a session can close it")
+	ErrSessionReviewed     = errors.New("collector session is not awaiting approval")
+	ErrSessionSelfApproval = errors.New("a Z-report must be reviewed by someone other than its collector")
+)
+
+type OpenSessionRequest struct {
+	CountyID        int32        `json:"county_id,omitempty"`
+	CollectionPoint string       `json:"collection_point"`
+	OpeningFloat    money.Amount `json:"opening_float"`
+}
+
+type CloseSessionRequest struct {
+	DeclaredCash *money.Amount `json:"declared_cash"` // cash counted in the till, float included
+	Note         string        `json:"note,omitempty"`
+}
+
+type ReviewSessionRequest struct {
+	Note string `json:"note,omitempty"` // required to reject
+}
+
+// ZReport is the summary of a session. While the session is open it shows
+// the takings so far.
+type ZReport struct {
+	Session       models.CollectorSession                `json:"session"`
+	Methods       []models.SumSessionPaymentsByMethodRow `json:"methods"`
+	CashCollected money.Amount                           `json:"cash_collected"`
+	ExpectedCash  money.Amount                           `json:"expected_cash"`
+}
+
+// expectedCash is what the till of a session should hold: the float plus the
+// completed cash payments.
+func expectedCash(float money.Amount, methods []models.SumSessionPaymentsByMethodRow) (cash, expected money.Amount) {
+	cash = money.Zero
+	for _, m := range methods {
+		if m.PaymentMethod == "cash" {
+			cash = cash.Add(m.Total)
+		}
+	}
+	return cash, float.Add(cash)
+}
+
+func zReport(ctx context.Context, repo Repository, session models.CollectorSession) (ZReport, error) {
+	methods, err := repo.SumSessionPaymentsByMethod(ctx, session.ID)
+	if err != nil {
+		return ZReport{}, err
+	}
+	cash, expected := expectedCash(session.OpeningFloat, methods)
+	return ZReport{Session: session, Methods: methods, CashCollected: cash, ExpectedCash: expected}, nil
+}
+
+// OpenSession starts a shift for userID at a collection point.
+func (s *Service) OpenSession(ctx context.Context, req OpenSessionRequest, userID string) (models.CollectorSession, error) {
+	countyID, err := auth.ResolveCounty(ctx, req.CountyID)
+	if err != nil {
+		return models.CollectorSession{}, err
+	}
+	if countyID == 0 || req.CollectionPoint == "" {
+		return models.CollectorSession{}, errors.New("county_id and collection_point are required")
+	}
+	if req.OpeningFloat.IsNegative() {
+		return models.CollectorSession{}, errors.New("opening_float cannot be negative")
+	}
+	collector, err := uuid.Parse(userID)
+	if err != nil {
+		return models.CollectorSession{}, err
+	}
+
+	session, err := s.repo.CreateCollectorSession(ctx, models.InsertCollectorSessionParams{
+		CountyID:        countyID,
+		CollectorID:     collector,
+		CollectionPoint: req.CollectionPoint,
+		OpeningFloat:    req.OpeningFloat,
+	})
+	if db.IsUniqueViolation(err) {
+		return models.CollectorSession{}, ErrSessionAlreadyOpen
+	}
+	return session, err
+}
+
+// CurrentSession returns the session userID has open.
+func (s *Service) CurrentSession(ctx context.Context, userID string) (ZReport, error) {
+	collector, err := uuid.Parse(userID)
+	if err != nil {
+		return ZReport{}, err
+	}
+	session, err := s.repo.GetOpenCollectorSession(ctx, collector)
+	if err != nil {
+		return ZReport{}, err
+	}
+	return zReport(ctx, s.repo, session)
+}
+
+// CloseSession ends a shift with the cash the collector counted and fixes
+// its Z-report for approval.
+func (s *Service) CloseSession(ctx context.Context, id string, req CloseSessionRequest, userID string) (ZReport, error) {
+	if s.uow == nil {
+		return ZReport{}, errors.New("collector sessions are not configured")
+	}
+	if req.DeclaredCash == nil || req.DeclaredCash.IsNegative() {
+		return ZReport{}, errors.New("declared_cash is required and cannot be negative")
+	}
+	collector, err := uuid.Parse(userID)
+	if err != nil {
+		return ZReport{}, err
+	}
+
+	var report ZReport
+	err = s.uow.Do(ctx, func(st Stores) error {
+		// Waits for payments still being added to the session.
+		session, err := st.Payments.GetCollectorSessionForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := auth.AuthorizeCounty(ctx, session.CountyID); err != nil {
+			return err
+		}
+		if session.Status != "open" {
+			return fmt.Errorf("%w: it is %s", ErrSessionNotOpen, session.Status)
+		}
+		if session.CollectorID != collector {
+			return ErrNotSessionCollector
+		}
+
+		methods, err := st.Payments.SumSessionPaymentsByMethod(ctx, session.ID)
+		if err != nil {
+			return err
+		}
+		cash, expected := expectedCash(session.OpeningFloat, methods)
+		session, err = st.Payments.CloseCollectorSession(ctx, models.CloseCollectorSessionParams{
+			ID:           session.ID,
+			ExpectedCash: money.NullAmount{Amount: expected, Valid: true},
+			DeclaredCash: money.NullAmount{Amount: *req.DeclaredCash, Valid: true},
+			Variance:     money.NullAmount{Amount: req.DeclaredCash.Sub(expected), Valid: true},
+			CloseNote:    sql.NullString{String: req.Note, Valid: req.Note != ""},
+		})
+		if err != nil {
+			return err
+		}
+		report = ZReport{Session: session, Methods: methods, CashCollected: cash, ExpectedCash: expected}
+		return nil
+	})
+	if err != nil {
+		return ZReport{}, err
+	}
+	return report, nil
+}
+
+// GetZReport returns a session with its takings.
+func (s *Service) GetZReport(ctx context.Context, id string) (ZReport, error) {
+	session, err := s.repo.GetCollectorSession(ctx, id)
+	if err != nil {
+		return ZReport{}, err
+	}
+	if err := auth.AuthorizeCounty(ctx, session.CountyID); err != nil {
+		return ZReport{}, err
+	}
+	return zReport(ctx, s.repo, session)
+}
+
+// ApproveSession signs off a closed Z-report.
+func (s *Service) ApproveSession(ctx context.Context, id string, req ReviewSessionRequest, userID string) (models.CollectorSession, error) {
+	return s.reviewSession(ctx, id, "approved", req.Note, userID)
+}
+
+// RejectSession disputes a Z-report, for instance over an unexplained
+// variance.
+func (s *Service) RejectSession(ctx context.Context, id string, req ReviewSessionRequest, userID string) (models.CollectorSession, error) {
+	if req.Note == "" {
+		return models.CollectorSession{}, errors.New("a note explaining the rejection is required")
+	}
+	return s.reviewSession(ctx, id, "rejected", req.Note, userID)
+}
+
+func (s *Service) reviewSession(ctx context.Context, id, status, note, userID string) (models.CollectorSession, error) {
+	if s.uow == nil {
+		return models.CollectorSession{}, errors.New("collector sessions are not configured")
+	}
+	reviewer, err := uuid.Parse(userID)
+	if err != nil {
+		return models.CollectorSession{}, err
+	}
+
+	var session models.CollectorSession
+	err = s.uow.Do(ctx, func(st Stores) error {
+		closed, err := st.Payments.GetCollectorSessionForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := auth.AuthorizeCounty(ctx, closed.CountyID); err != nil {
+			return err
+		}
+		if closed.Status != "closed" {
+			return fmt.Errorf("%w: it is %s", ErrSessionReviewed, closed.Status)
+		}
+		if closed.CollectorID == reviewer {
+			return ErrSessionSelfApproval
+		}
+		session, err = st.Payments.ReviewCollectorSession(ctx, models.ReviewCollectorSessionParams{
+			ID:         closed.ID,
+			Status:     status,
+			ReviewedBy: uuid.NullUUID{UUID: reviewer, Valid: true},
+			ReviewNote: sql.NullString{String: note, Valid: note != ""},
+		})
+		return err
+	})
+	if err != nil {
+		return models.CollectorSession{}, err
+	}
+	return session, nil
+}
+
+// ListSessions lists a county's sessions, newest first, optionally only
+// those in status.
+func (s *Service) ListSessions(ctx context.Context, countyID int32, status string, limit, offset int32) ([]models.CollectorSession, error) {
+	countyID, err := auth.ResolveCounty(ctx, countyID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListCollectorSessions(ctx, models.ListCollectorSessionsParams{
+		CountyID: countyID,
+		Status:   sql.NullString{String: status, Valid: status != ""},
+		Limit:    limit,
+		Offset:   offset,
+	})
+}
+
+// ListZReports is the end-of-day report of a county: the closed sessions
+// opened on day, totalled per collector and collection point.
+func (s *Service) ListZReports(ctx context.Context, countyID int32, day time.Time) ([]models.ListZReportsRow, error) {
+	countyID, err := auth.ResolveCounty(ctx, countyID)
+	if err != nil {
+		return nil, err
+	}
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	return s.repo.ListZReports(ctx, models.ListZReportsParams{
+		CountyID:   countyID,
+		OpenedFrom: from,
+		OpenedTo:   from.AddDate(0, 0, 1),
+	})
+}
+
+// joinSession finds the open session of the collector recording params and
+// fills in its collection point. Cash payments must have one; other payments
+// join it when there is one, so the Z-report shows everything taken during
+// the shift. The session stays share-locked until the payment is committed.
+func joinSession(ctx context.Context, st Stores, params models.InsertPaymentParams) (models.InsertPaymentParams, uuid.NullUUID, error) {
+	cash := params.PaymentMethod == "cash"
+	if !params.CollectedBy.Valid {
+		if cash {
+			return params, uuid.NullUUID{}, ErrNoOpenSession
+		}
+		return params, uuid.NullUUID{}, nil
+	}
+
+	session, err := st.Payments.GetOpenCollectorSession(ctx, params.CollectedBy.UUID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && session.CountyID != params.CountyID) {
+		if cash {
+			return params, uuid.NullUUID{}, ErrNoOpenSession
+		}
+		return params, uuid.NullUUID{}, nil
+	}
+	if err != nil {
+		return params, uuid.NullUUID{}, err
+	}
+
+	if !params.CollectionPoint.Valid || params.CollectionPoint.String == "" {
+		params.CollectionPoint = sql.NullString{String: session.CollectionPoint, Valid: true}
+	} else if cash && params.CollectionPoint.String != session.CollectionPoint {
+		return params, uuid.NullUUID{}, fmt.Errorf("%w at %s; this session is at %s", ErrNoOpenSession, params.CollectionPoint.String, session.CollectionPoint)
+	}
+	return params, uuid.NullUUID{UUID: session.ID, Valid: true}, nil
+}
@@ -0,0 +1,27 @@
+package payments
+
+import (
+	"testing"
+
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpectedCashCountsOnlyCash(t *testing.T) {
+	methods := []models.SumSessionPaymentsByMethodRow{
+		{PaymentMethod: "cash", Payments: 3, Total: money.MustParse("1500.50")},
+		{PaymentMethod: "mpesa", Payments: 2, Total: money.MustParse("4000")},
+		{PaymentMethod: "cheque", Payments: 1, Total: money.MustParse("10000")},
+	}
+
+	cash, expected := expectedCash(money.MustParse("2000"), methods)
+	assert.Zero(t, cash.Cmp(money.MustParse("1500.50")))
+	assert.Zero(t, expected.Cmp(money.MustParse("3500.50")))
+}
+
+func TestExpectedCashWithoutTakings(t *testing.T) {
+	cash, expected := expectedCash(money.MustParse("500"), nil)
+	assert.True(t, cash.IsZero())
+	assert.Zero(t, expected.Cmp(money.MustParse("500")))
+}
@@ -0,0 +1,149 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+)
+
+var (
+	ErrIllegalTransition = errors.New("illegal payment status transition")
+	ErrPaymentFrozen     = errors.New("completed payments cannot change")
+)
+
+// paymentTransitions is the payment lifecycle: the statuses each status may
+// move to. A payment is taken in as pending, starts processing once the money
+// is on its way and ends completed, failed or cancelled. Only a completed
+// payment can be refunded, and failed, cancelled and refunded are final.
+var paymentTransitions = map[string][]string{
+	"pending":    {"processing", "cancelled"},
+	"processing": {"completed", "failed", "cancelled"},
+	"completed":  {"refunded"},
+}
+
+// initialStatus reports whether a payment may be recorded in status: pending,
+// processing, or completed when the money is already in hand.
+func initialStatus(status string) bool {
+	return status == "pending" || status == "processing" || status == "completed"
+}
+
+// checkTransition allows staying in the same status and every move in
+// paymentTransitions.
+func checkTransition(from, to string) error {
+	if from == to {
+		return nil
+	}
+	for _, next := range paymentTransitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s to %s", ErrIllegalTransition, from, to)
+}
+
+// frozen reports whether a payment's financial fields are fixed. Once the
+// money is in they are on receipts and in the books; mistakes are corrected
+// by refunding the payment.
+func frozen(status string) bool {
+	return status == "completed" || status == "refunded"
+}
+
+// frozenChanges lists the financial fields req would change on current.
+func frozenChanges(current models.Payment, req UpdatePaymentRequest) []string {
+	var fields []string
+	changed := func(field string, value *string, old sql.NullString) {
+		if value != nil && *value != old.String {
+			fields = append(fields, field)
+		}
+	}
+	if req.Amount != nil && req.Amount.Cmp(current.Amount) != 0 {
+		fields = append(fields, "amount")
+	}
+	if req.PaymentMethod != nil && *req.PaymentMethod != current.PaymentMethod {
+		fields = append(fields, "payment_method")
+	}
+	changed("payment_channel", req.PaymentChannel, current.PaymentChannel)
+	changed("external_transaction_id", req.ExternalTransactionID, current.ExternalTransactionID)
+	changed("mpesa_receipt_number", req.MpesaReceiptNumber, current.MpesaReceiptNumber)
+	changed("bank_reference", req.BankReference, current.BankReference)
+	changed("cheque_number", req.ChequeNumber, current.ChequeNumber)
+	if req.CollectedBy != nil && *req.CollectedBy != current.CollectedBy.UUID.String() {
+		fields = append(fields, "collected_by")
+	}
+	return fields
+}
+
+func checkFrozen(current models.Payment, req UpdatePaymentRequest) error {
+	if !frozen(current.Status) {
+		return nil
+	}
+	if fields := frozenChanges(current, req); len(fields) > 0 {
+		return fmt.Errorf("%w: %s of a %s payment", ErrPaymentFrozen, strings.Join(fields, ", "), current.Status)
+	}
+	return nil
+}
+
+// createPayment inserts a payment into its collector's session and starts
+// its status history.
+func createPayment(ctx context.Context, st Stores, params models.InsertPaymentParams, reason string) (models.Payment, error) {
+	params, session, err := joinSession(ctx, st, params)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	payment, err := st.Payments.CreatePayment(ctx, params)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if session.Valid {
+		if err := st.Payments.AddSessionPayment(ctx, session.UUID, payment.ID); err != nil {
+			return models.Payment{}, err
+		}
+	}
+	return payment, recordStatus(ctx, st, payment, "", params.CollectedBy, reason)
+}
+
+// setStatus moves a payment the caller has locked to params.Status, checking
+// the move against the lifecycle, and records it. actor is empty for changes
+// made by the system.
+func setStatus(ctx context.Context, st Stores, payment models.Payment, params models.SetPaymentOutcomeParams, actor uuid.NullUUID, reason string) (models.Payment, error) {
+	if err := checkTransition(payment.Status, params.Status); err != nil {
+		return models.Payment{}, err
+	}
+	params.ID = payment.ID
+	updated, err := st.Payments.SetPaymentOutcome(ctx, params)
+	if err != nil {
+		return models.Payment{}, err
+	}
+	return updated, recordStatus(ctx, st, updated, payment.Status, actor, reason)
+}
+
+// recordStatus appends payment's current status to its history unless it is
+// still from. from is empty for a payment that has just been created.
+func recordStatus(ctx context.Context, st Stores, payment models.Payment, from string, actor uuid.NullUUID, reason string) error {
+	if from == payment.Status {
+		return nil
+	}
+	_, err := st.Payments.RecordPaymentStatusChange(ctx, models.InsertPaymentStatusChangeParams{
+		PaymentID:  payment.ID,
+		FromStatus: sql.NullString{String: from, Valid: from != ""},
+		ToStatus:   payment.Status,
+		Reason:     sql.NullString{String: reason, Valid: reason != ""},
+		ChangedBy:  actor,
+	})
+	return err
+}
+
+// GetPaymentHistory lists the statuses a payment has been through, oldest
+// first.
+func (s *Service) GetPaymentHistory(ctx context.Context, id string) ([]models.PaymentStatusHistory, error) {
+	payment, err := s.GetPayment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListPaymentStatusHistory(ctx, payment.ID)
+}
@@ -0,0 +1,59 @@
+package payments
+
+import (
+	"testing"
+
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckTransition(t *testing.T) {
+	allowed := [][2]string{
+		{"pending", "processing"},
+		{"pending", "cancelled"},
+		{"processing", "completed"},
+		{"processing", "failed"},
+		{"processing", "cancelled"},
+		{"completed", "refunded"},
+		{"completed", "completed"},
+	}
+	for _, tt := range allowed {
+		assert.NoError(t, checkTransition(tt[0], tt[1]), "%s to %s", tt[0], tt[1])
+	}
+
+	illegal := [][2]string{
+		{"pending", "refunded"},
+		{"completed", "pending"},
+		{"completed", "failed"},
+		{"failed", "processing"},
+		{"cancelled", "completed"},
+		{"refunded", "completed"},
+	}
+	for _, tt := range illegal {
+		assert.ErrorIs(t, checkTransition(tt[0], tt[1]), ErrIllegalTransition, "%s to %s", tt[0], tt[1])
+	}
+}
+
+func TestCheckFrozen(t *testing.T) {
+	amount := money.MustParse("750")
+	same := money.MustParse("500.00")
+	method := "cash"
+	name := "Jane Wanjiru"
+	cheque := "000123"
+
+	completed := models.Payment{
+		Status:        "completed",
+		Amount:        money.MustParse("500"),
+		PaymentMethod: "mpesa",
+	}
+	assert.NoError(t, checkFrozen(completed, UpdatePaymentRequest{Amount: &same, PayerName: &name}))
+
+	err := checkFrozen(completed, UpdatePaymentRequest{PaymentMethod: &method, ChequeNumber: &cheque})
+	assert.ErrorIs(t, err, ErrPaymentFrozen)
+	assert.Contains(t, err.Error(), "payment_method, cheque_number")
+
+	pending := completed
+	pending.Status = "pending"
+	assert.NoError(t, checkFrozen(pending, UpdatePaymentRequest{Amount: &amount, PaymentMethod: &method}))
+}
@@ -0,0 +1,234 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	assessmentmodels "github.com/sangkips/revenue-system/internal/domain/assessment/models"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
+	"github.com/sangkips/revenue-system/internal/mpesa"
+)
+
+// M-Pesa suspense holds money Daraja reported that no payment was waiting
+// for. The paybill is shared by every county and an entry has no county
+// until it is matched, so the queue is worked by super admins.
+
+var ErrSuspenseResolved = errors.New("M-Pesa suspense entry has already been resolved")
+
+// MatchMpesaSuspenseRequest names what an entry's money was for: the
+// assessment it should have been paid against, or the STK payment left
+// processing whose result it is.
+type MatchMpesaSuspenseRequest struct {
+	AssessmentID string `json:"assessment_id,omitempty"`
+	PaymentID    string `json:"payment_id,omitempty"`
+	Note         string `json:"note,omitempty"`
+}
+
+type IgnoreMpesaSuspenseRequest struct {
+	Note string `json:"note"` // why the money needs no payment, e.g. "reversed by Safaricom"
+}
+
+// MatchMpesaSuspense resolves an unmatched entry by hand. Matched to an
+// assessment, it becomes a completed M-Pesa payment allocated like a paybill
+// payment; matched to a processing STK payment, it is applied as that
+// payment's callback would have been.
+func (s *Service) MatchMpesaSuspense(ctx context.Context, id string, req MatchMpesaSuspenseRequest, userID string) (models.MpesaSuspense, error) {
+	if s.uow == nil {
+		return models.MpesaSuspense{}, ErrMpesaDisabled
+	}
+	if (req.AssessmentID == "") == (req.PaymentID == "") {
+		return models.MpesaSuspense{}, errors.New("exactly one of assessment_id and payment_id is required")
+	}
+
+	var resolved models.MpesaSuspense
+	var completed uuid.NullUUID
+	err := s.uow.Do(ctx, func(st Stores) error {
+		completed = uuid.NullUUID{}
+		entry, err := s.lockUnmatchedSuspense(ctx, st, id)
+		if err != nil {
+			return err
+		}
+
+		var payment models.Payment
+		if req.PaymentID != "" {
+			payment, err = s.applySuspenseToPayment(ctx, st, entry, req.PaymentID)
+		} else {
+			payment, err = s.paySuspenseToAssessment(ctx, st, entry, req.AssessmentID)
+		}
+		if err != nil {
+			return err
+		}
+		completed = uuid.NullUUID{UUID: payment.ID, Valid: true}
+
+		resolved, err = st.Payments.ResolveMpesaSuspense(ctx, models.ResolveMpesaSuspenseParams{
+			Status:     "matched",
+			CountyID:   sql.NullInt32{Int32: payment.CountyID, Valid: true},
+			PaymentID:  uuid.NullUUID{UUID: payment.ID, Valid: true},
+			ResolvedBy: nullUUID(userID),
+			Note:       sql.NullString{String: req.Note, Valid: req.Note != ""},
+			ID:         entry.ID,
+		})
+		return err
+	})
+	if err != nil {
+		return models.MpesaSuspense{}, err
+	}
+	s.renderReceipts(ctx, completed.UUID)
+	return resolved, nil
+}
+
+// applySuspenseToPayment completes an STK payment with the result held in
+// suspense, for when saving its checkout request failed after the push.
+func (s *Service) applySuspenseToPayment(ctx context.Context, st Stores, entry models.MpesaSuspense, paymentID string) (models.Payment, error) {
+	if entry.Source != "stk_callback" {
+		return models.Payment{}, errors.New("only STK results can be matched to a payment; match paybill payments to an assessment")
+	}
+	id, err := uuid.Parse(paymentID)
+	if err != nil {
+		return models.Payment{}, fmt.Errorf("payment %s not found", paymentID)
+	}
+	payment, err := st.Payments.GetPaymentForUpdate(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Payment{}, fmt.Errorf("payment %s not found", paymentID)
+	}
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if err := auth.AuthorizeCounty(ctx, payment.CountyID); err != nil {
+		return models.Payment{}, err
+	}
+	if payment.Status != "processing" || payment.PaymentChannel.String != "stk_push" {
+		return models.Payment{}, errors.New("only processing STK payments can take a held result")
+	}
+	if payment.ExternalTransactionID.Valid && payment.ExternalTransactionID.String != entry.Reference {
+		return models.Payment{}, errors.New("payment is waiting on a different checkout request")
+	}
+
+	if !payment.ExternalTransactionID.Valid {
+		if payment, err = st.Payments.SetPaymentCheckoutRequest(ctx, payment.ID, entry.Reference); err != nil {
+			return models.Payment{}, err
+		}
+	}
+	payment, ok, err := s.applySTKResult(ctx, st, payment, stkResult(entry))
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if !ok {
+		return models.Payment{}, fmt.Errorf("held amount %s does not match payment amount %s", entry.Amount.Amount, payment.Amount)
+	}
+	return payment, nil
+}
+
+// paySuspenseToAssessment records the held money as a completed payment
+// against the assessment it was meant for.
+func (s *Service) paySuspenseToAssessment(ctx context.Context, st Stores, entry models.MpesaSuspense, assessmentID string) (models.Payment, error) {
+	a, err := st.Assessments.GetAssessmentByID(ctx, assessmentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Payment{}, fmt.Errorf("assessment %s not found", assessmentID)
+	}
+	if err != nil {
+		return models.Payment{}, err
+	}
+	if err := auth.AuthorizeCounty(ctx, a.CountyID); err != nil {
+		return models.Payment{}, err
+	}
+
+	_, err = st.Payments.GetPaymentByMpesaReceiptNumber(ctx, entry.MpesaReceiptNumber.String)
+	if err == nil {
+		return models.Payment{}, fmt.Errorf("M-Pesa receipt %s is already on a payment", entry.MpesaReceiptNumber.String)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.Payment{}, err
+	}
+
+	payment, err := createPayment(ctx, st, suspensePaymentParams(entry, a), "M-Pesa payment matched from suspense")
+	if err != nil {
+		return models.Payment{}, err
+	}
+	return payment, s.allocateToAssessment(ctx, st, payment)
+}
+
+// suspensePaymentParams builds the payment for held money paid against a.
+func suspensePaymentParams(entry models.MpesaSuspense, a assessmentmodels.Assessment) models.InsertPaymentParams {
+	params := c2bPaymentParams(mpesa.C2BPayment{
+		TransID:     entry.MpesaReceiptNumber.String,
+		Amount:      entry.Amount.Amount,
+		PhoneNumber: entry.PhoneNumber.String,
+		PayerName:   entry.PayerName.String,
+		TransTime:   entry.TransactionDate.Time,
+	}, a)
+	params.PaymentDate.Valid = entry.TransactionDate.Valid
+	if entry.Source == "stk_callback" {
+		params.PaymentChannel = sql.NullString{String: "stk_push", Valid: true}
+		params.ExternalTransactionID = sql.NullString{String: entry.Reference, Valid: true}
+	}
+	return params
+}
+
+// IgnoreMpesaSuspense takes an entry that no payment should account for,
+// such as a transaction Safaricom reversed, out of the queue.
+func (s *Service) IgnoreMpesaSuspense(ctx context.Context, id string, req IgnoreMpesaSuspenseRequest, userID string) (models.MpesaSuspense, error) {
+	if s.uow == nil {
+		return models.MpesaSuspense{}, ErrMpesaDisabled
+	}
+	if req.Note == "" {
+		return models.MpesaSuspense{}, errors.New("note is required when ignoring an M-Pesa suspense entry")
+	}
+
+	var resolved models.MpesaSuspense
+	err := s.uow.Do(ctx, func(st Stores) error {
+		entry, err := s.lockUnmatchedSuspense(ctx, st, id)
+		if err != nil {
+			return err
+		}
+		resolved, err = st.Payments.ResolveMpesaSuspense(ctx, models.ResolveMpesaSuspenseParams{
+			Status:     "ignored",
+			ResolvedBy: nullUUID(userID),
+			Note:       sql.NullString{String: req.Note, Valid: true},
+			ID:         entry.ID,
+		})
+		return err
+	})
+	return resolved, err
+}
+
+func (s *Service) lockUnmatchedSuspense(ctx context.Context, st Stores, id string) (models.MpesaSuspense, error) {
+	if err := authorizeSuspense(ctx); err != nil {
+		return models.MpesaSuspense{}, err
+	}
+	entry, err := st.Payments.GetMpesaSuspenseForUpdate(ctx, id)
+	if err != nil {
+		return models.MpesaSuspense{}, err
+	}
+	if entry.Status != "unmatched" {
+		return models.MpesaSuspense{}, ErrSuspenseResolved
+	}
+	return entry, nil
+}
+
+// ListMpesaSuspense is the queue of M-Pesa results still waiting for a person
+// to match or ignore them, oldest first.
+func (s *Service) ListMpesaSuspense(ctx context.Context, limit int32, offset int32) ([]models.MpesaSuspense, error) {
+	if err := authorizeSuspense(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.ListUnmatchedMpesaSuspense(ctx, models.ListUnmatchedMpesaSuspenseParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func authorizeSuspense(ctx context.Context) error {
+	_, unrestricted, err := auth.CallerCounty(ctx)
+	if err != nil {
+		return err
+	}
+	if !unrestricted {
+		return auth.ErrCountyForbidden
+	}
+	return nil
+}
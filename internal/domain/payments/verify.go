@@ -0,0 +1,187 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/ledger"
+	"github.com/sangkips/revenue-system/internal/money"
+)
+
+// Anyone holding a receipt can check it without logging in, either by its
+// number or by scanning its QR code. The QR code carries a link with a token
+// signed by the server, so a code that verifies was printed by the county.
+// The public view leaves out everything that identifies the taxpayer beyond
+// a masked name, and only for signed tokens.
+
+var ErrInvalidReceiptToken = errors.New("the receipt QR code was not issued by the county")
+
+// tokenMACSize is how much of the HMAC a token carries; 128 bits is plenty
+// against forgery and keeps the QR code small.
+const tokenMACSize = 16
+
+// WithReceiptVerification signs the verification links printed in receipt QR
+// codes with secret. baseURL is the public address of this server.
+func WithReceiptVerification(secret []byte, baseURL string) Option {
+	return func(s *Service) {
+		s.verifyKey = secret
+		s.verifyURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// ReceiptVerification is the public view of a receipt.
+type ReceiptVerification struct {
+	Status        string              `json:"status"` // "valid", "voided" or "unverified"
+	ReceiptNumber string              `json:"receipt_number"`
+	County        string              `json:"county"`
+	IssuedOn      string              `json:"issued_on"`
+	Amount        money.Amount        `json:"amount"`
+	PaymentMethod string              `json:"payment_method"`
+	PaymentStatus string              `json:"payment_status"`
+	Voided        bool                `json:"voided"`
+	Payer         string              `json:"payer,omitempty"` // masked
+	Authentic     bool                `json:"authentic"`       // reached through a signed QR code
+	Ledger        ReceiptLedgerStatus `json:"ledger"`
+}
+
+// ReceiptLedgerStatus is what the ledger says about a receipt.
+type ReceiptLedgerStatus struct {
+	Recorded bool  `json:"recorded"`
+	Intact   bool  `json:"intact"` // the receipt still matches its ledger entries
+	Sealed   bool  `json:"sealed"`
+	Block    int64 `json:"block,omitempty"`
+}
+
+// receiptToken signs a receipt ID.
+func (s *Service) receiptToken(id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString(append(id[:], s.receiptMAC(id)...))
+}
+
+func (s *Service) receiptMAC(id uuid.UUID) []byte {
+	mac := hmac.New(sha256.New, s.verifyKey)
+	mac.Write([]byte("receipt:"))
+	mac.Write(id[:])
+	return mac.Sum(nil)[:tokenMACSize]
+}
+
+// parseReceiptToken returns the receipt ID a token was signed for.
+func (s *Service) parseReceiptToken(token string) (uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != len(uuid.UUID{})+tokenMACSize || len(s.verifyKey) == 0 {
+		return uuid.Nil, ErrInvalidReceiptToken
+	}
+	id, err := uuid.FromBytes(raw[:len(uuid.UUID{})])
+	if err != nil || !hmac.Equal(raw[len(uuid.UUID{}):], s.receiptMAC(id)) {
+		return uuid.Nil, ErrInvalidReceiptToken
+	}
+	return id, nil
+}
+
+// verificationURL is the link printed in a receipt's QR code, or "" when
+// verification links are not configured.
+func (s *Service) verificationURL(id uuid.UUID) string {
+	if s.verifyURL == "" || len(s.verifyKey) == 0 {
+		return ""
+	}
+	return s.verifyURL + "/verify/tokens/" + s.receiptToken(id)
+}
+
+// VerifyReceipt looks a receipt up by its number.
+func (s *Service) VerifyReceipt(ctx context.Context, number string) (ReceiptVerification, error) {
+	if number == "" {
+		return ReceiptVerification{}, sql.ErrNoRows
+	}
+	row, err := s.repo.GetReceiptVerification(ctx, models.GetReceiptVerificationParams{
+		ReceiptNumber: sql.NullString{String: number, Valid: true},
+	})
+	if err != nil {
+		return ReceiptVerification{}, err
+	}
+	return s.receiptVerification(ctx, row, false)
+}
+
+// VerifyReceiptToken looks up the receipt a QR code token was signed for.
+func (s *Service) VerifyReceiptToken(ctx context.Context, token string) (ReceiptVerification, error) {
+	id, err := s.parseReceiptToken(token)
+	if err != nil {
+		return ReceiptVerification{}, err
+	}
+	row, err := s.repo.GetReceiptVerification(ctx, models.GetReceiptVerificationParams{
+		ID: uuid.NullUUID{UUID: id, Valid: true},
+	})
+	if err != nil {
+		return ReceiptVerification{}, err
+	}
+	return s.receiptVerification(ctx, row, true)
+}
+
+func (s *Service) receiptVerification(ctx context.Context, row models.GetReceiptVerificationRow, authentic bool) (ReceiptVerification, error) {
+	entries, err := s.repo.ListReceiptLedgerEntriesByReceipt(ctx, row.ID)
+	if err != nil {
+		return ReceiptVerification{}, err
+	}
+	v := ReceiptVerification{
+		ReceiptNumber: row.ReceiptNumber,
+		County:        row.CountyName,
+		Amount:        row.Amount,
+		PaymentMethod: row.PaymentMethod,
+		PaymentStatus: row.PaymentStatus,
+		Voided:        row.VoidedAt.Valid,
+		Authentic:     authentic,
+		Ledger:        receiptLedgerStatus(entries),
+	}
+	if row.CreatedAt.Valid {
+		v.IssuedOn = row.CreatedAt.Time.Format("2006-01-02")
+	}
+	if authentic {
+		v.Payer = maskName(row.TaxpayerName)
+	}
+	switch {
+	case v.Voided || row.PaymentStatus == "refunded":
+		v.Status = "voided"
+	case row.PaymentStatus == "completed" && v.Ledger.Recorded && v.Ledger.Intact:
+		v.Status = "valid"
+	default:
+		v.Status = "unverified"
+	}
+	return v, nil
+}
+
+// receiptLedgerStatus checks each of a receipt's entries against the
+// receipt as it is now. Linking to neighbouring entries is left to the full
+// chain verification.
+func receiptLedgerStatus(entries []models.ListReceiptLedgerEntriesByReceiptRow) ReceiptLedgerStatus {
+	var status ReceiptLedgerStatus
+	if len(entries) == 0 || entries[0].Event != ledger.Issued {
+		return status
+	}
+	status.Recorded, status.Intact = true, true
+	for _, row := range entries {
+		e := models.ListReceiptLedgerEntriesRow(row)
+		check := newChainCheck(e.PaymentCountyID)
+		check.startAfter(e.Sequence-1, e.PreviousHash)
+		check.entry(e)
+		status.Intact = status.Intact && check.report.OK()
+		if e.Event == ledger.Issued && e.BlockNumber.Valid {
+			status.Sealed, status.Block = true, e.BlockNumber.Int64
+		}
+	}
+	return status
+}
+
+// maskName keeps the first letter of each word of a name.
+func maskName(name string) string {
+	words := strings.Fields(name)
+	for i, w := range words {
+		r := []rune(w)
+		words[i] = string(r[0]) + strings.Repeat("*", len(r)-1)
+	}
+	return strings.Join(words, " ")
+}
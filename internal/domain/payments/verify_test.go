@@ -0,0 +1,59 @@
+package payments
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/payments/models"
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiptToken(t *testing.T) {
+	s := NewService(nil, WithReceiptVerification([]byte("secret"), "https://revenue.example.go.ke/"))
+	id := uuid.New()
+
+	token := s.receiptToken(id)
+	got, err := s.parseReceiptToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, id, got)
+	assert.Equal(t, "https://revenue.example.go.ke/verify/tokens/"+token, s.verificationURL(id))
+
+	// A token for another receipt, or signed with another key, is refused.
+	forged := []byte(token)
+	forged[3] ^= 1
+	_, err = s.parseReceiptToken(string(forged))
+	assert.ErrorIs(t, err, ErrInvalidReceiptToken)
+
+	other := NewService(nil, WithReceiptVerification([]byte("other"), ""))
+	_, err = other.parseReceiptToken(token)
+	assert.ErrorIs(t, err, ErrInvalidReceiptToken)
+	assert.Empty(t, other.verificationURL(id))
+
+	_, err = s.parseReceiptToken("not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidReceiptToken)
+}
+
+func TestReceiptLedgerStatus(t *testing.T) {
+	entries, _, _ := testChain(t, 3, 2)
+	byReceipt := func(e models.ListReceiptLedgerEntriesRow) []models.ListReceiptLedgerEntriesByReceiptRow {
+		return []models.ListReceiptLedgerEntriesByReceiptRow{models.ListReceiptLedgerEntriesByReceiptRow(e)}
+	}
+
+	assert.Equal(t, ReceiptLedgerStatus{Recorded: true, Intact: true, Sealed: true, Block: 1}, receiptLedgerStatus(byReceipt(entries[0])))
+	assert.Equal(t, ReceiptLedgerStatus{Recorded: true, Intact: true}, receiptLedgerStatus(byReceipt(entries[2])))
+	assert.Equal(t, ReceiptLedgerStatus{}, receiptLedgerStatus(nil))
+
+	edited := entries[1]
+	edited.Amount = money.NullAmount{Amount: money.MustParse("15"), Valid: true}
+	status := receiptLedgerStatus(byReceipt(edited))
+	assert.True(t, status.Recorded)
+	assert.False(t, status.Intact)
+}
+
+func TestMaskName(t *testing.T) {
+	assert.Equal(t, "W****** T****** L**", maskName("Wanjiku Traders Ltd"))
+	assert.Equal(t, "Ö***", maskName(" Ödön "))
+	assert.Empty(t, maskName(""))
+}
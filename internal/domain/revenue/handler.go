@@ -8,6 +8,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
 	"github.com/sangkips/revenue-system/internal/domain/revenue/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
 )
 
 type Handler struct {
@@ -20,12 +21,12 @@ func NewHandler(db models.DBTX) *Handler {
 }
 
 func (h *Handler) RegisterRevenueRoutes(r chi.Router) {
-	r.Post("/", h.CreateRevenue)
-	r.Get("/{id}", h.GetRevenue)
-	r.Get("/", h.ListRevenues)
-	r.Get("/taxpayer/{taxpayer_id}", h.ListRevenuesByTaxpayerID)
-	r.Patch("/{id}", h.UpdateRevenue)
-	r.Delete("/{id}", h.DeleteRevenue)
+	r.With(auth.RequirePermission(auth.PermRevenuesWrite)).Post("/", h.CreateRevenue)
+	r.With(auth.RequirePermission(auth.PermRevenuesRead)).Get("/{id}", h.GetRevenue)
+	r.With(auth.RequirePermission(auth.PermRevenuesRead)).Get("/", h.ListRevenues)
+	r.With(auth.RequirePermission(auth.PermRevenuesRead)).Get("/taxpayer/{taxpayer_id}", h.ListRevenuesByTaxpayerID)
+	r.With(auth.RequirePermission(auth.PermRevenuesWrite)).Patch("/{id}", h.UpdateRevenue)
+	r.With(auth.RequirePermission(auth.PermRevenuesWrite)).Delete("/{id}", h.DeleteRevenue)
 }
 
 func (h *Handler) CreateRevenue(w http.ResponseWriter, r *http.Request) {
@@ -40,7 +41,7 @@ func (h *Handler) CreateRevenue(w http.ResponseWriter, r *http.Request) {
 	revenue, err := h.svc.CreateRevenue(ctx, req)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create revenue")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -54,7 +55,8 @@ func (h *Handler) GetRevenue(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	revenue, err := h.svc.GetRevenue(ctx, id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusNotFound))
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -78,7 +80,7 @@ func (h *Handler) ListRevenues(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	revenue, err := h.svc.ListRevenues(ctx, int32(countyID), int32(limit), int32(offset))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -96,7 +98,7 @@ func (h *Handler) UpdateRevenue(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	revenue, err := h.svc.UpdateRevenue(ctx, id, req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -120,7 +122,7 @@ func (h *Handler) ListRevenuesByTaxpayerID(w http.ResponseWriter, r *http.Reques
 	ctx := r.Context()
 	revenue, err := h.svc.ListRevenuesByTaxpayerID(ctx, taxpayerID, int32(limit), int32(offset))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -132,7 +134,7 @@ func (h *Handler) DeleteRevenue(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	ctx := r.Context()
 	if err := h.svc.DeleteRevenue(ctx, id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
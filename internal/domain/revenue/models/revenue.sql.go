@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/money"
 )
 
 const deleteRevenue = `-- name: DeleteRevenue :exec
@@ -59,7 +60,7 @@ RETURNING id, taxpayer_id, county_id, amount, revenue_type, transaction_date, de
 type InsertRevenueParams struct {
 	TaxpayerID      uuid.UUID      `json:"taxpayer_id"`
 	CountyID        int32          `json:"county_id"`
-	Amount          string         `json:"amount"`
+	Amount          money.Amount   `json:"amount"`
 	RevenueType     string         `json:"revenue_type"`
 	TransactionDate time.Time      `json:"transaction_date"`
 	Description     sql.NullString `json:"description"`
@@ -198,11 +199,11 @@ RETURNING id, taxpayer_id, county_id, amount, revenue_type, transaction_date, de
 `
 
 type UpdateRevenueParams struct {
-	Amount          sql.NullString `json:"amount"`
-	RevenueType     sql.NullString `json:"revenue_type"`
-	TransactionDate sql.NullTime   `json:"transaction_date"`
-	Description     sql.NullString `json:"description"`
-	ID              uuid.UUID      `json:"id"`
+	Amount          money.NullAmount `json:"amount"`
+	RevenueType     sql.NullString   `json:"revenue_type"`
+	TransactionDate sql.NullTime     `json:"transaction_date"`
+	Description     sql.NullString   `json:"description"`
+	ID              uuid.UUID        `json:"id"`
 }
 
 func (q *Queries) UpdateRevenue(ctx context.Context, arg UpdateRevenueParams) (Revenue, error) {
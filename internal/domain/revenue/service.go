@@ -4,11 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sangkips/revenue-system/internal/domain/revenue/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
+	"github.com/sangkips/revenue-system/internal/money"
 )
 
 type Service struct {
@@ -21,7 +22,13 @@ func NewService(repo Repository) *Service {
 
 
 func (s *Service) CreateRevenue(ctx context.Context, req CreateRevenueRequest) (models.Revenue, error) {
-	if req.TaxpayerID == "" || req.CountyID == 0 || req.Amount <= 0 || req.RevenueType == "" || req.TransactionDate.IsZero() {
+	countyID, err := auth.ResolveCounty(ctx, req.CountyID)
+	if err != nil {
+		return models.Revenue{}, err
+	}
+	req.CountyID = countyID
+
+	if req.TaxpayerID == "" || req.CountyID == 0 || !req.Amount.IsPositive() || req.RevenueType == "" || req.TransactionDate.IsZero() {
 		return models.Revenue{}, errors.New("taxpayer_id, county_id, amount, revenue_type, and transaction_date are required")
 	}
 
@@ -37,7 +44,7 @@ func (s *Service) CreateRevenue(ctx context.Context, req CreateRevenueRequest) (
 	params := models.InsertRevenueParams{
 		TaxpayerID:      taxpayerID,
 		CountyID:        req.CountyID,
-		Amount:          fmt.Sprintf("%.2f", req.Amount),
+		Amount:          req.Amount,
 		RevenueType:     req.RevenueType,
 		TransactionDate: req.TransactionDate, // To research this date furher
 		Description:     sql.NullString{String: req.Description, Valid: req.Description != ""},
@@ -47,10 +54,23 @@ func (s *Service) CreateRevenue(ctx context.Context, req CreateRevenueRequest) (
 }
 
 func (s *Service) GetRevenue(ctx context.Context, id string) (models.Revenue, error) {
-	return s.repo.GetRevenueByID(ctx, id)
+	revenue, err := s.repo.GetRevenueByID(ctx, id)
+	if err != nil {
+		return models.Revenue{}, err
+	}
+
+	if err := auth.AuthorizeCounty(ctx, revenue.CountyID); err != nil {
+		return models.Revenue{}, err
+	}
+	return revenue, nil
 }
 
 func (s *Service) ListRevenues(ctx context.Context, countyID, limit, offset int32) ([]models.Revenue, error) {
+	countyID, err := auth.ResolveCounty(ctx, countyID)
+	if err != nil {
+		return nil, err
+	}
+
 	return s.repo.ListRevenues(ctx, models.ListRevenuesParams{
 		CountyID: countyID,
 		Limit: limit,
@@ -59,11 +79,25 @@ func (s *Service) ListRevenues(ctx context.Context, countyID, limit, offset int3
 }
 
 func (s *Service) ListRevenuesByTaxpayerID(ctx context.Context, taxpayerID string, limit, offset int32) ([]models.Revenue, error) {
-	return s.repo.ListRevenuesByTaxpayerID(ctx, taxpayerID, limit, offset)
+	revenues, err := s.repo.ListRevenuesByTaxpayerID(ctx, taxpayerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	// A taxpayer belongs to a single county, so out-of-county rows mean the
+	// taxpayer itself is out of scope.
+	scoped := make([]models.Revenue, 0, len(revenues))
+	for _, revenue := range revenues {
+		if err := auth.AuthorizeCounty(ctx, revenue.CountyID); err != nil {
+			return nil, err
+		}
+		scoped = append(scoped, revenue)
+	}
+	return scoped, nil
 }
 
 func (s *Service) UpdateRevenue(ctx context.Context, id string, req UpdateRevenueRequest) (models.Revenue, error) {
-	if req.Amount != nil && *req.Amount <= 0 {
+	if req.Amount != nil && !req.Amount.IsPositive() {
 		return models.Revenue{}, errors.New("amount must be greater than 0 if provided")
 	}
 	if req.RevenueType != nil && (*req.RevenueType != "tax" && *req.RevenueType != "fee" && *req.RevenueType != "fine") {
@@ -77,15 +111,19 @@ func (s *Service) UpdateRevenue(ctx context.Context, id string, req UpdateRevenu
 	if err != nil {
 		return models.Revenue{}, err
 	}
+
+	if _, err := s.GetRevenue(ctx, id); err != nil {
+		return models.Revenue{}, err
+	}
 	params := models.UpdateRevenueParams{
 		ID:              revenueID,
-		Amount:          sql.NullString{Valid: req.Amount != nil, String: ""},
+		Amount:          money.NullAmount{Valid: req.Amount != nil},
 		RevenueType:     sql.NullString{Valid: req.RevenueType != nil, String: ""},
 		TransactionDate: sql.NullTime{Valid: req.TransactionDate != nil, Time: time.Time{}},
 		Description:     sql.NullString{Valid: req.Description != nil, String: ""},
 	}
 	if req.Amount != nil {
-		params.Amount = sql.NullString{Valid: true, String: fmt.Sprintf("%.2f", *req.Amount)}
+		params.Amount = money.NullAmount{Valid: true, Amount: *req.Amount}
 	}
 	if req.RevenueType != nil {
 		params.RevenueType = sql.NullString{Valid: true, String: *req.RevenueType}
@@ -101,6 +139,9 @@ func (s *Service) UpdateRevenue(ctx context.Context, id string, req UpdateRevenu
 }
 
 func (s *Service) DeleteRevenue(ctx context.Context, id string) error {
+	if _, err := s.GetRevenue(ctx, id); err != nil {
+		return err
+	}
 	return s.repo.DeleteRevenue(ctx, id)
 }
 
@@ -108,14 +149,14 @@ func (s *Service) DeleteRevenue(ctx context.Context, id string) error {
 type CreateRevenueRequest struct {
 	TaxpayerID      string    `json:"taxpayer_id"`
 	CountyID        int32     `json:"county_id"`
-	Amount          float64   `json:"amount"`
+	Amount          money.Amount `json:"amount"`
 	RevenueType     string    `json:"revenue_type"`
 	TransactionDate time.Time `json:"transaction_date"`
 	Description     string    `json:"description"`
 }
 
 type UpdateRevenueRequest struct {
-	Amount          *float64  `json:"amount,omitempty"`
+	Amount          *money.Amount `json:"amount,omitempty"`
 	RevenueType     *string   `json:"revenue_type,omitempty"`
 	TransactionDate *time.Time `json:"transaction_date,omitempty"`
 	Description     *string   `json:"description,omitempty"`
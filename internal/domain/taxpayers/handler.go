@@ -8,6 +8,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
 	"github.com/sangkips/revenue-system/internal/domain/taxpayers/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
 )
 
 
@@ -21,11 +22,11 @@ func NewHandler(db models.DBTX) *Handler {
 }
 
 func (h *Handler) RegisterTaxpayerRoutes(r chi.Router) {
-	r.Post("/", h.CreateTaxpayer)
-	r.Get("/{id}", h.GetTaxpayer)
-	r.Get("/", h.ListTaxpayers)
-	r.Patch("/{id}", h.UpdateTaxpayer)
-	r.Delete("/{id}", h.DeleteTaxpayer)
+	r.With(auth.RequirePermission(auth.PermTaxpayersWrite)).Post("/", h.CreateTaxpayer)
+	r.With(auth.RequirePermission(auth.PermTaxpayersRead)).Get("/{id}", h.GetTaxpayer)
+	r.With(auth.RequirePermission(auth.PermTaxpayersRead)).Get("/", h.ListTaxpayers)
+	r.With(auth.RequirePermission(auth.PermTaxpayersWrite)).Patch("/{id}", h.UpdateTaxpayer)
+	r.With(auth.RequirePermission(auth.PermTaxpayersWrite)).Delete("/{id}", h.DeleteTaxpayer)
 }
 
 func (h *Handler) CreateTaxpayer(w http.ResponseWriter, r *http.Request) {
@@ -39,7 +40,7 @@ func (h *Handler) CreateTaxpayer(w http.ResponseWriter, r *http.Request) {
 	taxpayer, err := h.svc.CreateTaxpayer(ctx, req)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create taxpayer")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 	
@@ -53,7 +54,7 @@ func (h *Handler) GetTaxpayer(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	taxpayer, err := h.svc.GetTaxpayer(ctx, id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusNotFound))
 		return
 	}
 
@@ -78,7 +79,7 @@ func (h *Handler) ListTaxpayers(w http.ResponseWriter, r *http.Request) {
 
 	taxpayer, err := h.svc.ListTaxpayers(ctx, int32(countyID), int32(limit), int32(offset))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -98,7 +99,7 @@ func (h *Handler) UpdateTaxpayer(w http.ResponseWriter, r *http.Request) {
 
 	taxpayer, err := h.svc.UpdateTaxpayer(ctx, id, req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -112,7 +113,7 @@ func (h *Handler) DeleteTaxpayer(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	if err := h.svc.DeleteTaxpayer(ctx, id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/sangkips/revenue-system/internal/domain/taxpayers/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
 )
 
 type Service struct {
@@ -18,6 +19,12 @@ func NewService(repo Repository) *Service {
 }
 
 func (s *Service) CreateTaxpayer(ctx context.Context, req CreateTaxpayerRequest) (models.InsertTaxpayerRow, error) {
+	countyID, err := auth.ResolveCounty(ctx, req.CountyID)
+	if err != nil {
+		return models.InsertTaxpayerRow{}, err
+	}
+	req.CountyID = countyID
+
 	if req.CountyID == 0 || req.TaxpayerType == "" || req.NationalID == "" || req.PhoneNumber == "" {
 		return models.InsertTaxpayerRow{}, errors.New("county_id, taxpayer_type, national_id, and phone number are required")
 	}
@@ -57,6 +64,11 @@ func (s *Service) CreateTaxpayer(ctx context.Context, req CreateTaxpayerRequest)
 }
 
 func (s *Service) ListTaxpayers(ctx context.Context, countyID, limit, offset int32) ([]models.ListTaxpayersRow, error) {
+	countyID, err := auth.ResolveCounty(ctx, countyID)
+	if err != nil {
+		return nil, err
+	}
+
 	return s.repo.ListTaxpayers(ctx, models.ListTaxpayersParams{
 		CountyID: countyID,
 		Limit:    limit,
@@ -66,7 +78,15 @@ func (s *Service) ListTaxpayers(ctx context.Context, countyID, limit, offset int
 
 
 func (s *Service) GetTaxpayer(ctx context.Context, id string) (models.GetTaxpayerByIDRow, error) {
-	return s.repo.GetTaxpayerByID(ctx, id)
+	taxpayer, err := s.repo.GetTaxpayerByID(ctx, id)
+	if err != nil {
+		return models.GetTaxpayerByIDRow{}, err
+	}
+
+	if err := auth.AuthorizeCounty(ctx, taxpayer.CountyID); err != nil {
+		return models.GetTaxpayerByIDRow{}, err
+	}
+	return taxpayer, nil
 }
 
 
@@ -76,6 +96,10 @@ func (s *Service) UpdateTaxpayer(ctx context.Context, id string, req UpdateTaxpa
 		return models.UpdateTaxpayerRow{}, err
 	}
 
+	if _, err := s.GetTaxpayer(ctx, id); err != nil {
+		return models.UpdateTaxpayerRow{}, err
+	}
+
 	params := models.UpdateTaxpayerParams{
 		ID:           taxpayerID,
 		Email:        req.Email,
@@ -90,6 +114,9 @@ func (s *Service) UpdateTaxpayer(ctx context.Context, id string, req UpdateTaxpa
 
 
 func (s *Service) DeleteTaxpayer(ctx context.Context, id string) error {
+	if _, err := s.GetTaxpayer(ctx, id); err != nil {
+		return err
+	}
 	return s.repo.DeleteTaxpayer(ctx, id)
 }
 
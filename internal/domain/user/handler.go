@@ -39,11 +39,11 @@ func NewHandler(db models.DBTX) *Handler {
 }
 
 func (h Handler) RegisterUserRoutes(r chi.Router) {
-	r.Post("/", h.CreateUser)
-	r.Get("/{id}", h.GetUser)
-	r.Get("/", h.ListUsers)
-	r.Patch("/{id}", h.UpdateUser)
-	r.Delete("/{id}", h.DeleteUser)
+	r.With(auth.RequirePermission(auth.PermUsersWrite)).Post("/", h.CreateUser)
+	r.With(auth.RequirePermission(auth.PermUsersRead)).Get("/{id}", h.GetUser)
+	r.With(auth.RequirePermission(auth.PermUsersRead)).Get("/", h.ListUsers)
+	r.With(auth.RequirePermission(auth.PermUsersWrite)).Patch("/{id}", h.UpdateUser)
+	r.With(auth.RequirePermission(auth.PermUsersWrite)).Delete("/{id}", h.DeleteUser)
 }
 
 func (h Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
@@ -56,10 +56,21 @@ func (h Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
+	userRole, ok := ctx.Value(auth.UserRoleKey).(string)
+	if !ok {
+		http.Error(w, "user role not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if !auth.CanAssignRole(userRole, req.Role) {
+		http.Error(w, "not allowed to assign role "+req.Role, http.StatusForbidden)
+		return
+	}
+
 	user, err := h.svc.CreateUser(ctx, req)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create user")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -75,7 +86,7 @@ func (h Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 	user, err := h.svc.GetUser(ctx, id)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to fetch user")
-		http.Error(w, err.Error(), http.StatusNotFound)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusNotFound))
 		return
 	}
 
@@ -114,7 +125,7 @@ func (h Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
 
 	users, err := h.svc.ListUsers(ctx, userRole, userCountyID, int32(limit), int32(offset))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -275,8 +286,21 @@ func (h Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
+	if req.Role != nil {
+		userRole, ok := ctx.Value(auth.UserRoleKey).(string)
+		if !ok {
+			http.Error(w, "user role not found in context", http.StatusUnauthorized)
+			return
+		}
+
+		if !auth.CanAssignRole(userRole, *req.Role) {
+			http.Error(w, "not allowed to assign role "+*req.Role, http.StatusForbidden)
+			return
+		}
+	}
+
 	if err := h.svc.UpdateUser(ctx, id, req); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -288,7 +312,7 @@ func (h Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	if err := h.svc.DeleteUser(ctx, id); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), auth.ErrorStatus(err, http.StatusBadRequest))
 		return
 	}
 
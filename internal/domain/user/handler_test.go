@@ -2,6 +2,7 @@ package user
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/go-chi/chi"
 	"github.com/sangkips/revenue-system/internal/domain/user/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -32,6 +34,7 @@ func TestHandler_CreateUser(t *testing.T) {
 	r.Post("/", handler.CreateUser)
 	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserRoleKey, "super_admin"))
 	rr := httptest.NewRecorder()
 
 	r.ServeHTTP(rr, req)
@@ -39,3 +42,30 @@ func TestHandler_CreateUser(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, rr.Code)
 	repo.AssertExpectations(t)
 }
+
+func TestHandler_CreateUser_ForbiddenRole(t *testing.T) {
+	repo := &MockRepository{}
+	svc := NewService(repo)
+	handler := &Handler{svc: svc}
+
+	countyID := int32(1)
+	reqBody := CreateUserRequest{
+		CountyID: &countyID,
+		Email:    "admin@example.com",
+		Password: "test123",
+		Role:     "super_admin",
+	}
+	body, _ := json.Marshal(reqBody)
+
+	r := chi.NewRouter()
+	r.Post("/", handler.CreateUser)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserRoleKey, "county_admin"))
+	rr := httptest.NewRecorder()
+
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	repo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,38 @@
+package user
+
+import (
+	"context"
+
+	"github.com/sangkips/revenue-system/internal/domain/user/models"
+)
+
+type LoginThrottleRepository interface {
+	GetLoginThrottle(ctx context.Context, throttleKey string) (models.LoginThrottle, error)
+	RecordLoginFailure(ctx context.Context, params models.RecordLoginFailureParams) (int32, error)
+	SetLoginLockout(ctx context.Context, params models.SetLoginLockoutParams) error
+	ClearLoginThrottle(ctx context.Context, throttleKey string) error
+}
+
+type loginThrottleRepository struct {
+	q *models.Queries
+}
+
+func NewLoginThrottleRepository(db models.DBTX) LoginThrottleRepository {
+	return &loginThrottleRepository{q: models.New(db)}
+}
+
+func (r *loginThrottleRepository) GetLoginThrottle(ctx context.Context, throttleKey string) (models.LoginThrottle, error) {
+	return r.q.GetLoginThrottle(ctx, throttleKey)
+}
+
+func (r *loginThrottleRepository) RecordLoginFailure(ctx context.Context, params models.RecordLoginFailureParams) (int32, error) {
+	return r.q.RecordLoginFailure(ctx, params)
+}
+
+func (r *loginThrottleRepository) SetLoginLockout(ctx context.Context, params models.SetLoginLockoutParams) error {
+	return r.q.SetLoginLockout(ctx, params)
+}
+
+func (r *loginThrottleRepository) ClearLoginThrottle(ctx context.Context, throttleKey string) error {
+	return r.q.ClearLoginThrottle(ctx, throttleKey)
+}
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/user/models"
+)
+
+type MFARepository interface {
+	UpsertUserMFASecret(ctx context.Context, params models.UpsertUserMFASecretParams) (models.UserMfa, error)
+	GetUserMFA(ctx context.Context, userID uuid.UUID) (models.UserMfa, error)
+	EnableUserMFA(ctx context.Context, userID uuid.UUID) error
+	RecordMFAStep(ctx context.Context, params models.RecordMFAStepParams) (int64, error)
+	DeleteUserMFA(ctx context.Context, userID uuid.UUID) error
+	CreateMFARecoveryCode(ctx context.Context, params models.CreateMFARecoveryCodeParams) error
+	ConsumeMFARecoveryCode(ctx context.Context, params models.ConsumeMFARecoveryCodeParams) (int64, error)
+	DeleteMFARecoveryCodes(ctx context.Context, userID uuid.UUID) error
+	IsCountyMFARequired(ctx context.Context, countyID int32) (bool, error)
+}
+
+type mfaRepository struct {
+	q *models.Queries
+}
+
+func NewMFARepository(db models.DBTX) MFARepository {
+	return &mfaRepository{q: models.New(db)}
+}
+
+func (r *mfaRepository) UpsertUserMFASecret(ctx context.Context, params models.UpsertUserMFASecretParams) (models.UserMfa, error) {
+	return r.q.UpsertUserMFASecret(ctx, params)
+}
+
+func (r *mfaRepository) GetUserMFA(ctx context.Context, userID uuid.UUID) (models.UserMfa, error) {
+	return r.q.GetUserMFA(ctx, userID)
+}
+
+func (r *mfaRepository) EnableUserMFA(ctx context.Context, userID uuid.UUID) error {
+	return r.q.EnableUserMFA(ctx, userID)
+}
+
+func (r *mfaRepository) RecordMFAStep(ctx context.Context, params models.RecordMFAStepParams) (int64, error) {
+	return r.q.RecordMFAStep(ctx, params)
+}
+
+func (r *mfaRepository) DeleteUserMFA(ctx context.Context, userID uuid.UUID) error {
+	return r.q.DeleteUserMFA(ctx, userID)
+}
+
+func (r *mfaRepository) CreateMFARecoveryCode(ctx context.Context, params models.CreateMFARecoveryCodeParams) error {
+	return r.q.CreateMFARecoveryCode(ctx, params)
+}
+
+func (r *mfaRepository) ConsumeMFARecoveryCode(ctx context.Context, params models.ConsumeMFARecoveryCodeParams) (int64, error) {
+	return r.q.ConsumeMFARecoveryCode(ctx, params)
+}
+
+func (r *mfaRepository) DeleteMFARecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	return r.q.DeleteMFARecoveryCodes(ctx, userID)
+}
+
+func (r *mfaRepository) IsCountyMFARequired(ctx context.Context, countyID int32) (bool, error) {
+	return r.q.IsCountyMFARequired(ctx, countyID)
+}
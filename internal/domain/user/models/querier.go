@@ -6,20 +6,53 @@ package models
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
+	ClearLoginThrottle(ctx context.Context, throttleKey string) error
+	ConsumeMFARecoveryCode(ctx context.Context, arg ConsumeMFARecoveryCodeParams) (int64, error)
+	// Marks the token used and returns its owner in one statement so a token can only be redeemed once
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	CreateMFARecoveryCode(ctx context.Context, arg CreateMFARecoveryCodeParams) error
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	DeleteMFARecoveryCodes(ctx context.Context, userID uuid.UUID) error
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	DeleteUserMFA(ctx context.Context, userID uuid.UUID) error
+	EnableUserMFA(ctx context.Context, userID uuid.UUID) error
+	// Only sessions that are unrevoked, unexpired and belong to an active user are returned
+	GetActiveSession(ctx context.Context, id uuid.UUID) (GetActiveSessionRow, error)
+	GetLoginThrottle(ctx context.Context, throttleKey string) (LoginThrottle, error)
+	GetSessionByRefreshTokenHash(ctx context.Context, refreshTokenHash string) (Session, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (GetUserByIDRow, error)
+	GetUserMFA(ctx context.Context, userID uuid.UUID) (UserMfa, error)
+	GetUserPasswordHash(ctx context.Context, id uuid.UUID) (string, error)
 	// Return the created user
 	InsertUser(ctx context.Context, arg InsertUserParams) (User, error)
+	InvalidateUserPasswordResetTokens(ctx context.Context, userID uuid.UUID) error
+	IsCountyMFARequired(ctx context.Context, countyID int32) (bool, error)
 	ListAllUsers(ctx context.Context, arg ListAllUsersParams) ([]ListAllUsersRow, error)
 	ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error)
+	// Counts a failure, starting over when the previous failure or lockout ended before reset_before
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (int32, error)
+	// Only moves forward, so each TOTP code is accepted at most once
+	RecordMFAStep(ctx context.Context, arg RecordMFAStepParams) (int64, error)
+	RevokeSession(ctx context.Context, id uuid.UUID) error
+	// A rotated-out refresh token presented again means it was copied; the session is revoked
+	RevokeSessionByPreviousRefreshTokenHash(ctx context.Context, previousRefreshTokenHash sql.NullString) (RevokeSessionByPreviousRefreshTokenHashRow, error)
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID) error
+	// The token being replaced is kept as previous_refresh_token_hash for reuse detection
+	RotateSessionRefreshToken(ctx context.Context, arg RotateSessionRefreshTokenParams) (int64, error)
+	SetLoginLockout(ctx context.Context, arg SetLoginLockoutParams) error
 	UpdateUser(ctx context.Context, arg UpdateUserParams) error
+	UpdateUserLastLogin(ctx context.Context, id uuid.UUID) error
 	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
+	// Starts (or restarts) enrolment; returns no row when MFA is already enabled
+	UpsertUserMFASecret(ctx context.Context, arg UpsertUserMFASecretParams) (UserMfa, error)
 }
 
 var _ Querier = (*Queries)(nil)
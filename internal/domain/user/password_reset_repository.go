@@ -0,0 +1,34 @@
+package user
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/user/models"
+)
+
+type PasswordResetRepository interface {
+	CreatePasswordResetToken(ctx context.Context, params models.CreatePasswordResetTokenParams) (models.PasswordResetToken, error)
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	InvalidateUserPasswordResetTokens(ctx context.Context, userID uuid.UUID) error
+}
+
+type passwordResetRepository struct {
+	q *models.Queries
+}
+
+func NewPasswordResetRepository(db models.DBTX) PasswordResetRepository {
+	return &passwordResetRepository{q: models.New(db)}
+}
+
+func (r *passwordResetRepository) CreatePasswordResetToken(ctx context.Context, params models.CreatePasswordResetTokenParams) (models.PasswordResetToken, error) {
+	return r.q.CreatePasswordResetToken(ctx, params)
+}
+
+func (r *passwordResetRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	return r.q.ConsumePasswordResetToken(ctx, tokenHash)
+}
+
+func (r *passwordResetRepository) InvalidateUserPasswordResetTokens(ctx context.Context, userID uuid.UUID) error {
+	return r.q.InvalidateUserPasswordResetTokens(ctx, userID)
+}
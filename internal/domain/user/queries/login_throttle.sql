@@ -0,0 +1,25 @@
+-- name: GetLoginThrottle :one
+SELECT throttle_key, failures, locked_until, last_failure_at
+FROM login_throttles
+WHERE throttle_key = @throttle_key;
+
+-- Counts a failure, starting over when the previous failure or lockout ended before reset_before
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (throttle_key, failures, last_failure_at)
+VALUES (@throttle_key, 1, CURRENT_TIMESTAMP)
+ON CONFLICT (throttle_key) DO UPDATE
+SET failures = CASE
+        WHEN GREATEST(login_throttles.last_failure_at, COALESCE(login_throttles.locked_until, login_throttles.last_failure_at)) < @reset_before
+        THEN 1
+        ELSE login_throttles.failures + 1
+    END,
+    last_failure_at = CURRENT_TIMESTAMP
+RETURNING failures;
+
+-- name: SetLoginLockout :exec
+UPDATE login_throttles
+SET locked_until = @locked_until
+WHERE throttle_key = @throttle_key;
+
+-- name: ClearLoginThrottle :exec
+DELETE FROM login_throttles WHERE throttle_key = @throttle_key;
@@ -0,0 +1,45 @@
+-- Starts (or restarts) enrolment; returns no row when MFA is already enabled
+-- name: UpsertUserMFASecret :one
+INSERT INTO user_mfa (user_id, secret)
+VALUES (@user_id, @secret)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, last_used_step = 0, confirmed_at = NULL, created_at = CURRENT_TIMESTAMP
+WHERE user_mfa.enabled = false
+RETURNING user_id, secret, enabled, last_used_step, confirmed_at, created_at;
+
+-- name: GetUserMFA :one
+SELECT user_id, secret, enabled, last_used_step, confirmed_at, created_at
+FROM user_mfa
+WHERE user_id = @user_id;
+
+-- name: EnableUserMFA :exec
+UPDATE user_mfa
+SET enabled = true, confirmed_at = CURRENT_TIMESTAMP
+WHERE user_id = @user_id;
+
+-- Only moves forward, so each TOTP code is accepted at most once
+-- name: RecordMFAStep :execrows
+UPDATE user_mfa
+SET last_used_step = @step
+WHERE user_id = @user_id AND last_used_step < @step;
+
+-- name: DeleteUserMFA :exec
+DELETE FROM user_mfa WHERE user_id = @user_id;
+
+-- name: CreateMFARecoveryCode :exec
+INSERT INTO mfa_recovery_codes (user_id, code_hash)
+VALUES (@user_id, @code_hash);
+
+-- name: ConsumeMFARecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = CURRENT_TIMESTAMP
+WHERE user_id = @user_id AND code_hash = @code_hash AND used_at IS NULL;
+
+-- name: DeleteMFARecoveryCodes :exec
+DELETE FROM mfa_recovery_codes WHERE user_id = @user_id;
+
+-- name: IsCountyMFARequired :one
+SELECT EXISTS (
+    SELECT 1 FROM county_mfa_policies
+    WHERE county_id = @county_id AND mfa_required = true
+) AS mfa_required;
@@ -0,0 +1,18 @@
+-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+VALUES (@user_id, @token_hash, @expires_at)
+RETURNING id, user_id, token_hash, expires_at, used_at, created_at;
+
+-- Marks the token used and returns its owner in one statement so a token can only be redeemed once
+-- name: ConsumePasswordResetToken :one
+UPDATE password_reset_tokens
+SET used_at = CURRENT_TIMESTAMP
+WHERE token_hash = @token_hash
+  AND used_at IS NULL
+  AND expires_at > CURRENT_TIMESTAMP
+RETURNING user_id;
+
+-- name: InvalidateUserPasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = CURRENT_TIMESTAMP
+WHERE user_id = @user_id AND used_at IS NULL;
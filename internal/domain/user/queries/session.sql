@@ -0,0 +1,43 @@
+-- name: CreateSession :one
+INSERT INTO sessions (user_id, refresh_token_hash, user_agent, ip_address, expires_at)
+VALUES (@user_id, @refresh_token_hash, @user_agent, @ip_address, @expires_at)
+RETURNING id, user_id, refresh_token_hash, user_agent, ip_address, expires_at, revoked_at, last_used_at, created_at, previous_refresh_token_hash;
+
+-- name: GetSessionByRefreshTokenHash :one
+SELECT id, user_id, refresh_token_hash, user_agent, ip_address, expires_at, revoked_at, last_used_at, created_at, previous_refresh_token_hash
+FROM sessions
+WHERE refresh_token_hash = @refresh_token_hash;
+
+-- Only sessions that are unrevoked, unexpired and belong to an active user are returned
+-- name: GetActiveSession :one
+SELECT s.id, s.user_id
+FROM sessions s
+JOIN users u ON u.id = s.user_id
+WHERE s.id = @id
+  AND s.revoked_at IS NULL
+  AND s.expires_at > CURRENT_TIMESTAMP
+  AND u.is_active = true;
+
+-- The token being replaced is kept as previous_refresh_token_hash for reuse detection
+-- name: RotateSessionRefreshToken :execrows
+UPDATE sessions
+SET refresh_token_hash = @refresh_token_hash, previous_refresh_token_hash = refresh_token_hash,
+    expires_at = @expires_at, last_used_at = CURRENT_TIMESTAMP
+WHERE id = @id AND refresh_token_hash = @current_refresh_token_hash AND revoked_at IS NULL;
+
+-- name: RevokeSession :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = @id AND revoked_at IS NULL;
+
+-- A rotated-out refresh token presented again means it was copied; the session is revoked
+-- name: RevokeSessionByPreviousRefreshTokenHash :one
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE previous_refresh_token_hash = @previous_refresh_token_hash AND revoked_at IS NULL
+RETURNING id, user_id;
+
+-- name: RevokeUserSessions :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = @user_id AND revoked_at IS NULL;
@@ -48,3 +48,11 @@ WHERE id = @id;
 
 -- name: DeleteUser :exec
 DELETE FROM users WHERE id = @id;
+
+-- name: GetUserPasswordHash :one
+SELECT password_hash FROM users WHERE id = @id;
+
+-- name: UpdateUserLastLogin :exec
+UPDATE users
+SET last_login = CURRENT_TIMESTAMP
+WHERE id = @id;
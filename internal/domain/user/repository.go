@@ -14,6 +14,9 @@ type Repository interface {
 	ListAllUsers(ctx context.Context, params models.ListAllUsersParams) ([]models.ListAllUsersRow, error)
 	GetUserByID(ctx context.Context, id string) (models.GetUserByIDRow, error)
 	UpdateUser(ctx context.Context, params models.UpdateUserParams) error
+	GetUserPasswordHash(ctx context.Context, id string) (string, error)
+	UpdateUserPassword(ctx context.Context, params models.UpdateUserPasswordParams) error
+	UpdateUserLastLogin(ctx context.Context, id uuid.UUID) error
 	DeleteUser(ctx context.Context, id string) error
 }
 
@@ -53,6 +56,22 @@ func (r *repository) UpdateUser(ctx context.Context, params models.UpdateUserPar
 	return r.q.UpdateUser(ctx, params)
 }
 
+func (r *repository) GetUserPasswordHash(ctx context.Context, id string) (string, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return "", err
+	}
+	return r.q.GetUserPasswordHash(ctx, parsedID)
+}
+
+func (r *repository) UpdateUserPassword(ctx context.Context, params models.UpdateUserPasswordParams) error {
+	return r.q.UpdateUserPassword(ctx, params)
+}
+
+func (r *repository) UpdateUserLastLogin(ctx context.Context, id uuid.UUID) error {
+	return r.q.UpdateUserLastLogin(ctx, id)
+}
+
 func (r *repository) DeleteUser(ctx context.Context, id string) error {
 	parsedID, err := uuid.Parse(id)
 	if err != nil {
@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/sangkips/revenue-system/internal/domain/user/models"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -35,8 +36,19 @@ func (s *Service) CreateUser(ctx context.Context, req CreateUserRequest) (models
 		return models.User{}, errors.New("invalid role")
 	}
 
-	if req.Role != "super_admin" && req.CountyID == nil {
-		return models.User{}, errors.New("county_id is required for non-super-admin roles")
+	if req.Role != "super_admin" {
+		var requested int32
+		if req.CountyID != nil {
+			requested = *req.CountyID
+		}
+
+		countyID, err := auth.ResolveCounty(ctx, requested)
+		if err != nil {
+			return models.User{}, err
+		}
+		req.CountyID = &countyID
+	} else {
+		req.CountyID = nil
 	}
 
 	hashedPsswd, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
@@ -83,7 +95,15 @@ func (s *Service) ListUsers(ctx context.Context, userRole string, userCountyID *
 }
 
 func (s *Service) GetUser(ctx context.Context, id string) (models.GetUserByIDRow, error) {
-	return s.repo.GetUserByID(ctx, id)
+	user, err := s.repo.GetUserByID(ctx, id)
+	if err != nil {
+		return models.GetUserByIDRow{}, err
+	}
+
+	if err := auth.AuthorizeNullCounty(ctx, user.CountyID); err != nil {
+		return models.GetUserByIDRow{}, err
+	}
+	return user, nil
 }
 
 func (s *Service) UpdateUser(ctx context.Context, id string, req UpdateUserRequest) error {
@@ -92,6 +112,10 @@ func (s *Service) UpdateUser(ctx context.Context, id string, req UpdateUserReque
 		return err
 	}
 
+	if _, err := s.GetUser(ctx, id); err != nil {
+		return err
+	}
+
 	params := models.UpdateUserParams{
 		ID: userID,
 	}
@@ -140,6 +164,9 @@ func (s *Service) UpdateUser(ctx context.Context, id string, req UpdateUserReque
 }
 
 func(s *Service) DeleteUser(ctx context.Context, id string) error {
+	if _, err := s.GetUser(ctx, id); err != nil {
+		return err
+	}
 	return s.repo.DeleteUser(ctx, id)
 }
 
@@ -3,6 +3,7 @@ package user
 import (
 	"context"
 
+	"github.com/google/uuid"
 	"github.com/sangkips/revenue-system/internal/domain/user/models"
 	"github.com/stretchr/testify/mock"
 )
@@ -47,6 +48,24 @@ func (m *MockRepository) UpdateUser(ctx context.Context, params models.UpdateUse
 	return args.Error(0)
 }
 
+func (m *MockRepository) GetUserPasswordHash(ctx context.Context, id string) (string, error) {
+	args := m.Called(ctx, id)
+
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRepository) UpdateUserPassword(ctx context.Context, params models.UpdateUserPasswordParams) error {
+	args := m.Called(ctx, params)
+
+	return args.Error(0)
+}
+
+func (m *MockRepository) UpdateUserLastLogin(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+
+	return args.Error(0)
+}
+
 func (m *MockRepository) DeleteUser(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 
@@ -0,0 +1,55 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/user/models"
+)
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, params models.CreateSessionParams) (models.Session, error)
+	GetSessionByRefreshTokenHash(ctx context.Context, refreshTokenHash string) (models.Session, error)
+	GetActiveSession(ctx context.Context, id uuid.UUID) (models.GetActiveSessionRow, error)
+	RotateSessionRefreshToken(ctx context.Context, params models.RotateSessionRefreshTokenParams) (int64, error)
+	RevokeSession(ctx context.Context, id uuid.UUID) error
+	RevokeSessionByPreviousRefreshTokenHash(ctx context.Context, refreshTokenHash string) (models.RevokeSessionByPreviousRefreshTokenHashRow, error)
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID) error
+}
+
+type sessionRepository struct {
+	q *models.Queries
+}
+
+func NewSessionRepository(db models.DBTX) SessionRepository {
+	return &sessionRepository{q: models.New(db)}
+}
+
+func (r *sessionRepository) CreateSession(ctx context.Context, params models.CreateSessionParams) (models.Session, error) {
+	return r.q.CreateSession(ctx, params)
+}
+
+func (r *sessionRepository) GetSessionByRefreshTokenHash(ctx context.Context, refreshTokenHash string) (models.Session, error) {
+	return r.q.GetSessionByRefreshTokenHash(ctx, refreshTokenHash)
+}
+
+func (r *sessionRepository) GetActiveSession(ctx context.Context, id uuid.UUID) (models.GetActiveSessionRow, error) {
+	return r.q.GetActiveSession(ctx, id)
+}
+
+func (r *sessionRepository) RotateSessionRefreshToken(ctx context.Context, params models.RotateSessionRefreshTokenParams) (int64, error) {
+	return r.q.RotateSessionRefreshToken(ctx, params)
+}
+
+func (r *sessionRepository) RevokeSession(ctx context.Context, id uuid.UUID) error {
+	return r.q.RevokeSession(ctx, id)
+}
+
+func (r *sessionRepository) RevokeSessionByPreviousRefreshTokenHash(ctx context.Context, refreshTokenHash string) (models.RevokeSessionByPreviousRefreshTokenHashRow, error) {
+	return r.q.RevokeSessionByPreviousRefreshTokenHash(ctx, sql.NullString{String: refreshTokenHash, Valid: true})
+}
+
+func (r *sessionRepository) RevokeUserSessions(ctx context.Context, userID uuid.UUID) error {
+	return r.q.RevokeUserSessions(ctx, userID)
+}
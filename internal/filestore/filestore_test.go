@@ -0,0 +1,53 @@
+package filestore
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalPutAndOpen(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	n, err := store.Put(ctx, "receipts/47/a.pdf", strings.NewReader("first"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+
+	// Putting the same key again replaces the file.
+	_, err = store.Put(ctx, "receipts/47/a.pdf", strings.NewReader("second"))
+	require.NoError(t, err)
+
+	f, err := store.Open(ctx, "receipts/47/a.pdf")
+	require.NoError(t, err)
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+}
+
+func TestLocalOpenMissing(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = store.Open(context.Background(), "receipts/missing.pdf")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalRejectsKeysOutsideRoot(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for _, key := range []string{"", ".", "..", "../escape.pdf", "receipts/../../escape.pdf", "/etc/passwd"} {
+		_, err := store.Put(ctx, key, strings.NewReader("x"))
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+		_, err = store.Open(ctx, key)
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
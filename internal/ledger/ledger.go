@@ -0,0 +1,109 @@
+// Package ledger holds the hashing rules of the tamper-evident receipt
+// ledger: how a receipt is reduced to a canonical record, how entries chain
+// to one another and how sealed blocks summarise their entries in a Merkle
+// root. Hashes are lowercase hex SHA-256.
+package ledger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Events a ledger entry records.
+const (
+	Issued = "issued"
+	Voided = "voided"
+)
+
+// GenesisHash is the previous hash of the first entry and the first block of
+// a chain.
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// Record is the canonical content of a ledger entry. Only fields that cannot
+// legitimately change after the event are included, so any later difference
+// is tampering. The JSON encoding of the struct, with its fixed field order,
+// is what gets hashed.
+type Record struct {
+	Event         string `json:"event"`
+	ReceiptID     string `json:"receipt_id"`
+	ReceiptNumber string `json:"receipt_number"`
+	ReceiptType   string `json:"receipt_type,omitempty"`
+	PaymentID     string `json:"payment_id"`
+	PaymentNumber string `json:"payment_number,omitempty"`
+	CountyID      int32  `json:"county_id"`
+	TaxpayerID    string `json:"taxpayer_id,omitempty"`
+	Amount        string `json:"amount,omitempty"`
+	PaymentMethod string `json:"payment_method,omitempty"`
+	Reference     string `json:"reference,omitempty"`
+	VoidedBy      string `json:"voided_by,omitempty"`
+	VoidReason    string `json:"void_reason,omitempty"`
+}
+
+// Hash is the hash of the record's canonical encoding.
+func (r Record) Hash() string {
+	data, err := json.Marshal(r)
+	if err != nil {
+		// A struct of strings always encodes.
+		panic(err)
+	}
+	return hashOf(data)
+}
+
+// EntryHash chains an entry to the one before it.
+func EntryHash(countyID int32, sequence int64, previousHash, recordHash string) string {
+	return hashOf([]byte(fmt.Sprintf("entry:%d:%d:%s:%s", countyID, sequence, previousHash, recordHash)))
+}
+
+// Block is the header of a sealed block.
+type Block struct {
+	CountyID          int32
+	Number            int64
+	FirstSequence     int64
+	LastSequence      int64
+	MerkleRoot        string
+	PreviousBlockHash string
+}
+
+// Hash chains a block to the one before it.
+func (b Block) Hash() string {
+	return hashOf([]byte(fmt.Sprintf("block:%d:%d:%d:%d:%s:%s",
+		b.CountyID, b.Number, b.FirstSequence, b.LastSequence, b.MerkleRoot, b.PreviousBlockHash)))
+}
+
+// MerkleRoot is the root of the binary Merkle tree over hashes, in order. A
+// level with an odd number of nodes carries its last node up unchanged. The
+// root of no hashes is GenesisHash.
+func MerkleRoot(hashes []string) (string, error) {
+	if len(hashes) == 0 {
+		return GenesisHash, nil
+	}
+	level := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		b, err := hex.DecodeString(h)
+		if err != nil || len(b) != sha256.Size {
+			return "", fmt.Errorf("invalid hash %q", h)
+		}
+		level[i] = b
+	}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			sum := sha256.Sum256(append(append([]byte{}, level[i]...), level[i+1]...))
+			next = append(next, sum[:])
+		}
+		level = next
+	}
+	return hex.EncodeToString(level[0]), nil
+}
+
+func hashOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
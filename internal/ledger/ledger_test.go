@@ -0,0 +1,79 @@
+package ledger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordHashIsStable(t *testing.T) {
+	r := Record{
+		Event:         Issued,
+		ReceiptID:     "7d6a3a52-5a1a-4c0c-9f0e-2f5d0f6a1b11",
+		ReceiptNumber: "047/RCT/2025-26/000123",
+		PaymentID:     "0b6c8f8e-3a61-4d7e-b8cb-6c1f2d0e9a22",
+		CountyID:      47,
+		Amount:        "12500.00",
+	}
+	assert.Equal(t, r.Hash(), r.Hash())
+	assert.Len(t, r.Hash(), 64)
+
+	edited := r
+	edited.Amount = "1250.00"
+	assert.NotEqual(t, r.Hash(), edited.Hash())
+}
+
+func TestEntryHashChains(t *testing.T) {
+	first := EntryHash(47, 1, GenesisHash, "aa")
+	second := EntryHash(47, 2, first, "bb")
+	assert.NotEqual(t, first, second)
+	assert.NotEqual(t, second, EntryHash(47, 2, GenesisHash, "bb"))
+	assert.NotEqual(t, first, EntryHash(1, 1, GenesisHash, "aa"))
+}
+
+func TestMerkleRoot(t *testing.T) {
+	leaf := func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	pair := func(a, b string) string {
+		x, _ := hex.DecodeString(a)
+		y, _ := hex.DecodeString(b)
+		sum := sha256.Sum256(append(x, y...))
+		return hex.EncodeToString(sum[:])
+	}
+	a, b, c := leaf("a"), leaf("b"), leaf("c")
+
+	root, err := MerkleRoot(nil)
+	require.NoError(t, err)
+	assert.Equal(t, GenesisHash, root)
+
+	root, err = MerkleRoot([]string{a})
+	require.NoError(t, err)
+	assert.Equal(t, a, root)
+
+	root, err = MerkleRoot([]string{a, b})
+	require.NoError(t, err)
+	assert.Equal(t, pair(a, b), root)
+
+	root, err = MerkleRoot([]string{a, b, c})
+	require.NoError(t, err)
+	assert.Equal(t, pair(pair(a, b), c), root)
+
+	swapped, err := MerkleRoot([]string{b, a, c})
+	require.NoError(t, err)
+	assert.NotEqual(t, root, swapped)
+
+	_, err = MerkleRoot([]string{a, "not-a-hash"})
+	assert.Error(t, err)
+}
+
+func TestBlockHashCoversHeader(t *testing.T) {
+	b := Block{CountyID: 47, Number: 3, FirstSequence: 11, LastSequence: 20, MerkleRoot: GenesisHash, PreviousBlockHash: GenesisHash}
+	moved := b
+	moved.LastSequence = 19
+	assert.NotEqual(t, b.Hash(), moved.Hash())
+}
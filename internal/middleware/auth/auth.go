@@ -2,27 +2,80 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/db"
+	"github.com/sangkips/revenue-system/internal/notify"
 	"github.com/sangkips/revenue-system/internal/domain/user/models"
 	taxpayerModels "github.com/sangkips/revenue-system/internal/domain/taxpayers/models"
+	"github.com/rs/zerolog/log"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
 type AuthService struct {
 	repo         Repository
 	taxpayerRepo TaxpayerRepository
+	sessions     SessionRepository
+	resets       PasswordResetRepository
+	notifier     notify.Notifier
 	secretKey    []byte
+	accessTTL    time.Duration
+	refreshTTL   time.Duration
+	resetTTL     time.Duration
+	resetURL     string
+
+	mfa                    MFARepository
+	mfaIssuer              string
+	mfaRequiredSuperAdmins bool
+
+	throttle       LoginThrottleRepository
+	throttlePolicy ThrottlePolicy
+
+	tx      db.Transactor
+	txRepos RegistrationRepos
 }
 
 type Repository interface {
 	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+	GetUserByID(ctx context.Context, id string) (models.GetUserByIDRow, error)
 	CreateUser(ctx context.Context, user models.InsertUserParams) (models.User, error)
+	GetUserPasswordHash(ctx context.Context, id string) (string, error)
+	UpdateUserPassword(ctx context.Context, params models.UpdateUserPasswordParams) error
+	UpdateUserLastLogin(ctx context.Context, id uuid.UUID) error
+}
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, params models.CreateSessionParams) (models.Session, error)
+	GetSessionByRefreshTokenHash(ctx context.Context, refreshTokenHash string) (models.Session, error)
+	GetActiveSession(ctx context.Context, id uuid.UUID) (models.GetActiveSessionRow, error)
+	RotateSessionRefreshToken(ctx context.Context, params models.RotateSessionRefreshTokenParams) (int64, error)
+	RevokeSession(ctx context.Context, id uuid.UUID) error
+	RevokeSessionByPreviousRefreshTokenHash(ctx context.Context, refreshTokenHash string) (models.RevokeSessionByPreviousRefreshTokenHashRow, error)
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID) error
+}
+
+// Option configures optional AuthService features.
+type Option func(*AuthService)
+
+// WithSessions enables short-lived access tokens bound to a server-side
+// session that can be refreshed and revoked.
+func WithSessions(sessions SessionRepository, accessTTL, refreshTTL time.Duration) Option {
+	return func(s *AuthService) {
+		s.sessions = sessions
+		s.accessTTL = accessTTL
+		s.refreshTTL = refreshTTL
+	}
 }
 
 type TaxpayerRepository interface {
@@ -30,54 +83,295 @@ type TaxpayerRepository interface {
 	GetTaxpayerByNationalID(ctx context.Context, nationalID string) (taxpayerModels.GetTaxpayerByNationalIDRow, error)
 }
 
-func NewAuthService(repo Repository, secretKey string) *AuthService {
-	return &AuthService{repo: repo, secretKey: []byte(secretKey)}
+// RegistrationRepos builds the repositories Register writes through, bound to
+// a transaction.
+type RegistrationRepos func(tx db.DBTX) (Repository, TaxpayerRepository)
+
+// WithTransactions makes Register create the user and taxpayer rows in one
+// transaction, so a failed taxpayer insert never leaves an orphaned user.
+func WithTransactions(tx db.Transactor, repos RegistrationRepos) Option {
+	return func(s *AuthService) {
+		s.tx = tx
+		s.txRepos = repos
+	}
 }
 
-func NewAuthServiceWithTaxpayer(repo Repository, taxpayerRepo TaxpayerRepository, secretKey string) *AuthService {
-	return &AuthService{
+func NewAuthService(repo Repository, secretKey string, opts ...Option) *AuthService {
+	return NewAuthServiceWithTaxpayer(repo, nil, secretKey, opts...)
+}
+
+func NewAuthServiceWithTaxpayer(repo Repository, taxpayerRepo TaxpayerRepository, secretKey string, opts ...Option) *AuthService {
+	s := &AuthService{
 		repo:         repo,
 		taxpayerRepo: taxpayerRepo,
 		secretKey:    []byte(secretKey),
+		accessTTL:    time.Hour * 24,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+
+	// Populated from the HTTP request, not the body.
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in"`
+
+	// Set instead of the tokens above when a second factor is needed. The
+	// client completes login at /auth/mfa/verify, or at /auth/mfa/enroll when
+	// policy requires MFA that the user has not set up yet.
+	MFARequired           bool   `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
+	MFAToken              string `json:"mfa_token,omitempty"`
+
+	// Returned once, when enrolment completes during login.
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 func (s AuthService) Login(ctx context.Context, req LoginRequest) (LoginResponse, error) {
+	keys := s.loginKeys(req.Email, req.IPAddress)
+	if err := s.checkThrottle(ctx, keys...); err != nil {
+		return LoginResponse{}, err
+	}
+
+	// Unknown email, wrong password and disabled account all return the same
+	// error so the endpoint cannot be used to enumerate accounts.
 	user, err := s.repo.GetUserByEmail(ctx, req.Email)
 	if err != nil {
-		return LoginResponse{}, errors.New("user not found")
+		if !errors.Is(err, sql.ErrNoRows) {
+			return LoginResponse{}, err
+		}
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(req.Password))
+		s.recordFailure(ctx, keys...)
+		return LoginResponse{}, ErrInvalidCredentials
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		return LoginResponse{}, errors.New("invalid credentials")
+		s.recordFailure(ctx, keys...)
+		return LoginResponse{}, ErrInvalidCredentials
+	}
+
+	if user.IsActive.Valid && !user.IsActive.Bool {
+		return LoginResponse{}, ErrInvalidCredentials
+	}
+
+	s.clearThrottle(ctx, s.accountKey(req.Email))
+
+	challenge, err := s.mfaChallenge(ctx, user.ID, user.Role, user.CountyID)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	if challenge != nil {
+		return *challenge, nil
+	}
+
+	return s.issueTokens(ctx, user.ID, user.Role, user.CountyID, req.UserAgent, req.IPAddress)
+}
+
+// issueTokens signs an access token and, when sessions are enabled, opens a
+// new session with its refresh token.
+func (s AuthService) issueTokens(ctx context.Context, userID uuid.UUID, role string, countyID sql.NullInt32, userAgent, ipAddress string) (LoginResponse, error) {
+	if err := s.repo.UpdateUserLastLogin(ctx, userID); err != nil {
+		return LoginResponse{}, err
+	}
+
+	if s.sessions == nil {
+		token, err := s.signAccessToken(userID, role, countyID, uuid.Nil)
+		if err != nil {
+			return LoginResponse{}, err
+		}
+		return LoginResponse{Token: token, ExpiresIn: int64(s.accessTTL.Seconds())}, nil
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return LoginResponse{}, err
 	}
 
+	session, err := s.sessions.CreateSession(ctx, models.CreateSessionParams{
+		UserID:           userID,
+		RefreshTokenHash: hashToken(refreshToken),
+		UserAgent:        sql.NullString{String: userAgent, Valid: userAgent != ""},
+		IpAddress:        sql.NullString{String: ipAddress, Valid: ipAddress != ""},
+		ExpiresAt:        time.Now().Add(s.refreshTTL),
+	})
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	token, err := s.signAccessToken(userID, role, countyID, session.ID)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	return LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.accessTTL.Seconds()),
+	}, nil
+}
+
+func (s AuthService) signAccessToken(userID uuid.UUID, role string, countyID sql.NullInt32, sessionID uuid.UUID) (string, error) {
 	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"role":    user.Role,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+		"user_id": userID,
+		"role":    role,
+		"exp":     time.Now().Add(s.accessTTL).Unix(),
+	}
+
+	if countyID.Valid {
+		claims["county_id"] = countyID.Int32
 	}
 
-	if user.CountyID.Valid {
-		claims["county_id"] = user.CountyID.Int32
+	if sessionID != uuid.Nil {
+		claims["sid"] = sessionID
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.secretKey)
+}
+
+// Refresh exchanges a refresh token for a new access token. The refresh token
+// is rotated on every use, so a stolen token stops working once the legitimate
+// client refreshes, and presenting a rotated-out token revokes the session.
+func (s AuthService) Refresh(ctx context.Context, req RefreshRequest) (LoginResponse, error) {
+	if s.sessions == nil {
+		return LoginResponse{}, errors.New("refresh tokens are not enabled")
+	}
 
-	tokenString, err := token.SignedString(s.secretKey)
+	if req.RefreshToken == "" {
+		return LoginResponse{}, ErrInvalidRefreshToken
+	}
+
+	currentHash := hashToken(req.RefreshToken)
+	session, err := s.sessions.GetSessionByRefreshTokenHash(ctx, currentHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return LoginResponse{}, s.revokeReusedToken(ctx, currentHash)
+	}
+	if err != nil {
+		return LoginResponse{}, ErrInvalidRefreshToken
+	}
+
+	// Also rejects revoked and expired sessions and deactivated users.
+	if _, err := s.sessions.GetActiveSession(ctx, session.ID); err != nil {
+		return LoginResponse{}, ErrInvalidRefreshToken
+	}
+
+	user, err := s.repo.GetUserByID(ctx, session.UserID.String())
+	if err != nil {
+		return LoginResponse{}, ErrInvalidRefreshToken
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	rows, err := s.sessions.RotateSessionRefreshToken(ctx, models.RotateSessionRefreshTokenParams{
+		RefreshTokenHash:        hashToken(refreshToken),
+		ExpiresAt:               time.Now().Add(s.refreshTTL),
+		ID:                      session.ID,
+		CurrentRefreshTokenHash: currentHash,
+	})
 	if err != nil {
 		return LoginResponse{}, err
 	}
-	return LoginResponse{Token: tokenString}, nil
+
+	// Another request rotated the token first, so this one presented a token
+	// that has just been rotated out.
+	if rows == 0 {
+		return LoginResponse{}, s.revokeReusedToken(ctx, currentHash)
+	}
+
+	token, err := s.signAccessToken(user.ID, user.Role, user.CountyID, session.ID)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	return LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.accessTTL.Seconds()),
+	}, nil
+}
+
+// revokeReusedToken revokes the session a refresh token was rotated out of.
+// Once a token is replaced only a copy of it can be presented, and there is no
+// telling whether the thief or the legitimate client holds the new one, so
+// neither keeps the session. It always reports ErrInvalidRefreshToken unless
+// the revocation itself fails.
+func (s AuthService) revokeReusedToken(ctx context.Context, refreshTokenHash string) error {
+	session, err := s.sessions.RevokeSessionByPreviousRefreshTokenHash(ctx, refreshTokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return err
+	}
+	log.Warn().Str("session_id", session.ID.String()).Str("user_id", session.UserID.String()).Msg("Rotated-out refresh token reused; session revoked")
+	return ErrInvalidRefreshToken
+}
+
+// Logout revokes the caller's session, or every session of the user when all is set.
+func (s AuthService) Logout(ctx context.Context, userID, sessionID string, all bool) error {
+	if s.sessions == nil {
+		return nil
+	}
+
+	if all {
+		parsedUserID, err := uuid.Parse(userID)
+		if err != nil {
+			return err
+		}
+		return s.sessions.RevokeUserSessions(ctx, parsedUserID)
+	}
+
+	parsedSessionID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return err
+	}
+	return s.sessions.RevokeSession(ctx, parsedSessionID)
+}
+
+// ValidateSession implements SessionValidator for JWTAuth.
+func (s AuthService) ValidateSession(ctx context.Context, sessionID string) error {
+	if s.sessions == nil {
+		return nil
+	}
+
+	parsedID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.sessions.GetActiveSession(ctx, parsedID)
+	return err
+}
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 type RegisterRequest struct {
@@ -161,13 +455,35 @@ func (s AuthService) Register(ctx context.Context, req RegisterRequest) (models.
 		IsActive:     sql.NullBool{Bool: true, Valid: true},
 	}
 	
-	user, err := s.repo.CreateUser(ctx, params)
+	if s.tx == nil {
+		return s.createAccount(ctx, s.repo, s.taxpayerRepo, params, req)
+	}
+
+	var user models.User
+	err = s.tx.WithTx(ctx, func(tx db.DBTX) error {
+		repo, taxpayerRepo := s.txRepos(tx)
+		user, err = s.createAccount(ctx, repo, taxpayerRepo, params, req)
+		return err
+	})
 	if err != nil {
 		return models.User{}, err
 	}
+	return user, nil
+}
+
+// createAccount inserts the user and, for taxpayer accounts, the linked
+// taxpayer profile.
+func (s AuthService) createAccount(ctx context.Context, repo Repository, taxpayerRepo TaxpayerRepository, params models.InsertUserParams, req RegisterRequest) (models.User, error) {
+	user, err := repo.CreateUser(ctx, params)
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return models.User{}, errors.New("a user with this email already exists")
+		}
+		return models.User{}, err
+	}
 
 	// Create taxpayer profile if role is "user" and taxpayer repo is available
-	if req.Role == "user" && s.taxpayerRepo != nil {
+	if req.Role == "user" && taxpayerRepo != nil {
 		taxpayerParams := taxpayerModels.InsertTaxpayerParams{
 			CountyID:     *req.CountyID,
 			UserID:       uuid.NullUUID{UUID: user.ID, Valid: true},
@@ -180,9 +496,12 @@ func (s AuthService) Register(ctx context.Context, req RegisterRequest) (models.
 			BusinessName: sql.NullString{String: req.BusinessName, Valid: req.BusinessName != ""},
 		}
 
-		_, err = s.taxpayerRepo.CreateTaxpayer(ctx, taxpayerParams)
+		_, err = taxpayerRepo.CreateTaxpayer(ctx, taxpayerParams)
 		if err != nil {
-			// In production, you'd want to rollback the user creation here
+			// Another registration can take the national ID after the check in Register.
+			if db.IsUniqueViolation(err) {
+				return models.User{}, errors.New("taxpayer with this national ID already exists")
+			}
 			return models.User{}, fmt.Errorf("failed to create taxpayer profile: %w", err)
 		}
 	}
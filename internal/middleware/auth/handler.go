@@ -0,0 +1,362 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type Handler struct {
+	service   *AuthService
+	secretKey string
+}
+
+func NewHandler(service *AuthService, secretKey string) *Handler {
+	return &Handler{service: service, secretKey: secretKey}
+}
+
+func (h *Handler) RegisterAuthRoutes(r chi.Router) {
+	r.Post("/login", h.Login)
+	r.Post("/register", h.Register)
+	r.Post("/refresh", h.Refresh)
+	r.Post("/password/forgot", h.ForgotPassword)
+	r.Post("/password/reset", h.ResetPassword)
+	r.Post("/mfa/verify", h.VerifyMFA)
+	r.Post("/mfa/enroll", h.BeginMFAEnrollmentWithToken)
+	r.Post("/mfa/enroll/confirm", h.CompleteMFAEnrollment)
+	r.With(JWTAuth(h.secretKey, h.service)).Post("/logout", h.Logout)
+}
+
+// RegisterAccountRoutes mounts self-service routes for the authenticated user.
+// The router must already apply JWTAuth.
+func (h *Handler) RegisterAccountRoutes(r chi.Router) {
+	r.Put("/me/password", h.ChangePassword)
+	r.Post("/me/mfa", h.BeginMFAEnrollment)
+	r.Post("/me/mfa/confirm", h.ConfirmMFAEnrollment)
+	r.Delete("/me/mfa", h.DisableMFA)
+}
+
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.UserAgent = r.UserAgent()
+	req.IPAddress = clientIP(r)
+
+	resp, err := h.service.Login(r.Context(), req)
+	if err != nil {
+		writeLoginError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+	var req RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Self-registration is for taxpayers only; staff accounts are created through /users.
+	if req.Role != "" && req.Role != RoleUser {
+		http.Error(w, "staff accounts must be created by an administrator", http.StatusForbidden)
+		return
+	}
+
+	user, err := h.service.Register(r.Context(), req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to register user")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type UserResponse struct {
+		ID        string `json:"id"`
+		Email     string `json:"email"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+		Role      string `json:"role"`
+	}
+	resp := UserResponse{
+		ID:        user.ID.String(),
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Role:      user.Role,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.Refresh(r.Context(), req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			status = http.StatusUnauthorized
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Logout revokes the current session. Pass ?all=true to sign out everywhere.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDKey).(string)
+	sessionID, _ := r.Context().Value(SessionIDKey).(string)
+	all := r.URL.Query().Get("all") == "true"
+
+	if err := h.service.Logout(r.Context(), userID, sessionID, all); err != nil {
+		log.Error().Err(err).Msg("Failed to revoke session")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ForgotPassword always answers 202 so callers cannot tell whether the email exists.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ForgotPassword(r.Context(), req); err != nil {
+		log.Error().Err(err).Msg("Failed to issue password reset")
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ResetPassword(r.Context(), req); err != nil {
+		http.Error(w, err.Error(), passwordErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangePassword is mounted at /users/me/password.
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+	if !ok {
+		http.Error(w, "user_id not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ChangePassword(r.Context(), userID, req); err != nil {
+		http.Error(w, err.Error(), passwordErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func passwordErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrWeakPassword), errors.Is(err, ErrInvalidResetToken):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrWrongPassword):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *Handler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var req MFAVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.UserAgent = r.UserAgent()
+	req.IPAddress = clientIP(r)
+
+	resp, err := h.service.VerifyMFA(r.Context(), req)
+	if err != nil {
+		writeLoginError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) BeginMFAEnrollmentWithToken(w http.ResponseWriter, r *http.Request) {
+	var req MFAEnrollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	enrollment, err := h.service.BeginMFAEnrollmentWithToken(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), mfaErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+func (h *Handler) CompleteMFAEnrollment(w http.ResponseWriter, r *http.Request) {
+	var req MFAEnrollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.UserAgent = r.UserAgent()
+	req.IPAddress = clientIP(r)
+
+	resp, err := h.service.CompleteMFAEnrollment(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), mfaErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) BeginMFAEnrollment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+	if !ok {
+		http.Error(w, "user_id not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	enrollment, err := h.service.BeginMFAEnrollment(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), mfaErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+func (h *Handler) ConfirmMFAEnrollment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+	if !ok {
+		http.Error(w, "user_id not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req MFAEnrollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	confirmation, err := h.service.ConfirmMFAEnrollment(r.Context(), userID, req.Code)
+	if err != nil {
+		http.Error(w, err.Error(), mfaErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(confirmation)
+}
+
+func (h *Handler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(UserIDKey).(string)
+	if !ok {
+		http.Error(w, "user_id not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req MFAEnrollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DisableMFA(r.Context(), userID, req.Code); err != nil {
+		http.Error(w, err.Error(), mfaErrorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeLoginError reports a failed login step. Lockouts get 429 with a
+// Retry-After header; unexpected errors are not echoed to the client.
+func writeLoginError(w http.ResponseWriter, err error) {
+	var lockout *LockoutError
+	if errors.As(err, &lockout) {
+		w.Header().Set("Retry-After", strconv.Itoa(lockout.RetryAfterSeconds()))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+
+	status := mfaErrorStatus(err)
+	if errors.Is(err, ErrInvalidCredentials) {
+		status = http.StatusUnauthorized
+	}
+
+	if status == http.StatusInternalServerError {
+		log.Error().Err(err).Msg("Login failed")
+		http.Error(w, "login failed", status)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func mfaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidMFACode), errors.Is(err, ErrInvalidMFAToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrMFAStaffOnly), errors.Is(err, ErrMFARequired):
+		return http.StatusForbidden
+	case errors.Is(err, ErrMFAAlreadyEnabled), errors.Is(err, ErrMFANotEnabled):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// clientIP returns the address of the directly connected client. Forwarded
+// headers are ignored because they are trivially spoofed.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,438 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/user/models"
+)
+
+const (
+	mfaTokenTTL       = 5 * time.Minute
+	recoveryCodeCount = 10
+
+	purposeMFA       = "mfa"
+	purposeMFAEnroll = "mfa_enroll"
+)
+
+var (
+	ErrInvalidMFACode    = errors.New("invalid MFA code")
+	ErrInvalidMFAToken   = errors.New("invalid or expired MFA token")
+	ErrMFANotEnabled     = errors.New("MFA is not enabled")
+	ErrMFAAlreadyEnabled = errors.New("MFA is already enabled")
+	ErrMFAStaffOnly      = errors.New("MFA is only available for staff accounts")
+	ErrMFARequired       = errors.New("MFA is required for this account and cannot be disabled")
+)
+
+// mfaPolicyRoles are the roles a county policy can force onto MFA.
+var mfaPolicyRoles = map[string]bool{
+	RoleSuperAdmin:  true,
+	RoleCountyAdmin: true,
+	RoleCollector:   true,
+}
+
+type MFARepository interface {
+	UpsertUserMFASecret(ctx context.Context, params models.UpsertUserMFASecretParams) (models.UserMfa, error)
+	GetUserMFA(ctx context.Context, userID uuid.UUID) (models.UserMfa, error)
+	EnableUserMFA(ctx context.Context, userID uuid.UUID) error
+	RecordMFAStep(ctx context.Context, params models.RecordMFAStepParams) (int64, error)
+	DeleteUserMFA(ctx context.Context, userID uuid.UUID) error
+	CreateMFARecoveryCode(ctx context.Context, params models.CreateMFARecoveryCodeParams) error
+	ConsumeMFARecoveryCode(ctx context.Context, params models.ConsumeMFARecoveryCodeParams) (int64, error)
+	DeleteMFARecoveryCodes(ctx context.Context, userID uuid.UUID) error
+	IsCountyMFARequired(ctx context.Context, countyID int32) (bool, error)
+}
+
+// WithMFA enables TOTP for staff accounts. Super admins have no county, so
+// whether MFA is mandatory for them is set here instead of by county policy.
+func WithMFA(mfa MFARepository, issuer string, requireForSuperAdmins bool) Option {
+	return func(s *AuthService) {
+		s.mfa = mfa
+		s.mfaIssuer = issuer
+		s.mfaRequiredSuperAdmins = requireForSuperAdmins
+	}
+}
+
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+type MFAEnrollRequest struct {
+	MFAToken string `json:"mfa_token,omitempty"`
+	Code     string `json:"code,omitempty"`
+
+	UserAgent string `json:"-"`
+	IPAddress string `json:"-"`
+}
+
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+type MFAConfirmation struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func isStaffRole(role string) bool {
+	for _, r := range StaffRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// mfaChallenge returns the response that replaces the tokens when the user
+// must present a second factor, or nil when password login is enough.
+func (s AuthService) mfaChallenge(ctx context.Context, userID uuid.UUID, role string, countyID sql.NullInt32) (*LoginResponse, error) {
+	if s.mfa == nil || !isStaffRole(role) {
+		return nil, nil
+	}
+
+	state, err := s.mfa.GetUserMFA(ctx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if err == nil && state.Enabled {
+		token, err := s.signMFAToken(userID, purposeMFA)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResponse{MFARequired: true, MFAToken: token}, nil
+	}
+
+	required, err := s.mfaRequired(ctx, role, countyID)
+	if err != nil || !required {
+		return nil, err
+	}
+
+	token, err := s.signMFAToken(userID, purposeMFAEnroll)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResponse{MFAEnrollmentRequired: true, MFAToken: token}, nil
+}
+
+func (s AuthService) mfaRequired(ctx context.Context, role string, countyID sql.NullInt32) (bool, error) {
+	if !mfaPolicyRoles[role] {
+		return false, nil
+	}
+
+	if role == RoleSuperAdmin {
+		return s.mfaRequiredSuperAdmins, nil
+	}
+
+	if !countyID.Valid {
+		return false, nil
+	}
+	return s.mfa.IsCountyMFARequired(ctx, countyID.Int32)
+}
+
+// signMFAToken issues the short-lived token that links the password step of a
+// login to the MFA step. It carries no role, so JWTAuth never accepts it.
+func (s AuthService) signMFAToken(userID uuid.UUID, purpose string) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"purpose": purpose,
+		"exp":     time.Now().Add(mfaTokenTTL).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secretKey)
+}
+
+func (s AuthService) parseMFAToken(tokenString, purpose string) (uuid.UUID, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidMFAToken
+		}
+		return s.secretKey, nil
+	})
+	if err != nil || !token.Valid {
+		return uuid.Nil, ErrInvalidMFAToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != purpose {
+		return uuid.Nil, ErrInvalidMFAToken
+	}
+
+	userID, _ := claims["user_id"].(string)
+	parsed, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil, ErrInvalidMFAToken
+	}
+	return parsed, nil
+}
+
+// VerifyMFA completes a login that returned MFARequired, using either a TOTP
+// code or one of the user's recovery codes.
+func (s AuthService) VerifyMFA(ctx context.Context, req MFAVerifyRequest) (LoginResponse, error) {
+	if s.mfa == nil {
+		return LoginResponse{}, ErrMFANotEnabled
+	}
+
+	userID, err := s.parseMFAToken(req.MFAToken, purposeMFA)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	// Six-digit codes are guessable without a limit on attempts.
+	key := s.mfaKey(userID.String())
+	if err := s.checkThrottle(ctx, key); err != nil {
+		return LoginResponse{}, err
+	}
+
+	if err := s.verifySecondFactor(ctx, userID, req); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.recordFailure(ctx, key)
+		}
+		return LoginResponse{}, err
+	}
+	s.clearThrottle(ctx, key)
+
+	return s.issueTokensForUser(ctx, userID, req.UserAgent, req.IPAddress)
+}
+
+func (s AuthService) verifySecondFactor(ctx context.Context, userID uuid.UUID, req MFAVerifyRequest) error {
+	switch {
+	case req.Code != "":
+		return s.checkTOTP(ctx, userID, req.Code, true)
+	case req.RecoveryCode != "":
+		rows, err := s.mfa.ConsumeMFARecoveryCode(ctx, models.ConsumeMFARecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(req.RecoveryCode),
+		})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrInvalidMFACode
+		}
+		return nil
+	default:
+		return ErrInvalidMFACode
+	}
+}
+
+// BeginMFAEnrollment generates a new TOTP secret for the user. MFA is not
+// enforced until ConfirmMFAEnrollment succeeds.
+func (s AuthService) BeginMFAEnrollment(ctx context.Context, userID string) (MFAEnrollment, error) {
+	if s.mfa == nil {
+		return MFAEnrollment{}, ErrMFANotEnabled
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+
+	if !isStaffRole(user.Role) {
+		return MFAEnrollment{}, ErrMFAStaffOnly
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+
+	if _, err := s.mfa.UpsertUserMFASecret(ctx, models.UpsertUserMFASecretParams{
+		UserID: user.ID,
+		Secret: secret,
+	}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return MFAEnrollment{}, ErrMFAAlreadyEnabled
+		}
+		return MFAEnrollment{}, err
+	}
+
+	return MFAEnrollment{
+		Secret:     secret,
+		OtpauthURI: otpauthURI(s.mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFAEnrollment turns MFA on once the user proves their authenticator
+// works, and returns a fresh set of recovery codes.
+func (s AuthService) ConfirmMFAEnrollment(ctx context.Context, userID string, code string) (MFAConfirmation, error) {
+	if s.mfa == nil {
+		return MFAConfirmation{}, ErrMFANotEnabled
+	}
+
+	parsedID, err := uuid.Parse(userID)
+	if err != nil {
+		return MFAConfirmation{}, err
+	}
+
+	if err := s.checkTOTP(ctx, parsedID, code, false); err != nil {
+		return MFAConfirmation{}, err
+	}
+
+	if err := s.mfa.EnableUserMFA(ctx, parsedID); err != nil {
+		return MFAConfirmation{}, err
+	}
+
+	codes, err := s.regenerateRecoveryCodes(ctx, parsedID)
+	if err != nil {
+		return MFAConfirmation{}, err
+	}
+	return MFAConfirmation{RecoveryCodes: codes}, nil
+}
+
+// BeginMFAEnrollmentWithToken is BeginMFAEnrollment for users who were told
+// at login that MFA is mandatory and therefore have no access token yet.
+func (s AuthService) BeginMFAEnrollmentWithToken(ctx context.Context, req MFAEnrollRequest) (MFAEnrollment, error) {
+	userID, err := s.parseMFAToken(req.MFAToken, purposeMFAEnroll)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	return s.BeginMFAEnrollment(ctx, userID.String())
+}
+
+// CompleteMFAEnrollment confirms a mandatory enrolment and finishes the login.
+func (s AuthService) CompleteMFAEnrollment(ctx context.Context, req MFAEnrollRequest) (LoginResponse, error) {
+	userID, err := s.parseMFAToken(req.MFAToken, purposeMFAEnroll)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	confirmation, err := s.ConfirmMFAEnrollment(ctx, userID.String(), req.Code)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	resp, err := s.issueTokensForUser(ctx, userID, req.UserAgent, req.IPAddress)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	resp.RecoveryCodes = confirmation.RecoveryCodes
+	return resp, nil
+}
+
+// DisableMFA removes the user's authenticator. A current code is required, and
+// users whose role and county make MFA mandatory cannot opt out.
+func (s AuthService) DisableMFA(ctx context.Context, userID string, code string) error {
+	if s.mfa == nil {
+		return ErrMFANotEnabled
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	required, err := s.mfaRequired(ctx, user.Role, user.CountyID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequired
+	}
+
+	if err := s.checkTOTP(ctx, user.ID, code, true); err != nil {
+		return err
+	}
+
+	if err := s.mfa.DeleteMFARecoveryCodes(ctx, user.ID); err != nil {
+		return err
+	}
+	return s.mfa.DeleteUserMFA(ctx, user.ID)
+}
+
+// checkTOTP validates code against the user's secret and records the time step
+// so the same code cannot be replayed. wantEnabled selects whether the secret
+// must already be confirmed (login) or still pending (enrolment).
+func (s AuthService) checkTOTP(ctx context.Context, userID uuid.UUID, code string, wantEnabled bool) error {
+	state, err := s.mfa.GetUserMFA(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMFANotEnabled
+	}
+	if err != nil {
+		return err
+	}
+
+	if state.Enabled != wantEnabled {
+		if state.Enabled {
+			return ErrMFAAlreadyEnabled
+		}
+		return ErrMFANotEnabled
+	}
+
+	step, ok := verifyTOTP(state.Secret, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	rows, err := s.mfa.RecordMFAStep(ctx, models.RecordMFAStepParams{Step: step, UserID: userID})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s AuthService) regenerateRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	if err := s.mfa.DeleteMFARecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.mfa.CreateMFARecoveryCode(ctx, models.CreateMFARecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(code),
+		}); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// issueTokensForUser reloads the user so role, county and active status are
+// current when a multi-step login completes.
+func (s AuthService) issueTokensForUser(ctx context.Context, userID uuid.UUID, userAgent, ipAddress string) (LoginResponse, error) {
+	user, err := s.repo.GetUserByID(ctx, userID.String())
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	if user.IsActive.Valid && !user.IsActive.Bool {
+		return LoginResponse{}, ErrInvalidCredentials
+	}
+
+	return s.issueTokens(ctx, user.ID, user.Role, user.CountyID, userAgent, ipAddress)
+}
+
+// newRecoveryCode returns a code such as "k3m9x-7qpdw".
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(totpEncoding.EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalised := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return hashToken(normalised)
+}
@@ -14,8 +14,16 @@ type contextKey string
 const UserIDKey contextKey = "user_id"
 const UserRoleKey contextKey = "user_role"
 const UserCountyIDKey contextKey = "user_county_id"
+const SessionIDKey contextKey = "session_id"
 
-func JWTAuth(secretKey string) func(http.Handler) http.Handler {
+// SessionValidator reports whether the session behind a token is still usable.
+type SessionValidator interface {
+	ValidateSession(ctx context.Context, sessionID string) error
+}
+
+// JWTAuth authenticates bearer tokens. When sessions is non-nil every token must
+// carry a session that is neither revoked nor expired and whose user is active.
+func JWTAuth(secretKey string, sessions SessionValidator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -50,6 +58,12 @@ func JWTAuth(secretKey string) func(http.Handler) http.Handler {
 				return
 			}
 
+			// Purpose-bound tokens (e.g. the MFA step of a login) are not access tokens.
+			if _, ok := claims["purpose"]; ok {
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+
 			userID, ok := claims["user_id"].(string)
 			if !ok {
 				http.Error(w, "Invalid user_id in token", http.StatusUnauthorized)
@@ -65,6 +79,21 @@ func JWTAuth(secretKey string) func(http.Handler) http.Handler {
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
 			ctx = context.WithValue(ctx, UserRoleKey, role)
 
+			if sessions != nil {
+				sessionID, ok := claims["sid"].(string)
+				if !ok {
+					http.Error(w, "Invalid session in token", http.StatusUnauthorized)
+					return
+				}
+
+				if err := sessions.ValidateSession(r.Context(), sessionID); err != nil {
+					log.Info().Err(err).Str("session_id", sessionID).Msg("Rejected token for inactive session")
+					http.Error(w, "Session revoked or expired", http.StatusUnauthorized)
+					return
+				}
+				ctx = context.WithValue(ctx, SessionIDKey, sessionID)
+			}
+
 			if countyIDFloat, exists := claims["county_id"]; exists {
 				if countyID, ok := countyIDFloat.(float64); ok {
 					ctx = context.WithValue(ctx, UserCountyIDKey, int32(countyID))
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubValidator struct {
+	revoked map[string]bool
+}
+
+func (v stubValidator) ValidateSession(ctx context.Context, sessionID string) error {
+	if v.revoked[sessionID] {
+		return errors.New("session revoked")
+	}
+	return nil
+}
+
+func signedToken(t *testing.T, claims jwt.MapClaims) string {
+	claims["exp"] = time.Now().Add(time.Minute).Unix()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	assert.NoError(t, err)
+	return token
+}
+
+func TestJWTAuth_Sessions(t *testing.T) {
+	validator := stubValidator{revoked: map[string]bool{"revoked": true}}
+	handler := JWTAuth("secret", validator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "active", r.Context().Value(SessionIDKey))
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   int
+	}{
+		{"active session", jwt.MapClaims{"user_id": "u1", "role": RoleCollector, "sid": "active"}, http.StatusOK},
+		{"revoked session", jwt.MapClaims{"user_id": "u1", "role": RoleCollector, "sid": "revoked"}, http.StatusUnauthorized},
+		{"missing session", jwt.MapClaims{"user_id": "u1", "role": RoleCollector}, http.StatusUnauthorized},
+		{"mfa token", jwt.MapClaims{"user_id": "u1", "role": RoleCollector, "sid": "active", "purpose": purposeMFA}, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+signedToken(t, tt.claims))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.want, rr.Code)
+		})
+	}
+}
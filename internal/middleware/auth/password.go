@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/sangkips/revenue-system/internal/domain/user/models"
+	"github.com/sangkips/revenue-system/internal/notify"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minPasswordLength = 8
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+	ErrWrongPassword     = errors.New("current password is incorrect")
+	ErrWeakPassword      = fmt.Errorf("password must be at least %d characters", minPasswordLength)
+)
+
+type PasswordResetRepository interface {
+	CreatePasswordResetToken(ctx context.Context, params models.CreatePasswordResetTokenParams) (models.PasswordResetToken, error)
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	InvalidateUserPasswordResetTokens(ctx context.Context, userID uuid.UUID) error
+}
+
+// WithPasswordReset enables the forgot-password flow. Reset tokens are valid
+// for ttl and delivered through notifier; resetURL, when set, is prefixed to
+// the token to build the link in the message.
+func WithPasswordReset(resets PasswordResetRepository, notifier notify.Notifier, ttl time.Duration, resetURL string) Option {
+	return func(s *AuthService) {
+		s.resets = resets
+		s.notifier = notifier
+		s.resetTTL = ttl
+		s.resetURL = resetURL
+	}
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ForgotPassword issues a single-use reset token and sends it to the user.
+// Unknown or disabled accounts are ignored silently so the endpoint cannot be
+// used to discover which emails are registered.
+func (s AuthService) ForgotPassword(ctx context.Context, req ForgotPasswordRequest) error {
+	if s.resets == nil {
+		return errors.New("password reset is not enabled")
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		log.Info().Str("email", req.Email).Msg("Password reset requested for unknown email")
+		return nil
+	}
+
+	if user.IsActive.Valid && !user.IsActive.Bool {
+		return nil
+	}
+
+	// Only the most recent link works.
+	if err := s.resets.InvalidateUserPasswordResetTokens(ctx, user.ID); err != nil {
+		return err
+	}
+
+	token, err := newRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.resets.CreatePasswordResetToken(ctx, models.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.resetTTL),
+	}); err != nil {
+		return err
+	}
+
+	return s.notifier.Send(ctx, notify.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use the following link to reset your password. It expires in %s.\n\n%s%s",
+			s.resetTTL, s.resetURL, token),
+	})
+}
+
+// ResetPassword redeems a reset token and signs the user out everywhere.
+func (s AuthService) ResetPassword(ctx context.Context, req ResetPasswordRequest) error {
+	if s.resets == nil {
+		return errors.New("password reset is not enabled")
+	}
+
+	if len(req.NewPassword) < minPasswordLength {
+		return ErrWeakPassword
+	}
+
+	if req.Token == "" {
+		return ErrInvalidResetToken
+	}
+
+	userID, err := s.resets.ConsumePasswordResetToken(ctx, hashToken(req.Token))
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	return s.setPassword(ctx, userID, req.NewPassword)
+}
+
+// ChangePassword sets a new password for an authenticated user after checking
+// the current one.
+func (s AuthService) ChangePassword(ctx context.Context, userID string, req ChangePasswordRequest) error {
+	if len(req.NewPassword) < minPasswordLength {
+		return ErrWeakPassword
+	}
+
+	parsedID, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+
+	hash, err := s.repo.GetUserPasswordHash(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.CurrentPassword)); err != nil {
+		return ErrWrongPassword
+	}
+
+	return s.setPassword(ctx, parsedID, req.NewPassword)
+}
+
+func (s AuthService) setPassword(ctx context.Context, userID uuid.UUID, password string) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdateUserPassword(ctx, models.UpdateUserPasswordParams{
+		PasswordHash: string(hashed),
+		ID:           userID,
+	}); err != nil {
+		return err
+	}
+
+	if s.resets != nil {
+		if err := s.resets.InvalidateUserPasswordResetTokens(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	// Existing sessions may belong to whoever knew the old password.
+	if s.sessions != nil {
+		return s.sessions.RevokeUserSessions(ctx, userID)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"net/http"
+)
+
+const (
+	RoleSuperAdmin     = "super_admin"
+	RoleCountyAdmin    = "county_admin"
+	RoleDepartmentHead = "department_head"
+	RoleCollector      = "collector"
+	RoleAuditor        = "auditor"
+	RoleUser           = "user"
+)
+
+// StaffRoles are the county employee roles, i.e. every role except taxpayer accounts.
+var StaffRoles = []string{RoleSuperAdmin, RoleCountyAdmin, RoleDepartmentHead, RoleCollector, RoleAuditor}
+
+type Permission string
+
+const (
+	PermUsersRead         Permission = "users:read"
+	PermUsersWrite        Permission = "users:write"
+	PermCountiesRead      Permission = "counties:read"
+	PermCountiesWrite     Permission = "counties:write"
+	PermTaxpayersRead     Permission = "taxpayers:read"
+	PermTaxpayersWrite    Permission = "taxpayers:write"
+	PermRevenuesRead      Permission = "revenues:read"
+	PermRevenuesWrite     Permission = "revenues:write"
+	PermAssessmentsRead   Permission = "assessments:read"
+	PermAssessmentsWrite  Permission = "assessments:write"
+	PermPaymentsRead      Permission = "payments:read"
+	PermPaymentsCollect   Permission = "payments:collect"   // record payments, allocations and receipts
+	PermPaymentsManage    Permission = "payments:manage"    // edit or delete recorded payments
+	PermPaymentsReconcile Permission = "payments:reconcile" // import bank statements and resolve unmatched lines
+	PermRefundsRequest    Permission = "refunds:request"    // ask for part of a payment to be given back
+	PermRefundsApprove    Permission = "refunds:approve"    // approve or reject someone else's refund request
+	PermSessionsApprove   Permission = "sessions:approve"   // approve or reject a collector's end-of-shift Z-report
+	PermSecurityManage    Permission = "security:manage"    // county security policy such as mandatory MFA
+	PermTariffsManage     Permission = "tariffs:manage"     // publish and retire the county's rate card
+)
+
+// rolePermissions is the permission matrix. A role not listed here has no permissions.
+var rolePermissions = map[string][]Permission{
+	RoleSuperAdmin: {
+		PermUsersRead, PermUsersWrite,
+		PermCountiesRead, PermCountiesWrite,
+		PermTaxpayersRead, PermTaxpayersWrite,
+		PermRevenuesRead, PermRevenuesWrite,
+		PermAssessmentsRead, PermAssessmentsWrite,
+		PermPaymentsRead, PermPaymentsCollect, PermPaymentsManage, PermPaymentsReconcile,
+		PermRefundsRequest, PermRefundsApprove,
+		PermSessionsApprove,
+		PermSecurityManage,
+		PermTariffsManage,
+	},
+	RoleCountyAdmin: {
+		PermUsersRead, PermUsersWrite,
+		PermCountiesRead,
+		PermTaxpayersRead, PermTaxpayersWrite,
+		PermRevenuesRead, PermRevenuesWrite,
+		PermAssessmentsRead, PermAssessmentsWrite,
+		PermPaymentsRead, PermPaymentsCollect, PermPaymentsManage, PermPaymentsReconcile,
+		PermRefundsRequest, PermRefundsApprove,
+		PermSessionsApprove,
+		PermSecurityManage,
+		PermTariffsManage,
+	},
+	RoleDepartmentHead: {
+		PermUsersRead,
+		PermCountiesRead,
+		PermTaxpayersRead, PermTaxpayersWrite,
+		PermRevenuesRead, PermRevenuesWrite,
+		PermAssessmentsRead, PermAssessmentsWrite,
+		PermPaymentsRead,
+		PermRefundsApprove,
+		PermSessionsApprove,
+	},
+	RoleCollector: {
+		PermCountiesRead,
+		PermTaxpayersRead, PermTaxpayersWrite,
+		PermAssessmentsRead,
+		PermPaymentsRead, PermPaymentsCollect,
+		PermRefundsRequest,
+	},
+	RoleAuditor: {
+		PermUsersRead,
+		PermCountiesRead,
+		PermTaxpayersRead,
+		PermRevenuesRead,
+		PermAssessmentsRead,
+		PermPaymentsRead,
+	},
+	RoleUser: {
+		PermCountiesRead,
+	},
+}
+
+// HasPermission reports whether the permission matrix grants perm to role.
+func HasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// RolesWith returns every role that is granted perm.
+func RolesWith(perm Permission) []string {
+	var roles []string
+	for role := range rolePermissions {
+		if HasPermission(role, perm) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// CanAssignRole reports whether a user with role actor may create or promote
+// another user to role target. Only super admins can mint other super admins.
+func CanAssignRole(actor, target string) bool {
+	switch actor {
+	case RoleSuperAdmin:
+		return true
+	case RoleCountyAdmin:
+		return target != RoleSuperAdmin
+	default:
+		return false
+	}
+}
+
+// RequireRoles rejects requests whose authenticated role is not one of roles.
+// It must be mounted after JWTAuth.
+func RequireRoles(roles ...string) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		allowed[role] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := r.Context().Value(UserRoleKey).(string)
+			if !ok || role == "" {
+				http.Error(w, "user role not found in context", http.StatusUnauthorized)
+				return
+			}
+
+			if !allowed[role] {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePermission rejects requests whose role is not granted perm.
+func RequirePermission(perm Permission) func(http.Handler) http.Handler {
+	return RequireRoles(RolesWith(perm)...)
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequirePermission(t *testing.T) {
+	handler := RequirePermission(PermPaymentsCollect)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	cases := map[string]int{
+		RoleSuperAdmin:     http.StatusCreated,
+		RoleCountyAdmin:    http.StatusCreated,
+		RoleCollector:      http.StatusCreated,
+		RoleDepartmentHead: http.StatusForbidden,
+		RoleAuditor:        http.StatusForbidden,
+		RoleUser:           http.StatusForbidden,
+	}
+
+	for role, want := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/payments", nil)
+		req = req.WithContext(context.WithValue(req.Context(), UserRoleKey, role))
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, want, rr.Code, role)
+	}
+}
+
+func TestRequirePermission_MissingRole(t *testing.T) {
+	handler := RequirePermission(PermPaymentsRead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/payments", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestAuditorIsReadOnly(t *testing.T) {
+	for _, perm := range rolePermissions[RoleAuditor] {
+		assert.Contains(t, string(perm), ":read")
+	}
+}
+
+func TestRefundsAreMakerChecker(t *testing.T) {
+	assert.True(t, HasPermission(RoleCollector, PermRefundsRequest))
+	assert.False(t, HasPermission(RoleCollector, PermRefundsApprove))
+	assert.True(t, HasPermission(RoleDepartmentHead, PermRefundsApprove))
+	assert.False(t, HasPermission(RoleDepartmentHead, PermRefundsRequest))
+}
+
+func TestCollectorsCannotApproveTheirOwnShift(t *testing.T) {
+	assert.True(t, HasPermission(RoleCollector, PermPaymentsCollect))
+	assert.False(t, HasPermission(RoleCollector, PermSessionsApprove))
+	assert.True(t, HasPermission(RoleDepartmentHead, PermSessionsApprove))
+	assert.False(t, HasPermission(RoleAuditor, PermSessionsApprove))
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/domain/user/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySession is a single session kept the way the sessions table keeps it;
+// the rest of SessionRepository is unused.
+type memorySession struct {
+	SessionRepository
+	session models.Session
+}
+
+func (m *memorySession) GetSessionByRefreshTokenHash(ctx context.Context, refreshTokenHash string) (models.Session, error) {
+	if m.session.RefreshTokenHash != refreshTokenHash {
+		return models.Session{}, sql.ErrNoRows
+	}
+	return m.session, nil
+}
+
+func (m *memorySession) GetActiveSession(ctx context.Context, id uuid.UUID) (models.GetActiveSessionRow, error) {
+	if m.session.ID != id || m.session.RevokedAt.Valid {
+		return models.GetActiveSessionRow{}, sql.ErrNoRows
+	}
+	return models.GetActiveSessionRow{ID: m.session.ID, UserID: m.session.UserID}, nil
+}
+
+func (m *memorySession) RotateSessionRefreshToken(ctx context.Context, params models.RotateSessionRefreshTokenParams) (int64, error) {
+	if m.session.ID != params.ID || m.session.RefreshTokenHash != params.CurrentRefreshTokenHash || m.session.RevokedAt.Valid {
+		return 0, nil
+	}
+	m.session.PreviousRefreshTokenHash = sql.NullString{String: m.session.RefreshTokenHash, Valid: true}
+	m.session.RefreshTokenHash = params.RefreshTokenHash
+	return 1, nil
+}
+
+func (m *memorySession) RevokeSessionByPreviousRefreshTokenHash(ctx context.Context, refreshTokenHash string) (models.RevokeSessionByPreviousRefreshTokenHashRow, error) {
+	if m.session.PreviousRefreshTokenHash.String != refreshTokenHash || m.session.RevokedAt.Valid {
+		return models.RevokeSessionByPreviousRefreshTokenHashRow{}, sql.ErrNoRows
+	}
+	m.session.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return models.RevokeSessionByPreviousRefreshTokenHashRow{ID: m.session.ID, UserID: m.session.UserID}, nil
+}
+
+type singleUser struct {
+	Repository
+	user models.GetUserByIDRow
+}
+
+func (u singleUser) GetUserByID(ctx context.Context, id string) (models.GetUserByIDRow, error) {
+	return u.user, nil
+}
+
+func TestRefresh_ReusedTokenRevokesSession(t *testing.T) {
+	user := models.GetUserByIDRow{ID: uuid.New(), Role: "revenue_officer", CountyID: sql.NullInt32{Int32: 47, Valid: true}}
+	sessions := &memorySession{session: models.Session{
+		ID:               uuid.New(),
+		UserID:           user.ID,
+		RefreshTokenHash: hashToken("first"),
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}}
+	svc := NewAuthService(singleUser{user: user}, "secret", WithSessions(sessions, time.Minute, time.Hour))
+	ctx := context.Background()
+
+	// The legitimate client rotates the token.
+	resp, err := svc.Refresh(ctx, RefreshRequest{RefreshToken: "first"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.RefreshToken)
+	assert.False(t, sessions.session.RevokedAt.Valid)
+
+	// A copy of the old token shows up: the session is revoked, so the new
+	// token stops working too.
+	_, err = svc.Refresh(ctx, RefreshRequest{RefreshToken: "first"})
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.True(t, sessions.session.RevokedAt.Valid)
+
+	_, err = svc.Refresh(ctx, RefreshRequest{RefreshToken: resp.RefreshToken})
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefresh_UnknownTokenRevokesNothing(t *testing.T) {
+	sessions := &memorySession{session: models.Session{
+		ID:               uuid.New(),
+		UserID:           uuid.New(),
+		RefreshTokenHash: hashToken("current"),
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}}
+	svc := NewAuthService(singleUser{}, "secret", WithSessions(sessions, time.Minute, time.Hour))
+
+	_, err := svc.Refresh(context.Background(), RefreshRequest{RefreshToken: "never-issued"})
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.False(t, sessions.session.RevokedAt.Valid)
+}
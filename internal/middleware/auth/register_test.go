@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sangkips/revenue-system/internal/db"
+	taxpayerModels "github.com/sangkips/revenue-system/internal/domain/taxpayers/models"
+	"github.com/sangkips/revenue-system/internal/domain/user/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTx records whether the unit of work committed.
+type fakeTx struct {
+	committed bool
+}
+
+func (f *fakeTx) WithTx(ctx context.Context, fn func(tx db.DBTX) error) error {
+	if err := fn(nil); err != nil {
+		return err
+	}
+	f.committed = true
+	return nil
+}
+
+type recordingUsers struct {
+	Repository
+	created int
+}
+
+func (r *recordingUsers) CreateUser(ctx context.Context, params models.InsertUserParams) (models.User, error) {
+	r.created++
+	return models.User{Email: params.Email}, nil
+}
+
+type failingTaxpayers struct{}
+
+func (failingTaxpayers) CreateTaxpayer(ctx context.Context, params taxpayerModels.InsertTaxpayerParams) (taxpayerModels.InsertTaxpayerRow, error) {
+	return taxpayerModels.InsertTaxpayerRow{}, errors.New("insert failed")
+}
+
+func (failingTaxpayers) GetTaxpayerByNationalID(ctx context.Context, nationalID string) (taxpayerModels.GetTaxpayerByNationalIDRow, error) {
+	return taxpayerModels.GetTaxpayerByNationalIDRow{}, errors.New("not found")
+}
+
+func TestRegister_RollsBackUserWhenTaxpayerFails(t *testing.T) {
+	users := &recordingUsers{}
+	tx := &fakeTx{}
+	svc := NewAuthServiceWithTaxpayer(users, failingTaxpayers{}, "secret",
+		WithTransactions(tx, func(db.DBTX) (Repository, TaxpayerRepository) {
+			return users, failingTaxpayers{}
+		}),
+	)
+
+	countyID := int32(1)
+	_, err := svc.Register(context.Background(), RegisterRequest{
+		Email:        "trader@example.com",
+		Password:     "password123",
+		FirstName:    "Jane",
+		LastName:     "Trader",
+		CountyID:     &countyID,
+		TaxpayerType: "individual",
+		NationalID:   "12345678",
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, users.created)
+	assert.False(t, tx.committed)
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrCountyForbidden = errors.New("record belongs to another county")
+	ErrCountyRequired  = errors.New("county_id is required")
+	ErrNoCountyClaim   = errors.New("county_id is required for non-super-admin roles")
+)
+
+// CallerCounty returns the county the authenticated caller is bound to.
+// Super admins are not bound to a county and get unrestricted=true.
+func CallerCounty(ctx context.Context) (countyID int32, unrestricted bool, err error) {
+	role, ok := ctx.Value(UserRoleKey).(string)
+	if !ok || role == "" {
+		return 0, false, errors.New("user role not found in context")
+	}
+
+	if role == RoleSuperAdmin {
+		return 0, true, nil
+	}
+
+	countyID, ok = ctx.Value(UserCountyIDKey).(int32)
+	if !ok || countyID == 0 {
+		return 0, false, ErrNoCountyClaim
+	}
+	return countyID, false, nil
+}
+
+// ResolveCounty returns the county a create or list operation must target.
+// Non-super-admins always act on the county in their token; naming a different
+// county in the request is rejected rather than silently rewritten.
+func ResolveCounty(ctx context.Context, requested int32) (int32, error) {
+	countyID, unrestricted, err := CallerCounty(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if unrestricted {
+		if requested == 0 {
+			return 0, ErrCountyRequired
+		}
+		return requested, nil
+	}
+
+	if requested != 0 && requested != countyID {
+		return 0, ErrCountyForbidden
+	}
+	return countyID, nil
+}
+
+// AuthorizeCounty checks that the caller may read or modify a record owned by countyID.
+func AuthorizeCounty(ctx context.Context, countyID int32) error {
+	callerCounty, unrestricted, err := CallerCounty(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !unrestricted && callerCounty != countyID {
+		return ErrCountyForbidden
+	}
+	return nil
+}
+
+// AuthorizeNullCounty is AuthorizeCounty for records whose county is optional.
+// Records without a county (super admin accounts) are only visible to super admins.
+func AuthorizeNullCounty(ctx context.Context, countyID sql.NullInt32) error {
+	if countyID.Valid {
+		return AuthorizeCounty(ctx, countyID.Int32)
+	}
+
+	_, unrestricted, err := CallerCounty(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !unrestricted {
+		return ErrCountyForbidden
+	}
+	return nil
+}
+
+// ErrorStatus maps tenancy errors to their HTTP status, falling back to fallback
+// for everything else.
+func ErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, ErrCountyForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrCountyRequired), errors.Is(err, ErrNoCountyClaim):
+		return http.StatusBadRequest
+	default:
+		return fallback
+	}
+}
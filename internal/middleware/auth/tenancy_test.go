@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func countyCtx(role string, countyID int32) context.Context {
+	ctx := context.WithValue(context.Background(), UserRoleKey, role)
+	if countyID != 0 {
+		ctx = context.WithValue(ctx, UserCountyIDKey, countyID)
+	}
+	return ctx
+}
+
+func TestResolveCounty(t *testing.T) {
+	countyID, err := ResolveCounty(countyCtx(RoleCollector, 47), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(47), countyID)
+
+	_, err = ResolveCounty(countyCtx(RoleCollector, 47), 1)
+	assert.ErrorIs(t, err, ErrCountyForbidden)
+
+	countyID, err = ResolveCounty(countyCtx(RoleSuperAdmin, 0), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), countyID)
+
+	_, err = ResolveCounty(countyCtx(RoleSuperAdmin, 0), 0)
+	assert.ErrorIs(t, err, ErrCountyRequired)
+}
+
+func TestAuthorizeCounty(t *testing.T) {
+	assert.NoError(t, AuthorizeCounty(countyCtx(RoleAuditor, 47), 47))
+	assert.ErrorIs(t, AuthorizeCounty(countyCtx(RoleAuditor, 47), 1), ErrCountyForbidden)
+	assert.NoError(t, AuthorizeCounty(countyCtx(RoleSuperAdmin, 0), 1))
+	assert.ErrorIs(t, AuthorizeCounty(countyCtx(RoleCountyAdmin, 0), 1), ErrNoCountyClaim)
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sangkips/revenue-system/internal/domain/user/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrTooManyAttempts    = errors.New("too many failed attempts, try again later")
+)
+
+// LockoutError is returned while a throttle key is locked. It matches
+// ErrTooManyAttempts with errors.Is.
+type LockoutError struct {
+	Until time.Time
+}
+
+func (e *LockoutError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *LockoutError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// RetryAfterSeconds is the value for the Retry-After header.
+func (e *LockoutError) RetryAfterSeconds() int {
+	seconds := int(time.Until(e.Until).Seconds()) + 1
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+type LoginThrottleRepository interface {
+	GetLoginThrottle(ctx context.Context, throttleKey string) (models.LoginThrottle, error)
+	RecordLoginFailure(ctx context.Context, params models.RecordLoginFailureParams) (int32, error)
+	SetLoginLockout(ctx context.Context, params models.SetLoginLockoutParams) error
+	ClearLoginThrottle(ctx context.Context, throttleKey string) error
+}
+
+// ThrottlePolicy controls when failed attempts lock a key. Once a key reaches
+// its threshold every further failure doubles the lockout, up to MaxLockout.
+// Counters start over after Window passes without a failure or lockout.
+type ThrottlePolicy struct {
+	AccountThreshold int32
+	IPThreshold      int32
+	BaseLockout      time.Duration
+	MaxLockout       time.Duration
+	Window           time.Duration
+}
+
+// DefaultThrottlePolicy tolerates typos but caps guessing at a handful of
+// attempts per account per hour. IPs get a higher threshold because counties
+// often put many collectors behind one NAT.
+var DefaultThrottlePolicy = ThrottlePolicy{
+	AccountThreshold: 5,
+	IPThreshold:      50,
+	BaseLockout:      time.Minute,
+	MaxLockout:       time.Hour,
+	Window:           time.Hour,
+}
+
+// WithLoginThrottle enables failed-attempt tracking and lockout.
+func WithLoginThrottle(throttle LoginThrottleRepository, policy ThrottlePolicy) Option {
+	return func(s *AuthService) {
+		s.throttle = throttle
+		s.throttlePolicy = policy
+	}
+}
+
+type throttleKey struct {
+	key       string
+	threshold int32
+}
+
+func (s AuthService) accountKey(email string) throttleKey {
+	return throttleKey{"account:" + strings.ToLower(strings.TrimSpace(email)), s.throttlePolicy.AccountThreshold}
+}
+
+func (s AuthService) ipKey(ip string) throttleKey {
+	return throttleKey{"ip:" + ip, s.throttlePolicy.IPThreshold}
+}
+
+func (s AuthService) mfaKey(userID string) throttleKey {
+	return throttleKey{"mfa:" + userID, s.throttlePolicy.AccountThreshold}
+}
+
+// loginKeys returns the keys a password attempt counts against.
+func (s AuthService) loginKeys(email, ip string) []throttleKey {
+	keys := []throttleKey{s.accountKey(email)}
+	if ip != "" {
+		keys = append(keys, s.ipKey(ip))
+	}
+	return keys
+}
+
+// checkThrottle fails with a LockoutError if any key is currently locked.
+func (s AuthService) checkThrottle(ctx context.Context, keys ...throttleKey) error {
+	if s.throttle == nil {
+		return nil
+	}
+
+	var until time.Time
+	for _, k := range keys {
+		t, err := s.throttle.GetLoginThrottle(ctx, k.key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if t.LockedUntil.Valid && t.LockedUntil.Time.After(time.Now()) && t.LockedUntil.Time.After(until) {
+			until = t.LockedUntil.Time
+		}
+	}
+
+	if !until.IsZero() {
+		return &LockoutError{Until: until}
+	}
+	return nil
+}
+
+// recordFailure counts a failed attempt against every key and locks those
+// that have reached their threshold.
+func (s AuthService) recordFailure(ctx context.Context, keys ...throttleKey) {
+	if s.throttle == nil {
+		return
+	}
+
+	now := time.Now()
+	for _, k := range keys {
+		failures, err := s.throttle.RecordLoginFailure(ctx, models.RecordLoginFailureParams{
+			ThrottleKey: k.key,
+			ResetBefore: now.Add(-s.throttlePolicy.Window),
+		})
+		if err != nil {
+			log.Error().Err(err).Str("key", k.key).Msg("Failed to record login failure")
+			continue
+		}
+
+		if failures < k.threshold {
+			continue
+		}
+
+		lockout := s.throttlePolicy.lockoutFor(failures - k.threshold)
+		if err := s.throttle.SetLoginLockout(ctx, models.SetLoginLockoutParams{
+			LockedUntil: sql.NullTime{Time: now.Add(lockout), Valid: true},
+			ThrottleKey: k.key,
+		}); err != nil {
+			log.Error().Err(err).Str("key", k.key).Msg("Failed to lock login")
+			continue
+		}
+		log.Warn().Str("key", k.key).Int32("failures", failures).Dur("lockout", lockout).Msg("Login locked after repeated failures")
+	}
+}
+
+func (s AuthService) clearThrottle(ctx context.Context, k throttleKey) {
+	if s.throttle == nil {
+		return
+	}
+
+	if err := s.throttle.ClearLoginThrottle(ctx, k.key); err != nil {
+		log.Error().Err(err).Str("key", k.key).Msg("Failed to clear login throttle")
+	}
+}
+
+// lockoutFor returns the lockout after excess failures beyond the threshold.
+func (p ThrottlePolicy) lockoutFor(excess int32) time.Duration {
+	lockout := p.BaseLockout
+	for i := int32(0); i < excess && lockout < p.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > p.MaxLockout {
+		lockout = p.MaxLockout
+	}
+	return lockout
+}
+
+// dummyPasswordHash is compared against when the email is unknown so the
+// response time does not reveal whether an account exists.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
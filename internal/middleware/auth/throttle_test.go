@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/sangkips/revenue-system/internal/domain/user/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// noUsers is a Repository in which no email is registered.
+type noUsers struct {
+	Repository
+}
+
+func (noUsers) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	return models.User{}, sql.ErrNoRows
+}
+
+type memoryThrottle struct {
+	rows map[string]models.LoginThrottle
+}
+
+func (m *memoryThrottle) GetLoginThrottle(ctx context.Context, key string) (models.LoginThrottle, error) {
+	t, ok := m.rows[key]
+	if !ok {
+		return models.LoginThrottle{}, sql.ErrNoRows
+	}
+	return t, nil
+}
+
+func (m *memoryThrottle) RecordLoginFailure(ctx context.Context, params models.RecordLoginFailureParams) (int32, error) {
+	t := m.rows[params.ThrottleKey]
+	t.ThrottleKey = params.ThrottleKey
+	t.Failures++
+	t.LastFailureAt = time.Now()
+	m.rows[params.ThrottleKey] = t
+	return t.Failures, nil
+}
+
+func (m *memoryThrottle) SetLoginLockout(ctx context.Context, params models.SetLoginLockoutParams) error {
+	t := m.rows[params.ThrottleKey]
+	t.LockedUntil = params.LockedUntil
+	m.rows[params.ThrottleKey] = t
+	return nil
+}
+
+func (m *memoryThrottle) ClearLoginThrottle(ctx context.Context, key string) error {
+	delete(m.rows, key)
+	return nil
+}
+
+func TestLogin_LocksAccountAfterRepeatedFailures(t *testing.T) {
+	throttle := &memoryThrottle{rows: map[string]models.LoginThrottle{}}
+	svc := NewAuthService(noUsers{}, "secret", WithLoginThrottle(throttle, DefaultThrottlePolicy))
+	req := LoginRequest{Email: "Nobody@example.com", Password: "guess", IPAddress: "10.0.0.1"}
+
+	for i := int32(0); i < DefaultThrottlePolicy.AccountThreshold; i++ {
+		_, err := svc.Login(context.Background(), req)
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	_, err := svc.Login(context.Background(), req)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+
+	// The account key is case-insensitive, and the IP is still below its threshold.
+	_, err = svc.Login(context.Background(), LoginRequest{Email: "nobody@example.com", Password: "guess"})
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	assert.Equal(t, DefaultThrottlePolicy.AccountThreshold, throttle.rows["ip:10.0.0.1"].Failures)
+}
+
+func TestThrottlePolicy_LockoutFor(t *testing.T) {
+	p := DefaultThrottlePolicy
+	assert.Equal(t, time.Minute, p.lockoutFor(0))
+	assert.Equal(t, 4*time.Minute, p.lockoutFor(2))
+	assert.Equal(t, time.Hour, p.lockoutFor(30))
+}
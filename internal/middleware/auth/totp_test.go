@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTPCode_RFC6238(t *testing.T) {
+	// RFC 6238 appendix B SHA-1 vectors, truncated to six digits.
+	secret := []byte("12345678901234567890")
+	assert.Equal(t, "287082", totpCode(secret, 59/totpPeriod))
+	assert.Equal(t, "081804", totpCode(secret, 1111111109/totpPeriod))
+	assert.Equal(t, "005924", totpCode(secret, 1234567890/totpPeriod))
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111109, 0)
+
+	step, ok := verifyTOTP(secret, "081804", now)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1111111109/totpPeriod), step)
+
+	_, ok = verifyTOTP(secret, "081804", now.Add(5*time.Minute))
+	assert.False(t, ok)
+
+	_, ok = verifyTOTP(secret, "123", now)
+	assert.False(t, ok)
+}
+
+func TestOtpauthURI(t *testing.T) {
+	uri := otpauthURI("County Revenue", "collector@example.com", "ABC")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/County%20Revenue:collector@example.com?"))
+	assert.Contains(t, uri, "secret=ABC")
+}
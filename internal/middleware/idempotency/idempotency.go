@@ -0,0 +1,263 @@
+// Package idempotency lets clients retry POST requests safely. A request sent
+// with an Idempotency-Key header is handled once; repeating it with the same
+// key and body replays the stored response instead of running it again.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
+)
+
+const (
+	// Header carries the client's key for a request.
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses served from the store.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// Store keeps each key's request and, once it has been handled, its
+// response. GetIdempotencyKey returns sql.ErrNoRows for a key that is not
+// taken. RenewIdempotencyKey restarts the stale clock of a key whose request
+// is still being handled.
+type Store interface {
+	ClaimIdempotencyKey(ctx context.Context, claim Claim) (bool, error)
+	GetIdempotencyKey(ctx context.Context, key Key) (Record, error)
+	RenewIdempotencyKey(ctx context.Context, key Key) error
+	CompleteIdempotencyKey(ctx context.Context, key Key, resp Response) error
+	ReleaseIdempotencyKey(ctx context.Context, key Key) error
+}
+
+// Key is a client's Idempotency-Key; keys are scoped to the user sending them.
+type Key struct {
+	UserID uuid.UUID
+	Key    string
+}
+
+// Claim takes a key for a new request. A key already taken is only handed
+// over once its response is older than ExpiredBefore, or when its request
+// has not been renewed since StaleBefore and never finished.
+type Claim struct {
+	Key
+	RequestHash   string
+	ExpiredBefore time.Time
+	StaleBefore   time.Time
+}
+
+// Record is the request stored under a key. Response is nil while the
+// request is still being handled.
+type Record struct {
+	RequestHash string
+	Response    *Response
+}
+
+// Response is what a repeated request is answered with.
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Policy controls how long keys are honoured.
+type Policy struct {
+	// TTL is how long a response is replayed before its key can be reused.
+	TTL time.Duration
+	// InFlightTimeout frees the key of a request that never finished, for
+	// instance because the server stopped while handling it. A request still
+	// running renews its key every third of it, so a retry cannot take the
+	// key over from a slow request and move the money a second time.
+	InFlightTimeout time.Duration
+	// MaxBodyBytes caps the size of a request body.
+	MaxBodyBytes int64
+}
+
+// DefaultPolicy covers a collector retrying for the rest of a working day.
+var DefaultPolicy = Policy{
+	TTL:             24 * time.Hour,
+	InFlightTimeout: time.Minute,
+	MaxBodyBytes:    1 << 20,
+}
+
+// Middleware makes POST requests carrying an Idempotency-Key idempotent per
+// user. Requests without the header pass straight through. A key reused with
+// a different request, or while its first request is still running, is
+// rejected with 409. Responses with a 5xx status are not stored, so the
+// client can retry them. It must be mounted after JWTAuth.
+func Middleware(store Store, policy Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLength {
+				http.Error(w, "Idempotency-Key must be at most "+strconv.Itoa(maxKeyLength)+" characters", http.StatusBadRequest)
+				return
+			}
+			userID, err := uuid.Parse(userIDFrom(r.Context()))
+			if err != nil {
+				http.Error(w, "user not authenticated", http.StatusUnauthorized)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, policy.MaxBodyBytes))
+			if err != nil {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			hash := requestHash(r, body)
+
+			now := time.Now()
+			k := Key{UserID: userID, Key: key}
+			claimed, err := store.ClaimIdempotencyKey(r.Context(), Claim{
+				Key:           k,
+				RequestHash:   hash,
+				ExpiredBefore: now.Add(-policy.TTL),
+				StaleBefore:   now.Add(-policy.InFlightTimeout),
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to claim idempotency key")
+				http.Error(w, "could not record idempotency key", http.StatusInternalServerError)
+				return
+			}
+			if !claimed {
+				replay(w, r, store, k, hash)
+				return
+			}
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			stopRenewing := renew(r.Context(), store, k, policy.InFlightTimeout/3)
+			next.ServeHTTP(rec, r)
+			stopRenewing()
+
+			// The response has gone out; record it even if the client left.
+			ctx := context.WithoutCancel(r.Context())
+			if rec.status >= http.StatusInternalServerError {
+				err = store.ReleaseIdempotencyKey(ctx, k)
+			} else {
+				err = store.CompleteIdempotencyKey(ctx, k, Response{
+					Status:      rec.status,
+					ContentType: rec.Header().Get("Content-Type"),
+					Body:        rec.body.Bytes(),
+				})
+			}
+			if err != nil {
+				log.Error().Err(err).Str("idempotency_key", key).Msg("Failed to store idempotent response")
+			}
+		})
+	}
+}
+
+// renew keeps key from going stale while its request is handled, renewing it
+// every interval until the returned function is called.
+func renew(ctx context.Context, store Store, key Key, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+	ctx = context.WithoutCancel(ctx)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := store.RenewIdempotencyKey(ctx, key); err != nil {
+					log.Warn().Err(err).Str("idempotency_key", key.Key).Msg("Failed to renew idempotency key")
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// replay answers a request whose key is already taken.
+func replay(w http.ResponseWriter, r *http.Request, store Store, key Key, hash string) {
+	stored, err := store.GetIdempotencyKey(r.Context(), key)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Released between the claim and now; the client can simply retry.
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "a request with this Idempotency-Key is still being processed", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load idempotency key")
+		http.Error(w, "could not load idempotency key", http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case stored.RequestHash != hash:
+		http.Error(w, "Idempotency-Key was already used for a different request", http.StatusConflict)
+	case stored.Response == nil:
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "a request with this Idempotency-Key is still being processed", http.StatusConflict)
+	default:
+		if stored.Response.ContentType != "" {
+			w.Header().Set("Content-Type", stored.Response.ContentType)
+		}
+		w.Header().Set(ReplayedHeader, "true")
+		w.WriteHeader(stored.Response.Status)
+		w.Write(stored.Response.Body)
+	}
+}
+
+// requestHash fingerprints what the request asks for, so a key cannot be
+// replayed against a different endpoint or body.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func userIDFrom(ctx context.Context) string {
+	userID, _ := ctx.Value(auth.UserIDKey).(string)
+	return userID
+}
+
+// recorder passes the response through while keeping a copy to store.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
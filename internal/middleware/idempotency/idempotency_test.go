@@ -0,0 +1,217 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sangkips/revenue-system/internal/middleware/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeKey struct {
+	Record
+	createdAt time.Time
+}
+
+type fakeStore struct {
+	mu   sync.Mutex
+	keys map[Key]fakeKey
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{keys: map[Key]fakeKey{}}
+}
+
+func (s *fakeStore) ClaimIdempotencyKey(ctx context.Context, claim Claim) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if k, ok := s.keys[claim.Key]; ok {
+		stale := k.Response == nil && k.createdAt.Before(claim.StaleBefore)
+		if !k.createdAt.Before(claim.ExpiredBefore) && !stale {
+			return false, nil
+		}
+	}
+	s.keys[claim.Key] = fakeKey{Record: Record{RequestHash: claim.RequestHash}, createdAt: time.Now()}
+	return true, nil
+}
+
+func (s *fakeStore) GetIdempotencyKey(ctx context.Context, key Key) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[key]
+	if !ok {
+		return Record{}, sql.ErrNoRows
+	}
+	return k.Record, nil
+}
+
+func (s *fakeStore) RenewIdempotencyKey(ctx context.Context, key Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if k, ok := s.keys[key]; ok && k.Response == nil {
+		k.createdAt = time.Now()
+		s.keys[key] = k
+	}
+	return nil
+}
+
+func (s *fakeStore) CompleteIdempotencyKey(ctx context.Context, key Key, resp Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := s.keys[key]
+	k.Response = &resp
+	s.keys[key] = k
+	return nil
+}
+
+func (s *fakeStore) ReleaseIdempotencyKey(ctx context.Context, key Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, key)
+	return nil
+}
+
+// countingHandler creates a "payment" per call and answers with its number.
+func countingHandler(calls *int, status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		*calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, `{"payment":`+strconv.Itoa(*calls)+`}`)
+	})
+}
+
+func send(t *testing.T, h http.Handler, userID, key, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, userID))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestMiddleware_ReplaysDuplicate(t *testing.T) {
+	calls := 0
+	h := Middleware(newFakeStore(), DefaultPolicy)(countingHandler(&calls, http.StatusCreated))
+	user := uuid.NewString()
+
+	first := send(t, h, user, "key-1", `{"amount":"100"}`)
+	second := send(t, h, user, "key-1", `{"amount":"100"}`)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "application/json", second.Header().Get("Content-Type"))
+	assert.Equal(t, "true", second.Header().Get(ReplayedHeader))
+	assert.Empty(t, first.Header().Get(ReplayedHeader))
+}
+
+func TestMiddleware_RejectsDifferentBody(t *testing.T) {
+	calls := 0
+	h := Middleware(newFakeStore(), DefaultPolicy)(countingHandler(&calls, http.StatusCreated))
+	user := uuid.NewString()
+
+	send(t, h, user, "key-1", `{"amount":"100"}`)
+	rr := send(t, h, user, "key-1", `{"amount":"200"}`)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestMiddleware_KeysAreScopedToUser(t *testing.T) {
+	calls := 0
+	h := Middleware(newFakeStore(), DefaultPolicy)(countingHandler(&calls, http.StatusCreated))
+
+	send(t, h, uuid.NewString(), "key-1", `{}`)
+	send(t, h, uuid.NewString(), "key-1", `{}`)
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestMiddleware_InFlight(t *testing.T) {
+	store := newFakeStore()
+	user := uuid.New()
+	_, err := store.ClaimIdempotencyKey(context.Background(), Claim{
+		Key:           Key{UserID: user, Key: "key-1"},
+		RequestHash:   requestHash(httptest.NewRequest(http.MethodPost, "/payments", nil), []byte(`{}`)),
+		ExpiredBefore: time.Now().Add(-time.Hour),
+		StaleBefore:   time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	calls := 0
+	rr := send(t, Middleware(store, DefaultPolicy)(countingHandler(&calls, http.StatusCreated)), user.String(), "key-1", `{}`)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	assert.Equal(t, 0, calls)
+}
+
+func TestMiddleware_ServerErrorsAreRetried(t *testing.T) {
+	calls := 0
+	h := Middleware(newFakeStore(), DefaultPolicy)(countingHandler(&calls, http.StatusInternalServerError))
+	user := uuid.NewString()
+
+	send(t, h, user, "key-1", `{}`)
+	send(t, h, user, "key-1", `{}`)
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestMiddleware_WithoutKeyPassesThrough(t *testing.T) {
+	calls := 0
+	h := Middleware(newFakeStore(), DefaultPolicy)(countingHandler(&calls, http.StatusCreated))
+	user := uuid.NewString()
+
+	send(t, h, user, "", `{}`)
+	send(t, h, user, "", `{}`)
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestMiddleware_SlowRequestKeepsItsKey(t *testing.T) {
+	store := newFakeStore()
+	policy := DefaultPolicy
+	policy.InFlightTimeout = 60 * time.Millisecond
+	user := uuid.NewString()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	calls := 0
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	})
+	h := Middleware(store, policy)(slow)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		send(t, h, user, "key-1", `{}`)
+	}()
+	<-started
+
+	// Well past the in-flight timeout, the running request still holds the key.
+	time.Sleep(3 * policy.InFlightTimeout)
+	rr := send(t, h, user, "key-1", `{}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	close(release)
+	<-done
+	assert.Equal(t, 1, calls)
+}
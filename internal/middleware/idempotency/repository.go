@@ -0,0 +1,106 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sangkips/revenue-system/internal/db"
+)
+
+// The idempotency_keys table is only used by this middleware, whichever
+// router it is mounted on, so its queries live here rather than in a domain.
+
+// Takes the key for a new request. A key already taken is only handed over
+// once it has expired, or when the request holding it stopped renewing it.
+const claimIdempotencyKey = `
+INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, idempotency_key) DO UPDATE
+SET request_hash = EXCLUDED.request_hash,
+    response_status = NULL,
+    response_content_type = NULL,
+    response_body = NULL,
+    created_at = CURRENT_TIMESTAMP,
+    completed_at = NULL
+WHERE idempotency_keys.created_at < $4
+    OR (idempotency_keys.response_status IS NULL AND idempotency_keys.created_at < $5)`
+
+const getIdempotencyKey = `
+SELECT request_hash, response_status, response_content_type, response_body
+FROM idempotency_keys
+WHERE user_id = $1 AND idempotency_key = $2`
+
+// Pushes back when the key of a request still being handled goes stale.
+const renewIdempotencyKey = `
+UPDATE idempotency_keys
+SET created_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND idempotency_key = $2 AND response_status IS NULL`
+
+const completeIdempotencyKey = `
+UPDATE idempotency_keys
+SET response_status = $1,
+    response_content_type = $2,
+    response_body = $3,
+    completed_at = CURRENT_TIMESTAMP
+WHERE user_id = $4 AND idempotency_key = $5`
+
+// Frees the key of a request that failed in a way worth retrying.
+const releaseIdempotencyKey = `
+DELETE FROM idempotency_keys
+WHERE user_id = $1 AND idempotency_key = $2 AND response_status IS NULL`
+
+type repository struct {
+	db db.DBTX
+}
+
+// NewRepository stores keys in the idempotency_keys table.
+func NewRepository(db db.DBTX) Store {
+	return &repository{db: db}
+}
+
+// ClaimIdempotencyKey reports whether the key was taken for this request.
+func (r *repository) ClaimIdempotencyKey(ctx context.Context, claim Claim) (bool, error) {
+	result, err := r.db.ExecContext(ctx, claimIdempotencyKey,
+		claim.UserID, claim.Key.Key, claim.RequestHash, claim.ExpiredBefore, claim.StaleBefore)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+func (r *repository) GetIdempotencyKey(ctx context.Context, key Key) (Record, error) {
+	var record Record
+	var status sql.NullInt32
+	var contentType sql.NullString
+	var body []byte
+	err := r.db.QueryRowContext(ctx, getIdempotencyKey, key.UserID, key.Key).
+		Scan(&record.RequestHash, &status, &contentType, &body)
+	if err != nil {
+		return Record{}, err
+	}
+	if status.Valid {
+		record.Response = &Response{Status: int(status.Int32), ContentType: contentType.String, Body: body}
+	}
+	return record, nil
+}
+
+func (r *repository) RenewIdempotencyKey(ctx context.Context, key Key) error {
+	_, err := r.db.ExecContext(ctx, renewIdempotencyKey, key.UserID, key.Key)
+	return err
+}
+
+func (r *repository) CompleteIdempotencyKey(ctx context.Context, key Key, resp Response) error {
+	_, err := r.db.ExecContext(ctx, completeIdempotencyKey,
+		int32(resp.Status),
+		sql.NullString{String: resp.ContentType, Valid: resp.ContentType != ""},
+		resp.Body,
+		key.UserID,
+		key.Key)
+	return err
+}
+
+func (r *repository) ReleaseIdempotencyKey(ctx context.Context, key Key) error {
+	_, err := r.db.ExecContext(ctx, releaseIdempotencyKey, key.UserID, key.Key)
+	return err
+}
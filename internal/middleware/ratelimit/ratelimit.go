@@ -0,0 +1,147 @@
+// Package ratelimit caps how often a client may call the public endpoints.
+// Each client address gets a token bucket: it may make Burst requests at
+// once and regains one every Per/Requests. The buckets live in memory, so
+// each server instance limits on its own. Behind a reverse proxy the proxy
+// must be listed in Policy.TrustedProxies, or every client shares its bucket.
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Policy is the rate a client is held to.
+type Policy struct {
+	Requests int           // requests allowed per Per
+	Per      time.Duration // window Requests are spread over
+	Burst    int           // requests a quiet client may make at once; Requests when 0
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For header is
+	// believed. With none, clients are told apart by the connecting address.
+	TrustedProxies []netip.Prefix
+}
+
+// Limiter tracks the buckets of the clients it has seen.
+type Limiter struct {
+	policy  Policy
+	now     func() time.Time
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	seen   time.Time
+}
+
+// New returns a Limiter enforcing p.
+func New(p Policy) *Limiter {
+	if p.Burst <= 0 {
+		p.Burst = p.Requests
+	}
+	return &Limiter{policy: p, now: time.Now, buckets: map[string]*bucket{}}
+}
+
+// Allow takes a token from key's bucket. When there is none it reports how
+// long until there will be.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	interval := l.policy.Per / time.Duration(l.policy.Requests)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.policy.Burst), seen: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.policy.Burst), b.tokens+float64(now.Sub(b.seen))/float64(interval))
+	b.seen = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(interval))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep forgets clients whose buckets have refilled, at most once per Per.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < l.policy.Per {
+		return
+	}
+	l.swept = now
+	full := l.policy.Per * time.Duration(l.policy.Burst) / time.Duration(l.policy.Requests)
+	for key, b := range l.buckets {
+		if now.Sub(b.seen) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Middleware answers 429 Too Many Requests, with a Retry-After header, to
+// clients over the limit. Clients are told apart by the address that
+// connected, or by the one a trusted proxy forwarded; see clientIP.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := l.clientIP(r)
+		ok, wait := l.Allow(ip)
+		if !ok {
+			log.Warn().Str("remote_addr", ip).Str("path", r.URL.Path).Msg("Rate limit exceeded")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "too many requests, try again later", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP is the address a request came from. When it arrived through a
+// trusted proxy, that is the right-most X-Forwarded-For entry not added by a
+// trusted proxy; entries further left were written by the client and are
+// ignored, so a forged header cannot buy a fresh bucket.
+func (l *Limiter) clientIP(r *http.Request) string {
+	host := remoteHost(r)
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !l.trusted(addr) {
+		return host
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Not written by a proxy we trust; count it against the proxy.
+			return host
+		}
+		if !l.trusted(hop) {
+			return hop.Unmap().String()
+		}
+	}
+	return host
+}
+
+func (l *Limiter) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range l.policy.TrustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
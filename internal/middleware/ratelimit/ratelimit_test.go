@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllow(t *testing.T) {
+	now := time.Date(2025, 8, 14, 10, 0, 0, 0, time.UTC)
+	l := New(Policy{Requests: 6, Per: time.Minute, Burst: 2})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		ok, _ := l.Allow("10.0.0.1")
+		assert.True(t, ok, "burst request %d", i)
+	}
+	ok, wait := l.Allow("10.0.0.1")
+	assert.False(t, ok)
+	assert.Equal(t, 10*time.Second, wait)
+
+	// Other clients have their own bucket.
+	ok, _ = l.Allow("10.0.0.2")
+	assert.True(t, ok)
+
+	// A token comes back every Per/Requests.
+	now = now.Add(10 * time.Second)
+	ok, _ = l.Allow("10.0.0.1")
+	assert.True(t, ok)
+	ok, _ = l.Allow("10.0.0.1")
+	assert.False(t, ok)
+
+	// Idle buckets refill no further than Burst and are then forgotten.
+	now = now.Add(time.Hour)
+	ok, _ = l.Allow("10.0.0.1")
+	assert.True(t, ok)
+	assert.Len(t, l.buckets, 1)
+}
+
+func TestMiddleware(t *testing.T) {
+	l := New(Policy{Requests: 1, Per: time.Minute})
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(remote, forwarded string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/verify/receipts/1", nil)
+		req.RemoteAddr = remote
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, send("192.0.2.1:5000", "").Code)
+	// A new port or a forged forwarded address is still the same client.
+	rec := send("192.0.2.1:5001", "198.51.100.7")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, send("192.0.2.2:5000", "").Code)
+}
+
+func TestMiddlewareBehindProxy(t *testing.T) {
+	l := New(Policy{Requests: 1, Per: time.Minute, TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}})
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(remote, forwarded string) int {
+		req := httptest.NewRequest(http.MethodGet, "/verify/receipts/1", nil)
+		req.RemoteAddr = remote
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Clients behind the proxy get a bucket each.
+	assert.Equal(t, http.StatusOK, send("10.0.0.5:443", "198.51.100.7"))
+	assert.Equal(t, http.StatusOK, send("10.0.0.5:443", "198.51.100.8"))
+	assert.Equal(t, http.StatusTooManyRequests, send("10.0.0.6:443", "198.51.100.7, 10.0.0.5"))
+
+	// What the client wrote to the left of the proxy's entry is ignored.
+	assert.Equal(t, http.StatusTooManyRequests, send("10.0.0.5:443", "203.0.113.9, 198.51.100.8"))
+
+	// An untrusted sender cannot pick its address.
+	assert.Equal(t, http.StatusOK, send("192.0.2.1:5000", "198.51.100.9"))
+	assert.Equal(t, http.StatusTooManyRequests, send("192.0.2.1:5000", "198.51.100.10"))
+}
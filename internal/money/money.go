@@ -0,0 +1,500 @@
+// Package money provides an exact fixed-point amount in Kenyan shillings.
+//
+// Amounts are held as an integer number of cents, so sums, differences and
+// comparisons are exact. The only operations that round are those that scale
+// an amount (MulRatio, Times), and they take an explicit Rounding mode. All
+// DECIMAL(15,2) columns map to Amount, which reads and writes them as the
+// textual form Postgres uses for NUMERIC.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Currency is the ISO 4217 code of every Amount.
+const Currency = "KES"
+
+// scale is the number of decimal places carried by Amount and Quantity.
+const scale = 2
+
+const unit = 100 // 10^scale
+
+// maxMinor bounds parsed values well inside int64 so sums cannot overflow in
+// practice. It is larger than anything DECIMAL(15,2) can hold.
+const maxMinor = 1<<62 - 1
+
+var (
+	ErrSyntax    = errors.New("money: invalid amount")
+	ErrPrecision = errors.New("money: more than 2 decimal places")
+	ErrRange     = errors.New("money: amount out of range")
+)
+
+// Rounding selects how a scaled amount that falls between two cents is
+// resolved.
+type Rounding int
+
+const (
+	// HalfUp rounds halves away from zero. It is the county's default for
+	// charges and penalties.
+	HalfUp Rounding = iota
+	// HalfEven rounds halves to the even cent, for aggregates where HalfUp
+	// would bias totals upward.
+	HalfEven
+	// Down truncates towards zero.
+	Down
+)
+
+// Amount is an exact sum of money in KES. The zero value is KES 0.00.
+type Amount struct {
+	minor int64
+}
+
+// Zero is KES 0.00.
+var Zero = Amount{}
+
+// FromMinor returns the amount worth minor cents.
+func FromMinor(minor int64) Amount {
+	return Amount{minor: minor}
+}
+
+// FromShillings returns the amount worth a whole number of shillings.
+func FromShillings(shillings int64) Amount {
+	return Amount{minor: shillings * unit}
+}
+
+// Parse reads a decimal string such as "1500", "-12.5" or "1500.00". Trailing
+// zeros beyond two decimal places are accepted; any other extra precision is
+// rejected rather than rounded so no value is silently changed.
+func Parse(s string) (Amount, error) {
+	v, err := parseFixed(s)
+	if err != nil {
+		return Amount{}, err
+	}
+	return Amount{minor: v}, nil
+}
+
+// MustParse is like Parse but panics on error. Intended for constants and tests.
+func MustParse(s string) Amount {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Minor returns the amount in cents.
+func (a Amount) Minor() int64 { return a.minor }
+
+func (a Amount) Add(b Amount) Amount { return Amount{minor: a.minor + b.minor} }
+func (a Amount) Sub(b Amount) Amount { return Amount{minor: a.minor - b.minor} }
+func (a Amount) Neg() Amount         { return Amount{minor: -a.minor} }
+
+func (a Amount) Abs() Amount {
+	if a.minor < 0 {
+		return a.Neg()
+	}
+	return a
+}
+
+// Cmp returns -1, 0 or +1 as a is less than, equal to or greater than b.
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a.minor < b.minor:
+		return -1
+	case a.minor > b.minor:
+		return 1
+	}
+	return 0
+}
+
+// Sign returns -1, 0 or +1 according to the sign of a.
+func (a Amount) Sign() int { return a.Cmp(Zero) }
+
+func (a Amount) IsZero() bool     { return a.minor == 0 }
+func (a Amount) IsPositive() bool { return a.minor > 0 }
+func (a Amount) IsNegative() bool { return a.minor < 0 }
+
+// Min returns the smaller of a and b.
+func Min(a, b Amount) Amount {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return b
+}
+
+// Max returns the larger of a and b.
+func Max(a, b Amount) Amount {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// Sum adds amounts.
+func Sum(amounts ...Amount) Amount {
+	var total Amount
+	for _, a := range amounts {
+		total = total.Add(a)
+	}
+	return total
+}
+
+// MulRatio returns a × num / den rounded to the cent with mode. It is the
+// building block for rates: a 16% levy is MulRatio(16, 100, HalfUp).
+func (a Amount) MulRatio(num, den int64, mode Rounding) Amount {
+	if den == 0 {
+		panic("money: division by zero")
+	}
+	n := new(big.Int).Mul(big.NewInt(a.minor), big.NewInt(num))
+	return Amount{minor: roundQuo(n, big.NewInt(den), mode)}
+}
+
+// Times returns a × q rounded to the cent with mode, e.g. a unit price times
+// a quantity.
+func (a Amount) Times(q Quantity, mode Rounding) Amount {
+	return a.MulRatio(q.hundredths, unit, mode)
+}
+
+// Percent returns p percent of a rounded to the cent with mode, e.g. a 2.5%
+// levy on a declared value.
+func (a Amount) Percent(p Quantity, mode Rounding) Amount {
+	return a.MulRatio(p.hundredths, 100*unit, mode)
+}
+
+// Split divides a into n parts that differ by at most one cent and sum
+// exactly to a. Earlier parts receive the extra cents.
+func (a Amount) Split(n int) []Amount {
+	if n <= 0 {
+		return nil
+	}
+	parts := make([]Amount, n)
+	q, r := a.minor/int64(n), a.minor%int64(n)
+	for i := range parts {
+		parts[i] = Amount{minor: q}
+		switch {
+		case r > 0:
+			parts[i].minor++
+			r--
+		case r < 0:
+			parts[i].minor--
+			r++
+		}
+	}
+	return parts
+}
+
+// String returns the plain decimal form, e.g. "-1234.50", as stored in the
+// database and sent over JSON.
+func (a Amount) String() string {
+	return formatFixed(a.minor)
+}
+
+// Format returns the amount for display to people, e.g. "KES 1,234.50".
+func (a Amount) Format() string {
+	s := formatFixed(a.Abs().minor)
+	whole, frac, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	if a.IsNegative() {
+		b.WriteByte('-')
+	}
+	b.WriteString(Currency)
+	b.WriteByte(' ')
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	b.WriteByte('.')
+	b.WriteString(frac)
+	return b.String()
+}
+
+// MarshalJSON encodes the amount as a decimal string so clients never see a
+// binary float.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + a.String() + `"`), nil
+}
+
+// UnmarshalJSON accepts either a JSON string or a JSON number. Numbers are
+// read from their literal text, not via float64, so 0.1 stays exactly 0.10.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s, err := jsonDecimal(data)
+	if err != nil {
+		return err
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Scan implements sql.Scanner for NUMERIC columns.
+func (a *Amount) Scan(src any) error {
+	if src == nil {
+		return errors.New("money: cannot scan NULL into Amount")
+	}
+	v, err := scanFixed(src)
+	if err != nil {
+		return err
+	}
+	a.minor = v
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// NullAmount is an Amount that may be NULL.
+type NullAmount struct {
+	Amount Amount
+	Valid  bool
+}
+
+func (n *NullAmount) Scan(src any) error {
+	if src == nil {
+		*n = NullAmount{}
+		return nil
+	}
+	n.Valid = true
+	return n.Amount.Scan(src)
+}
+
+func (n NullAmount) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Amount.Value()
+}
+
+func (n NullAmount) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return n.Amount.MarshalJSON()
+}
+
+func (n *NullAmount) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*n = NullAmount{}
+		return nil
+	}
+	n.Valid = true
+	return n.Amount.UnmarshalJSON(data)
+}
+
+// Quantity is an exact count with two decimal places, such as the quantity
+// on an assessment line. The zero value is 0.00.
+type Quantity struct {
+	hundredths int64
+}
+
+// ParseQuantity reads a decimal string with the same rules as Parse.
+func ParseQuantity(s string) (Quantity, error) {
+	v, err := parseFixed(s)
+	if err != nil {
+		return Quantity{}, err
+	}
+	return Quantity{hundredths: v}, nil
+}
+
+// QuantityOf returns the whole quantity n.
+func QuantityOf(n int64) Quantity {
+	return Quantity{hundredths: n * unit}
+}
+
+func (q Quantity) IsZero() bool     { return q.hundredths == 0 }
+func (q Quantity) IsPositive() bool { return q.hundredths > 0 }
+func (q Quantity) IsNegative() bool { return q.hundredths < 0 }
+func (q Quantity) String() string   { return formatFixed(q.hundredths) }
+
+func (q Quantity) Sub(b Quantity) Quantity { return Quantity{hundredths: q.hundredths - b.hundredths} }
+
+// Cmp returns -1, 0 or +1 as q is less than, equal to or greater than b.
+func (q Quantity) Cmp(b Quantity) int {
+	switch {
+	case q.hundredths < b.hundredths:
+		return -1
+	case q.hundredths > b.hundredths:
+		return 1
+	}
+	return 0
+}
+
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + q.String() + `"`), nil
+}
+
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	s, err := jsonDecimal(data)
+	if err != nil {
+		return err
+	}
+	v, err := ParseQuantity(s)
+	if err != nil {
+		return err
+	}
+	*q = v
+	return nil
+}
+
+func (q *Quantity) Scan(src any) error {
+	if src == nil {
+		return errors.New("money: cannot scan NULL into Quantity")
+	}
+	v, err := scanFixed(src)
+	if err != nil {
+		return err
+	}
+	q.hundredths = v
+	return nil
+}
+
+func (q Quantity) Value() (driver.Value, error) {
+	return q.String(), nil
+}
+
+// NullQuantity is a Quantity that may be NULL.
+type NullQuantity struct {
+	Quantity Quantity
+	Valid    bool
+}
+
+func (n *NullQuantity) Scan(src any) error {
+	if src == nil {
+		*n = NullQuantity{}
+		return nil
+	}
+	n.Valid = true
+	return n.Quantity.Scan(src)
+}
+
+func (n NullQuantity) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Quantity.Value()
+}
+
+func (n NullQuantity) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return n.Quantity.MarshalJSON()
+}
+
+func (n *NullQuantity) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*n = NullQuantity{}
+		return nil
+	}
+	n.Valid = true
+	return n.Quantity.UnmarshalJSON(data)
+}
+
+// parseFixed parses s into an integer count of hundredths.
+func parseFixed(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg, s = true, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+	if len(frac) > scale {
+		if strings.Trim(frac[scale:], "0") != "" {
+			return 0, fmt.Errorf("%w: %q", ErrPrecision, s)
+		}
+		frac = frac[:scale]
+	}
+	for len(frac) < scale {
+		frac += "0"
+	}
+
+	var v int64
+	for _, c := range whole + frac {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+		}
+		if v > (maxMinor-int64(c-'0'))/10 {
+			return 0, ErrRange
+		}
+		v = v*10 + int64(c-'0')
+	}
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
+func formatFixed(v int64) string {
+	sign := ""
+	u := uint64(v)
+	if v < 0 {
+		sign, u = "-", uint64(-v)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, u/unit, u%unit)
+}
+
+func scanFixed(src any) (int64, error) {
+	switch v := src.(type) {
+	case string:
+		return parseFixed(v)
+	case []byte:
+		return parseFixed(string(v))
+	case int64:
+		if v > maxMinor/unit || v < -maxMinor/unit {
+			return 0, ErrRange
+		}
+		return v * unit, nil
+	default:
+		return 0, fmt.Errorf("money: cannot scan %T", src)
+	}
+}
+
+// jsonDecimal returns the decimal text of a JSON string or number literal.
+func jsonDecimal(data []byte) (string, error) {
+	s := string(data)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1], nil
+	}
+	if strings.ContainsAny(s, "eE") {
+		return "", fmt.Errorf("%w: exponent notation %s", ErrSyntax, s)
+	}
+	return s, nil
+}
+
+// roundQuo returns n / d rounded to an integer with mode.
+func roundQuo(n, d *big.Int, mode Rounding) int64 {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Sign() != 0 && mode != Down {
+		// Compare 2|r| with |d| to decide which way the remainder falls.
+		twice := new(big.Int).Abs(r)
+		twice.Lsh(twice, 1)
+		c := twice.Cmp(new(big.Int).Abs(d))
+		if c > 0 || c == 0 && (mode == HalfUp || q.Bit(0) == 1) {
+			if n.Sign()*d.Sign() < 0 {
+				q.Sub(q, big.NewInt(1))
+			} else {
+				q.Add(q, big.NewInt(1))
+			}
+		}
+	}
+	if !q.IsInt64() {
+		panic(ErrRange)
+	}
+	return q.Int64()
+}
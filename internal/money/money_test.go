@@ -0,0 +1,150 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		err  error
+	}{
+		{"0", 0, nil},
+		{"1500", 150000, nil},
+		{"12.5", 1250, nil},
+		{"-12.05", -1205, nil},
+		{"+0.10", 10, nil},
+		{".5", 50, nil},
+		{"1500.000", 150000, nil},
+		{"0.001", 0, ErrPrecision},
+		{"", 0, ErrSyntax},
+		{"1,000", 0, ErrSyntax},
+		{"abc", 0, ErrSyntax},
+		{"99999999999999999999", 0, ErrRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if tt.err != nil {
+				assert.True(t, errors.Is(err, tt.err), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Minor())
+		})
+	}
+}
+
+func TestNoFloatDrift(t *testing.T) {
+	total := Zero
+	for i := 0; i < 10; i++ {
+		total = total.Add(MustParse("0.10"))
+	}
+	assert.Equal(t, "1.00", total.String())
+}
+
+func TestMulRatioRounding(t *testing.T) {
+	a := MustParse("0.25")
+	assert.Equal(t, "0.13", a.MulRatio(1, 2, HalfUp).String())
+	assert.Equal(t, "0.12", a.MulRatio(1, 2, HalfEven).String())
+	assert.Equal(t, "0.12", a.MulRatio(1, 2, Down).String())
+	assert.Equal(t, "-0.13", a.Neg().MulRatio(1, 2, HalfUp).String())
+	assert.Equal(t, "160.00", MustParse("1000").MulRatio(16, 100, HalfUp).String())
+
+	assert.Equal(t, "3.75", MustParse("1.50").Times(mustQuantity(t, "2.5"), HalfUp).String())
+	assert.Equal(t, "0.17", MustParse("0.33").Times(mustQuantity(t, "0.5"), HalfUp).String())
+
+	assert.Equal(t, "25000.00", MustParse("1000000").Percent(mustQuantity(t, "2.5"), HalfUp).String())
+	assert.Equal(t, "0.01", MustParse("0.33").Percent(mustQuantity(t, "2.5"), HalfUp).String())
+}
+
+func TestQuantityCmp(t *testing.T) {
+	assert.Equal(t, -1, QuantityOf(10).Cmp(mustQuantity(t, "10.01")))
+	assert.Equal(t, 0, QuantityOf(10).Cmp(mustQuantity(t, "10.00")))
+	assert.Equal(t, "2.50", mustQuantity(t, "12.5").Sub(QuantityOf(10)).String())
+	assert.True(t, QuantityOf(3).Sub(QuantityOf(4)).IsNegative())
+}
+
+func TestSplit(t *testing.T) {
+	parts := MustParse("100").Split(3)
+	assert.Equal(t, []string{"33.34", "33.33", "33.33"}, []string{parts[0].String(), parts[1].String(), parts[2].String()})
+	assert.Equal(t, MustParse("100"), Sum(parts...))
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "KES 1,234,567.50", MustParse("1234567.5").Format())
+	assert.Equal(t, "-KES 999.00", MustParse("-999").Format())
+}
+
+func TestJSON(t *testing.T) {
+	var req struct {
+		Amount   Amount     `json:"amount"`
+		Text     Amount     `json:"text"`
+		Optional NullAmount `json:"optional"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"amount": 0.1, "text": "1500.50", "optional": null}`), &req))
+	assert.Equal(t, int64(10), req.Amount.Minor())
+	assert.Equal(t, int64(150050), req.Text.Minor())
+	assert.False(t, req.Optional.Valid)
+
+	out, err := json.Marshal(req)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"0.10","text":"1500.50","optional":null}`, string(out))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": 1e3}`), &req))
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": 10.005}`), &req))
+}
+
+func TestScanValue(t *testing.T) {
+	var a Amount
+	require.NoError(t, a.Scan([]byte("42.10")))
+	assert.Equal(t, int64(4210), a.Minor())
+	require.NoError(t, a.Scan(int64(3)))
+	assert.Equal(t, int64(300), a.Minor())
+	assert.Error(t, a.Scan(nil))
+
+	v, err := a.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "3.00", v)
+
+	var n NullAmount
+	require.NoError(t, n.Scan(nil))
+	assert.False(t, n.Valid)
+	v, err = n.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+}
+
+func mustQuantity(t *testing.T, s string) Quantity {
+	t.Helper()
+	q, err := ParseQuantity(s)
+	require.NoError(t, err)
+	return q
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"0", "Kenya Shillings Zero Only"},
+		{"7", "Kenya Shillings Seven Only"},
+		{"15", "Kenya Shillings Fifteen Only"},
+		{"40", "Kenya Shillings Forty Only"},
+		{"1250.50", "Kenya Shillings One Thousand Two Hundred and Fifty and Cents Fifty Only"},
+		{"1005", "Kenya Shillings One Thousand and Five Only"},
+		{"100000", "Kenya Shillings One Hundred Thousand Only"},
+		{"2300415.05", "Kenya Shillings Two Million Three Hundred Thousand Four Hundred and Fifteen and Cents Five Only"},
+		{"0.99", "Kenya Shillings Zero and Cents Ninety-Nine Only"},
+		{"-21", "Minus Kenya Shillings Twenty-One Only"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, MustParse(tt.in).Words(), tt.in)
+	}
+}
@@ -0,0 +1,84 @@
+package money
+
+import "strings"
+
+var (
+	smallNumbers = []string{
+		"Zero", "One", "Two", "Three", "Four", "Five", "Six", "Seven", "Eight", "Nine",
+		"Ten", "Eleven", "Twelve", "Thirteen", "Fourteen", "Fifteen", "Sixteen",
+		"Seventeen", "Eighteen", "Nineteen",
+	}
+	tens = []string{"", "", "Twenty", "Thirty", "Forty", "Fifty", "Sixty", "Seventy", "Eighty", "Ninety"}
+
+	scales = []struct {
+		size int64
+		name string
+	}{
+		{1_000_000_000_000, "Trillion"},
+		{1_000_000_000, "Billion"},
+		{1_000_000, "Million"},
+		{1_000, "Thousand"},
+	}
+)
+
+// Words spells the amount out as it is written on receipts and cheques, e.g.
+// "Kenya Shillings One Thousand Two Hundred and Fifty and Cents Fifty Only".
+func (a Amount) Words() string {
+	var b strings.Builder
+	if a.IsNegative() {
+		b.WriteString("Minus ")
+	}
+	abs := a.Abs().minor
+	b.WriteString("Kenya Shillings ")
+	b.WriteString(numberWords(abs / 100))
+	if cents := abs % 100; cents != 0 {
+		b.WriteString(" and Cents ")
+		b.WriteString(numberWords(cents))
+	}
+	b.WriteString(" Only")
+	return b.String()
+}
+
+// numberWords spells out n >= 0 in British English.
+func numberWords(n int64) string {
+	if n == 0 {
+		return smallNumbers[0]
+	}
+	var parts []string
+	for _, scale := range scales {
+		if n >= scale.size {
+			parts = append(parts, hundredsWords(n/scale.size, false)+" "+scale.name)
+			n %= scale.size
+		}
+	}
+	if n > 0 {
+		parts = append(parts, hundredsWords(n, len(parts) > 0))
+	}
+	return strings.Join(parts, " ")
+}
+
+// hundredsWords spells out 0 < n < 1000. and is set when a larger scale came
+// before, so that 1,005 reads "One Thousand and Five".
+func hundredsWords(n int64, and bool) string {
+	var parts []string
+	if n >= 100 {
+		parts = append(parts, smallNumbers[n/100], "Hundred")
+		n %= 100
+		and = true
+	}
+	if n == 0 {
+		return strings.Join(parts, " ")
+	}
+	if and {
+		parts = append(parts, "and")
+	}
+	switch {
+	case n < 20:
+		parts = append(parts, smallNumbers[n])
+	case n%10 == 0:
+		parts = append(parts, tens[n/10])
+	default:
+		parts = append(parts, tens[n/10]+"-"+smallNumbers[n%10])
+	}
+	return strings.Join(parts, " ")
+}
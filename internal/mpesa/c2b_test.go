@@ -0,0 +1,164 @@
+package mpesa
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseC2BDarajaSample(t *testing.T) {
+	body := `{
+		"TransactionType": "Pay Bill",
+		"TransID": "RKTQDM7W6S",
+		"TransTime": "20191122063845",
+		"TransAmount": "10.00",
+		"BusinessShortCode": "600638",
+		"BillRefNumber": " NRB-ASM-0001 ",
+		"InvoiceNumber": "",
+		"OrgAccountBalance": "49197.00",
+		"ThirdPartyTransID": "",
+		"MSISDN": "254708374149",
+		"FirstName": "John",
+		"MiddleName": "",
+		"LastName": "Doe"
+	}`
+
+	p, err := ParseC2B(strings.NewReader(body))
+	require.NoError(t, err)
+	assert.Equal(t, "RKTQDM7W6S", p.TransID)
+	assert.Equal(t, "10.00", p.Amount.String())
+	assert.Equal(t, "600638", p.ShortCode)
+	assert.Equal(t, "NRB-ASM-0001", p.BillRefNumber)
+	assert.Equal(t, "John Doe", p.PayerName)
+	assert.Equal(t, time.Date(2019, 11, 22, 3, 38, 45, 0, time.UTC), p.TransTime.UTC())
+}
+
+func TestParseC2BRejectsMalformed(t *testing.T) {
+	for name, body := range map[string]string{
+		"no trans id":     `{"TransTime": "20191122063845", "TransAmount": "10"}`,
+		"bad amount":      `{"TransID": "X", "TransTime": "20191122063845", "TransAmount": "ten"}`,
+		"bad time":        `{"TransID": "X", "TransTime": "2019-11-22", "TransAmount": "10"}`,
+		"not json":        `TransID=X`,
+		"sub-cent amount": `{"TransID": "X", "TransTime": "20191122063845", "TransAmount": "10.005"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseC2B(strings.NewReader(body))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseAllowList(t *testing.T) {
+	nets, err := ParseAllowList("196.201.214.0/24, 196.201.213.114,,::1")
+	require.NoError(t, err)
+	require.Len(t, nets, 3)
+	assert.Equal(t, "196.201.213.114/32", nets[1].String())
+
+	_, err = ParseAllowList("196.201.214.0/33")
+	assert.Error(t, err)
+	_, err = ParseAllowList("safaricom")
+	assert.Error(t, err)
+}
+
+func TestGuard(t *testing.T) {
+	nets, err := ParseAllowList("196.201.214.0/24")
+	require.NoError(t, err)
+	handler := Guard(GuardConfig{AllowedNets: nets, SigningSecret: "shh"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&v))
+		assert.Equal(t, "RKTQDM7W6S", v["TransID"])
+	}))
+
+	body := `{"TransID":"RKTQDM7W6S"}`
+	tests := []struct {
+		name       string
+		remoteAddr string
+		signature  string
+		want       int
+	}{
+		{"allowed and signed", "196.201.214.200:443", Sign("shh", []byte(body)), http.StatusOK},
+		{"mapped IPv4 address", "[::ffff:196.201.214.200]:443", Sign("shh", []byte(body)), http.StatusOK},
+		{"outside allow list", "10.0.0.1:443", Sign("shh", []byte(body)), http.StatusForbidden},
+		{"missing signature", "196.201.214.200:443", "", http.StatusForbidden},
+		{"wrong secret", "196.201.214.200:443", Sign("other", []byte(body)), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/paybill/confirmation", strings.NewReader(body))
+			req.RemoteAddr = tt.remoteAddr
+			if tt.signature != "" {
+				req.Header.Set(SignatureHeader, tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
+
+func TestSimulatorC2B(t *testing.T) {
+	validated := make(chan C2BPayment, 4)
+	confirmed := make(chan C2BPayment, 4)
+	receiver := httptest.NewServer(Guard(GuardConfig{SigningSecret: "shh"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := ParseC2B(r.Body)
+		require.NoError(t, err)
+		switch r.URL.Path {
+		case "/validation":
+			validated <- p
+			code := C2BAccepted
+			if p.BillRefNumber != "NRB-ASM-0001" {
+				code = C2BInvalidAccountNumber
+			}
+			json.NewEncoder(w).Encode(C2BResponse{ResultCode: code})
+		case "/confirmation":
+			confirmed <- p
+			json.NewEncoder(w).Encode(C2BResponse{ResultCode: C2BAccepted})
+		}
+	})))
+	t.Cleanup(receiver.Close)
+
+	sim := NewSimulator(SimulatorConfig{
+		ConsumerKey:    "key",
+		ConsumerSecret: "secret",
+		ShortCode:      "174379",
+		Passkey:        "passkey",
+		SigningSecret:  "shh",
+	})
+	daraja := httptest.NewServer(sim)
+	t.Cleanup(daraja.Close)
+
+	client := NewClient(Config{BaseURL: daraja.URL, ConsumerKey: "key", ConsumerSecret: "secret", ShortCode: "174379"}, nil)
+	ctx := context.Background()
+	require.NoError(t, client.RegisterC2BURLs(ctx, receiver.URL+"/validation", receiver.URL+"/confirmation"))
+
+	token, err := client.accessToken(ctx)
+	require.NoError(t, err)
+	simulate := func(ref string) {
+		body := `{"ShortCode":"174379","CommandID":"CustomerPayBillOnline","Amount":250,"Msisdn":"0712345678","BillRefNumber":"` + ref + `"}`
+		req, err := http.NewRequest(http.MethodPost, daraja.URL+"/mpesa/c2b/v1/simulate", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		sim.Wait()
+	}
+
+	simulate("NRB-ASM-0001")
+	p := <-validated
+	assert.Equal(t, p, <-confirmed)
+	assert.Equal(t, "250.00", p.Amount.String())
+	assert.Equal(t, "254712345678", p.PhoneNumber)
+
+	simulate("UNKNOWN")
+	<-validated
+	assert.Empty(t, confirmed, "declined payments must not be confirmed")
+}
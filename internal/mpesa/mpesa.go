@@ -0,0 +1,336 @@
+// Package mpesa integrates with Safaricom's Daraja API for Lipa na M-Pesa
+// Online (STK Push) and paybill (C2B) payments. Client initiates payment
+// prompts on a customer's phone and ParseCallback decodes the result Daraja
+// posts back; ParseC2B decodes paybill validation and confirmation requests.
+// Simulator stands in for Daraja so the full flow can run without network
+// access.
+package mpesa
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sangkips/revenue-system/internal/money"
+)
+
+// Daraja result codes seen in STK callbacks.
+const (
+	ResultSuccess           = 0
+	ResultInsufficientFunds = 1
+	ResultCancelled         = 1032 // customer dismissed the prompt
+	ResultTimeout           = 1037 // customer's phone could not be reached
+	ResultWrongPIN          = 2001
+)
+
+// timestampLayout is Daraja's timestamp format, in East Africa Time.
+const timestampLayout = "20060102150405"
+
+var eat = time.FixedZone("EAT", 3*60*60)
+
+var ErrFractionalAmount = errors.New("mpesa: amount must be whole shillings")
+
+// APIError is a non-success response from Daraja.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("mpesa: %s (%s, HTTP %d)", e.Message, e.Code, e.StatusCode)
+}
+
+type Config struct {
+	BaseURL        string // e.g. https://sandbox.safaricom.co.ke
+	ConsumerKey    string
+	ConsumerSecret string
+	ShortCode      string // paybill receiving the payments
+	Passkey        string
+	CallbackURL    string // where Daraja posts STK results
+}
+
+type Client struct {
+	cfg  Config
+	http *http.Client
+	now  func() time.Time
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+func NewClient(cfg Config, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &Client{cfg: cfg, http: httpClient, now: time.Now}
+}
+
+type STKPushRequest struct {
+	PhoneNumber      string // any common Kenyan format; normalised to 2547XXXXXXXX
+	Amount           money.Amount
+	AccountReference string // shown to the customer, at most 12 characters
+	Description      string
+}
+
+type STKPushResponse struct {
+	MerchantRequestID   string `json:"MerchantRequestID"`
+	CheckoutRequestID   string `json:"CheckoutRequestID"`
+	ResponseCode        string `json:"ResponseCode"`
+	ResponseDescription string `json:"ResponseDescription"`
+	CustomerMessage     string `json:"CustomerMessage"`
+}
+
+// stkPushBody is the wire format of an STK Push request.
+type stkPushBody struct {
+	BusinessShortCode string `json:"BusinessShortCode"`
+	Password          string `json:"Password"`
+	Timestamp         string `json:"Timestamp"`
+	TransactionType   string `json:"TransactionType"`
+	Amount            int64  `json:"Amount"`
+	PartyA            string `json:"PartyA"`
+	PartyB            string `json:"PartyB"`
+	PhoneNumber       string `json:"PhoneNumber"`
+	CallBackURL       string `json:"CallBackURL"`
+	AccountReference  string `json:"AccountReference"`
+	TransactionDesc   string `json:"TransactionDesc"`
+}
+
+// STKPush prompts the customer to authorise a payment to the configured
+// paybill. A successful response only means the prompt was sent; the outcome
+// arrives later on the callback URL, keyed by CheckoutRequestID.
+func (c *Client) STKPush(ctx context.Context, req STKPushRequest) (STKPushResponse, error) {
+	if req.Amount.Minor()%100 != 0 {
+		return STKPushResponse{}, ErrFractionalAmount
+	}
+	if !req.Amount.IsPositive() {
+		return STKPushResponse{}, errors.New("mpesa: amount must be positive")
+	}
+	phone, err := NormalizePhone(req.PhoneNumber)
+	if err != nil {
+		return STKPushResponse{}, err
+	}
+
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return STKPushResponse{}, err
+	}
+
+	timestamp := c.now().In(eat).Format(timestampLayout)
+	body := stkPushBody{
+		BusinessShortCode: c.cfg.ShortCode,
+		Password:          Password(c.cfg.ShortCode, c.cfg.Passkey, timestamp),
+		Timestamp:         timestamp,
+		TransactionType:   "CustomerPayBillOnline",
+		Amount:            req.Amount.Minor() / 100,
+		PartyA:            phone,
+		PartyB:            c.cfg.ShortCode,
+		PhoneNumber:       phone,
+		CallBackURL:       c.cfg.CallbackURL,
+		AccountReference:  truncate(req.AccountReference, 12),
+		TransactionDesc:   truncate(req.Description, 13),
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return STKPushResponse{}, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.BaseURL+"/mpesa/stkpush/v1/processrequest", bytes.NewReader(payload))
+	if err != nil {
+		return STKPushResponse{}, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	var resp STKPushResponse
+	if err := c.do(httpReq, &resp); err != nil {
+		return STKPushResponse{}, err
+	}
+	if resp.ResponseCode != "0" {
+		return STKPushResponse{}, &APIError{StatusCode: http.StatusOK, Code: resp.ResponseCode, Message: resp.ResponseDescription}
+	}
+	return resp, nil
+}
+
+// accessToken returns a cached OAuth token, fetching a new one shortly before
+// the old one expires.
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && c.now().Before(c.tokenExpiry) {
+		return c.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.BaseURL+"/oauth/v1/generate?grant_type=client_credentials", nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(c.cfg.ConsumerKey, c.cfg.ConsumerSecret)
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   string `json:"expires_in"`
+	}
+	if err := c.do(req, &resp); err != nil {
+		return "", err
+	}
+	seconds, err := strconv.Atoi(resp.ExpiresIn)
+	if err != nil || resp.AccessToken == "" {
+		return "", errors.New("mpesa: malformed token response")
+	}
+
+	c.token = resp.AccessToken
+	c.tokenExpiry = c.now().Add(time.Duration(seconds)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+func (c *Client) do(req *http.Request, out any) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			ErrorCode    string `json:"errorCode"`
+			ErrorMessage string `json:"errorMessage"`
+		}
+		json.Unmarshal(body, &apiErr)
+		if apiErr.ErrorMessage == "" {
+			apiErr.ErrorMessage = http.StatusText(resp.StatusCode)
+		}
+		return &APIError{StatusCode: resp.StatusCode, Code: apiErr.ErrorCode, Message: apiErr.ErrorMessage}
+	}
+	return json.Unmarshal(body, out)
+}
+
+// Password is the STK Push password: base64(shortcode + passkey + timestamp).
+func Password(shortCode, passkey, timestamp string) string {
+	return base64.StdEncoding.EncodeToString([]byte(shortCode + passkey + timestamp))
+}
+
+// NormalizePhone converts 07XXXXXXXX, 01XXXXXXXX, +2547XXXXXXXX and similar to
+// the 254XXXXXXXXX form Daraja expects.
+func NormalizePhone(phone string) (string, error) {
+	p := strings.NewReplacer(" ", "", "-", "").Replace(phone)
+	p = strings.TrimPrefix(p, "+")
+	switch {
+	case strings.HasPrefix(p, "254") && len(p) == 12:
+	case strings.HasPrefix(p, "0") && len(p) == 10:
+		p = "254" + p[1:]
+	case (strings.HasPrefix(p, "7") || strings.HasPrefix(p, "1")) && len(p) == 9:
+		p = "254" + p
+	default:
+		return "", fmt.Errorf("mpesa: invalid phone number %q", phone)
+	}
+	if _, err := strconv.ParseUint(p, 10, 64); err != nil || (p[3] != '7' && p[3] != '1') {
+		return "", fmt.Errorf("mpesa: invalid phone number %q", phone)
+	}
+	return p, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// Callback is the outcome of an STK Push as posted by Daraja. The metadata
+// fields are only set when ResultCode is ResultSuccess.
+type Callback struct {
+	MerchantRequestID string
+	CheckoutRequestID string
+	ResultCode        int
+	ResultDesc        string
+
+	Amount          money.Amount
+	ReceiptNumber   string
+	TransactionDate time.Time
+	PhoneNumber     string
+}
+
+func (c Callback) Succeeded() bool { return c.ResultCode == ResultSuccess }
+
+// callbackEnvelope is the wire format of an STK callback.
+type callbackEnvelope struct {
+	Body struct {
+		STKCallback struct {
+			MerchantRequestID string `json:"MerchantRequestID"`
+			CheckoutRequestID string `json:"CheckoutRequestID"`
+			ResultCode        int    `json:"ResultCode"`
+			ResultDesc        string `json:"ResultDesc"`
+			CallbackMetadata  *struct {
+				Item []callbackItem `json:"Item"`
+			} `json:"CallbackMetadata,omitempty"`
+		} `json:"stkCallback"`
+	} `json:"Body"`
+}
+
+type callbackItem struct {
+	Name  string          `json:"Name"`
+	Value json.RawMessage `json:"Value,omitempty"`
+}
+
+// ParseCallback decodes an STK callback body.
+func ParseCallback(r io.Reader) (Callback, error) {
+	var env callbackEnvelope
+	if err := json.NewDecoder(r).Decode(&env); err != nil {
+		return Callback{}, err
+	}
+	stk := env.Body.STKCallback
+	if stk.CheckoutRequestID == "" {
+		return Callback{}, errors.New("mpesa: callback has no CheckoutRequestID")
+	}
+
+	cb := Callback{
+		MerchantRequestID: stk.MerchantRequestID,
+		CheckoutRequestID: stk.CheckoutRequestID,
+		ResultCode:        stk.ResultCode,
+		ResultDesc:        stk.ResultDesc,
+	}
+	if !cb.Succeeded() || stk.CallbackMetadata == nil {
+		return cb, nil
+	}
+
+	for _, item := range stk.CallbackMetadata.Item {
+		raw := strings.Trim(string(item.Value), `"`)
+		switch item.Name {
+		case "Amount":
+			if err := cb.Amount.UnmarshalJSON(item.Value); err != nil {
+				return Callback{}, fmt.Errorf("mpesa: callback amount: %w", err)
+			}
+		case "MpesaReceiptNumber":
+			cb.ReceiptNumber = raw
+		case "TransactionDate":
+			t, err := time.ParseInLocation(timestampLayout, raw, eat)
+			if err != nil {
+				return Callback{}, fmt.Errorf("mpesa: callback transaction date: %w", err)
+			}
+			cb.TransactionDate = t
+		case "PhoneNumber":
+			cb.PhoneNumber = raw
+		}
+	}
+	if cb.ReceiptNumber == "" {
+		return Callback{}, errors.New("mpesa: successful callback has no receipt number")
+	}
+	return cb, nil
+}
@@ -0,0 +1,139 @@
+package mpesa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T) (*Client, *Simulator, chan Callback) {
+	t.Helper()
+
+	callbacks := make(chan Callback, 4)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cb, err := ParseCallback(r.Body)
+		require.NoError(t, err)
+		callbacks <- cb
+	}))
+	t.Cleanup(receiver.Close)
+
+	sim := NewSimulator(SimulatorConfig{
+		ConsumerKey:    "key",
+		ConsumerSecret: "secret",
+		ShortCode:      "174379",
+		Passkey:        "passkey",
+	})
+	daraja := httptest.NewServer(sim)
+	t.Cleanup(daraja.Close)
+
+	client := NewClient(Config{
+		BaseURL:        daraja.URL,
+		ConsumerKey:    "key",
+		ConsumerSecret: "secret",
+		ShortCode:      "174379",
+		Passkey:        "passkey",
+		CallbackURL:    receiver.URL,
+	}, nil)
+	return client, sim, callbacks
+}
+
+func receive(t *testing.T, callbacks chan Callback) Callback {
+	t.Helper()
+	select {
+	case cb := <-callbacks:
+		return cb
+	case <-time.After(5 * time.Second):
+		t.Fatal("no callback received")
+		return Callback{}
+	}
+}
+
+func TestSTKPushSuccess(t *testing.T) {
+	client, _, callbacks := newTestClient(t)
+
+	resp, err := client.STKPush(context.Background(), STKPushRequest{
+		PhoneNumber:      "0712 345 678",
+		Amount:           money.MustParse("1500"),
+		AccountReference: "NRB-ASM-0001",
+		Description:      "Business permit",
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.CheckoutRequestID)
+
+	cb := receive(t, callbacks)
+	assert.True(t, cb.Succeeded())
+	assert.Equal(t, resp.CheckoutRequestID, cb.CheckoutRequestID)
+	assert.Equal(t, "1500.00", cb.Amount.String())
+	assert.Equal(t, "254712345678", cb.PhoneNumber)
+	assert.Len(t, cb.ReceiptNumber, 10)
+	assert.False(t, cb.TransactionDate.IsZero())
+}
+
+func TestSTKPushCancelled(t *testing.T) {
+	client, _, callbacks := newTestClient(t)
+
+	_, err := client.STKPush(context.Background(), STKPushRequest{PhoneNumber: "254700001032", Amount: money.MustParse("10")})
+	require.NoError(t, err)
+
+	cb := receive(t, callbacks)
+	assert.False(t, cb.Succeeded())
+	assert.Equal(t, ResultCancelled, cb.ResultCode)
+	assert.Empty(t, cb.ReceiptNumber)
+}
+
+func TestSTKPushRejectsCents(t *testing.T) {
+	client, _, _ := newTestClient(t)
+
+	_, err := client.STKPush(context.Background(), STKPushRequest{PhoneNumber: "0712345678", Amount: money.MustParse("10.50")})
+	assert.ErrorIs(t, err, ErrFractionalAmount)
+}
+
+func TestSTKPushBadCredentials(t *testing.T) {
+	client, _, _ := newTestClient(t)
+	client.cfg.ConsumerSecret = "wrong"
+
+	_, err := client.STKPush(context.Background(), STKPushRequest{PhoneNumber: "0712345678", Amount: money.MustParse("10")})
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+}
+
+func TestNormalizePhone(t *testing.T) {
+	for in, want := range map[string]string{
+		"0712345678":     "254712345678",
+		"+254712345678":  "254712345678",
+		"712345678":      "254712345678",
+		"0112-345-678":   "254112345678",
+		"254 712 345678": "254712345678",
+	} {
+		got, err := NormalizePhone(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	for _, in := range []string{"", "12345", "0812345678", "07123456789"} {
+		_, err := NormalizePhone(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestParseCallbackDarajaSample(t *testing.T) {
+	body := `{"Body":{"stkCallback":{"MerchantRequestID":"29115-34620561-1","CheckoutRequestID":"ws_CO_191220191020363925",
+		"ResultCode":0,"ResultDesc":"The service request is processed successfully.",
+		"CallbackMetadata":{"Item":[{"Name":"Amount","Value":1.00},{"Name":"MpesaReceiptNumber","Value":"NLJ7RT61SV"},
+		{"Name":"Balance"},{"Name":"TransactionDate","Value":20191219102115},{"Name":"PhoneNumber","Value":254708374149}]}}}}`
+
+	cb, err := ParseCallback(strings.NewReader(body))
+	require.NoError(t, err)
+	assert.Equal(t, "1.00", cb.Amount.String())
+	assert.Equal(t, "NLJ7RT61SV", cb.ReceiptNumber)
+	assert.Equal(t, "254708374149", cb.PhoneNumber)
+	assert.Equal(t, time.Date(2019, 12, 19, 10, 21, 15, 0, eat).Unix(), cb.TransactionDate.Unix())
+}
@@ -0,0 +1,367 @@
+package mpesa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sangkips/revenue-system/internal/money"
+)
+
+// SimulatorConfig configures a Simulator. The credentials must match the
+// Client's Config or requests are rejected as Daraja would.
+type SimulatorConfig struct {
+	ConsumerKey    string
+	ConsumerSecret string
+	ShortCode      string
+	Passkey        string
+	// CallbackDelay is how long the simulated customer takes to respond to
+	// the prompt before the callback is sent.
+	CallbackDelay time.Duration
+
+	// C2B URLs used until a client registers its own.
+	ValidationURL   string
+	ConfirmationURL string
+	// SigningSecret signs C2B requests with SignatureHeader when set.
+	SigningSecret string
+}
+
+// Simulator is an in-process stand-in for the Daraja OAuth, STK Push and C2B
+// endpoints. Simulated paybill payments are sent to the registered validation
+// URL and, if accepted, to the confirmation URL. Accepted pushes are answered by posting a callback to the
+// request's CallBackURL. The outcome is chosen by the last four digits of the
+// phone number so tests can exercise each path:
+//
+//	...1032  customer cancels (ResultCancelled)
+//	...1037  phone unreachable (ResultTimeout)
+//	...2001  wrong PIN (ResultWrongPIN)
+//	...0001  insufficient funds (ResultInsufficientFunds)
+//	anything else succeeds
+type Simulator struct {
+	cfg  SimulatorConfig
+	http *http.Client
+	mux  *http.ServeMux
+
+	mu              sync.Mutex
+	tokens          map[string]time.Time
+	seq             int
+	validationURL   string
+	confirmationURL string
+	wg              sync.WaitGroup
+}
+
+func NewSimulator(cfg SimulatorConfig) *Simulator {
+	s := &Simulator{
+		cfg:    cfg,
+		http:   &http.Client{Timeout: 10 * time.Second},
+		mux:    http.NewServeMux(),
+		tokens: map[string]time.Time{},
+
+		validationURL:   cfg.ValidationURL,
+		confirmationURL: cfg.ConfirmationURL,
+	}
+	s.mux.HandleFunc("GET /oauth/v1/generate", s.handleToken)
+	s.mux.HandleFunc("POST /mpesa/stkpush/v1/processrequest", s.handleSTKPush)
+	s.mux.HandleFunc("POST /mpesa/c2b/v1/registerurl", s.handleRegisterURL)
+	s.mux.HandleFunc("POST /mpesa/c2b/v1/simulate", s.handleC2BSimulate)
+	return s
+}
+
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Wait blocks until every pending callback has been delivered.
+func (s *Simulator) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Simulator) handleToken(w http.ResponseWriter, r *http.Request) {
+	key, secret, ok := r.BasicAuth()
+	if !ok || key != s.cfg.ConsumerKey || secret != s.cfg.ConsumerSecret {
+		writeDarajaError(w, http.StatusBadRequest, "400.008.01", "Invalid Authentication passed")
+		return
+	}
+
+	token := randomString(28)
+	s.mu.Lock()
+	s.tokens[token] = time.Now().Add(time.Hour)
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]string{"access_token": token, "expires_in": "3599"})
+}
+
+// authorized reports whether r carries a live access token, answering with
+// Daraja's error when it does not.
+func (s *Simulator) authorized(w http.ResponseWriter, r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	expiry, ok := s.tokens[token]
+	s.mu.Unlock()
+	if !ok || time.Now().After(expiry) {
+		writeDarajaError(w, http.StatusUnauthorized, "404.001.03", "Invalid Access Token")
+		return false
+	}
+	return true
+}
+
+func (s *Simulator) handleSTKPush(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+
+	var req stkPushBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDarajaError(w, http.StatusBadRequest, "400.002.02", "Bad Request - Invalid Body")
+		return
+	}
+	switch {
+	case req.BusinessShortCode != s.cfg.ShortCode:
+		writeDarajaError(w, http.StatusBadRequest, "400.002.02", "Bad Request - Invalid BusinessShortCode")
+		return
+	case req.Password != Password(s.cfg.ShortCode, s.cfg.Passkey, req.Timestamp):
+		writeDarajaError(w, http.StatusBadRequest, "400.002.02", "Bad Request - Invalid Password")
+		return
+	case req.Amount < 1:
+		writeDarajaError(w, http.StatusBadRequest, "400.002.02", "Bad Request - Invalid Amount")
+		return
+	case req.CallBackURL == "":
+		writeDarajaError(w, http.StatusBadRequest, "400.002.02", "Bad Request - Invalid CallBackURL")
+		return
+	}
+	if _, err := NormalizePhone(req.PhoneNumber); err != nil {
+		writeDarajaError(w, http.StatusBadRequest, "400.002.02", "Bad Request - Invalid PhoneNumber")
+		return
+	}
+
+	s.mu.Lock()
+	s.seq++
+	now := time.Now()
+	resp := STKPushResponse{
+		MerchantRequestID:   fmt.Sprintf("%d-%d-1", now.Unix()%100000, s.seq),
+		CheckoutRequestID:   fmt.Sprintf("ws_CO_%s%06d", now.In(eat).Format("020120061504"), s.seq),
+		ResponseCode:        "0",
+		ResponseDescription: "Success. Request accepted for processing",
+		CustomerMessage:     "Success. Request accepted for processing",
+	}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		time.Sleep(s.cfg.CallbackDelay)
+		s.sendCallback(req, resp)
+	}()
+
+	writeJSON(w, resp)
+}
+
+func (s *Simulator) sendCallback(req stkPushBody, resp STKPushResponse) {
+	var env callbackEnvelope
+	stk := &env.Body.STKCallback
+	stk.MerchantRequestID = resp.MerchantRequestID
+	stk.CheckoutRequestID = resp.CheckoutRequestID
+
+	switch req.PhoneNumber[len(req.PhoneNumber)-4:] {
+	case "1032":
+		stk.ResultCode, stk.ResultDesc = ResultCancelled, "Request cancelled by user"
+	case "1037":
+		stk.ResultCode, stk.ResultDesc = ResultTimeout, "DS timeout user cannot be reached"
+	case "2001":
+		stk.ResultCode, stk.ResultDesc = ResultWrongPIN, "The initiator information is invalid."
+	case "0001":
+		stk.ResultCode, stk.ResultDesc = ResultInsufficientFunds, "The balance is insufficient for the transaction."
+	default:
+		stk.ResultCode, stk.ResultDesc = ResultSuccess, "The service request is processed successfully."
+		items := []struct {
+			name  string
+			value any
+		}{
+			{"Amount", req.Amount},
+			{"MpesaReceiptNumber", strings.ToUpper(randomString(10))},
+			{"TransactionDate", json.Number(time.Now().In(eat).Format(timestampLayout))},
+			{"PhoneNumber", json.Number(req.PhoneNumber)},
+		}
+		stk.CallbackMetadata = &struct {
+			Item []callbackItem `json:"Item"`
+		}{}
+		for _, item := range items {
+			raw, _ := json.Marshal(item.value)
+			stk.CallbackMetadata.Item = append(stk.CallbackMetadata.Item, callbackItem{Name: item.name, Value: raw})
+		}
+	}
+
+	payload, _ := json.Marshal(env)
+	cbResp, err := s.http.Post(req.CallBackURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		log.Warn().Err(err).Str("checkout_request_id", resp.CheckoutRequestID).Msg("Simulator could not deliver STK callback")
+		return
+	}
+	cbResp.Body.Close()
+}
+
+func (s *Simulator) handleRegisterURL(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+
+	var req struct {
+		ShortCode       string `json:"ShortCode"`
+		ResponseType    string `json:"ResponseType"`
+		ConfirmationURL string `json:"ConfirmationURL"`
+		ValidationURL   string `json:"ValidationURL"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDarajaError(w, http.StatusBadRequest, "400.002.02", "Bad Request - Invalid Body")
+		return
+	}
+	if req.ShortCode != s.cfg.ShortCode {
+		writeDarajaError(w, http.StatusBadRequest, "400.002.02", "Bad Request - Invalid ShortCode")
+		return
+	}
+	if req.ValidationURL == "" || req.ConfirmationURL == "" {
+		writeDarajaError(w, http.StatusBadRequest, "400.002.02", "Bad Request - Invalid URL")
+		return
+	}
+
+	s.mu.Lock()
+	s.validationURL, s.confirmationURL = req.ValidationURL, req.ConfirmationURL
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]string{
+		"OriginatorCoversationID": randomString(20),
+		"ResponseCode":            "0",
+		"ResponseDescription":     "success",
+	})
+}
+
+// handleC2BSimulate stands in for a customer paying the paybill from their
+// phone. The validation and confirmation requests are sent asynchronously.
+func (s *Simulator) handleC2BSimulate(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+
+	var req struct {
+		ShortCode     string `json:"ShortCode"`
+		CommandID     string `json:"CommandID"`
+		Amount        int64  `json:"Amount"`
+		Msisdn        string `json:"Msisdn"`
+		BillRefNumber string `json:"BillRefNumber"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDarajaError(w, http.StatusBadRequest, "400.002.02", "Bad Request - Invalid Body")
+		return
+	}
+	phone, err := NormalizePhone(req.Msisdn)
+	switch {
+	case req.ShortCode != s.cfg.ShortCode:
+		writeDarajaError(w, http.StatusBadRequest, "400.002.02", "Bad Request - Invalid ShortCode")
+		return
+	case req.Amount < 1:
+		writeDarajaError(w, http.StatusBadRequest, "400.002.02", "Bad Request - Invalid Amount")
+		return
+	case err != nil:
+		writeDarajaError(w, http.StatusBadRequest, "400.002.02", "Bad Request - Invalid Msisdn")
+		return
+	}
+
+	s.mu.Lock()
+	validationURL, confirmationURL := s.validationURL, s.confirmationURL
+	s.mu.Unlock()
+	if confirmationURL == "" {
+		writeDarajaError(w, http.StatusBadRequest, "400.002.02", "Bad Request - No URLs registered for ShortCode")
+		return
+	}
+
+	body := c2bBody{
+		TransactionType:   "Pay Bill",
+		TransID:           strings.ToUpper(randomString(10)),
+		TransTime:         time.Now().In(eat).Format(timestampLayout),
+		TransAmount:       money.FromShillings(req.Amount).String(),
+		BusinessShortCode: req.ShortCode,
+		BillRefNumber:     req.BillRefNumber,
+		MSISDN:            phone,
+		FirstName:         "John",
+		LastName:          "Doe",
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		time.Sleep(s.cfg.CallbackDelay)
+		if validationURL != "" {
+			var resp C2BResponse
+			if err := s.postC2B(validationURL, body, &resp); err != nil || resp.ResultCode != C2BAccepted {
+				log.Info().Err(err).Str("trans_id", body.TransID).Str("result_code", resp.ResultCode).Msg("Simulated C2B payment declined at validation")
+				return
+			}
+		}
+		if err := s.postC2B(confirmationURL, body, nil); err != nil {
+			log.Warn().Err(err).Str("trans_id", body.TransID).Msg("Simulator could not deliver C2B confirmation")
+		}
+	}()
+
+	writeJSON(w, map[string]string{
+		"OriginatorCoversationID": randomString(20),
+		"ResponseCode":            "0",
+		"ResponseDescription":     "Accept the service request successfully.",
+	})
+}
+
+func (s *Simulator) postC2B(url string, body c2bBody, out any) error {
+	payload, _ := json.Marshal(body)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.SigningSecret != "" {
+		req.Header.Set(SignatureHeader, Sign(s.cfg.SigningSecret, payload))
+	}
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeDarajaError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"requestId":    randomString(12),
+		"errorCode":    code,
+		"errorMessage": message,
+	})
+}
+
+const alphanumeric = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphanumeric[int(b[i])%len(alphanumeric)]
+	}
+	return string(b)
+}
@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sangkips/revenue-system/internal/mpesa"
+)
+
+// Africa's Talking recipient status codes. 100 to 102 mean the message was
+// accepted; the ones below can never succeed for that recipient.
+const (
+	atInvalidPhoneNumber    = 403
+	atUnsupportedNumberType = 404
+	atUserInBlacklist       = 406
+)
+
+type AfricasTalkingConfig struct {
+	BaseURL  string // https://api.africastalking.com, or https://api.sandbox.africastalking.com
+	Username string // "sandbox" in the sandbox
+	APIKey   string
+	SenderID string // registered alphanumeric sender; the shared shortcode when empty
+}
+
+// AfricasTalking sends SMS through the Africa's Talking bulk messaging API.
+type AfricasTalking struct {
+	cfg  AfricasTalkingConfig
+	http *http.Client
+}
+
+func NewAfricasTalking(cfg AfricasTalkingConfig, httpClient *http.Client) *AfricasTalking {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &AfricasTalking{cfg: cfg, http: httpClient}
+}
+
+type atResponse struct {
+	SMSMessageData struct {
+		Message    string `json:"Message"`
+		Recipients []struct {
+			StatusCode int    `json:"statusCode"`
+			Number     string `json:"number"`
+			Status     string `json:"status"`
+			MessageID  string `json:"messageId"`
+		} `json:"Recipients"`
+	} `json:"SMSMessageData"`
+}
+
+// Send texts msg.Body to msg.To, a Kenyan phone number in any common format.
+func (a *AfricasTalking) Send(ctx context.Context, msg Message) error {
+	phone, err := mpesa.NormalizePhone(msg.To)
+	if err != nil {
+		return Permanent(err)
+	}
+	form := url.Values{
+		"username": {a.cfg.Username},
+		"to":       {"+" + phone},
+		"message":  {msg.Body},
+	}
+	if a.cfg.SenderID != "" {
+		form.Set("from", a.cfg.SenderID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.BaseURL+"/version1/messaging", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("apiKey", a.cfg.APIKey)
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("africastalking: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var out atResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		return fmt.Errorf("africastalking: decoding response: %w", err)
+	}
+	if len(out.SMSMessageData.Recipients) == 0 {
+		return fmt.Errorf("africastalking: message not sent: %s", out.SMSMessageData.Message)
+	}
+	r := out.SMSMessageData.Recipients[0]
+	switch {
+	case r.StatusCode >= 100 && r.StatusCode <= 102:
+		return nil
+	case r.StatusCode == atInvalidPhoneNumber || r.StatusCode == atUnsupportedNumberType || r.StatusCode == atUserInBlacklist:
+		return Permanent(fmt.Errorf("africastalking: %s (%d) for %s", r.Status, r.StatusCode, r.Number))
+	}
+	return fmt.Errorf("africastalking: %s (%d) for %s", r.Status, r.StatusCode, r.Number)
+}
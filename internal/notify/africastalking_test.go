@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAfricasTalkingSend(t *testing.T) {
+	var status int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/version1/messaging", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("apiKey"))
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "sandbox", r.PostForm.Get("username"))
+		assert.Equal(t, "+254712345678", r.PostForm.Get("to"))
+		assert.Equal(t, "COUNTY", r.PostForm.Get("from"))
+		assert.Equal(t, "Receipt 047/RCT/2025-26/000001", r.PostForm.Get("message"))
+		if status == http.StatusUnauthorized {
+			http.Error(w, "The supplied authentication is invalid", status)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"SMSMessageData":{"Message":"Sent to 1/1","Recipients":[{"statusCode":%d,"number":"+254712345678","status":"x","messageId":"ATXid_1"}]}}`, status)
+	}))
+	defer srv.Close()
+
+	at := NewAfricasTalking(AfricasTalkingConfig{BaseURL: srv.URL + "/", Username: "sandbox", APIKey: "secret", SenderID: "COUNTY"}, nil)
+	msg := Message{To: "0712 345 678", Body: "Receipt 047/RCT/2025-26/000001"}
+
+	status = 101
+	assert.NoError(t, at.Send(context.Background(), msg))
+
+	status = 403
+	err := at.Send(context.Background(), msg)
+	require.Error(t, err)
+	assert.True(t, IsPermanent(err))
+
+	for _, status = range []int{405, 502, http.StatusUnauthorized} {
+		err = at.Send(context.Background(), msg)
+		require.Error(t, err, "status %d", status)
+		assert.False(t, IsPermanent(err), "status %d", status)
+	}
+
+	err = at.Send(context.Background(), Message{To: "12345", Body: "x"})
+	assert.True(t, IsPermanent(err))
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"context"
+	"sync"
+)
+
+// Recorder is a Notifier that keeps the messages it is sent in memory, for
+// tests and for running without a provider. Fail, when set, decides whether
+// a send fails.
+type Recorder struct {
+	Fail func(Message) error
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (r *Recorder) Send(ctx context.Context, msg Message) error {
+	if r.Fail != nil {
+		if err := r.Fail(msg); err != nil {
+			return err
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+// Sent returns the messages sent so far.
+func (r *Recorder) Sent() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Message(nil), r.sent...)
+}
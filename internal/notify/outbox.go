@@ -0,0 +1,193 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Channels a message can be sent on.
+const (
+	SMS   = "sms"
+	Email = "email"
+)
+
+// Store is the notification outbox. Messages are written to it in the same
+// transaction as the work that causes them; the Dispatcher sends them later.
+type Store interface {
+	ClaimNotifications(ctx context.Context, claim Claim) ([]Outgoing, error)
+	MarkNotificationSent(ctx context.Context, id uuid.UUID) error
+	RetryNotification(ctx context.Context, retry Retry) error
+	FailNotification(ctx context.Context, failure Failure) error
+}
+
+// Claim takes up to Limit due messages and leases them until LeaseUntil, so
+// a dispatcher that dies mid-send only delays them.
+type Claim struct {
+	LeaseUntil time.Time
+	Limit      int
+}
+
+// Outgoing is a claimed message. Attempts includes the one being made.
+type Outgoing struct {
+	ID      uuid.UUID
+	Channel string
+	Message
+	Attempts    int
+	MaxAttempts int
+}
+
+// Retry puts a message that failed back in the outbox until NextAttemptAt.
+type Retry struct {
+	ID            uuid.UUID
+	NextAttemptAt time.Time
+	LastError     string
+}
+
+// Failure gives up on a message.
+type Failure struct {
+	ID        uuid.UUID
+	LastError string
+}
+
+// Policy controls how the outbox is drained.
+type Policy struct {
+	// Batch is how many messages are claimed at a time.
+	Batch int
+	// Lease is how long a claimed message is left to its sender before it
+	// becomes due again, for instance because the server stopped mid-send.
+	Lease time.Duration
+	// RetryBase is the wait before the first retry; it doubles with each
+	// further attempt up to RetryMax.
+	RetryBase time.Duration
+	RetryMax  time.Duration
+}
+
+// DefaultPolicy waits 5, 10, 20 and 40 minutes between five attempts, which
+// rides out most provider outages.
+var DefaultPolicy = Policy{
+	Batch:     50,
+	Lease:     5 * time.Minute,
+	RetryBase: 5 * time.Minute,
+	RetryMax:  time.Hour,
+}
+
+// Dispatcher sends outbox messages through the Notifier of their channel.
+type Dispatcher struct {
+	store    Store
+	channels map[string]Notifier
+	policy   Policy
+	now      func() time.Time
+}
+
+// NewDispatcher returns a Dispatcher sending each channel's messages with
+// channels[channel]. Messages for a channel without a notifier fail.
+func NewDispatcher(store Store, channels map[string]Notifier, policy Policy) *Dispatcher {
+	return &Dispatcher{store: store, channels: channels, policy: policy, now: time.Now}
+}
+
+// Dispatch sends every due message and returns how many were sent. Messages
+// that fail are retried later, or given up once out of attempts.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	sent := 0
+	for {
+		batch, err := d.store.ClaimNotifications(ctx, Claim{
+			LeaseUntil: d.now().Add(d.policy.Lease),
+			Limit:      d.policy.Batch,
+		})
+		if err != nil {
+			return sent, err
+		}
+		for _, msg := range batch {
+			ok, err := d.deliver(ctx, msg)
+			if err != nil {
+				return sent, err
+			}
+			if ok {
+				sent++
+			}
+		}
+		if len(batch) < d.policy.Batch {
+			return sent, nil
+		}
+	}
+}
+
+// deliver sends one claimed message and records the outcome. Only failing to
+// record it is returned as an error.
+func (d *Dispatcher) deliver(ctx context.Context, msg Outgoing) (bool, error) {
+	err := d.send(ctx, msg)
+	if err == nil {
+		return true, d.store.MarkNotificationSent(ctx, msg.ID)
+	}
+
+	logger := log.Warn().Err(err).Str("notification_id", msg.ID.String()).Str("channel", msg.Channel).Int("attempt", msg.Attempts)
+	if IsPermanent(err) || msg.Attempts >= msg.MaxAttempts {
+		logger.Msg("Giving up on notification")
+		return false, d.store.FailNotification(ctx, Failure{ID: msg.ID, LastError: err.Error()})
+	}
+	next := d.now().Add(retryDelay(msg.Attempts, d.policy.RetryBase, d.policy.RetryMax))
+	logger.Time("next_attempt_at", next).Msg("Notification failed; will retry")
+	return false, d.store.RetryNotification(ctx, Retry{ID: msg.ID, NextAttemptAt: next, LastError: err.Error()})
+}
+
+func (d *Dispatcher) send(ctx context.Context, msg Outgoing) error {
+	notifier, ok := d.channels[msg.Channel]
+	if !ok || notifier == nil {
+		return Permanent(fmt.Errorf("no provider for %s messages", msg.Channel))
+	}
+	// A send that outlives the lease could be repeated by another dispatcher.
+	ctx, cancel := context.WithTimeout(ctx, d.policy.Lease/2)
+	defer cancel()
+	return notifier.Send(ctx, msg.Message)
+}
+
+// Run drains the outbox every interval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := d.Dispatch(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to dispatch notifications")
+			}
+			if sent > 0 {
+				log.Info().Int("sent", sent).Msg("Sent notifications")
+			}
+		}
+	}
+}
+
+// retryDelay is how long to wait after the attempt-th failed attempt.
+func retryDelay(attempt int, base, ceiling time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < ceiling; i++ {
+		delay *= 2
+	}
+	return min(delay, ceiling)
+}
+
+// Permanent marks a send error that retrying cannot fix, such as an invalid
+// recipient. The message is failed at once.
+func Permanent(err error) error {
+	return &permanentError{err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
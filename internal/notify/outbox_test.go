@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMessage is a row of fakeStore.
+type fakeMessage struct {
+	Outgoing
+	Status        string
+	NextAttemptAt time.Time
+	LastError     string
+}
+
+// fakeStore is an in-memory outbox that claims like the real one: due
+// pending messages, with the attempt counted and the lease applied.
+type fakeStore struct {
+	now      func() time.Time
+	messages []*fakeMessage
+}
+
+func (s *fakeStore) add(channel, to string, maxAttempts int) *fakeMessage {
+	msg := &fakeMessage{
+		Outgoing:      Outgoing{ID: uuid.New(), Channel: channel, Message: Message{To: to, Body: "hello"}, MaxAttempts: maxAttempts},
+		Status:        "pending",
+		NextAttemptAt: s.now(),
+	}
+	s.messages = append(s.messages, msg)
+	return msg
+}
+
+func (s *fakeStore) find(id uuid.UUID) *fakeMessage {
+	for _, m := range s.messages {
+		if m.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) ClaimNotifications(ctx context.Context, claim Claim) ([]Outgoing, error) {
+	var out []Outgoing
+	for _, m := range s.messages {
+		if len(out) == claim.Limit {
+			break
+		}
+		if m.Status == "pending" && !m.NextAttemptAt.After(s.now()) {
+			m.Attempts++
+			m.NextAttemptAt = claim.LeaseUntil
+			out = append(out, m.Outgoing)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStore) MarkNotificationSent(ctx context.Context, id uuid.UUID) error {
+	s.find(id).Status = "sent"
+	return nil
+}
+
+func (s *fakeStore) RetryNotification(ctx context.Context, retry Retry) error {
+	m := s.find(retry.ID)
+	m.NextAttemptAt, m.LastError = retry.NextAttemptAt, retry.LastError
+	return nil
+}
+
+func (s *fakeStore) FailNotification(ctx context.Context, failure Failure) error {
+	m := s.find(failure.ID)
+	m.Status, m.LastError = "failed", failure.LastError
+	return nil
+}
+
+func TestDispatch(t *testing.T) {
+	now := time.Date(2025, 8, 14, 10, 0, 0, 0, time.UTC)
+	store := &fakeStore{now: func() time.Time { return now }}
+	down := true
+	sms := &Recorder{Fail: func(msg Message) error {
+		if msg.To == "0700000000" {
+			return Permanent(errors.New("invalid phone number"))
+		}
+		if down {
+			return errors.New("gateway timeout")
+		}
+		return nil
+	}}
+	email := &Recorder{}
+	d := NewDispatcher(store, map[string]Notifier{SMS: sms, Email: email}, Policy{Batch: 2, Lease: time.Minute, RetryBase: time.Minute, RetryMax: 4 * time.Minute})
+	d.now = store.now
+
+	flaky := store.add(SMS, "0712345678", 3)
+	invalid := store.add(SMS, "0700000000", 3)
+	mail := store.add(Email, "wanjiru@example.com", 3)
+	fax := store.add("fax", "020 000000", 3)
+
+	sent, err := d.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, "sent", mail.Status)
+	assert.Len(t, email.Sent(), 1)
+	assert.Equal(t, "failed", invalid.Status)
+	assert.Equal(t, "invalid phone number", invalid.LastError)
+	assert.Equal(t, "failed", fax.Status)
+	assert.Equal(t, "pending", flaky.Status)
+	assert.Equal(t, now.Add(time.Minute), flaky.NextAttemptAt)
+
+	// Nothing is due before the retry.
+	sent, err = d.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+	assert.EqualValues(t, 1, flaky.Attempts)
+
+	// The second failure waits twice as long; the third is the last.
+	now = now.Add(time.Minute)
+	_, err = d.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(2*time.Minute), flaky.NextAttemptAt)
+	now = now.Add(2 * time.Minute)
+	_, err = d.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "failed", flaky.Status)
+	assert.Equal(t, "gateway timeout", flaky.LastError)
+
+	// A message that goes through on a retry is sent.
+	down = false
+	recovered := store.add(SMS, "0712345678", 3)
+	now = now.Add(time.Minute)
+	recovered.NextAttemptAt = now
+	sent, err = d.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, "sent", recovered.Status)
+	assert.Len(t, sms.Sent(), 1)
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{
+		1: time.Minute,
+		2: 2 * time.Minute,
+		3: 4 * time.Minute,
+		4: 8 * time.Minute,
+		5: 10 * time.Minute,
+		9: 10 * time.Minute,
+	} {
+		assert.Equal(t, want, retryDelay(attempt, time.Minute, 10*time.Minute), "attempt %d", attempt)
+	}
+}
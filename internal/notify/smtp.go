@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     int    // 587 for STARTTLS submission
+	Username string // no authentication when empty
+	Password string
+	From     string // e.g. "Nairobi County <receipts@nairobi.go.ke>"
+}
+
+// SMTP sends email through a mail server. STARTTLS is used when the server
+// offers it, which net/smtp requires before it will send a password.
+type SMTP struct {
+	cfg SMTPConfig
+	now func() time.Time
+}
+
+func NewSMTP(cfg SMTPConfig) *SMTP {
+	return &SMTP{cfg: cfg, now: time.Now}
+}
+
+// Send emails msg to msg.To as plain text. net/smtp cannot be cancelled, so
+// ctx is only checked before connecting.
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(s.cfg.From)
+	if err != nil {
+		return fmt.Errorf("smtp: invalid sender %q: %w", s.cfg.From, err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return Permanent(fmt.Errorf("smtp: invalid recipient %q: %w", msg.To, err))
+	}
+	body, err := s.message(from, to, msg)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	err = smtp.SendMail(addr, auth, from.Address, []string{to.Address}, body)
+	var reply *textproto.Error
+	if errors.As(err, &reply) && rejectedRecipient(reply.Code) {
+		return Permanent(fmt.Errorf("smtp: %w", err))
+	}
+	return err
+}
+
+// rejectedRecipient reports whether an SMTP reply code refuses the mailbox
+// itself, as opposed to a temporary or server-side failure.
+func rejectedRecipient(code int) bool {
+	return code == 550 || code == 551 || code == 553
+}
+
+// message builds the RFC 5322 message for msg.
+func (s *SMTP) message(from, to *mail.Address, msg Message) ([]byte, error) {
+	var b bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", s.now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+
+	// The writer turns the body's line breaks into CRLF.
+	w := quotedprintable.NewWriter(&b)
+	if _, err := w.Write([]byte(msg.Body)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"mime"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMTPMessage(t *testing.T) {
+	s := NewSMTP(SMTPConfig{From: "Nairobi County <receipts@nairobi.go.ke>"})
+	s.now = func() time.Time { return time.Date(2025, 8, 14, 10, 0, 0, 0, time.UTC) }
+	from, _ := mail.ParseAddress(s.cfg.From)
+	to, _ := mail.ParseAddress("wanjiru@example.com")
+
+	raw, err := s.message(from, to, Message{Subject: "Risiti ya malipo — 047/RCT/000001", Body: "Dear Wanjiru,\n\nKES 1,500.00 received.\n"})
+	require.NoError(t, err)
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	require.NoError(t, err)
+	sender, err := new(mail.AddressParser).Parse(msg.Header.Get("From"))
+	require.NoError(t, err)
+	assert.Equal(t, "receipts@nairobi.go.ke", sender.Address)
+	assert.Equal(t, "<wanjiru@example.com>", msg.Header.Get("To"))
+	assert.Equal(t, "Thu, 14 Aug 2025 10:00:00 +0000", msg.Header.Get("Date"))
+	assert.Contains(t, string(raw), "\r\n\r\nDear Wanjiru,\r\n\r\nKES 1,500.00 received.\r\n")
+
+	decoded, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Risiti ya malipo — 047/RCT/000001", decoded)
+}
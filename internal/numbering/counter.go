@@ -0,0 +1,42 @@
+package numbering
+
+import (
+	"context"
+
+	"github.com/sangkips/revenue-system/internal/db"
+)
+
+const getCountyCode = `SELECT code FROM counties WHERE id = $1`
+
+// Takes the next number of a county's document series. The row stays locked
+// until the surrounding transaction ends, so numbers are issued one at a time
+// and a rollback returns the number.
+const nextDocumentSequence = `
+INSERT INTO document_sequences (county_id, document_type, financial_year, last_value)
+VALUES ($1, $2, $3, 1)
+ON CONFLICT (county_id, document_type, financial_year) DO UPDATE
+SET last_value = document_sequences.last_value + 1,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING last_value`
+
+type counter struct {
+	db db.DBTX
+}
+
+// NewCounter keeps the series in the document_sequences table. db must be
+// the transaction that stores the document being numbered.
+func NewCounter(db db.DBTX) Counter {
+	return &counter{db: db}
+}
+
+func (c *counter) GetCountyCode(ctx context.Context, countyID int32) (string, error) {
+	var code string
+	err := c.db.QueryRowContext(ctx, getCountyCode, countyID).Scan(&code)
+	return code, err
+}
+
+func (c *counter) NextDocumentSequence(ctx context.Context, countyID int32, documentType, financialYear string) (int64, error) {
+	var seq int64
+	err := c.db.QueryRowContext(ctx, nextDocumentSequence, countyID, documentType, financialYear).Scan(&seq)
+	return seq, err
+}
@@ -0,0 +1,108 @@
+// Package numbering issues the numbers of payments, assessments and receipts,
+// such as NRB/PAY/2025-26/000123. Every county has its own series per kind of
+// document, restarting at 1 each financial year. Numbers are gap-free as long
+// as the counter is advanced in the transaction that stores the document.
+package numbering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the kind of document a series numbers; its value appears in the
+// number.
+type Kind string
+
+const (
+	Payment    Kind = "PAY"
+	Assessment Kind = "ASM"
+	Receipt    Kind = "RCT"
+)
+
+var ErrInvalidScheme = errors.New("numbering: invalid numbering scheme")
+
+// Counter advances the stored series. Both methods must run in the caller's
+// transaction, and NextDocumentSequence must hold a lock on the series until
+// it ends.
+type Counter interface {
+	GetCountyCode(ctx context.Context, countyID int32) (string, error)
+	NextDocumentSequence(ctx context.Context, countyID int32, documentType, financialYear string) (int64, error)
+}
+
+// Scheme describes what numbers look like.
+type Scheme struct {
+	// Pattern lays out a number from the placeholders {county}, {kind}, {fy}
+	// and {seq}. {seq} is required; the others may be left out.
+	Pattern string
+	// YearStart is the first month of the financial year. Kenyan counties
+	// budget from July to June.
+	YearStart time.Month
+	// Digits is the width the sequence is zero-padded to.
+	Digits int
+}
+
+var DefaultScheme = Scheme{
+	Pattern:   "{county}/{kind}/{fy}/{seq}",
+	YearStart: time.July,
+	Digits:    6,
+}
+
+func (s Scheme) Validate() error {
+	if !strings.Contains(s.Pattern, "{seq}") {
+		return fmt.Errorf("%w: pattern %q has no {seq} placeholder", ErrInvalidScheme, s.Pattern)
+	}
+	if s.YearStart < time.January || s.YearStart > time.December {
+		return fmt.Errorf("%w: financial year start month must be 1-12", ErrInvalidScheme)
+	}
+	if s.Digits < 1 || s.Digits > 18 {
+		return fmt.Errorf("%w: digits must be 1-18", ErrInvalidScheme)
+	}
+	return nil
+}
+
+// FinancialYear names the financial year t falls in: "2025-26" for one
+// starting in July 2025, or just "2025" when years start in January.
+func (s Scheme) FinancialYear(t time.Time) string {
+	year := t.Year()
+	if s.YearStart == time.January {
+		return strconv.Itoa(year)
+	}
+	if t.Month() < s.YearStart {
+		year--
+	}
+	return fmt.Sprintf("%d-%02d", year, (year+1)%100)
+}
+
+// Format lays out the number of the seq-th document of a series.
+func (s Scheme) Format(countyCode string, kind Kind, financialYear string, seq int64) string {
+	return strings.NewReplacer(
+		"{county}", countyCode,
+		"{kind}", string(kind),
+		"{fy}", financialYear,
+		"{seq}", fmt.Sprintf("%0*d", s.Digits, seq),
+	).Replace(s.Pattern)
+}
+
+// Next takes the next number of the county's kind series for the financial
+// year that at falls in.
+func (s Scheme) Next(ctx context.Context, c Counter, countyID int32, kind Kind, at time.Time) (string, error) {
+	code, err := c.GetCountyCode(ctx, countyID)
+	if err != nil {
+		return "", fmt.Errorf("numbering: county %d: %w", countyID, err)
+	}
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		code = fmt.Sprintf("%03d", countyID)
+	}
+
+	fy := s.FinancialYear(at)
+	seq, err := c.NextDocumentSequence(ctx, countyID, string(kind), fy)
+	if err != nil {
+		return "", fmt.Errorf("numbering: next %s number: %w", kind, err)
+	}
+	return s.Format(code, kind, fy, seq), nil
+}
@@ -0,0 +1,74 @@
+package numbering
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCounter struct {
+	codes  map[int32]string
+	series map[string]int64
+}
+
+func (c *fakeCounter) GetCountyCode(ctx context.Context, countyID int32) (string, error) {
+	return c.codes[countyID], nil
+}
+
+func (c *fakeCounter) NextDocumentSequence(ctx context.Context, countyID int32, documentType, financialYear string) (int64, error) {
+	key := c.codes[countyID] + "|" + documentType + "|" + financialYear
+	c.series[key]++
+	return c.series[key], nil
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestFinancialYear(t *testing.T) {
+	assert.Equal(t, "2025-26", DefaultScheme.FinancialYear(date(2025, 7, 1)))
+	assert.Equal(t, "2024-25", DefaultScheme.FinancialYear(date(2025, 6, 30)))
+	assert.Equal(t, "2099-00", DefaultScheme.FinancialYear(date(2099, 12, 31)))
+
+	calendar := DefaultScheme
+	calendar.YearStart = time.January
+	assert.Equal(t, "2025", calendar.FinancialYear(date(2025, 6, 30)))
+}
+
+func TestNext(t *testing.T) {
+	c := &fakeCounter{codes: map[int32]string{47: "nrb", 1: "MSA", 2: ""}, series: map[string]int64{}}
+	ctx := context.Background()
+	next := func(countyID int32, kind Kind, at time.Time) string {
+		n, err := DefaultScheme.Next(ctx, c, countyID, kind, at)
+		require.NoError(t, err)
+		return n
+	}
+
+	assert.Equal(t, "NRB/PAY/2025-26/000001", next(47, Payment, date(2025, 8, 1)))
+	assert.Equal(t, "NRB/PAY/2025-26/000002", next(47, Payment, date(2026, 6, 30)))
+	assert.Equal(t, "NRB/RCT/2025-26/000001", next(47, Receipt, date(2025, 8, 1)))
+	assert.Equal(t, "MSA/PAY/2025-26/000001", next(1, Payment, date(2025, 8, 1)))
+	assert.Equal(t, "NRB/PAY/2026-27/000001", next(47, Payment, date(2026, 7, 1)))
+	assert.Equal(t, "002/ASM/2025-26/000001", next(2, Assessment, date(2025, 8, 1)))
+}
+
+func TestFormat(t *testing.T) {
+	s := Scheme{Pattern: "{kind}-{seq}", YearStart: time.July, Digits: 4}
+	require.NoError(t, s.Validate())
+	assert.Equal(t, "RCT-0042", s.Format("NRB", Receipt, "2025-26", 42))
+	assert.Equal(t, "RCT-12345", s.Format("NRB", Receipt, "2025-26", 12345))
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, DefaultScheme.Validate())
+	for _, s := range []Scheme{
+		{Pattern: "{county}/{kind}", YearStart: time.July, Digits: 6},
+		{Pattern: "{seq}", YearStart: 13, Digits: 6},
+		{Pattern: "{seq}", YearStart: time.July, Digits: 0},
+	} {
+		assert.ErrorIs(t, s.Validate(), ErrInvalidScheme)
+	}
+}
@@ -0,0 +1,72 @@
+package receipt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/skip2/go-qrcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDocument() Document {
+	return Document{
+		CountyName:      "Nairobi",
+		CountyCode:      "047",
+		ReceiptNumber:   "047/RCT/2025-26/000123",
+		ReceiptType:     "official",
+		IssuedAt:        time.Date(2025, 8, 14, 10, 30, 0, 0, time.UTC),
+		PaymentNumber:   "047/PAY/2025-26/000456",
+		PaymentMethod:   "mpesa",
+		Reference:       "SGH7K2L9PQ",
+		Amount:          money.MustParse("12500"),
+		TaxpayerName:    "Wanjiku Traders Ltd",
+		TaxpayerID:      "PVT-2019-0042",
+		Collector:       "Achieng Otieno",
+		CollectionPoint: "City Hall",
+		Lines: []Line{
+			{Reference: "047/ASM/2025-26/000010", Description: "Single Business Permit 2025-26", Amount: money.MustParse("10000")},
+		},
+		QRData: "047/RCT/2025-26/000123",
+	}
+}
+
+func TestRenderProducesPDF(t *testing.T) {
+	out, err := NewRenderer("").Render(testDocument())
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(out, []byte("%PDF-")))
+	assert.Contains(t, string(out), "%%EOF")
+}
+
+func TestRenderIsDeterministic(t *testing.T) {
+	r := NewRenderer("")
+	first, err := r.Render(testDocument())
+	require.NoError(t, err)
+	second, err := r.Render(testDocument())
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+}
+
+func TestRenderUsesCountyLogo(t *testing.T) {
+	dir := t.TempDir()
+	logo, err := qrcode.Encode("logo", qrcode.Low, 64)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "047.png"), logo, 0o600))
+
+	plain, err := NewRenderer("").Render(testDocument())
+	require.NoError(t, err)
+	branded, err := NewRenderer(dir).Render(testDocument())
+	require.NoError(t, err)
+	assert.Greater(t, len(branded), len(plain))
+}
+
+func TestRenderRequiresReceiptNumber(t *testing.T) {
+	doc := testDocument()
+	doc.ReceiptNumber = ""
+	_, err := NewRenderer("").Render(doc)
+	assert.Error(t, err)
+}
@@ -0,0 +1,180 @@
+// Package tariff works out county charges from a rate card. A Tariff is one
+// charge of a county's Finance Act, such as a single business permit class or
+// a parking rate; given what the taxpayer declares it produces the Charge
+// that becomes an assessment line. Charges are rounded half up to the cent.
+package tariff
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sangkips/revenue-system/internal/money"
+)
+
+// Method is how a tariff turns its input into a charge.
+type Method string
+
+const (
+	Flat       Method = "flat"       // Amount for each unit assessed
+	PerUnit    Method = "per_unit"   // Amount per unit counted, e.g. per square metre
+	Percentage Method = "percentage" // Rate percent of the declared value
+	Tiered     Method = "tiered"     // the band the quantity falls in
+)
+
+var (
+	ErrInvalidTariff = errors.New("tariff: invalid rate card")
+	ErrInvalidInput  = errors.New("tariff: invalid input")
+)
+
+// Band is one band of a tiered tariff. It covers quantities from LowerBound
+// up to the next band's lower bound and charges Amount plus UnitAmount for
+// each unit above LowerBound. A flat fee per band leaves UnitAmount zero; a
+// graduated scale sets Amount to the charge at LowerBound.
+type Band struct {
+	LowerBound money.Quantity `json:"lower_bound"`
+	Amount     money.Amount   `json:"amount"`
+	UnitAmount money.Amount   `json:"unit_amount"`
+}
+
+// Tariff is a rate card entry.
+type Tariff struct {
+	Code    string
+	Name    string
+	Method  Method
+	Amount  money.Amount   // Flat fee or PerUnit rate
+	Unit    string         // what PerUnit and Tiered count, e.g. "sq m"; for descriptions only
+	Rate    money.Quantity // Percentage rate, in percent
+	Minimum money.NullAmount
+	Maximum money.NullAmount
+	Bands   []Band // Tiered bands, by ascending LowerBound; the first starts at 0
+}
+
+// Input is what the taxpayer declares for a tariff.
+type Input struct {
+	// Quantity is the number of units for Flat and PerUnit tariffs, 1 when
+	// zero for Flat, and the measure Tiered bands are drawn on.
+	Quantity money.Quantity
+	// Value is what a Percentage tariff is charged on.
+	Value money.Amount
+}
+
+// Charge is a tariff's charge for one input, in the shape of an assessment
+// line.
+type Charge struct {
+	Code        string         `json:"tariff_code"`
+	Description string         `json:"description"`
+	Quantity    money.Quantity `json:"quantity"`
+	UnitAmount  money.Amount   `json:"unit_amount"`
+	Total       money.Amount   `json:"total_amount"`
+}
+
+// Validate checks that the tariff can compute a charge.
+func (t Tariff) Validate() error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidTariff, t.Code, fmt.Sprintf(format, args...))
+	}
+	if t.Code == "" || t.Name == "" {
+		return fmt.Errorf("%w: code and name are required", ErrInvalidTariff)
+	}
+	switch t.Method {
+	case Flat, PerUnit:
+		if t.Amount.IsNegative() || (t.Amount.IsZero() && !t.Minimum.Valid) {
+			return invalid("amount must be greater than 0")
+		}
+	case Percentage:
+		if !t.Rate.IsPositive() || t.Rate.Cmp(money.QuantityOf(100)) > 0 {
+			return invalid("rate must be a percentage above 0 and at most 100")
+		}
+	case Tiered:
+		if len(t.Bands) == 0 || !t.Bands[0].LowerBound.IsZero() {
+			return invalid("bands must start at 0")
+		}
+		for i, b := range t.Bands {
+			if i > 0 && b.LowerBound.Cmp(t.Bands[i-1].LowerBound) <= 0 {
+				return invalid("band lower bounds must increase")
+			}
+			if b.Amount.IsNegative() || b.UnitAmount.IsNegative() {
+				return invalid("band amounts must not be negative")
+			}
+		}
+	default:
+		return invalid("method must be flat, per_unit, percentage or tiered")
+	}
+	if t.Minimum.Valid && t.Minimum.Amount.IsNegative() {
+		return invalid("minimum must not be negative")
+	}
+	if t.Minimum.Valid && t.Maximum.Valid && t.Maximum.Amount.Cmp(t.Minimum.Amount) < 0 {
+		return invalid("maximum is below the minimum")
+	}
+	return nil
+}
+
+// Charge works out what the tariff charges for in.
+func (t Tariff) Charge(in Input) (Charge, error) {
+	if err := t.Validate(); err != nil {
+		return Charge{}, err
+	}
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidInput, t.Code, fmt.Sprintf(format, args...))
+	}
+	if in.Quantity.IsNegative() {
+		return Charge{}, invalid("quantity must not be negative")
+	}
+
+	c := Charge{Code: t.Code, Description: t.Name}
+	switch t.Method {
+	case Flat:
+		c.Quantity = in.Quantity
+		if c.Quantity.IsZero() {
+			c.Quantity = money.QuantityOf(1)
+		}
+		c.UnitAmount = t.Amount
+		c.Total = t.Amount.Times(c.Quantity, money.HalfUp)
+
+	case PerUnit:
+		if !in.Quantity.IsPositive() {
+			return Charge{}, invalid("quantity is required")
+		}
+		c.Quantity, c.UnitAmount = in.Quantity, t.Amount
+		c.Total = t.Amount.Times(in.Quantity, money.HalfUp)
+		if t.Unit != "" {
+			c.Description = fmt.Sprintf("%s (%s %s)", t.Name, in.Quantity, t.Unit)
+		}
+
+	case Percentage:
+		if !in.Value.IsPositive() {
+			return Charge{}, invalid("value must be greater than 0")
+		}
+		c.Total = in.Value.Percent(t.Rate, money.HalfUp)
+		c.Description = fmt.Sprintf("%s (%s%% of %s)", t.Name, t.Rate, in.Value.Format())
+
+	case Tiered:
+		band := t.Bands[0]
+		for _, b := range t.Bands[1:] {
+			if in.Quantity.Cmp(b.LowerBound) < 0 {
+				break
+			}
+			band = b
+		}
+		c.Total = band.Amount.Add(band.UnitAmount.Times(in.Quantity.Sub(band.LowerBound), money.HalfUp))
+		c.Description = fmt.Sprintf("%s (%s %s)", t.Name, in.Quantity, t.Unit)
+		if t.Unit == "" {
+			c.Description = fmt.Sprintf("%s (%s)", t.Name, in.Quantity)
+		}
+	}
+
+	switch {
+	case t.Minimum.Valid && c.Total.Cmp(t.Minimum.Amount) < 0:
+		c.Total = t.Minimum.Amount
+		c.Description += ", minimum charge"
+	case t.Maximum.Valid && c.Total.Cmp(t.Maximum.Amount) > 0:
+		c.Total = t.Maximum.Amount
+		c.Description += ", maximum charge"
+	}
+	// Lines that are not a plain price times a count are one unit of their
+	// total, so quantity × unit amount always matches.
+	if c.Quantity.IsZero() || c.UnitAmount.Times(c.Quantity, money.HalfUp) != c.Total {
+		c.Quantity, c.UnitAmount = money.QuantityOf(1), c.Total
+	}
+	return c, nil
+}
@@ -0,0 +1,126 @@
+package tariff
+
+import (
+	"testing"
+
+	"github.com/sangkips/revenue-system/internal/money"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func quantity(t *testing.T, s string) money.Quantity {
+	t.Helper()
+	q, err := money.ParseQuantity(s)
+	require.NoError(t, err)
+	return q
+}
+
+func TestCharge(t *testing.T) {
+	permit := Tariff{
+		Code: "SBP-SMALL", Name: "Single business permit, small trader", Method: Tiered, Unit: "employees",
+		Bands: []Band{
+			{LowerBound: money.QuantityOf(0), Amount: money.MustParse("5000")},
+			{LowerBound: money.QuantityOf(6), Amount: money.MustParse("10000")},
+			{LowerBound: money.QuantityOf(21), Amount: money.MustParse("20000"), UnitAmount: money.MustParse("500")},
+		},
+	}
+	tests := []struct {
+		name   string
+		tariff Tariff
+		in     Input
+		want   Charge
+	}{
+		{
+			name:   "flat fee defaults to one",
+			tariff: Tariff{Code: "FIRE", Name: "Fire inspection", Method: Flat, Amount: money.MustParse("2500")},
+			want:   Charge{Code: "FIRE", Description: "Fire inspection", Quantity: money.QuantityOf(1), UnitAmount: money.MustParse("2500"), Total: money.MustParse("2500")},
+		},
+		{
+			name:   "flat fee per item",
+			tariff: Tariff{Code: "SIGN", Name: "Signboard", Method: Flat, Amount: money.MustParse("3000")},
+			in:     Input{Quantity: money.QuantityOf(3)},
+			want:   Charge{Code: "SIGN", Description: "Signboard", Quantity: money.QuantityOf(3), UnitAmount: money.MustParse("3000"), Total: money.MustParse("9000")},
+		},
+		{
+			name:   "per unit",
+			tariff: Tariff{Code: "ADV", Name: "Billboard", Method: PerUnit, Amount: money.MustParse("450"), Unit: "sq m"},
+			in:     Input{Quantity: quantity(t, "12.5")},
+			want:   Charge{Code: "ADV", Description: "Billboard (12.50 sq m)", Quantity: quantity(t, "12.5"), UnitAmount: money.MustParse("450"), Total: money.MustParse("5625")},
+		},
+		{
+			name:   "percentage",
+			tariff: Tariff{Code: "RATES", Name: "Land rates", Method: Percentage, Rate: quantity(t, "0.25")},
+			in:     Input{Value: money.MustParse("3000000")},
+			want:   Charge{Code: "RATES", Description: "Land rates (0.25% of KES 3,000,000.00)", Quantity: money.QuantityOf(1), UnitAmount: money.MustParse("7500"), Total: money.MustParse("7500")},
+		},
+		{
+			name:   "percentage below the minimum",
+			tariff: Tariff{Code: "RATES", Name: "Land rates", Method: Percentage, Rate: quantity(t, "0.25"), Minimum: money.NullAmount{Amount: money.MustParse("1000"), Valid: true}},
+			in:     Input{Value: money.MustParse("100000")},
+			want:   Charge{Code: "RATES", Description: "Land rates (0.25% of KES 100,000.00), minimum charge", Quantity: money.QuantityOf(1), UnitAmount: money.MustParse("1000"), Total: money.MustParse("1000")},
+		},
+		{
+			name:   "per unit above the maximum",
+			tariff: Tariff{Code: "ADV", Name: "Billboard", Method: PerUnit, Amount: money.MustParse("450"), Maximum: money.NullAmount{Amount: money.MustParse("20000"), Valid: true}},
+			in:     Input{Quantity: money.QuantityOf(100)},
+			want:   Charge{Code: "ADV", Description: "Billboard, maximum charge", Quantity: money.QuantityOf(1), UnitAmount: money.MustParse("20000"), Total: money.MustParse("20000")},
+		},
+		{
+			name:   "lowest band",
+			tariff: permit,
+			in:     Input{Quantity: money.QuantityOf(0)},
+			want:   Charge{Code: "SBP-SMALL", Description: "Single business permit, small trader (0.00 employees)", Quantity: money.QuantityOf(1), UnitAmount: money.MustParse("5000"), Total: money.MustParse("5000")},
+		},
+		{
+			name:   "band lower bounds are inclusive",
+			tariff: permit,
+			in:     Input{Quantity: money.QuantityOf(6)},
+			want:   Charge{Code: "SBP-SMALL", Description: "Single business permit, small trader (6.00 employees)", Quantity: money.QuantityOf(1), UnitAmount: money.MustParse("10000"), Total: money.MustParse("10000")},
+		},
+		{
+			name:   "graduated top band",
+			tariff: permit,
+			in:     Input{Quantity: money.QuantityOf(25)},
+			want:   Charge{Code: "SBP-SMALL", Description: "Single business permit, small trader (25.00 employees)", Quantity: money.QuantityOf(1), UnitAmount: money.MustParse("22000"), Total: money.MustParse("22000")},
+		},
+		{
+			name:   "free band",
+			tariff: Tariff{Code: "PARK", Name: "Parking", Method: Tiered, Unit: "hours", Bands: []Band{{}, {LowerBound: money.QuantityOf(1), Amount: money.MustParse("100")}}},
+			in:     Input{Quantity: quantity(t, "0.50")},
+			want:   Charge{Code: "PARK", Description: "Parking (0.50 hours)", Quantity: money.QuantityOf(1), UnitAmount: money.Zero, Total: money.Zero},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.tariff.Charge(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestChargeRejects(t *testing.T) {
+	flat := Tariff{Code: "FIRE", Name: "Fire inspection", Method: Flat, Amount: money.MustParse("2500")}
+	perUnit := Tariff{Code: "ADV", Name: "Billboard", Method: PerUnit, Amount: money.MustParse("450")}
+	percent := Tariff{Code: "RATES", Name: "Land rates", Method: Percentage, Rate: quantity(t, "0.25")}
+
+	_, err := perUnit.Charge(Input{})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = percent.Charge(Input{})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = flat.Charge(Input{Quantity: quantity(t, "-1")})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	for name, bad := range map[string]Tariff{
+		"no method":      {Code: "X", Name: "X"},
+		"zero flat":      {Code: "X", Name: "X", Method: Flat},
+		"rate over 100":  {Code: "X", Name: "X", Method: Percentage, Rate: money.QuantityOf(101)},
+		"no bands":       {Code: "X", Name: "X", Method: Tiered},
+		"gap at zero":    {Code: "X", Name: "X", Method: Tiered, Bands: []Band{{LowerBound: money.QuantityOf(1)}}},
+		"unsorted bands": {Code: "X", Name: "X", Method: Tiered, Bands: []Band{{}, {LowerBound: money.QuantityOf(5)}, {LowerBound: money.QuantityOf(5)}}},
+		"max below min": {Code: "X", Name: "X", Method: Flat, Amount: money.MustParse("1"),
+			Minimum: money.NullAmount{Amount: money.MustParse("10"), Valid: true}, Maximum: money.NullAmount{Amount: money.MustParse("5"), Valid: true}},
+	} {
+		assert.ErrorIs(t, bad.Validate(), ErrInvalidTariff, name)
+	}
+}
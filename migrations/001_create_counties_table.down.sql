@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_counties_code;
+DROP TABLE IF EXISTS counties;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_employee_id;
+DROP INDEX IF EXISTS idx_users_email;
+DROP INDEX IF EXISTS idx_users_county_id;
+DROP TABLE IF EXISTS users;
+
+DROP EXTENSION IF EXISTS "uuid-ossp";
@@ -0,0 +1,2 @@
+-- 002 never creates the username column, so there is nothing to restore.
+SELECT 1;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_assessment_items_assessment;
+DROP INDEX IF EXISTS idx_assessments_due_date;
+DROP INDEX IF EXISTS idx_assessments_status;
+DROP INDEX IF EXISTS idx_assessments_taxpayer;
+
+DROP TABLE IF EXISTS assessment_items;
+DROP TABLE IF EXISTS assessments;
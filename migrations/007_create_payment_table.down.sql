@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_payments_method;
+DROP INDEX IF EXISTS idx_payments_status;
+DROP INDEX IF EXISTS idx_payments_date;
+DROP INDEX IF EXISTS idx_payments_assessment;
+DROP INDEX IF EXISTS idx_payments_taxpayer;
+
+DROP TABLE IF EXISTS payments;
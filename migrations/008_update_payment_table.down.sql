@@ -0,0 +1,25 @@
+DROP INDEX IF EXISTS idx_receipts_blockchain_hash;
+DROP INDEX IF EXISTS idx_receipts_payment;
+DROP INDEX IF EXISTS idx_payment_allocations_assessment;
+DROP INDEX IF EXISTS idx_payment_allocations_payment;
+
+DROP TABLE IF EXISTS receipts;
+DROP TABLE IF EXISTS payment_allocations;
+
+-- Restore the original status constraint (without 'refunded')
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check
+CHECK (status IN ('pending', 'processing', 'completed', 'failed', 'cancelled'));
+
+ALTER TABLE payments
+DROP COLUMN IF EXISTS reconciled_by,
+DROP COLUMN IF EXISTS reconciliation_date,
+DROP COLUMN IF EXISTS reconciled,
+DROP COLUMN IF EXISTS block_number,
+DROP COLUMN IF EXISTS blockchain_hash,
+DROP COLUMN IF EXISTS gps_coordinates,
+DROP COLUMN IF EXISTS collection_point,
+DROP COLUMN IF EXISTS failure_reason,
+DROP COLUMN IF EXISTS cheque_number,
+DROP COLUMN IF EXISTS bank_reference,
+DROP COLUMN IF EXISTS mpesa_receipt_number;
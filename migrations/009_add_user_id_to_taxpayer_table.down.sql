@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS trigger_taxpayers_updated_at ON taxpayers;
+DROP TRIGGER IF EXISTS trigger_users_updated_at ON users;
+DROP FUNCTION IF EXISTS sync_updated_at();
+
+DROP INDEX IF EXISTS idx_taxpayers_national_id;
+DROP INDEX IF EXISTS idx_taxpayers_user_id;
+
+ALTER TABLE taxpayers DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_application_documents_application;
+DROP INDEX IF EXISTS idx_applications_status;
+DROP INDEX IF EXISTS idx_applications_taxpayer;
+
+DROP TABLE IF EXISTS application_assessments;
+DROP TABLE IF EXISTS health_certificates;
+DROP TABLE IF EXISTS seasonal_parking_tickets;
+DROP TABLE IF EXISTS building_approvals;
+DROP TABLE IF EXISTS single_business_permits;
+DROP TABLE IF EXISTS application_documents;
+DROP TABLE IF EXISTS applications;
@@ -0,0 +1,29 @@
+-- TOTP multi-factor authentication for staff accounts
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL, -- base32 TOTP secret
+    enabled BOOLEAN NOT NULL DEFAULT false, -- set once the first code is confirmed
+    last_used_step BIGINT NOT NULL DEFAULT 0, -- last accepted TOTP time step, prevents code replay
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL, -- SHA-256 hex of the normalised code
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
+
+-- Per-county policy; when mfa_required is set, county_admin and collector accounts must use MFA
+CREATE TABLE IF NOT EXISTS county_mfa_policies (
+    county_id INTEGER PRIMARY KEY REFERENCES counties(id) ON DELETE CASCADE,
+    mfa_required BOOLEAN NOT NULL DEFAULT false,
+    updated_by UUID REFERENCES users(id),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_payments_mpesa_receipt_number;
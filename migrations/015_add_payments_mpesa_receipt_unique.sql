@@ -0,0 +1,5 @@
+-- An M-Pesa receipt number identifies exactly one transaction. Enforcing it
+-- makes repeated C2B confirmations for the same TransID idempotent.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_mpesa_receipt_number
+    ON payments(mpesa_receipt_number)
+    WHERE mpesa_receipt_number IS NOT NULL;
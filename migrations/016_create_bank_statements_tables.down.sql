@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_payments_unreconciled;
+DROP TABLE IF EXISTS bank_statement_lines;
+DROP TABLE IF EXISTS bank_statements;
@@ -0,0 +1,49 @@
+-- Imported bank statements of the county collection accounts. The file hash
+-- stops the same statement from being imported twice.
+CREATE TABLE IF NOT EXISTS bank_statements (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    county_id INTEGER NOT NULL REFERENCES counties(id) ON DELETE RESTRICT,
+    format TEXT NOT NULL CHECK (format IN ('csv', 'mt940', 'camt053')),
+    file_name TEXT,
+    file_sha256 VARCHAR(64) NOT NULL,
+    account_number TEXT,
+    statement_reference TEXT,
+    line_count INTEGER NOT NULL DEFAULT 0,
+    matched_count INTEGER NOT NULL DEFAULT 0,
+    total_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    imported_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    imported_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (county_id, file_sha256)
+);
+
+-- One row per credit on a statement. Lines stay 'unmatched' until the
+-- matcher or a person ties them to a payment, or a person ignores them.
+CREATE TABLE IF NOT EXISTS bank_statement_lines (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    statement_id UUID NOT NULL REFERENCES bank_statements(id) ON DELETE CASCADE,
+    county_id INTEGER NOT NULL REFERENCES counties(id) ON DELETE RESTRICT,
+    line_number INTEGER NOT NULL,
+    value_date DATE NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    reference TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'unmatched' CHECK (status IN ('unmatched', 'matched', 'ignored')),
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    match_method TEXT CHECK (match_method IN ('reference', 'amount_date', 'manual')),
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (statement_id, line_number)
+);
+
+-- A payment is settled by at most one statement line.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bank_statement_lines_payment
+    ON bank_statement_lines(payment_id)
+    WHERE payment_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_bank_statement_lines_queue
+    ON bank_statement_lines(county_id, value_date)
+    WHERE status = 'unmatched';
+CREATE INDEX IF NOT EXISTS idx_payments_unreconciled
+    ON payments(county_id, payment_date)
+    WHERE status = 'completed' AND reconciled IS NOT TRUE;
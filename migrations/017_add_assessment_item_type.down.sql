@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_assessments_open_by_due;
+ALTER TABLE assessment_items DROP COLUMN IF EXISTS item_type;
@@ -0,0 +1,11 @@
+-- Splits what an assessment charges into principal, penalty and interest so
+-- payments can be allocated component by component. Existing items and
+-- assessments without items are principal.
+ALTER TABLE assessment_items
+ADD COLUMN IF NOT EXISTS item_type VARCHAR(20) NOT NULL DEFAULT 'principal'
+    CHECK (item_type IN ('principal', 'penalty', 'interest'));
+
+-- Open assessments are allocated oldest-due first.
+CREATE INDEX IF NOT EXISTS idx_assessments_open_by_due
+    ON assessments(taxpayer_id, due_date)
+    WHERE status IN ('pending', 'approved');
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS taxpayer_credit_ledger;
+DROP TABLE IF EXISTS taxpayer_credit_balances;
@@ -0,0 +1,30 @@
+-- Money a taxpayer has paid beyond what their assessments called for. The
+-- balance row is updated in place so concurrent movements serialise on it
+-- and the CHECK stops credit from going negative.
+CREATE TABLE IF NOT EXISTS taxpayer_credit_balances (
+    taxpayer_id UUID PRIMARY KEY REFERENCES taxpayers(id) ON DELETE RESTRICT,
+    county_id INTEGER NOT NULL REFERENCES counties(id) ON DELETE RESTRICT,
+    balance DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Every movement of credit. Each entry is tied to the payment whose money it
+-- is, so a payment's amount always equals its allocations plus its credit.
+--   overpayment  +  part of a completed payment no assessment needed
+--   applied      -  credit allocated to an assessment
+--   refund       -  credit paid back to the taxpayer
+CREATE TABLE IF NOT EXISTS taxpayer_credit_ledger (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    county_id INTEGER NOT NULL REFERENCES counties(id) ON DELETE RESTRICT,
+    taxpayer_id UUID NOT NULL REFERENCES taxpayers(id) ON DELETE RESTRICT,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE RESTRICT,
+    entry_type VARCHAR(20) NOT NULL CHECK (entry_type IN ('overpayment', 'applied', 'refund')),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount <> 0),
+    balance_after DECIMAL(15,2) NOT NULL CHECK (balance_after >= 0),
+    description TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_taxpayer_credit_ledger_taxpayer ON taxpayer_credit_ledger(taxpayer_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_taxpayer_credit_ledger_payment ON taxpayer_credit_ledger(payment_id);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_payment_refund_reversals_refund;
+DROP INDEX IF EXISTS idx_payment_refunds_pending;
+DROP INDEX IF EXISTS idx_payment_refunds_payment;
+
+ALTER TABLE receipts
+DROP COLUMN IF EXISTS void_reason,
+DROP COLUMN IF EXISTS voided_by,
+DROP COLUMN IF EXISTS voided_at;
+
+DROP TABLE IF EXISTS payment_refund_reversals;
+DROP TABLE IF EXISTS payment_refunds;
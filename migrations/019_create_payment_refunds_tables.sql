@@ -0,0 +1,44 @@
+-- A request to give back some or all of a completed payment. Refunds follow
+-- maker-checker: a collector requests, a department head approves, and the
+-- two must be different people.
+CREATE TABLE IF NOT EXISTS payment_refunds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE RESTRICT,
+    county_id INTEGER NOT NULL REFERENCES counties(id) ON DELETE RESTRICT,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    requested_by UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    requested_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    reviewed_by UUID REFERENCES users(id) ON DELETE RESTRICT,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    review_note TEXT,
+    CHECK (reviewed_by IS NULL OR reviewed_by <> requested_by)
+);
+
+-- What an approved refund undid, so the original payment's allocations and
+-- receipts can be traced after they have been reduced or voided.
+--   credit      part of the payment's credit paid back
+--   allocation  an allocation reduced by amount; the row goes once it reaches 0
+--   receipt     a receipt voided because the whole payment was refunded
+CREATE TABLE IF NOT EXISTS payment_refund_reversals (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    refund_id UUID NOT NULL REFERENCES payment_refunds(id) ON DELETE RESTRICT,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE RESTRICT,
+    reversal_type VARCHAR(20) NOT NULL CHECK (reversal_type IN ('credit', 'allocation', 'receipt')),
+    allocation_id UUID,
+    assessment_id UUID REFERENCES assessments(id) ON DELETE RESTRICT,
+    allocation_type VARCHAR(20),
+    receipt_id UUID REFERENCES receipts(id) ON DELETE RESTRICT,
+    amount DECIMAL(15,2),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE receipts
+ADD COLUMN IF NOT EXISTS voided_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS voided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS void_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_payment_refunds_payment ON payment_refunds(payment_id);
+CREATE INDEX IF NOT EXISTS idx_payment_refunds_pending ON payment_refunds(county_id, requested_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_payment_refund_reversals_refund ON payment_refund_reversals(refund_id);
@@ -0,0 +1,13 @@
+-- Counters behind server-issued document numbers such as NRB/PAY/2025-26/000123.
+-- Each county numbers every document type from 1 again in each financial
+-- year. A number is taken by incrementing the row in the same transaction
+-- that inserts the document, so the row lock serialises concurrent issuers
+-- and a rolled-back insert gives its number back, leaving no gaps.
+CREATE TABLE IF NOT EXISTS document_sequences (
+    county_id INTEGER NOT NULL REFERENCES counties(id) ON DELETE CASCADE,
+    document_type VARCHAR(10) NOT NULL,
+    financial_year VARCHAR(9) NOT NULL,
+    last_value BIGINT NOT NULL CHECK (last_value > 0),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (county_id, document_type, financial_year)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_payment_status_history_payment_id;
+DROP TABLE IF EXISTS payment_status_history;
@@ -0,0 +1,19 @@
+-- Every status a payment has been in, who moved it there and why. The first
+-- row of a payment has no from_status. changed_by is empty for changes made
+-- by the system, such as an M-Pesa callback.
+CREATE TABLE IF NOT EXISTS payment_status_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    reason TEXT,
+    changed_by UUID REFERENCES users(id) ON DELETE RESTRICT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_status_history_payment_id ON payment_status_history(payment_id, created_at);
+
+-- Payments recorded before the history existed start from their current status.
+INSERT INTO payment_status_history (payment_id, to_status, reason, created_at)
+SELECT id, status, 'recorded before status history was kept', COALESCE(created_at, CURRENT_TIMESTAMP)
+FROM payments;
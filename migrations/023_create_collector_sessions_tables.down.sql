@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_collector_session_payments_session_id;
+DROP TABLE IF EXISTS collector_session_payments;
+DROP INDEX IF EXISTS idx_collector_sessions_county_opened;
+DROP INDEX IF EXISTS idx_collector_sessions_open;
+DROP TABLE IF EXISTS collector_sessions;
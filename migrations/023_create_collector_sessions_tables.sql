@@ -0,0 +1,38 @@
+-- A collector's shift at a collection point. The collector opens it with the
+-- float handed over in the till and closes it by declaring the cash counted.
+-- Closing fixes what the till should hold, the float plus completed cash
+-- payments, and the variance; the summary is the shift's Z-report, which a
+-- supervisor other than the collector approves or rejects.
+CREATE TABLE IF NOT EXISTS collector_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    county_id INTEGER NOT NULL REFERENCES counties(id) ON DELETE RESTRICT,
+    collector_id UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    collection_point TEXT NOT NULL,
+    opening_float DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (opening_float >= 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'closed', 'approved', 'rejected')),
+    opened_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    expected_cash DECIMAL(15,2),
+    declared_cash DECIMAL(15,2) CHECK (declared_cash >= 0),
+    variance DECIMAL(15,2),
+    close_note TEXT,
+    reviewed_by UUID REFERENCES users(id) ON DELETE RESTRICT,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    review_note TEXT,
+    CHECK (reviewed_by IS NULL OR reviewed_by <> collector_id),
+    CHECK (status = 'open' OR (closed_at IS NOT NULL AND expected_cash IS NOT NULL AND declared_cash IS NOT NULL))
+);
+
+-- A collector works one shift at a time.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_collector_sessions_open ON collector_sessions(collector_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_collector_sessions_county_opened ON collector_sessions(county_id, opened_at);
+
+-- Payments a collector recorded during a session. Cash payments always belong
+-- to one.
+CREATE TABLE IF NOT EXISTS collector_session_payments (
+    session_id UUID NOT NULL REFERENCES collector_sessions(id) ON DELETE RESTRICT,
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    PRIMARY KEY (payment_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collector_session_payments_session_id ON collector_session_payments(session_id);
@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS trigger_receipt_ledger_blocks_append_only ON receipt_ledger_blocks;
+DROP TRIGGER IF EXISTS trigger_receipt_ledger_entries_append_only ON receipt_ledger_entries;
+DROP FUNCTION IF EXISTS receipt_ledger_append_only();
+DROP INDEX IF EXISTS idx_receipt_ledger_entries_unsealed;
+DROP INDEX IF EXISTS idx_receipt_ledger_entries_receipt_id;
+DROP TABLE IF EXISTS receipt_ledger_entries;
+DROP TABLE IF EXISTS receipt_ledger_blocks;
+DROP TABLE IF EXISTS receipt_ledger_heads;
@@ -0,0 +1,81 @@
+-- Tamper-evident ledger of receipts. Each county keeps its own chain: every
+-- receipt issued or voided appends an entry whose hash covers the canonical
+-- receipt record and the hash of the entry before it, so editing or deleting
+-- any receipt or entry breaks the chain. Entries are periodically sealed into
+-- blocks whose Merkle root covers their entry hashes and which chain to the
+-- block before them in the same way.
+
+-- The tip of each county's chain. Appending locks the row, which serialises
+-- issuers within a county, and it remembers how long the chain should be so
+-- that entries cut off the end are noticed.
+CREATE TABLE IF NOT EXISTS receipt_ledger_heads (
+    county_id INTEGER PRIMARY KEY REFERENCES counties(id) ON DELETE RESTRICT,
+    last_sequence BIGINT NOT NULL DEFAULT 0,
+    last_entry_hash VARCHAR(64) NOT NULL DEFAULT repeat('0', 64),
+    last_block_number BIGINT NOT NULL DEFAULT 0,
+    last_block_hash VARCHAR(64) NOT NULL DEFAULT repeat('0', 64),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS receipt_ledger_blocks (
+    county_id INTEGER NOT NULL REFERENCES counties(id) ON DELETE RESTRICT,
+    block_number BIGINT NOT NULL CHECK (block_number > 0),
+    first_sequence BIGINT NOT NULL,
+    last_sequence BIGINT NOT NULL,
+    entry_count INTEGER NOT NULL CHECK (entry_count > 0),
+    merkle_root VARCHAR(64) NOT NULL,
+    previous_block_hash VARCHAR(64) NOT NULL,
+    block_hash VARCHAR(64) NOT NULL UNIQUE,
+    sealed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (county_id, block_number),
+    CHECK (last_sequence >= first_sequence)
+);
+
+-- receipt_id has no foreign key so that a deleted receipt shows up in
+-- verification as an entry without its receipt; receipts.blockchain_hash is
+-- the hash of the entry that issued the receipt.
+CREATE TABLE IF NOT EXISTS receipt_ledger_entries (
+    county_id INTEGER NOT NULL REFERENCES counties(id) ON DELETE RESTRICT,
+    sequence BIGINT NOT NULL CHECK (sequence > 0),
+    receipt_id UUID NOT NULL,
+    event VARCHAR(10) NOT NULL CHECK (event IN ('issued', 'voided')),
+    record_hash VARCHAR(64) NOT NULL,
+    previous_hash VARCHAR(64) NOT NULL,
+    entry_hash VARCHAR(64) NOT NULL UNIQUE,
+    block_number BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (county_id, sequence),
+    FOREIGN KEY (county_id, block_number) REFERENCES receipt_ledger_blocks(county_id, block_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_receipt_ledger_entries_receipt_id ON receipt_ledger_entries(receipt_id);
+CREATE INDEX IF NOT EXISTS idx_receipt_ledger_entries_unsealed ON receipt_ledger_entries(county_id, sequence) WHERE block_number IS NULL;
+
+-- Entries and blocks are never changed once written, except that sealing
+-- records the block an entry went into.
+CREATE OR REPLACE FUNCTION receipt_ledger_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND TG_TABLE_NAME = 'receipt_ledger_entries'
+        AND OLD.block_number IS NULL AND NEW.block_number IS NOT NULL
+        AND (NEW.county_id, NEW.sequence, NEW.receipt_id, NEW.event, NEW.record_hash, NEW.previous_hash, NEW.entry_hash, NEW.created_at)
+            IS NOT DISTINCT FROM (OLD.county_id, OLD.sequence, OLD.receipt_id, OLD.event, OLD.record_hash, OLD.previous_hash, OLD.entry_hash, OLD.created_at) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_receipt_ledger_entries_append_only ON receipt_ledger_entries;
+CREATE TRIGGER trigger_receipt_ledger_entries_append_only BEFORE UPDATE OR DELETE ON receipt_ledger_entries
+    FOR EACH ROW EXECUTE FUNCTION receipt_ledger_append_only();
+
+DROP TRIGGER IF EXISTS trigger_receipt_ledger_blocks_append_only ON receipt_ledger_blocks;
+CREATE TRIGGER trigger_receipt_ledger_blocks_append_only BEFORE UPDATE OR DELETE ON receipt_ledger_blocks
+    FOR EACH ROW EXECUTE FUNCTION receipt_ledger_append_only();
+
+-- The ledger columns used to be whatever clients sent. Clear them; receipts
+-- issued before the ledger existed are added with "server ledger backfill".
+UPDATE payments SET blockchain_hash = NULL, block_number = NULL
+WHERE blockchain_hash IS NOT NULL OR block_number IS NOT NULL;
+UPDATE receipts SET block_number = NULL, blockchain_verified = FALSE
+WHERE block_number IS NOT NULL OR blockchain_verified IS DISTINCT FROM FALSE;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notification_outbox_receipt_id;
+DROP INDEX IF EXISTS idx_notification_outbox_due;
+DROP TABLE IF EXISTS notification_outbox;
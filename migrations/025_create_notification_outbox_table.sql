@@ -0,0 +1,25 @@
+-- Outgoing SMS and email. Messages are written here in the transaction that
+-- causes them, such as issuing a receipt, so none is lost or sent for work
+-- that was rolled back. A dispatcher claims due messages, sends them through
+-- the channel's provider and either marks them sent or schedules a retry;
+-- after max_attempts a message is given up as failed.
+CREATE TABLE IF NOT EXISTS notification_outbox (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    county_id INTEGER NOT NULL REFERENCES counties(id) ON DELETE RESTRICT,
+    channel VARCHAR(10) NOT NULL CHECK (channel IN ('sms', 'email')),
+    recipient VARCHAR(255) NOT NULL,
+    subject TEXT,
+    body TEXT NOT NULL,
+    receipt_id UUID REFERENCES receipts(id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL CHECK (max_attempts > 0),
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_due ON notification_outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_receipt_id ON notification_outbox(receipt_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS tariff_bands;
+DROP INDEX IF EXISTS idx_tariffs_county_year;
+DROP TABLE IF EXISTS tariffs;
@@ -0,0 +1,54 @@
+-- The county's rate card: what each fee, levy or charge in its Finance Act
+-- costs. A tariff is one charge, identified within the county by its code,
+-- and is effective over a date range; a new Finance Act publishes a new
+-- version of the code from the date it takes effect rather than editing the
+-- old one, so assessments already raised keep the rates they were raised at.
+--
+-- How a charge is worked out depends on the method:
+--   flat        amount, once per unit assessed
+--   per_unit    amount per unit, e.g. per square metre
+--   percentage  rate percent of the value declared
+--   tiered      the band the quantity falls in: its amount plus unit_amount
+--               for each unit above the band's lower bound
+-- minimum_amount and maximum_amount bound the charge of any method.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE IF NOT EXISTS tariffs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    county_id INTEGER NOT NULL REFERENCES counties(id) ON DELETE RESTRICT,
+    code VARCHAR(50) NOT NULL,
+    name TEXT NOT NULL,
+    assessment_type TEXT NOT NULL,
+    financial_year TEXT NOT NULL,
+    method VARCHAR(20) NOT NULL CHECK (method IN ('flat', 'per_unit', 'percentage', 'tiered')),
+    amount DECIMAL(15,2) CHECK (amount >= 0),
+    unit TEXT,
+    rate DECIMAL(5,2) CHECK (rate > 0 AND rate <= 100),
+    minimum_amount DECIMAL(15,2) CHECK (minimum_amount >= 0),
+    maximum_amount DECIMAL(15,2) CHECK (maximum_amount >= minimum_amount),
+    effective_from DATE NOT NULL,
+    effective_to DATE CHECK (effective_to >= effective_from),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (method NOT IN ('flat', 'per_unit') OR amount IS NOT NULL),
+    CHECK (method <> 'percentage' OR rate IS NOT NULL),
+    -- Only one version of a code is in effect on any day.
+    EXCLUDE USING gist (
+        county_id WITH =,
+        code WITH =,
+        daterange(effective_from, effective_to, '[]') WITH &&
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_tariffs_county_year ON tariffs(county_id, financial_year);
+
+-- Bands of a tiered tariff. A band covers quantities from lower_bound up to,
+-- but not including, the next band's lower bound.
+CREATE TABLE IF NOT EXISTS tariff_bands (
+    tariff_id UUID NOT NULL REFERENCES tariffs(id) ON DELETE CASCADE,
+    lower_bound DECIMAL(15,2) NOT NULL CHECK (lower_bound >= 0),
+    amount DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (amount >= 0),
+    unit_amount DECIMAL(15,2) NOT NULL DEFAULT 0 CHECK (unit_amount >= 0),
+    PRIMARY KEY (tariff_id, lower_bound)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_mpesa_suspense_queue;
+DROP TABLE IF EXISTS mpesa_suspense;
@@ -0,0 +1,33 @@
+-- M-Pesa results that no payment was waiting for: STK Push callbacks for a
+-- checkout request no payment carries and paybill confirmations whose
+-- account reference matches no assessment. Daraja is only acknowledged once
+-- the result is stored here, so money that has moved is never only in a log.
+-- reference is the CheckoutRequestID of an STK callback or the TransID of a
+-- paybill payment; Daraja's retries of the same result are stored once.
+--
+-- Paid entries wait as 'unmatched' until a person matches them to a payment
+-- or assessment, or ignores them. Unpaid STK results are stored as 'ignored'
+-- since no money moved. The county is unknown until an entry is matched.
+CREATE TABLE IF NOT EXISTS mpesa_suspense (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    county_id INTEGER REFERENCES counties(id) ON DELETE RESTRICT,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('stk_callback', 'c2b')),
+    reference TEXT NOT NULL,
+    result_code INTEGER NOT NULL DEFAULT 0,
+    result_desc TEXT,
+    mpesa_receipt_number TEXT,
+    amount DECIMAL(15,2) CHECK (amount > 0),
+    phone_number TEXT,
+    account_reference TEXT,
+    payer_name TEXT,
+    transaction_date TIMESTAMP WITH TIME ZONE,
+    status VARCHAR(20) NOT NULL DEFAULT 'unmatched' CHECK (status IN ('unmatched', 'matched', 'ignored')),
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (source, reference)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mpesa_suspense_queue ON mpesa_suspense(created_at) WHERE status = 'unmatched';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_sessions_previous_refresh_token_hash;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS previous_refresh_token_hash;
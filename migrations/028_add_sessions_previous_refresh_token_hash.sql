@@ -0,0 +1,5 @@
+-- Keep the refresh token a session was last rotated away from, so a client
+-- presenting it again is recognised as token reuse
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS previous_refresh_token_hash VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_previous_refresh_token_hash ON sessions(previous_refresh_token_hash);
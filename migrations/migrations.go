@@ -0,0 +1,9 @@
+// Package migrations embeds the SQL schema migrations so the server binary can
+// apply them without the source tree. Each NNN_name.sql file has a matching
+// NNN_name.down.sql that reverses it.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS